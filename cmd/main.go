@@ -1,24 +1,100 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/varnit-ta/PlacementLog/cmd/server"
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-const port = ":8080"
-
 func main() {
-	app, err := server.InitApp()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
 
+func run() error {
+	cfg, err := config.Load("")
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	// Traces are exported only when an OTLP collector is configured.
+	var exporter sdktrace.SpanExporter
+	if cfg.Telemetry.OTLPEndpoint != "" {
+		exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.Telemetry.OTLPEndpoint))
+		if err != nil {
+			return fmt.Errorf("error creating trace exporter: %v", err)
+		}
+		exporter = exp
+	}
+
+	shutdownTracing := telemetry.InitTracing(exporter)
+
+	app, err := server.InitApp(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           app.Routes(),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
-	log.Println("Starting Server...")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Starting Server...")
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		app.Close()
+		return fmt.Errorf("error starting the server: %v", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal during the drain kills the process immediately.
+	stop()
+
+	log.Println("Shutting down, draining in-flight requests...")
+	app.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
 
-	if err = http.ListenAndServe(port, app.Routes()); err != nil {
-		log.Fatalf("error starting the server: %v\n", err)
+	if err = srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down the server: %v\n", err)
 	}
+
+	if err = app.Close(); err != nil {
+		log.Printf("error closing the database: %v\n", err)
+	}
+
+	if err = shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("error flushing traces: %v\n", err)
+	}
+
+	log.Println("Server stopped")
+
+	return nil
 }
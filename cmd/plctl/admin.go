@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	adminauth "github.com/varnit-ta/PlacementLog/internal/adminAuth"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/twofactor"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+)
+
+// generatedPasswordLength is the length of passwords generated for admins.
+const generatedPasswordLength = 20
+
+// passwordAlphabet leaves out characters that are easily confused, such as l, 1, O and 0.
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789-_.!"
+
+/*
+admin runs the admin subcommands.
+*/
+func (c *cli) admin(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: plctl admin create|reset-password|list|disable|enable|reset-2fa")
+	}
+
+	ctx := context.Background()
+
+	service, policy, err := c.adminService()
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("plctl admin "+args[0], flag.ContinueOnError)
+	username := flags.String("username", "", "the admin's username")
+	super := flags.Bool("super", false, "create a super admin, who can register other admins")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of standard input")
+
+	if err = flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] != "list" && *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+
+	switch args[0] {
+	case "create":
+		role := db.AdminRoleAdmin
+		if *super {
+			role = db.AdminRoleSuper
+		}
+
+		pass, generated, err := c.readPassword(*passwordStdin, *username, policy)
+		if err != nil {
+			return err
+		}
+
+		admin, err := service.CreateAdmin(ctx, *username, pass, role)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "created %s %s (%s)\n", admin.Role, admin.Username, admin.ID)
+		c.printGenerated(generated, pass)
+		return nil
+
+	case "reset-password":
+		pass, generated, err := c.readPassword(*passwordStdin, *username, policy)
+		if err != nil {
+			return err
+		}
+
+		if err = service.ResetPassword(ctx, *username, pass); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "password of %s reset\n", *username)
+		c.printGenerated(generated, pass)
+		return nil
+
+	case "list":
+		admins, err := service.ListAdmins(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "USERNAME\tROLE\tSTATUS\tCREATED\tID")
+		for _, a := range admins {
+			status := "enabled"
+			if a.Disabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.Username, a.Role, status, a.CreatedAt, a.ID)
+		}
+		return tw.Flush()
+
+	case "disable", "enable":
+		if err = service.SetDisabled(ctx, *username, args[0] == "disable"); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "%s %sd\n", *username, args[0])
+		return nil
+
+	case "reset-2fa":
+		admin, err := service.GetAdminByUsername(ctx, *username)
+		if err != nil {
+			return err
+		}
+
+		if err = twofactor.NewTwoFactorRepo(c.conn.DB).Disable(ctx, auth.RoleAdmin, admin.ID); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "two-factor authentication of %s removed; it is set up again at the next login\n", *username)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown admin command %q", args[0])
+	}
+}
+
+/*
+adminService builds the admin service and password policy from the
+configuration. The service issues no tokens, so only the account management
+methods may be used.
+*/
+func (c *cli) adminService() (*adminauth.AdminService, *password.Policy, error) {
+	policy, err := password.NewPolicy(c.cfg.Password.MinLength, c.cfg.Password.MinClasses, c.cfg.Password.BlocklistFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasher := password.NewHasher(password.Params{
+		Memory:      uint32(c.cfg.Password.Argon2Memory),
+		Iterations:  uint32(c.cfg.Password.Argon2Iterations),
+		Parallelism: uint8(c.cfg.Password.Argon2Parallelism),
+	})
+
+	return adminauth.NewAdminService(c.newAdminRepo(c.sqlDB(), hasher), nil, nil, policy), policy, nil
+}
+
+/*
+readPassword reads the password from standard input, or generates one that
+satisfies the password policy.
+*/
+func (c *cli) readPassword(fromStdin bool, username string, policy *password.Policy) (string, bool, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(c.in).ReadString('\n')
+		if err != nil && line == "" {
+			return "", false, fmt.Errorf("error reading password from standard input: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), false, nil
+	}
+
+	for attempt := 0; attempt < 100; attempt++ {
+		pass, err := generatePassword(generatedPasswordLength)
+		if err != nil {
+			return "", false, err
+		}
+		if policy.Check(pass, username) == nil {
+			return pass, true, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("cannot generate a password that satisfies the password policy; use -password-stdin")
+}
+
+func (c *cli) printGenerated(generated bool, pass string) {
+	if generated {
+		fmt.Fprintf(c.out, "generated password (shown once): %s\n", pass)
+	}
+}
+
+func generatePassword(length int) (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(passwordAlphabet)))
+
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error generating password: %v", err)
+		}
+		b.WriteByte(passwordAlphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
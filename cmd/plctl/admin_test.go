@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	adminauth "github.com/varnit-ta/PlacementLog/internal/adminAuth"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+)
+
+// fakeAdminRepo keeps admins in memory and records the passwords it stores.
+type fakeAdminRepo struct {
+	admins    []db.Admin
+	passwords map[string]string
+}
+
+func (f *fakeAdminRepo) find(username string) *db.Admin {
+	for i := range f.admins {
+		if f.admins[i].Username == username {
+			return &f.admins[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeAdminRepo) setPassword(username, pass string) {
+	if f.passwords == nil {
+		f.passwords = map[string]string{}
+	}
+	f.passwords[username] = pass
+}
+
+func (f *fakeAdminRepo) Login(ctx context.Context, username, password string) (*db.Admin, error) {
+	return nil, adminauth.ErrAdminNotFound
+}
+func (f *fakeAdminRepo) Register(ctx context.Context, username, password, role string) (*db.Admin, error) {
+	f.admins = append(f.admins, db.Admin{ID: "admin-" + username, Username: username, Role: role})
+	f.setPassword(username, password)
+	return f.find(username), nil
+}
+func (f *fakeAdminRepo) GetAdmin(ctx context.Context, id string) (*db.Admin, error) {
+	return nil, adminauth.ErrAdminNotFound
+}
+func (f *fakeAdminRepo) GetAdminByUsername(ctx context.Context, username string) (*db.Admin, error) {
+	if a := f.find(username); a != nil {
+		return a, nil
+	}
+	return nil, adminauth.ErrAdminNotFound
+}
+func (f *fakeAdminRepo) ListAdmins(ctx context.Context) ([]db.Admin, error) {
+	return f.admins, nil
+}
+func (f *fakeAdminRepo) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	a := f.find(username)
+	if a == nil {
+		return adminauth.ErrAdminNotFound
+	}
+	a.Disabled = disabled
+	return nil
+}
+func (f *fakeAdminRepo) UpdatePassword(ctx context.Context, username, password string) error {
+	if f.find(username) == nil {
+		return adminauth.ErrAdminNotFound
+	}
+	f.setPassword(username, password)
+	return nil
+}
+
+func TestReadPassword(t *testing.T) {
+	policy, err := password.NewPolicy(12, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("stdin", func(t *testing.T) {
+		c, _ := newTestCLI(strings.NewReader("Correct-Horse-9\r\nignored\n"), nil)
+		pass, generated, err := c.readPassword(true, "root", policy)
+		if err != nil || generated || pass != "Correct-Horse-9" {
+			t.Errorf("expected the first line, got %q %v %v", pass, generated, err)
+		}
+	})
+
+	t.Run("stdin without newline", func(t *testing.T) {
+		c, _ := newTestCLI(strings.NewReader("Correct-Horse-9"), nil)
+		if pass, _, err := c.readPassword(true, "root", policy); err != nil || pass != "Correct-Horse-9" {
+			t.Errorf("expected the whole input, got %q %v", pass, err)
+		}
+	})
+
+	t.Run("empty stdin", func(t *testing.T) {
+		c, _ := newTestCLI(strings.NewReader(""), nil)
+		if _, _, err := c.readPassword(true, "root", policy); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		c, _ := newTestCLI(strings.NewReader(""), nil)
+		pass, generated, err := c.readPassword(false, "root", policy)
+		if err != nil || !generated {
+			t.Fatalf("expected a generated password, got %v %v", generated, err)
+		}
+		if len(pass) != generatedPasswordLength || policy.Check(pass, "root") != nil {
+			t.Errorf("generated password %q does not satisfy the policy", pass)
+		}
+		for _, r := range pass {
+			if !strings.ContainsRune(passwordAlphabet, r) {
+				t.Errorf("generated password %q has %q outside the alphabet", pass, r)
+			}
+		}
+	})
+}
+
+func TestAdmin_Create(t *testing.T) {
+	repo := &fakeAdminRepo{}
+
+	c, out := newTestCLI(strings.NewReader("Correct-Horse-9\n"), repo)
+	if err := c.run([]string{"admin", "create", "-username", "root", "-super", "-password-stdin"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if a := repo.find("root"); a == nil || a.Role != db.AdminRoleSuper || repo.passwords["root"] != "Correct-Horse-9" {
+		t.Fatalf("expected super admin root with the given password, got %+v", repo.admins)
+	}
+	if strings.Contains(out.String(), "generated password") {
+		t.Errorf("expected no generated password, got %q", out.String())
+	}
+
+	c, out = newTestCLI(strings.NewReader(""), repo)
+	if err := c.run([]string{"admin", "create", "-username", "mod"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if a := repo.find("mod"); a == nil || a.Role != db.AdminRoleAdmin {
+		t.Fatalf("expected admin mod, got %+v", repo.admins)
+	}
+	if !strings.Contains(out.String(), "generated password (shown once): "+repo.passwords["mod"]+"\n") {
+		t.Errorf("expected the generated password to be printed, got %q", out.String())
+	}
+
+	c, _ = newTestCLI(strings.NewReader("short\n"), repo)
+	if err := c.run([]string{"admin", "create", "-username", "weak", "-password-stdin"}); err == nil || repo.find("weak") != nil {
+		t.Errorf("expected a weak password to be refused, got %v", err)
+	}
+}
+
+func TestAdmin_DisableKeepsASuperAdmin(t *testing.T) {
+	repo := &fakeAdminRepo{admins: []db.Admin{
+		{Username: "root", Role: db.AdminRoleSuper},
+		{Username: "old", Role: db.AdminRoleSuper, Disabled: true},
+		{Username: "mod", Role: db.AdminRoleAdmin},
+	}}
+
+	c, _ := newTestCLI(strings.NewReader(""), repo)
+	if err := c.run([]string{"admin", "disable", "-username", "root"}); err == nil || repo.find("root").Disabled {
+		t.Fatalf("expected disabling the last enabled super admin to fail, got %v", err)
+	}
+
+	c, out := newTestCLI(strings.NewReader(""), repo)
+	if err := c.run([]string{"admin", "disable", "-username", "mod"}); err != nil || !repo.find("mod").Disabled {
+		t.Fatalf("expected mod to be disabled, got %v", err)
+	}
+	if out.String() != "mod disabled\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	c, _ = newTestCLI(strings.NewReader(""), repo)
+	if err := c.run([]string{"admin", "enable", "-username", "old"}); err != nil || repo.find("old").Disabled {
+		t.Fatalf("expected old to be enabled, got %v", err)
+	}
+
+	c, _ = newTestCLI(strings.NewReader(""), repo)
+	if err := c.run([]string{"admin", "disable", "-username", "root"}); err != nil || !repo.find("root").Disabled {
+		t.Errorf("expected root to be disabled once old is enabled, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+plctl is the PlacementLog maintenance CLI. It works directly against the
+configured database, using the same configuration as the server (the
+CONFIG_FILE YAML file, .env and environment variables).
+
+Usage:
+
+	plctl [-config file] <command> [arguments]
+
+Commands:
+
+	admin create -username name [-super] [-password-stdin]
+	admin reset-password -username name [-password-stdin]
+	admin list
+	admin disable -username name
+	admin enable -username name
+	admin reset-2fa -username name
+	migrate [status]
+	roster import file.csv
+	users purge
+
+Without -password-stdin a random password is generated and printed once.
+*/
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	adminauth "github.com/varnit-ta/PlacementLog/internal/adminAuth"
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+)
+
+const usage = `usage: plctl [-config file] <command> [arguments]
+
+commands:
+  admin create -username name [-super] [-password-stdin]
+                                  create an admin; -super allows it to register other admins
+  admin reset-password -username name [-password-stdin]
+                                  set a new password for an admin
+  admin list                      list admins
+  admin disable -username name    block an admin from logging in
+  admin enable -username name     allow a disabled admin to log in again
+  admin reset-2fa -username name  remove an admin's two-factor enrollment
+  migrate                         apply pending database migrations
+  migrate status                  list pending database migrations
+  roster import file.csv          import the student roster
+  users purge                     delete the accounts whose deletion cooling-off has ended
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "plctl:", err)
+		os.Exit(1)
+	}
+}
+
+/*
+cli carries the loaded configuration and database connection to the
+subcommands. The connect, pendingMigrations and newAdminRepo hooks default
+to the configured database; tests replace them.
+*/
+type cli struct {
+	cfg  *config.Config
+	conn *db.DB
+	in   io.Reader
+	out  io.Writer
+
+	connect           func(configFile string) (*config.Config, *db.DB, error)
+	pendingMigrations func(ctx context.Context, conn *sql.DB) ([]string, error)
+	newAdminRepo      func(conn *sql.DB, hasher *password.Hasher) adminauth.AdminRepository
+}
+
+func newCLI(in io.Reader, out io.Writer) *cli {
+	return &cli{
+		in:                in,
+		out:               out,
+		connect:           connect,
+		pendingMigrations: db.PendingMigrations,
+		newAdminRepo: func(conn *sql.DB, hasher *password.Hasher) adminauth.AdminRepository {
+			return adminauth.NewAdminRepo(conn, hasher)
+		},
+	}
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	return newCLI(in, out).run(args)
+}
+
+/*
+connect loads the configuration and opens the database it names.
+*/
+func connect(configFile string) (*config.Config, *db.DB, error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := db.InitDatabse(cfg.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, conn, nil
+}
+
+/*
+run parses the global flags, connects to the database and runs the command.
+*/
+func (c *cli) run(args []string) error {
+	flags := flag.NewFlagSet("plctl", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	configFile := flags.String("config", "", "path to the YAML config file (defaults to $CONFIG_FILE)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("no command given")
+	}
+
+	commands := map[string]func(*cli, []string) error{
+		"admin":   (*cli).admin,
+		"migrate": (*cli).migrate,
+		"roster":  (*cli).roster,
+		"users":   (*cli).users,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	cfg, conn, err := c.connect(*configFile)
+	if err != nil {
+		return err
+	}
+	if conn != nil {
+		defer conn.Close()
+	}
+
+	c.cfg, c.conn = cfg, conn
+
+	// Every command except migrate needs the current schema.
+	if args[0] != "migrate" {
+		pending, err := c.pendingMigrations(context.Background(), c.sqlDB())
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database has %d pending migrations; run plctl migrate first", len(pending))
+		}
+	}
+
+	return command(c, args[1:])
+}
+
+// sqlDB returns the database handle, or nil when there is no connection.
+func (c *cli) sqlDB() *sql.DB {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.DB
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+
+	adminauth "github.com/varnit-ta/PlacementLog/internal/adminAuth"
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+)
+
+/*
+newTestCLI returns a cli that connects to no database: it reports the given
+pending migrations and keeps admins in repo.
+*/
+func newTestCLI(in io.Reader, repo *fakeAdminRepo, pending ...string) (*cli, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	c := newCLI(in, out)
+
+	c.connect = func(configFile string) (*config.Config, *db.DB, error) {
+		cfg := &config.Config{}
+		cfg.Password.MinLength = 12
+		cfg.Password.MinClasses = 3
+		return cfg, nil, nil
+	}
+	c.pendingMigrations = func(ctx context.Context, conn *sql.DB) ([]string, error) {
+		return pending, nil
+	}
+	c.newAdminRepo = func(conn *sql.DB, hasher *password.Hasher) adminauth.AdminRepository {
+		return repo
+	}
+
+	return c, out
+}
+
+func TestRun_Arguments(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no command", nil, "no command given"},
+		{"unknown command", []string{"frobnicate"}, `unknown command "frobnicate"`},
+		{"unknown flag", []string{"-verbose", "admin", "list"}, "flag provided but not defined: -verbose"},
+		{"admin without subcommand", []string{"admin"}, "usage: plctl admin"},
+		{"unknown admin command", []string{"admin", "promote", "-username", "root"}, `unknown admin command "promote"`},
+		{"admin without username", []string{"admin", "disable"}, "-username is required"},
+		{"migrate with extra arguments", []string{"migrate", "down"}, "usage: plctl migrate [status]"},
+		{"roster without file", []string{"roster", "import"}, "usage: plctl roster import file.csv"},
+		{"users without purge", []string{"users", "delete"}, "usage: plctl users purge"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cli, _ := newTestCLI(strings.NewReader(""), &fakeAdminRepo{})
+			err := cli.run(c.args)
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRun_PendingMigrations(t *testing.T) {
+	repo := &fakeAdminRepo{admins: []db.Admin{{Username: "root", Role: db.AdminRoleSuper}}}
+
+	c, _ := newTestCLI(strings.NewReader(""), repo, "0007_sessions.sql")
+	err := c.run([]string{"admin", "list"})
+	if err == nil || !strings.Contains(err.Error(), "1 pending migrations; run plctl migrate first") {
+		t.Fatalf("expected the command to be refused, got %v", err)
+	}
+
+	c, out := newTestCLI(strings.NewReader(""), repo, "0007_sessions.sql")
+	if err = c.run([]string{"migrate", "status"}); err != nil {
+		t.Fatalf("expected migrate status to run, got %v", err)
+	}
+	if out.String() != "pending 0007_sessions.sql\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	c, out = newTestCLI(strings.NewReader(""), repo)
+	if err = c.run([]string{"admin", "list"}); err != nil {
+		t.Fatalf("expected admin list to run, got %v", err)
+	}
+	if !strings.Contains(out.String(), "root") {
+		t.Errorf("expected root to be listed, got %q", out.String())
+	}
+}
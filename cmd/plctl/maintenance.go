@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/privacy"
+	"github.com/varnit-ta/PlacementLog/internal/roster"
+)
+
+/*
+migrate applies the pending migrations, or lists them with "status".
+*/
+func (c *cli) migrate(args []string) error {
+	ctx := context.Background()
+
+	pending, err := c.pendingMigrations(ctx, c.sqlDB())
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 && args[0] == "status" {
+		if len(pending) == 0 {
+			fmt.Fprintln(c.out, "database is up to date")
+		}
+		for _, version := range pending {
+			fmt.Fprintln(c.out, "pending", version)
+		}
+		return nil
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("usage: plctl migrate [status]")
+	}
+
+	if err = db.Migrate(ctx, c.conn.DB); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "applied %d migrations\n", len(pending))
+	return nil
+}
+
+/*
+roster imports the student roster from a CSV file, as the
+/admin/roster/import endpoint does.
+*/
+func (c *cli) roster(args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return fmt.Errorf("usage: plctl roster import file.csv")
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return fmt.Errorf("error opening roster: %v", err)
+	}
+	defer f.Close()
+
+	n, err := roster.NewRosterService(roster.NewRosterRepo(c.conn.DB)).ImportCSV(context.Background(), f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "imported %d roster entries\n", n)
+	return nil
+}
+
+/*
+users purges the accounts whose deletion cooling-off period has ended, for
+deployments that run the purge from cron with
+ACCOUNT_DELETION_PURGE_INTERVAL=0.
+*/
+func (c *cli) users(args []string) error {
+	if len(args) != 1 || args[0] != "purge" {
+		return fmt.Errorf("usage: plctl users purge")
+	}
+
+	// The server's response cache lives in its own process; cached post
+	// listings naming purged authors expire after CACHE_TTL.
+	srv := privacy.NewPrivacyService(privacy.NewPrivacyRepo(c.conn.DB), nil, nil, c.cfg.Account, nil)
+
+	n, err := srv.PurgeDue(context.Background())
+	fmt.Fprintf(c.out, "purged %d accounts\n", n)
+	return err
+}
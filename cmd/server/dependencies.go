@@ -1,101 +1,488 @@
 package server
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	adminauth "github.com/varnit-ta/PlacementLog/internal/adminAuth"
+	"github.com/varnit-ta/PlacementLog/internal/apikeys"
+	"github.com/varnit-ta/PlacementLog/internal/config"
 	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/health"
+	"github.com/varnit-ta/PlacementLog/internal/impersonation"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
 	placements "github.com/varnit-ta/PlacementLog/internal/placements"
 	"github.com/varnit-ta/PlacementLog/internal/posts"
+	"github.com/varnit-ta/PlacementLog/internal/privacy"
+	"github.com/varnit-ta/PlacementLog/internal/profile"
+	"github.com/varnit-ta/PlacementLog/internal/roster"
+	"github.com/varnit-ta/PlacementLog/internal/sso"
+	"github.com/varnit-ta/PlacementLog/internal/stats"
+	"github.com/varnit-ta/PlacementLog/internal/twofactor"
 	userauth "github.com/varnit-ta/PlacementLog/internal/userAuth"
+	"github.com/varnit-ta/PlacementLog/internal/users"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
 	"github.com/varnit-ta/PlacementLog/pkg/middleware"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+	"github.com/varnit-ta/PlacementLog/pkg/ratelimit"
+	"github.com/varnit-ta/PlacementLog/pkg/session"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 type App struct {
-	userAuthHandler   *userauth.UserAuthHandler
-	postHandler       *posts.PostsHandler
-	adminHandler      *adminauth.AdminAuthHandler
-	placementsHandler *placements.PlacementsHandler
+	cfg                  *config.Config
+	db                   *db.DB
+	tokens               *jwt.Manager
+	sessions             *session.Manager
+	rateLimits           ratelimit.Store
+	healthHandler        *health.HealthHandler
+	userAuthHandler      *userauth.UserAuthHandler
+	postHandler          *posts.PostsHandler
+	adminService         *adminauth.AdminService
+	adminHandler         *adminauth.AdminAuthHandler
+	placementsHandler    *placements.PlacementsHandler
+	loginGuardHandler    *loginguard.LoginGuardHandler
+	rosterHandler        *roster.RosterHandler
+	twoFactorHandler     *twofactor.TwoFactorHandler
+	ssoHandler           *sso.SSOHandler
+	profileHandler       *profile.ProfileHandler
+	privacyHandler       *privacy.PrivacyHandler
+	usersService         *users.UsersService
+	usersHandler         *users.UsersHandler
+	apiKeysService       *apikeys.APIKeysService
+	apiKeysHandler       *apikeys.APIKeysHandler
+	statsHandler         *stats.StatsHandler
+	impersonationService *impersonation.ImpersonationService
+	impersonationHandler *impersonation.ImpersonationHandler
+	stopPurger           context.CancelFunc
 }
 
-func InitApp() (*App, error) {
-	db, err := db.InitDatabse()
+func InitApp(cfg *config.Config) (*App, error) {
+	tokens, err := newTokenManager(cfg.JWT)
 
 	if err != nil {
 		return nil, err
 	}
 
-	userAuthRepo := userauth.NewUserAuthRepo(db)
-	userAuthService := userauth.NewUserAuthService(userAuthRepo)
-	userAuthHandler := userauth.NewUserAuthHandler(userAuthService)
+	mailer, err := newMailSender(cfg.Mail)
 
-	postRepo := posts.NewPostsRepo(db)
-	postService := posts.NewPostsService(postRepo)
-	postHandler := posts.NewPostsHandler(postService)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := password.NewPolicy(cfg.Password.MinLength, cfg.Password.MinClasses, cfg.Password.BlocklistFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.InitDatabse(cfg.Database)
 
-	adminRepo := adminauth.NewAdminRepo(db)
-	adminService := adminauth.NewAdminService(adminRepo)
-	adminHandler := adminauth.NewAdminAuthHandler(adminService)
+	if err != nil {
+		return nil, err
+	}
 
-	placementsRepo := placements.NewPlacementsRepo(db)
-	placementsService := placements.NewPlacementsService(placementsRepo)
-	placementsHandler := placements.NewPlacementsHandler(placementsService)
+	if err = db.Migrate(context.Background(), conn.DB); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err = telemetry.RegisterDBStats(conn.DB, "primary"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if replica := conn.Replica(); replica != nil {
+		if err = telemetry.RegisterDBStats(replica, "replica"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	// Cookie sessions are optional; a nil manager keeps bearer tokens only.
+	var sessions *session.Manager
+	if cfg.Session.Cookies {
+		sessions = session.NewManager(tokens, session.Options{
+			CookieName:     cfg.Session.CookieName,
+			CSRFCookieName: cfg.Session.CSRFCookieName,
+			Domain:         cfg.Session.CookieDomain,
+			Secure:         cfg.Session.Secure,
+			SameSite:       session.ParseSameSite(cfg.Session.SameSite),
+		})
+	}
+
+	healthHandler := health.NewHealthHandler(conn, func(ctx context.Context) ([]string, error) {
+		return db.PendingMigrations(ctx, conn.DB)
+	})
+
+	responseCache := cache.New(cache.NewMemoryStore(), cfg.Cache.TTL)
+
+	var rateLimits ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimits = ratelimit.NewPostgresStore(conn.DB)
+	}
+
+	loginEventsRepo := loginguard.NewLoginEventsRepo(conn.DB)
+	loginGuard := loginguard.NewLoginGuard(loginguard.NewPostgresStore(conn.DB), loginEventsRepo, cfg.Login)
+	loginGuardHandler := loginguard.NewLoginGuardHandler(loginGuard)
+
+	hasher := password.NewHasher(password.Params{
+		Memory:      uint32(cfg.Password.Argon2Memory),
+		Iterations:  uint32(cfg.Password.Argon2Iterations),
+		Parallelism: uint8(cfg.Password.Argon2Parallelism),
+	})
+
+	twoFactorRepo := twofactor.NewTwoFactorRepo(conn.DB)
+	twoFactorService := twofactor.NewTwoFactorService(twoFactorRepo, tokens, loginGuard, cfg.TwoFactor)
+	twoFactorHandler := twofactor.NewTwoFactorHandler(twoFactorService, sessions)
+
+	userAuthRepo := userauth.NewUserAuthRepo(conn.DB, hasher)
+	rosterRepo := roster.NewRosterRepo(conn.DB)
+	rosterService := roster.NewRosterService(rosterRepo)
+	rosterHandler := roster.NewRosterHandler(rosterService)
+
+	userAuthService := userauth.NewUserAuthService(userAuthRepo, twoFactorService, loginGuard, mailer, rosterRepo, passwordPolicy, cfg.Account)
+	userAuthHandler := userauth.NewUserAuthHandler(userAuthService, sessions)
+
+	ssoRepo := sso.NewSSORepo(conn.DB)
+	ssoService := sso.NewSSOService(ssoRepo, twoFactorService, rosterRepo, userAuthRepo, cfg.SSO, cfg.Account)
+	ssoHandler := sso.NewSSOHandler(ssoService, sessions, sso.CookieOptions{
+		Domain:   cfg.Session.CookieDomain,
+		Secure:   cfg.Session.Secure,
+		SameSite: session.ParseSameSite(cfg.Session.SameSite),
+	})
+
+	usersRepo := users.NewUsersRepo(conn.DB)
+	usersService := users.NewUsersService(usersRepo, userAuthService)
+	usersHandler := users.NewUsersHandler(usersService)
+
+	postRepo := posts.NewPostsRepo(conn)
+	postService := posts.NewPostsService(postRepo, responseCache)
+	postHandler := posts.NewPostsHandler(postService, responseCache)
+
+	profileRepo := profile.NewProfileRepo(conn)
+	profileService := profile.NewProfileService(profileRepo, userAuthService, cfg.Profile, postService.InvalidateApproved)
+	profileHandler := profile.NewProfileHandler(profileService)
+
+	privacyRepo := privacy.NewPrivacyRepo(conn.DB)
+	privacyService := privacy.NewPrivacyService(privacyRepo, userAuthService, mailer, cfg.Account, postService.InvalidateApproved)
+	privacyHandler := privacy.NewPrivacyHandler(privacyService)
+
+	adminRepo := adminauth.NewAdminRepo(conn.DB, hasher)
+	adminService := adminauth.NewAdminService(adminRepo, twoFactorService, loginGuard, passwordPolicy)
+	adminHandler := adminauth.NewAdminAuthHandler(adminService, sessions)
+
+	placementsRepo := placements.NewPlacementsRepo(conn)
+	placementsService := placements.NewPlacementsService(placementsRepo, responseCache)
+	placementsHandler := placements.NewPlacementsHandler(placementsService, responseCache)
+
+	apiKeysRepo := apikeys.NewAPIKeysRepo(conn.DB)
+	apiKeysService := apikeys.NewAPIKeysService(apiKeysRepo, cfg.APIKeys)
+	apiKeysHandler := apikeys.NewAPIKeysHandler(apiKeysService)
+
+	statsRepo := stats.NewStatsRepo(conn)
+	statsService := stats.NewStatsService(statsRepo)
+	statsHandler := stats.NewStatsHandler(statsService)
+
+	impersonationRepo := impersonation.NewImpersonationRepo(conn.DB)
+	impersonationService := impersonation.NewImpersonationService(impersonationRepo, tokens, cfg.JWT.ImpersonationTokenTTL)
+	impersonationHandler := impersonation.NewImpersonationHandler(impersonationService)
+
+	// Accounts whose deletion cooling-off has ended are purged in the
+	// background unless the interval is 0 (e.g. when plctl runs from cron).
+	stopPurger := func() {}
+	if cfg.Account.DeletionPurgeInterval > 0 {
+		var purgeCtx context.Context
+		purgeCtx, stopPurger = context.WithCancel(context.Background())
+		go privacyService.RunPurger(purgeCtx, cfg.Account.DeletionPurgeInterval)
+	}
 
 	return &App{
-		userAuthHandler:   userAuthHandler,
-		postHandler:       postHandler,
-		adminHandler:      adminHandler,
-		placementsHandler: placementsHandler,
+		cfg:                  cfg,
+		db:                   conn,
+		tokens:               tokens,
+		sessions:             sessions,
+		rateLimits:           rateLimits,
+		healthHandler:        healthHandler,
+		userAuthHandler:      userAuthHandler,
+		postHandler:          postHandler,
+		adminService:         adminService,
+		adminHandler:         adminHandler,
+		placementsHandler:    placementsHandler,
+		loginGuardHandler:    loginGuardHandler,
+		rosterHandler:        rosterHandler,
+		twoFactorHandler:     twoFactorHandler,
+		ssoHandler:           ssoHandler,
+		profileHandler:       profileHandler,
+		privacyHandler:       privacyHandler,
+		usersService:         usersService,
+		usersHandler:         usersHandler,
+		apiKeysService:       apiKeysService,
+		apiKeysHandler:       apiKeysHandler,
+		statsHandler:         statsHandler,
+		impersonationService: impersonationService,
+		impersonationHandler: impersonationHandler,
+		stopPurger:           stopPurger,
 	}, nil
 }
 
+/*
+newTokenManager loads the configured signing keys from their PEM files and
+builds the token manager.
+*/
+func newTokenManager(cfg config.JWTConfig) (*jwt.Manager, error) {
+	var keys []*jwt.Key
+
+	for _, k := range cfg.Keys {
+		key, err := jwt.LoadKey(jwt.KeyOptions{
+			ID:        k.ID,
+			Algorithm: k.Algorithm,
+			File:      k.File,
+			NotBefore: k.NotBefore,
+		})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return jwt.NewManager(jwt.Options{
+		Secret:        []byte(cfg.Secret),
+		Keys:          keys,
+		RotationGrace: cfg.RotationGrace,
+		AcceptHS256:   cfg.AcceptHS256,
+		UserTTL:       cfg.UserTokenTTL,
+		AdminTTL:      cfg.AdminTokenTTL,
+	})
+}
+
+/*
+newMailSender creates the mail sender selected by the configured driver.
+*/
+func newMailSender(cfg config.MailConfig) (mail.Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mail.NewSMTPSender(mail.SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		})
+	case "file":
+		return mail.NewFileSender(cfg.Dir, cfg.From)
+	default:
+		return mail.NewLogSender(cfg.From), nil
+	}
+}
+
 func (a App) Routes() http.Handler {
 	r := chi.NewRouter()
 
+	if a.cfg.Server.TrustForwardedFor {
+		r.Use(middleware.TrustedProxy)
+	}
+
+	r.Use(telemetry.Middleware)
+	r.Use(middleware.MaxBodyBytes(a.cfg.Server.MaxBodyBytes))
+
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-User-ID"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins:   a.cfg.CORS.AllowedOrigins,
+		AllowedMethods:   a.cfg.CORS.AllowedMethods,
+		AllowedHeaders:   a.cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   a.cfg.CORS.ExposedHeaders,
+		AllowCredentials: a.cfg.CORS.AllowCredentials,
+		MaxAge:           int(a.cfg.CORS.MaxAge.Seconds()),
 	}))
 
-	// Public routes (no authentication required)
+	r.Use(middleware.SecurityHeaders(middleware.SecurityHeaderOptions{
+		HSTS:                      a.cfg.Security.HSTS,
+		ContentSecurityPolicy:     a.cfg.Security.ContentSecurityPolicy,
+		ReferrerPolicy:            a.cfg.Security.ReferrerPolicy,
+		FrameOptions:              a.cfg.Security.FrameOptions,
+		CrossOriginResourcePolicy: a.cfg.Security.CrossOriginResourcePolicy,
+	}))
+
+	// In cookie session mode the session cookie stands in for the bearer
+	// token of every authenticated route, guarded by the CSRF check.
+	if a.sessions != nil {
+		r.Use(a.sessions.Middleware)
+	}
+
+	// API keys are only admitted where a route accepts them: RequireScope
+	// on public routes and PermissionMiddleware on protected ones.
+	r.Use(middleware.APIKeyMiddleware(a.apiKeysService))
+
+	// Requests made with impersonation tokens are audited, and refused
+	// unless they only read when the token is read-only.
+	r.Use(middleware.ImpersonationMiddleware(a.tokens, a.impersonationService, a.impersonationService))
+
+	// Operational endpoints
+	r.Get("/healthz", a.healthHandler.Healthz)
+	r.Get("/readyz", a.healthHandler.Readyz)
+	r.Method(http.MethodGet, "/metrics", telemetry.MetricsHandler())
+	r.Get("/.well-known/jwks.json", a.tokens.JWKSHandler)
+
+	// Public routes (no authentication required; a valid token identifies the caller)
 	r.Group(func(r chi.Router) {
-		r.Post("/auth/login", a.userAuthHandler.Login)
-		r.Post("/auth/register", a.userAuthHandler.Register)
-		r.Post("/admin/login", a.adminHandler.Login)
-		r.Get("/placements", a.placementsHandler.GetAllPlacements)
-		r.Get("/placements/company-branch", a.placementsHandler.GetCompanyBranchMap)
-		r.Get("/placements/branch-company", a.placementsHandler.GetBranchCompanyMap)
-		r.Get("/posts", a.postHandler.GetAll)
+		r.Use(middleware.OptionalAuthMiddleware(a.tokens))
+
+		r.Group(func(r chi.Router) {
+			r.Use(a.rateLimit("auth", a.cfg.RateLimit.AuthRequests, a.cfg.RateLimit.AuthWindow))
+
+			r.Post("/auth/login", a.userAuthHandler.Login)
+			r.Post("/auth/register", a.userAuthHandler.Register)
+			r.Post("/admin/login", a.adminHandler.Login)
+			r.Post("/auth/email/verify", a.userAuthHandler.VerifyEmail)
+			r.Post("/auth/password/forgot", a.userAuthHandler.ForgotPassword)
+			r.Post("/auth/password/reset", a.userAuthHandler.ResetPassword)
+			r.Get("/auth/oidc/providers", a.ssoHandler.Providers)
+			r.Post("/auth/oidc/{provider}/start", a.ssoHandler.Start)
+			r.Post("/auth/oidc/{provider}/callback", a.ssoHandler.Callback)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(a.rateLimit("public", a.cfg.RateLimit.PublicRequests, a.cfg.RateLimit.PublicWindow))
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(auth.PermPlacementsRead))
+
+				r.Get("/placements", a.placementsHandler.GetAllPlacements)
+				r.Get("/placements/company-branch", a.placementsHandler.GetCompanyBranchMap)
+				r.Get("/placements/branch-company", a.placementsHandler.GetBranchCompanyMap)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(auth.PermPostsRead))
+
+				r.Get("/posts", a.postHandler.GetAll)
+				r.Get("/posts/{id}", a.postHandler.GetPost)
+			})
+			r.Get("/users/{id}", a.profileHandler.GetAuthor)
+			// Avatars are embedded by the frontend, which may be on another origin.
+			r.With(middleware.HeaderOverrides(map[string]string{
+				"Cross-Origin-Resource-Policy": "cross-origin",
+			})).Get("/users/{id}/avatar", a.profileHandler.GetAvatar)
+		})
+	})
+
+	// Second factor routes (users and admins, including sessions that still
+	// have to verify or set up their second factor)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.SecondFactorMiddleware(a.tokens))
+		r.Use(middleware.DenyImpersonation)
+		r.Use(a.rateLimit("auth", a.cfg.RateLimit.AuthRequests, a.cfg.RateLimit.AuthWindow))
+
+		r.Post("/auth/2fa/enroll", a.twoFactorHandler.Enroll)
+		r.Post("/auth/2fa/confirm", a.twoFactorHandler.Confirm)
+		r.Post("/auth/2fa/verify", a.twoFactorHandler.Verify)
+		r.Post("/auth/2fa/recovery-codes", a.twoFactorHandler.RegenerateRecoveryCodes)
+		r.Post("/auth/2fa/disable", a.twoFactorHandler.Disable)
 	})
 
+	postBodyLimit := middleware.MaxBodyBytes(a.cfg.Server.MaxPostBodyBytes)
+
 	// User authenticated routes
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.UserAuthMiddleware)
+		r.Use(middleware.UserAuthMiddleware(a.tokens, a.usersService))
+		r.Use(a.rateLimit("user", a.cfg.RateLimit.UserRequests, a.cfg.RateLimit.UserWindow))
 
 		r.Post("/auth/logout", a.userAuthHandler.Logout)
-		r.Post("/posts", a.postHandler.AddPost)
-		r.Put("/posts", a.postHandler.UpdatePost)
-		r.Delete("/posts", a.postHandler.DeletePost)
+		r.With(middleware.DenyImpersonation).Post("/auth/email", a.userAuthHandler.AttachEmail)
+		r.With(postBodyLimit).Post("/posts", a.postHandler.AddPost)
+		r.With(postBodyLimit).Put("/posts/{id}", a.postHandler.UpdatePost)
+		r.Delete("/posts/{id}", a.postHandler.DeletePost)
 		r.Get("/posts/user", a.postHandler.GetByUser)
+		r.Get("/users/me", a.profileHandler.GetMe)
+		r.Patch("/users/me", a.profileHandler.UpdateMe)
+		r.With(middleware.DenyImpersonation).Post("/users/me/password", a.userAuthHandler.ChangePassword)
+		r.Get("/users/me/oidc", a.ssoHandler.Identities)
+		r.With(middleware.DenyImpersonation).Post("/users/me/oidc/{provider}/link", a.ssoHandler.Link)
+		r.With(middleware.DenyImpersonation).Delete("/users/me/oidc/{provider}", a.ssoHandler.Unlink)
+		r.Put("/users/me/avatar", a.profileHandler.UploadAvatar)
+		r.Delete("/users/me/avatar", a.profileHandler.DeleteAvatar)
+		r.With(middleware.DenyImpersonation).Get("/users/me/export", a.privacyHandler.Export)
+		r.With(middleware.DenyImpersonation).Post("/users/me/deletion", a.privacyHandler.RequestDeletion)
+		r.With(middleware.DenyImpersonation).Delete("/users/me/deletion", a.privacyHandler.CancelDeletion)
 	})
 
 	// Admin authenticated routes
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.AdminAuthMiddleware)
+		r.Use(middleware.AdminAuthMiddleware(a.tokens, a.adminService))
+		r.Use(a.rateLimit("admin", a.cfg.RateLimit.AdminRequests, a.cfg.RateLimit.AdminWindow))
 
 		r.Post("/admin/logout", a.adminHandler.Logout)
-		r.Post("/admin/register", a.adminHandler.Register)
-		r.Get("/admin/posts", a.postHandler.GetAllPostsForAdmin)
-		r.Put("/admin/posts/review", a.postHandler.ReviewPost)
-		r.Delete("/admin/posts", a.postHandler.DeletePostAsAdmin)
-		r.Post("/admin/placements", a.placementsHandler.AddPlacement)
+
+		// Every other admin route requires the permission of its area.
+		admins := r.With(middleware.RequirePermission(auth.PermAdminsManage))
+		admins.Post("/admin/register", a.adminHandler.Register)
+
+		moderate := r.With(middleware.RequirePermission(auth.PermPostsModerate))
+		moderate.Get("/admin/posts", a.postHandler.GetAllPostsForAdmin)
+		moderate.Put("/admin/posts/{id}/review", a.postHandler.ReviewPost)
+		moderate.Delete("/admin/posts/{id}", a.postHandler.DeletePostAsAdmin)
+
+		r.With(middleware.RequirePermission(auth.PermPlacementsWrite)).Post("/admin/placements", a.placementsHandler.AddPlacement)
+
+		users := r.With(middleware.RequirePermission(auth.PermUsersManage))
+		users.Post("/admin/login-lockouts/unlock", a.loginGuardHandler.Unlock)
+		users.Post("/admin/roster/import", a.rosterHandler.ImportRoster)
+		users.Get("/admin/registrations/pending", a.rosterHandler.GetPendingRegistrations)
+		users.Put("/admin/registrations/review", a.rosterHandler.ReviewRegistration)
+		users.Get("/admin/users", a.usersHandler.ListUsers)
+		users.Get("/admin/users/{id}", a.usersHandler.GetUser)
+		users.Put("/admin/users/{id}/suspension", a.usersHandler.Suspend)
+		users.Delete("/admin/users/{id}/suspension", a.usersHandler.Unsuspend)
+		users.Post("/admin/users/{id}/password-reset", a.usersHandler.ForcePasswordReset)
+		users.Put("/admin/users/{id}/verification", a.usersHandler.SetVerification)
+		users.Post("/admin/users/{id}/impersonation", a.impersonationHandler.Impersonate)
+		users.Get("/admin/impersonations", a.impersonationHandler.ListImpersonations)
+		users.Get("/admin/impersonations/{id}", a.impersonationHandler.GetImpersonation)
+
+		apiKeys := r.With(middleware.RequirePermission(auth.PermAPIKeysManage))
+		apiKeys.Post("/admin/api-keys", a.apiKeysHandler.CreateKey)
+		apiKeys.Get("/admin/api-keys", a.apiKeysHandler.ListKeys)
+		apiKeys.Delete("/admin/api-keys/{id}", a.apiKeysHandler.RevokeKey)
+	})
+
+	// Routes for admins and API keys, gated by permission
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.PermissionMiddleware(a.tokens, a.adminService, auth.PermStatsRead))
+		r.Use(a.rateLimit("admin", a.cfg.RateLimit.AdminRequests, a.cfg.RateLimit.AdminWindow))
+
+		r.Get("/admin/stats", a.statsHandler.GetStats)
 	})
 
 	return r
 }
+
+/*
+rateLimit returns the rate limiting middleware of a route group. It must be
+installed after the group's authentication middleware.
+*/
+func (a App) rateLimit(group string, requests int, window time.Duration) func(http.Handler) http.Handler {
+	return ratelimit.New(a.rateLimits, group, ratelimit.Limit{Requests: requests, Window: window}).Middleware
+}
+
+/*
+SetDraining makes /readyz fail so the load balancer stops routing new
+requests to this instance before the HTTP server is shut down.
+*/
+func (a App) SetDraining() {
+	a.healthHandler.SetDraining()
+}
+
+/*
+Close releases the resources held by the application, stopping the account
+purger and closing the database connection pool. It must only be called after
+the HTTP server has stopped.
+*/
+func (a App) Close() error {
+	a.stopPurger()
+	return a.db.Close()
+}
@@ -1,11 +1,12 @@
 package adminauth
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
 
-	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/session"
 	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
@@ -14,7 +15,8 @@ AdminAuthHandler handles admin authentication HTTP requests.
 Provides endpoints for admin login, registration, and logout.
 */
 type AdminAuthHandler struct {
-	service *AdminService
+	service  *AdminService
+	sessions *session.Manager
 }
 
 /*
@@ -22,12 +24,13 @@ NewAdminAuthHandler creates a new AdminAuthHandler instance with the provided se
 
 Parameters:
 - service: The admin authentication service
+- sessions: Sets the session cookies of logins in cookie session mode (nil when disabled)
 
 Returns:
 - *AdminAuthHandler: A new handler instance
 */
-func NewAdminAuthHandler(service *AdminService) *AdminAuthHandler {
-	return &AdminAuthHandler{service: service}
+func NewAdminAuthHandler(service *AdminService, sessions *session.Manager) *AdminAuthHandler {
+	return &AdminAuthHandler{service: service, sessions: sessions}
 }
 
 /*
@@ -38,6 +41,15 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+/*
+registerRequest represents the JSON payload for admin registration requests.
+*/
+type registerRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
 /*
 responsePayload represents the JSON response for successful admin authentication.
 Returns both userid and username for better admin identification.
@@ -45,7 +57,10 @@ Returns both userid and username for better admin identification.
 type responsePayload struct {
 	UserID   string `json:"userid"`
 	Username string `json:"username"`
-	Token    string `json:"token"`
+	Token    string `json:"token,omitempty"`
+	// TwoFactor is set when Token is a challenge token: "pending" asks for a
+	// code at /auth/2fa/verify, "enroll" for setting up TOTP at /auth/2fa/enroll.
+	TwoFactor string `json:"two_factor,omitempty"`
 }
 
 /*
@@ -67,31 +82,58 @@ Response (200 OK):
 	{
 	  "userid": "admin_uuid",
 	  "username": "admin_username",
-	  "token": "jwt_token_here"
+	  "token": "jwt_token_here",
+	  "two_factor": "pending"
 	}
 
+When two_factor is set the token is a short-lived challenge token: "pending"
+asks for a TOTP code at /auth/2fa/verify, and "enroll" means TOTP is required
+for admins and must be set up at /auth/2fa/enroll and /auth/2fa/confirm
+first. Either step returns the token for the full session.
+
+In cookie session mode the token is set as an HttpOnly cookie instead of
+being returned, and the CSRF token is returned in the X-CSRF-Token header.
+
 Returns:
 - 200 OK: Successful login with token
 - 400 Bad Request: Invalid request format
 - 401 Unauthorized: Invalid credentials
+- 403 Forbidden: The admin account is disabled
+- 429 Too Many Requests: Too many failed attempts; Retry-After gives the wait in seconds
 */
 func (h AdminAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, err)
 		return
 	}
 
-	token, admin, err := h.service.Login(req.Username, req.Password)
+	token, secondFactor, admin, err := h.service.Login(r.Context(), req.Username, req.Password, utils.ClientIP(r))
+	var blocked *loginguard.BlockedError
+	if errors.As(err, &blocked) {
+		w.Header().Set("Retry-After", blocked.RetryAfterHeader())
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, ErrAdminDisabled) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	if token, err = h.sessions.Issue(w, token); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	resp := responsePayload{
-		UserID:   admin.ID,
-		Username: admin.Username,
-		Token:    token,
+		UserID:    admin.ID,
+		Username:  admin.Username,
+		Token:     token,
+		TwoFactor: secondFactor,
 	}
 
 	utils.WriteJSON(w, resp, http.StatusOK)
@@ -99,8 +141,8 @@ func (h AdminAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 /*
 Register handles admin registration requests.
-Requires admin authentication to register new admins.
-Only existing admins can register new admin accounts.
+Only super admins can register new admin accounts; the first super admin is
+created with the plctl CLI.
 
 HTTP Method: POST
 Endpoint: /admin/register
@@ -112,9 +154,12 @@ Request Body:
 
 	{
 	  "username": "new_admin_username",
-	  "password": "new_admin_password"
+	  "password": "new_admin_password",
+	  "role": "admin"
 	}
 
+The role is optional and is "admin" or "super_admin"; it defaults to "admin".
+
 Response (201 Created):
 
 	{
@@ -127,29 +172,27 @@ Returns:
 - 201 Created: Successful registration with token
 - 400 Bad Request: Invalid request format
 - 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The caller is not a super admin
 - 409 Conflict: Username already exists
 */
 func (h AdminAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		http.Error(w, "unauthorized: missing or invalid Authorization header", http.StatusUnauthorized)
+	principal, ok := auth.FromContext(r.Context())
+	if !ok || !principal.Can(auth.PermAdminsManage) {
+		http.Error(w, "unauthorized: admin token required", http.StatusUnauthorized)
 		return
 	}
 
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	_, err := jwt.ValidateAdminToken(token)
-	if err != nil {
-		http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+	var req registerRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, err)
 		return
 	}
 
-	var req loginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	tokenStr, admin, err := h.service.Register(r.Context(), principal.ID, req.Username, req.Password, req.Role)
+	if errors.Is(err, ErrNotSuperAdmin) {
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
-
-	tokenStr, admin, err := h.service.Register(req.Username, req.Password)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusConflict)
 		return
@@ -182,7 +225,10 @@ Response (200 OK):
 	}
 
 Note: The client should remove the JWT token from local storage after calling this endpoint.
+In cookie session mode the session cookies are cleared.
 */
 func (h AdminAuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.sessions.Clear(w)
+
 	utils.WriteJSON(w, map[string]string{"message": "admin logged out successfully"}, http.StatusOK)
 }
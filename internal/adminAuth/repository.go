@@ -1,11 +1,15 @@
 package adminauth
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 
 	"github.com/varnit-ta/PlacementLog/internal/db"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 /*
@@ -13,7 +17,8 @@ AdminRepo handles admin authentication data access operations.
 Provides methods for admin login and registration with database interactions.
 */
 type AdminRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	hasher *password.Hasher
 }
 
 /*
@@ -21,18 +26,20 @@ NewAdminRepo creates a new AdminRepo instance with the provided database connect
 
 Parameters:
 - db: The database connection
+- hasher: Hashes and verifies passwords
 
 Returns:
 - *AdminRepo: A new repository instance
 */
-func NewAdminRepo(db *sql.DB) *AdminRepo {
-	return &AdminRepo{db: db}
+func NewAdminRepo(db *sql.DB, hasher *password.Hasher) *AdminRepo {
+	return &AdminRepo{db: db, hasher: hasher}
 }
 
 /*
 Login validates admin credentials against the database.
 
 Parameters:
+- ctx: The request context
 - username: The admin's username
 - password: The admin's password
 
@@ -43,15 +50,19 @@ Returns:
 The function:
 1. Validates that username and password are provided
 2. Queries the database for the admin
-3. Compares the provided password with the hashed password using bcrypt
-4. Returns admin information upon successful authentication
+3. Verifies the provided password against the stored hash
+4. Rehashes the password if the stored hash is bcrypt or uses older argon2id parameters
+5. Rejects disabled admins once the password has matched
+6. Returns admin information upon successful authentication
 
 Possible errors:
 - "all fields are required": Missing username or password
-- "admin not found": Admin not found in database
-- "incorrect password": Password doesn't match
+- auth.ErrInvalidCredentials: Admin not found or password doesn't match
+- ErrAdminDisabled: The account has been disabled
 */
-func (repo AdminRepo) Login(username, password string) (*db.Admin, error) {
+func (repo AdminRepo) Login(ctx context.Context, username, password string) (*db.Admin, error) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.Login", "SELECT", "placement_log_admins").End()
+
 	if username == "" || password == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
@@ -60,32 +71,66 @@ func (repo AdminRepo) Login(username, password string) (*db.Admin, error) {
 	var hashedPass string
 
 	query := `
-		SELECT id, username, password 
+		SELECT id, username, password, role, disabled_at IS NOT NULL
 		FROM placement_log_admins 
 		WHERE username = $1;
 	`
 
-	err := repo.db.QueryRow(query, username).Scan(&admin.ID, &admin.Username, &hashedPass)
+	err := repo.db.QueryRowContext(ctx, query, username).Scan(&admin.ID, &admin.Username, &hashedPass, &admin.Role, &admin.Disabled)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("admin not found")
+		repo.hasher.CompareDummy(password)
+		return nil, auth.ErrInvalidCredentials
 	} else if err != nil {
 		return nil, fmt.Errorf("db error: %v", err)
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(password)) != nil {
-		return nil, fmt.Errorf("incorrect password")
+	match, rehash, err := repo.hasher.Verify(password, hashedPass)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying password: %v", err)
+	}
+
+	if !match {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if admin.Disabled {
+		return nil, ErrAdminDisabled
+	}
+
+	if rehash {
+		repo.rehash(ctx, admin.ID, password, hashedPass)
 	}
 
 	return &admin, nil
 }
 
+/*
+rehash replaces an outdated password hash after a successful login.
+The update only applies if the stored hash is still the one that was
+verified. Failures are logged and do not affect the login.
+*/
+func (repo AdminRepo) rehash(ctx context.Context, adminID, password, oldHash string) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.rehash", "UPDATE", "placement_log_admins").End()
+
+	hashedPass, err := repo.hasher.Hash(password)
+	if err == nil {
+		_, err = repo.db.ExecContext(ctx, `UPDATE placement_log_admins SET password = $2 WHERE id = $1 AND password = $3;`, adminID, hashedPass, oldHash)
+	}
+
+	if err != nil {
+		log.Printf("adminauth: rehashing password of %s: %v", adminID, err)
+	}
+}
+
 /*
 Register creates a new admin account in the database.
 
 Parameters:
+- ctx: The request context
 - username: The admin's username
 - password: The admin's password
+- role: db.AdminRoleAdmin or db.AdminRoleSuper
 
 Returns:
 - *db.Admin: The newly created admin information
@@ -93,7 +138,7 @@ Returns:
 
 The function:
 1. Validates that username and password are provided
-2. Hashes the password using bcrypt with default cost
+2. Hashes the password with argon2id
 3. Inserts the new admin into the database
 4. Returns the created admin information
 
@@ -102,24 +147,26 @@ Possible errors:
 - "error hashing password": Password hashing failed
 - "failed to register admin": Database insertion failed
 */
-func (repo AdminRepo) Register(username, password string) (*db.Admin, error) {
+func (repo AdminRepo) Register(ctx context.Context, username, password, role string) (*db.Admin, error) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.Register", "INSERT", "placement_log_admins").End()
+
 	if username == "" || password == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPass, err := repo.hasher.Hash(password)
 	if err != nil {
 		return nil, fmt.Errorf("error hashing password: %v", err)
 	}
 
 	query := `
-		INSERT INTO placement_log_admins (username, password)
-		VALUES ($1, $2)
+		INSERT INTO placement_log_admins (username, password, role)
+		VALUES ($1, $2, $3)
 		RETURNING id;
 	`
 
 	var adminID string
-	err = repo.db.QueryRow(query, username, hashedPass).Scan(&adminID)
+	err = repo.db.QueryRowContext(ctx, query, username, hashedPass, role).Scan(&adminID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to register admin: %v", err)
@@ -128,5 +175,162 @@ func (repo AdminRepo) Register(username, password string) (*db.Admin, error) {
 	return &db.Admin{
 		ID:       adminID,
 		Username: username,
+		Role:     role,
 	}, nil
 }
+
+/*
+GetAdmin returns an admin by ID.
+
+Parameters:
+- ctx: The request context
+- id: The admin ID
+
+Returns:
+- *db.Admin: The admin
+- error: "admin not found" or a database error
+*/
+func (repo AdminRepo) GetAdmin(ctx context.Context, id string) (*db.Admin, error) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.GetAdmin", "SELECT", "placement_log_admins").End()
+
+	return repo.scanAdmin(repo.db.QueryRowContext(ctx, `
+		SELECT id, username, role, disabled_at IS NOT NULL, COALESCE(created_at::text, '')
+		FROM placement_log_admins
+		WHERE id = $1;
+	`, id))
+}
+
+/*
+GetAdminByUsername returns an admin by username.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+
+Returns:
+- *db.Admin: The admin
+- error: "admin not found" or a database error
+*/
+func (repo AdminRepo) GetAdminByUsername(ctx context.Context, username string) (*db.Admin, error) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.GetAdminByUsername", "SELECT", "placement_log_admins").End()
+
+	return repo.scanAdmin(repo.db.QueryRowContext(ctx, `
+		SELECT id, username, role, disabled_at IS NOT NULL, COALESCE(created_at::text, '')
+		FROM placement_log_admins
+		WHERE username = $1;
+	`, username))
+}
+
+func (repo AdminRepo) scanAdmin(row *sql.Row) (*db.Admin, error) {
+	var admin db.Admin
+	err := row.Scan(&admin.ID, &admin.Username, &admin.Role, &admin.Disabled, &admin.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrAdminNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &admin, nil
+}
+
+/*
+ListAdmins returns every admin account, oldest first.
+
+Returns:
+- []db.Admin: The admins, including disabled ones
+- error: Any database error
+*/
+func (repo AdminRepo) ListAdmins(ctx context.Context) ([]db.Admin, error) {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.ListAdmins", "SELECT", "placement_log_admins").End()
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, username, role, disabled_at IS NOT NULL, COALESCE(created_at::text, '')
+		FROM placement_log_admins
+		ORDER BY created_at, username;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch admins: %v", err)
+	}
+	defer rows.Close()
+
+	admins := []db.Admin{}
+	for rows.Next() {
+		var admin db.Admin
+		if err = rows.Scan(&admin.ID, &admin.Username, &admin.Role, &admin.Disabled, &admin.CreatedAt); err != nil {
+			return nil, err
+		}
+		admins = append(admins, admin)
+	}
+
+	return admins, rows.Err()
+}
+
+/*
+SetDisabled disables or re-enables an admin account.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+- disabled: true to disable the account, false to enable it
+
+Returns:
+- error: "admin not found" or a database error
+*/
+func (repo AdminRepo) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.SetDisabled", "UPDATE", "placement_log_admins").End()
+
+	res, err := repo.db.ExecContext(ctx, `
+		UPDATE placement_log_admins
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) END, updated_at = CURRENT_TIMESTAMP
+		WHERE username = $1;
+	`, username, disabled)
+
+	return checkAdminUpdated(res, err)
+}
+
+/*
+UpdatePassword replaces an admin's password.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+- password: The new password
+
+Returns:
+- error: "admin not found", a hashing error or a database error
+*/
+func (repo AdminRepo) UpdatePassword(ctx context.Context, username, password string) error {
+	defer telemetry.TraceQuery(ctx, "AdminRepo.UpdatePassword", "UPDATE", "placement_log_admins").End()
+
+	hashedPass, err := repo.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %v", err)
+	}
+
+	res, err := repo.db.ExecContext(ctx, `UPDATE placement_log_admins SET password = $2, updated_at = CURRENT_TIMESTAMP WHERE username = $1;`, username, hashedPass)
+
+	return checkAdminUpdated(res, err)
+}
+
+func checkAdminUpdated(res sql.Result, err error) error {
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n == 0 {
+		return ErrAdminNotFound
+	}
+
+	return nil
+}
+
+// Ensure AdminRepo implements AdminRepository
+var _ AdminRepository = (*AdminRepo)(nil)
@@ -1,16 +1,57 @@
 package adminauth
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/varnit-ta/PlacementLog/internal/db"
-	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var (
+	// ErrAdminDisabled is returned by Login for a disabled admin account.
+	ErrAdminDisabled = errors.New("admin account is disabled")
+	// ErrNotSuperAdmin is returned when an admin without the super admin role registers an admin.
+	ErrNotSuperAdmin = errors.New("only super admins can register admins")
+	// ErrAdminNotFound is returned for unknown admins.
+	ErrAdminNotFound = errors.New("admin not found")
 )
 
+// Define AdminRepository interface for testability
+//go:generate mockgen -destination=mock_admin_repo.go -package=adminauth . AdminRepository
+
+type AdminRepository interface {
+	Login(ctx context.Context, username, password string) (*db.Admin, error)
+	Register(ctx context.Context, username, password, role string) (*db.Admin, error)
+	GetAdmin(ctx context.Context, id string) (*db.Admin, error)
+	GetAdminByUsername(ctx context.Context, username string) (*db.Admin, error)
+	ListAdmins(ctx context.Context) ([]db.Admin, error)
+	SetDisabled(ctx context.Context, username string, disabled bool) error
+	UpdatePassword(ctx context.Context, username, password string) error
+}
+
 /*
 AdminService handles admin authentication business logic.
-Provides methods for admin login and registration with JWT token generation.
+Provides methods for admin login and registration with JWT token generation,
+and the account management used by the plctl CLI.
 */
 type AdminService struct {
-	repo *AdminRepo
+	repo   AdminRepository
+	tokens TokenIssuer
+	guard  *loginguard.LoginGuard
+	policy *password.Policy
+}
+
+/*
+TokenIssuer issues the token of a login whose password has been verified,
+together with its second factor state; the state is empty for a full session.
+*/
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, accountType, accountID string) (string, string, error)
 }
 
 /*
@@ -18,51 +59,79 @@ NewAdminService creates a new AdminService instance with the provided repository
 
 Parameters:
 - repo: The admin authentication repository
+- tokens: Issues the tokens of logins, with a challenge when a second factor is due (may be nil when only managing accounts)
+- guard: The login throttle (nil disables throttling)
+- policy: The rules for new passwords (nil accepts any password)
 
 Returns:
 - *AdminService: A new service instance
 */
-func NewAdminService(repo *AdminRepo) *AdminService {
-	return &AdminService{repo: repo}
+func NewAdminService(repo AdminRepository, tokens TokenIssuer, guard *loginguard.LoginGuard, policy *password.Policy) *AdminService {
+	return &AdminService{repo: repo, tokens: tokens, guard: guard, policy: policy}
 }
 
 /*
 Login authenticates an admin with the provided credentials and generates a JWT token.
 
 Parameters:
+- ctx: The request context
 - username: The admin's username
 - password: The admin's password
+- ip: The client IP, used to throttle repeated failures
 
 Returns:
 - string: JWT token for the authenticated admin
+- string: The second factor state of the token; a challenge token only reaches the /auth/2fa endpoints
 - *db.Admin: The authenticated admin information
 - error: Any error that occurred during authentication
 
 The function:
-1. Validates the admin credentials against the database
-2. Generates a JWT token with "admin" role
-3. Returns the token and admin information upon successful authentication
+1. Rejects the attempt while the account or IP is backing off or locked out
+2. Validates the admin credentials against the database
+3. Records a failure, or clears the account's failures on success
+4. Issues a JWT token with "admin" role, or a challenge token when a second factor is due
+5. Returns the token and admin information upon successful authentication
+
+Possible errors:
+- *loginguard.BlockedError: Too many recent failures
+- auth.ErrInvalidCredentials: Unknown username or wrong password
+- ErrAdminDisabled: The account has been disabled
 */
-func (s AdminService) Login(username, password string) (string, *db.Admin, error) {
-	admin, err := s.repo.Login(username, password)
+func (s AdminService) Login(ctx context.Context, username, password, ip string) (string, string, *db.Admin, error) {
+	attempt, err := s.guard.Check(ctx, auth.RoleAdmin, username, ip)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
+	defer attempt.Release(ctx)
 
-	token, err := jwt.GenerateJwtToken(admin.ID, "admin")
+	admin, err := s.repo.Login(ctx, username, password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		attempt.Fail(ctx)
+	}
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 
-	return token, admin, nil
+	attempt.Succeed(ctx)
+
+	token, secondFactor, err := s.tokens.IssueToken(ctx, auth.RoleAdmin, admin.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return token, secondFactor, admin, nil
 }
 
 /*
-Register creates a new admin account and generates a JWT token.
+Register creates a new admin account on behalf of a logged-in super admin
+and generates a JWT token.
 
 Parameters:
+- ctx: The request context
+- callerID: The ID of the admin making the request
 - username: The admin's username
-- password: The admin's password
+- pass: The admin's password
+- role: db.AdminRoleAdmin or db.AdminRoleSuper; empty means db.AdminRoleAdmin
 
 Returns:
 - string: JWT token for the newly registered admin
@@ -70,21 +139,187 @@ Returns:
 - error: Any error that occurred during registration
 
 The function:
-1. Hashes the password using bcrypt
-2. Creates a new admin in the database
-3. Generates a JWT token with "admin" role
+1. Checks that the caller is an enabled super admin
+2. Creates the admin as CreateAdmin does
+3. Issues a JWT token with "admin" role (an enrollment challenge when admins must set up a second factor)
 4. Returns the token and admin information upon successful registration
+
+Possible errors:
+- ErrNotSuperAdmin: The caller is not an enabled super admin
 */
-func (s AdminService) Register(username, password string) (string, *db.Admin, error) {
-	admin, err := s.repo.Register(username, password)
+func (s AdminService) Register(ctx context.Context, callerID, username, pass, role string) (string, *db.Admin, error) {
+	caller, err := s.repo.GetAdmin(ctx, callerID)
+	if err != nil || caller.Role != db.AdminRoleSuper || caller.Disabled {
+		return "", nil, ErrNotSuperAdmin
+	}
+
+	admin, err := s.CreateAdmin(ctx, username, pass, role)
 	if err != nil {
 		return "", nil, err
 	}
 
-	token, err := jwt.GenerateJwtToken(admin.ID, "admin")
+	token, _, err := s.tokens.IssueToken(ctx, auth.RoleAdmin, admin.ID)
 	if err != nil {
 		return "", nil, err
 	}
 
 	return token, admin, nil
 }
+
+/*
+CreateAdmin creates a new admin account without issuing a token.
+It is used by Register and by the plctl CLI to bootstrap the first super admin.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+- pass: The admin's password
+- role: db.AdminRoleAdmin or db.AdminRoleSuper; empty means db.AdminRoleAdmin
+
+Returns:
+- *db.Admin: The newly created admin
+- error: Any error that occurred
+
+Possible errors:
+- "invalid role": Unknown role
+- Password policy errors
+- "failed to register admin": Database insertion failed, e.g. a duplicate username
+*/
+func (s AdminService) CreateAdmin(ctx context.Context, username, pass, role string) (*db.Admin, error) {
+	if role == "" {
+		role = db.AdminRoleAdmin
+	}
+
+	if role != db.AdminRoleAdmin && role != db.AdminRoleSuper {
+		return nil, fmt.Errorf("invalid role: must be '%s' or '%s'", db.AdminRoleAdmin, db.AdminRoleSuper)
+	}
+
+	if err := s.policy.Check(pass, username); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Register(ctx, username, pass, role)
+}
+
+/*
+ResetPassword sets a new password for an admin.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+- pass: The new password
+
+Returns:
+- error: A password policy error, "admin not found" or a database error
+*/
+func (s AdminService) ResetPassword(ctx context.Context, username, pass string) error {
+	if err := s.policy.Check(pass, username); err != nil {
+		return err
+	}
+
+	return s.repo.UpdatePassword(ctx, username, pass)
+}
+
+/*
+ListAdmins returns every admin account, oldest first.
+
+Returns:
+- []db.Admin: The admins, including disabled ones
+- error: Any database error
+*/
+func (s AdminService) ListAdmins(ctx context.Context) ([]db.Admin, error) {
+	return s.repo.ListAdmins(ctx)
+}
+
+/*
+GetAdminByUsername returns an admin by username.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+
+Returns:
+- *db.Admin: The admin
+- error: "admin not found" or a database error
+*/
+func (s AdminService) GetAdminByUsername(ctx context.Context, username string) (*db.Admin, error) {
+	return s.repo.GetAdminByUsername(ctx, username)
+}
+
+/*
+SetDisabled disables or re-enables an admin account.
+A disabled admin can no longer log in, and the tokens issued before are
+refused by CheckAccount.
+
+Parameters:
+- ctx: The request context
+- username: The admin's username
+- disabled: true to disable the account, false to enable it
+
+Returns:
+- error: Any error that occurred
+
+Possible errors:
+- "admin not found": No such admin
+- "cannot disable the last enabled super admin": Disabling would leave no one able to register admins
+*/
+func (s AdminService) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	if disabled {
+		admins, err := s.repo.ListAdmins(ctx)
+		if err != nil {
+			return err
+		}
+
+		target, others := false, 0
+		for _, a := range admins {
+			if a.Role != db.AdminRoleSuper || a.Disabled {
+				continue
+			}
+			if a.Username == username {
+				target = true
+			} else {
+				others++
+			}
+		}
+
+		if target && others == 0 {
+			return fmt.Errorf("cannot disable the last enabled super admin")
+		}
+	}
+
+	return s.repo.SetDisabled(ctx, username, disabled)
+}
+
+/*
+CheckAccount reports whether an admin account may still use its tokens. It
+implements auth.AccountChecker for the admin authentication middleware.
+
+Parameters:
+- ctx: The request context
+- adminID: The admin's ID
+
+Returns:
+- error: nil for enabled admins, auth.ErrAccountNotFound, auth.ErrAccountDisabled or a database error
+*/
+func (s AdminService) CheckAccount(ctx context.Context, adminID string) error {
+	if !utils.IsUUID(adminID) {
+		return auth.ErrAccountNotFound
+	}
+
+	admin, err := s.repo.GetAdmin(ctx, adminID)
+	if errors.Is(err, ErrAdminNotFound) {
+		return auth.ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if admin.Disabled {
+		return auth.ErrAccountDisabled
+	}
+
+	return nil
+}
+
+// Ensure AdminService implements auth.AccountChecker
+var _ auth.AccountChecker = (*AdminService)(nil)
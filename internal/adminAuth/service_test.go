@@ -0,0 +1,150 @@
+package adminauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+type mockAdminRepo struct {
+	LoginFunc              func(username, password string) (*db.Admin, error)
+	RegisterFunc           func(username, password, role string) (*db.Admin, error)
+	GetAdminFunc           func(id string) (*db.Admin, error)
+	GetAdminByUsernameFunc func(username string) (*db.Admin, error)
+	ListAdminsFunc         func() ([]db.Admin, error)
+	SetDisabledFunc        func(username string, disabled bool) error
+	UpdatePasswordFunc     func(username, password string) error
+}
+
+func (m *mockAdminRepo) Login(ctx context.Context, username, password string) (*db.Admin, error) {
+	return m.LoginFunc(username, password)
+}
+func (m *mockAdminRepo) Register(ctx context.Context, username, password, role string) (*db.Admin, error) {
+	return m.RegisterFunc(username, password, role)
+}
+func (m *mockAdminRepo) GetAdmin(ctx context.Context, id string) (*db.Admin, error) {
+	return m.GetAdminFunc(id)
+}
+func (m *mockAdminRepo) GetAdminByUsername(ctx context.Context, username string) (*db.Admin, error) {
+	return m.GetAdminByUsernameFunc(username)
+}
+func (m *mockAdminRepo) ListAdmins(ctx context.Context) ([]db.Admin, error) {
+	return m.ListAdminsFunc()
+}
+func (m *mockAdminRepo) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	return m.SetDisabledFunc(username, disabled)
+}
+func (m *mockAdminRepo) UpdatePassword(ctx context.Context, username, password string) error {
+	return m.UpdatePasswordFunc(username, password)
+}
+
+type mockTokenIssuer struct{}
+
+func (mockTokenIssuer) IssueToken(ctx context.Context, accountType, accountID string) (string, string, error) {
+	return "token-" + accountID, "", nil
+}
+
+func TestAdminService_Register(t *testing.T) {
+	admins := map[string]*db.Admin{
+		"super":    {ID: "super", Role: db.AdminRoleSuper},
+		"plain":    {ID: "plain", Role: db.AdminRoleAdmin},
+		"disabled": {ID: "disabled", Role: db.AdminRoleSuper, Disabled: true},
+	}
+	var created string
+	repo := &mockAdminRepo{
+		GetAdminFunc: func(id string) (*db.Admin, error) {
+			if a, ok := admins[id]; ok {
+				return a, nil
+			}
+			return nil, ErrAdminNotFound
+		},
+		RegisterFunc: func(username, password, role string) (*db.Admin, error) {
+			created = role
+			return &db.Admin{ID: "new", Username: username, Role: role}, nil
+		},
+	}
+	s := NewAdminService(repo, mockTokenIssuer{}, nil, nil)
+
+	for _, caller := range []string{"plain", "disabled", "missing"} {
+		if _, _, err := s.Register(context.Background(), caller, "newadmin", "secret", ""); !errors.Is(err, ErrNotSuperAdmin) {
+			t.Errorf("caller %s: expected ErrNotSuperAdmin, got %v", caller, err)
+		}
+	}
+
+	token, admin, err := s.Register(context.Background(), "super", "newadmin", "secret", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "token-new" || admin.Username != "newadmin" || created != db.AdminRoleAdmin {
+		t.Errorf("unexpected result %s %+v, role %s", token, admin, created)
+	}
+
+	if _, _, err = s.Register(context.Background(), "super", "newadmin", "secret", "owner"); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+}
+
+func TestAdminService_SetDisabled_KeepsASuperAdmin(t *testing.T) {
+	admins := []db.Admin{
+		{Username: "root", Role: db.AdminRoleSuper},
+		{Username: "old", Role: db.AdminRoleSuper, Disabled: true},
+		{Username: "mod", Role: db.AdminRoleAdmin},
+	}
+	var disabled []string
+	repo := &mockAdminRepo{
+		ListAdminsFunc: func() ([]db.Admin, error) { return admins, nil },
+		SetDisabledFunc: func(username string, d bool) error {
+			disabled = append(disabled, username)
+			return nil
+		},
+	}
+	s := NewAdminService(repo, nil, nil, nil)
+
+	if err := s.SetDisabled(context.Background(), "root", true); err == nil {
+		t.Error("expected disabling the last enabled super admin to fail")
+	}
+	if err := s.SetDisabled(context.Background(), "mod", true); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := s.SetDisabled(context.Background(), "root", false); err != nil {
+		t.Errorf("expected enabling to succeed, got %v", err)
+	}
+	if len(disabled) != 2 || disabled[0] != "mod" || disabled[1] != "root" {
+		t.Errorf("unexpected repo calls %v", disabled)
+	}
+}
+
+func TestAdminService_CheckAccount(t *testing.T) {
+	const enabledID, disabledID = "5f0c6a52-8e3b-4a8f-9d1e-2b7c3f4a5d60", "5f0c6a52-8e3b-4a8f-9d1e-2b7c3f4a5d61"
+	admins := map[string]*db.Admin{
+		enabledID:  {ID: enabledID},
+		disabledID: {ID: disabledID, Disabled: true},
+	}
+	repo := &mockAdminRepo{
+		GetAdminFunc: func(id string) (*db.Admin, error) {
+			if a, ok := admins[id]; ok {
+				return a, nil
+			}
+			return nil, ErrAdminNotFound
+		},
+	}
+	s := NewAdminService(repo, nil, nil, nil)
+
+	cases := []struct {
+		id   string
+		want error
+	}{
+		{enabledID, nil},
+		{disabledID, auth.ErrAccountDisabled},
+		{"5f0c6a52-8e3b-4a8f-9d1e-2b7c3f4a5d62", auth.ErrAccountNotFound},
+		{"not-a-uuid", auth.ErrAccountNotFound},
+	}
+	for _, c := range cases {
+		if err := s.CheckAccount(context.Background(), c.id); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.id, c.want, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package apikeys
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var errForbidden = errors.New("unauthorized: admin token required")
+
+/*
+APIKeysHandler handles the admin endpoints for API keys.
+*/
+type APIKeysHandler struct {
+	srv *APIKeysService
+}
+
+/*
+NewAPIKeysHandler creates a new APIKeysHandler instance with the provided service.
+
+Parameters:
+- srv: The API keys service
+
+Returns:
+- *APIKeysHandler: A new handler instance
+*/
+func NewAPIKeysHandler(srv *APIKeysService) *APIKeysHandler {
+	return &APIKeysHandler{srv: srv}
+}
+
+/*
+keyManager returns the ID of the calling admin when it may manage API keys.
+*/
+func keyManager(r *http.Request) (string, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok || !principal.IsAdmin() || !principal.Can(auth.PermAPIKeysManage) {
+		return "", false
+	}
+	return principal.ID, true
+}
+
+/*
+createKeyRequest represents the JSON payload for creating an API key.
+*/
+type createKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+/*
+createKeyResponse is a new key together with the key itself.
+*/
+type createKeyResponse struct {
+	*db.APIKey
+	Key string `json:"key"`
+}
+
+/*
+CreateKey creates an API key for an integration or bot. The key is only
+shown in this response; it is sent as "Authorization: Bearer <key>".
+
+HTTP Method: POST
+Endpoint: /admin/api-keys
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body ("expires_at" is optional and defaults to the configured lifetime):
+
+	{
+	  "name": "discord bot",
+	  "scopes": ["placements:read", "posts:read"],
+	  "expires_at": "2027-01-01T00:00:00Z"
+	}
+
+Response (201 Created):
+
+	{
+	  "id": "key_uuid",
+	  "name": "discord bot",
+	  "prefix": "plk_AbCdEfGh",
+	  "scopes": ["placements:read", "posts:read"],
+	  "created_by": "admin_uuid",
+	  "created_at": "2026-01-01T00:00:00Z",
+	  "expires_at": "2027-01-01T00:00:00Z",
+	  "key": "plk_AbCdEfGh..."
+	}
+
+Returns:
+- 201 Created: The key
+- 400 Bad Request: Missing or too long name, unknown scopes, or an expiry in the past or beyond the maximum lifetime
+- 401 Unauthorized: Missing or invalid admin token
+*/
+func (h *APIKeysHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := keyManager(r)
+	if !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	var payload createKeyRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	key, secret, err := h.srv.Create(r.Context(), adminID, payload.Name, payload.Scopes, payload.ExpiresAt)
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, createKeyResponse{APIKey: key, Key: secret}, http.StatusCreated)
+}
+
+/*
+ListKeys lists all API keys, including expired and revoked ones. The keys
+themselves are never shown again; "prefix" tells them apart.
+
+HTTP Method: GET
+Endpoint: /admin/api-keys
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK):
+
+	[
+	  {
+	    "id": "key_uuid",
+	    "name": "discord bot",
+	    "prefix": "plk_AbCdEfGh",
+	    "scopes": ["placements:read", "posts:read"],
+	    "created_by": "admin_uuid",
+	    "created_at": "2026-01-01T00:00:00Z",
+	    "expires_at": "2027-01-01T00:00:00Z",
+	    "last_used_at": "2026-01-05T12:00:00Z"
+	  }
+	]
+
+Returns:
+- 200 OK: The keys, newest first
+- 401 Unauthorized: Missing or invalid admin token
+*/
+func (h *APIKeysHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	if _, ok := keyManager(r); !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.srv.List(r.Context())
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, keys, http.StatusOK)
+}
+
+/*
+RevokeKey revokes an API key; requests made with it are refused from then on.
+
+HTTP Method: DELETE
+Endpoint: /admin/api-keys/{id}
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Returns:
+- 200 OK: Revoked
+- 401 Unauthorized: Missing or invalid admin token
+- 404 Not Found: No such key
+*/
+func (h *APIKeysHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if _, ok := keyManager(r); !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	err := h.srv.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, ErrKeyNotFound) {
+		utils.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "api key revoked"}, http.StatusOK)
+}
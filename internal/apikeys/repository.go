@@ -0,0 +1,207 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+APIKeysRepo stores API keys. Only the hashes of the keys are stored.
+*/
+type APIKeysRepo struct {
+	db *sql.DB
+}
+
+/*
+NewAPIKeysRepo creates a new APIKeysRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *APIKeysRepo: A new repository instance
+*/
+func NewAPIKeysRepo(db *sql.DB) *APIKeysRepo {
+	return &APIKeysRepo{db: db}
+}
+
+const keyColumns = `id, name, prefix, scopes, COALESCE(created_by::text, ''), created_at, expires_at, last_used_at, revoked_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (*db.APIKey, error) {
+	var key db.APIKey
+	var lastUsed, revoked sql.NullTime
+
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&lastUsed,
+		&revoked,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+
+	return &key, nil
+}
+
+/*
+CreateKey stores a new API key.
+
+Parameters:
+- ctx: The request context
+- key: The name, display prefix, scopes, creating admin and expiry of the key
+- keyHash: Hex SHA-256 of the key
+
+Returns:
+- *db.APIKey: The stored key with its ID and creation time
+- error: Any database error
+*/
+func (r *APIKeysRepo) CreateKey(ctx context.Context, key db.APIKey, keyHash string) (*db.APIKey, error) {
+	defer telemetry.TraceQuery(ctx, "APIKeysRepo.CreateKey", "INSERT", "placement_log_api_keys").End()
+
+	created, err := scanKey(r.db.QueryRowContext(ctx, `
+		INSERT INTO placement_log_api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6)
+		RETURNING `+keyColumns+`;
+	`, key.Name, key.Prefix, keyHash, pq.Array(key.Scopes), key.CreatedBy, key.ExpiresAt))
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return created, nil
+}
+
+/*
+ListKeys returns all API keys, including expired and revoked ones.
+
+Returns:
+- []db.APIKey: The keys, newest first
+- error: Any database error
+*/
+func (r *APIKeysRepo) ListKeys(ctx context.Context) ([]db.APIKey, error) {
+	defer telemetry.TraceQuery(ctx, "APIKeysRepo.ListKeys", "SELECT", "placement_log_api_keys").End()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+keyColumns+` FROM placement_log_api_keys ORDER BY created_at DESC;`)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []db.APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return keys, nil
+}
+
+/*
+RevokeKey revokes an API key; revoking a revoked key keeps the first
+revocation time.
+
+Parameters:
+- ctx: The request context
+- id: The key's ID
+
+Returns:
+- error: ErrKeyNotFound when there is no such key, or a database error
+*/
+func (r *APIKeysRepo) RevokeKey(ctx context.Context, id string) error {
+	defer telemetry.TraceQuery(ctx, "APIKeysRepo.RevokeKey", "UPDATE", "placement_log_api_keys").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_api_keys
+		SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id::text = $1;
+	`, id)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrKeyNotFound
+	}
+
+	return nil
+}
+
+/*
+GetKeyByHash returns the API key with a hash.
+
+Parameters:
+- ctx: The request context
+- keyHash: Hex SHA-256 of the presented key
+
+Returns:
+- *db.APIKey: The key, or nil when no key has the hash
+- error: Any database error
+*/
+func (r *APIKeysRepo) GetKeyByHash(ctx context.Context, keyHash string) (*db.APIKey, error) {
+	defer telemetry.TraceQuery(ctx, "APIKeysRepo.GetKeyByHash", "SELECT", "placement_log_api_keys").End()
+
+	key, err := scanKey(r.db.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM placement_log_api_keys WHERE key_hash = $1;`, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return key, nil
+}
+
+/*
+TouchKey records the use of an API key.
+
+Parameters:
+- ctx: The request context
+- id: The key's ID
+- at: When the key was used
+
+Returns:
+- error: Any database error
+*/
+func (r *APIKeysRepo) TouchKey(ctx context.Context, id string, at time.Time) error {
+	defer telemetry.TraceQuery(ctx, "APIKeysRepo.TouchKey", "UPDATE", "placement_log_api_keys").End()
+
+	if _, err := r.db.ExecContext(ctx, `UPDATE placement_log_api_keys SET last_used_at = $2 WHERE id = $1;`, id, at); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+// Ensure APIKeysRepo implements APIKeysRepository
+var _ APIKeysRepository = (*APIKeysRepo)(nil)
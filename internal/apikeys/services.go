@@ -0,0 +1,204 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+// ErrKeyNotFound is returned when revoking a key that does not exist.
+var ErrKeyNotFound = errors.New("api key not found")
+
+const (
+	// maxNameLength matches the name column.
+	maxNameLength = 100
+	// displayPrefixLength is how much of a key is kept to tell keys apart.
+	displayPrefixLength = len(auth.APIKeyPrefix) + 8
+	// touchInterval limits the last-used updates of a busy key.
+	touchInterval = time.Minute
+)
+
+// Define APIKeysRepository interface for testability
+//go:generate mockgen -destination=mock_apikeys_repo.go -package=apikeys . APIKeysRepository
+
+type APIKeysRepository interface {
+	CreateKey(ctx context.Context, key db.APIKey, keyHash string) (*db.APIKey, error)
+	ListKeys(ctx context.Context) ([]db.APIKey, error)
+	RevokeKey(ctx context.Context, id string) error
+	GetKeyByHash(ctx context.Context, keyHash string) (*db.APIKey, error)
+	TouchKey(ctx context.Context, id string, at time.Time) error
+}
+
+/*
+APIKeysService manages the API keys used by integrations and bots, and
+authenticates requests made with them. A key grants only its scopes, which
+are taken from auth.APIKeyScopes.
+*/
+type APIKeysService struct {
+	repo APIKeysRepository
+	cfg  config.APIKeysConfig
+	now  func() time.Time
+}
+
+/*
+NewAPIKeysService creates a new APIKeysService instance.
+
+Parameters:
+- repo: The API keys repository
+- cfg: The default and maximum key lifetimes
+
+Returns:
+- *APIKeysService: A new service instance
+*/
+func NewAPIKeysService(repo APIKeysRepository, cfg config.APIKeysConfig) *APIKeysService {
+	return &APIKeysService{repo: repo, cfg: cfg, now: time.Now}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Create creates an API key.
+
+Parameters:
+- ctx: The request context
+- adminID: The admin creating the key
+- name: A name telling what the key is for
+- scopes: The scopes granted to the key
+- expiresAt: When the key expires; nil for the configured default lifetime
+
+Returns:
+- *db.APIKey: The stored key
+- string: The key itself, which is not stored and cannot be shown again
+- error: Any error that occurred
+
+The function:
+1. Validates the name and the scopes, which must be known and not repeated
+2. Checks that the expiry is in the future and within the maximum lifetime
+3. Generates a random key and stores its hash
+*/
+func (s *APIKeysService) Create(ctx context.Context, adminID, name string, scopes []string, expiresAt *time.Time) (*db.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return nil, "", fmt.Errorf("name is required and must be at most %d characters", maxNameLength)
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	for i, scope := range scopes {
+		if !slices.Contains(auth.APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("unknown scope %q; valid scopes are %s", scope, strings.Join(auth.APIKeyScopes, ", "))
+		}
+		if slices.Contains(scopes[:i], scope) {
+			return nil, "", fmt.Errorf("scope %q is repeated", scope)
+		}
+	}
+
+	now := s.now()
+	expires := now.Add(s.cfg.DefaultTTL)
+	if expiresAt != nil {
+		expires = *expiresAt
+	}
+	if !expires.After(now) {
+		return nil, "", fmt.Errorf("expiry must be in the future")
+	}
+	if expires.Sub(now) > s.cfg.MaxTTL {
+		return nil, "", fmt.Errorf("expiry must be within %s", s.cfg.MaxTTL)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", fmt.Errorf("error generating key: %v", err)
+	}
+	secret := auth.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key, err := s.repo.CreateKey(ctx, db.APIKey{
+		Name:      name,
+		Prefix:    secret[:displayPrefixLength],
+		Scopes:    scopes,
+		CreatedBy: adminID,
+		ExpiresAt: expires.UTC(),
+	}, hashKey(secret))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+/*
+List returns all API keys, including expired and revoked ones.
+
+Returns:
+- []db.APIKey: The keys, newest first
+- error: Any database error
+*/
+func (s *APIKeysService) List(ctx context.Context) ([]db.APIKey, error) {
+	return s.repo.ListKeys(ctx)
+}
+
+/*
+Revoke revokes an API key; requests made with it are refused from then on.
+
+Parameters:
+- ctx: The request context
+- id: The key's ID
+
+Returns:
+- error: ErrKeyNotFound when there is no such key, or a database error
+*/
+func (s *APIKeysService) Revoke(ctx context.Context, id string) error {
+	return s.repo.RevokeKey(ctx, id)
+}
+
+/*
+VerifyAPIKey authenticates a request made with an API key.
+
+Parameters:
+- ctx: The request context
+- key: The presented key
+
+Returns:
+- *auth.Principal: A principal granted the key's scopes
+- error: auth.ErrInvalidAPIKey for unknown, expired and revoked keys, or a database error
+
+The last use of a key is recorded at most once a minute; failing to record
+it does not fail the request.
+*/
+func (s *APIKeysService) VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	stored, err := s.repo.GetKeyByHash(ctx, hashKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if stored == nil || stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= touchInterval {
+		if err := s.repo.TouchKey(ctx, stored.ID, now.UTC()); err != nil {
+			log.Printf("failed to record use of api key %s: %v", stored.ID, err)
+		}
+	}
+
+	return auth.NewAPIKeyPrincipal(stored.ID, stored.Scopes), nil
+}
+
+// Ensure APIKeysService implements auth.APIKeyVerifier
+var _ auth.APIKeyVerifier = (*APIKeysService)(nil)
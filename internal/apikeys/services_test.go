@@ -0,0 +1,186 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+type mockAPIKeysRepo struct {
+	CreateKeyFunc    func(key db.APIKey, keyHash string) (*db.APIKey, error)
+	ListKeysFunc     func() ([]db.APIKey, error)
+	RevokeKeyFunc    func(id string) error
+	GetKeyByHashFunc func(keyHash string) (*db.APIKey, error)
+	TouchKeyFunc     func(id string, at time.Time) error
+}
+
+func (m *mockAPIKeysRepo) CreateKey(ctx context.Context, key db.APIKey, keyHash string) (*db.APIKey, error) {
+	return m.CreateKeyFunc(key, keyHash)
+}
+func (m *mockAPIKeysRepo) ListKeys(ctx context.Context) ([]db.APIKey, error) {
+	return m.ListKeysFunc()
+}
+func (m *mockAPIKeysRepo) RevokeKey(ctx context.Context, id string) error {
+	return m.RevokeKeyFunc(id)
+}
+func (m *mockAPIKeysRepo) GetKeyByHash(ctx context.Context, keyHash string) (*db.APIKey, error) {
+	return m.GetKeyByHashFunc(keyHash)
+}
+func (m *mockAPIKeysRepo) TouchKey(ctx context.Context, id string, at time.Time) error {
+	return m.TouchKeyFunc(id, at)
+}
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(repo *mockAPIKeysRepo) *APIKeysService {
+	s := NewAPIKeysService(repo, config.APIKeysConfig{DefaultTTL: 90 * 24 * time.Hour, MaxTTL: 365 * 24 * time.Hour})
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestAPIKeysService_Create(t *testing.T) {
+	var stored db.APIKey
+	var storedHash string
+	repo := &mockAPIKeysRepo{
+		CreateKeyFunc: func(key db.APIKey, keyHash string) (*db.APIKey, error) {
+			stored, storedHash = key, keyHash
+			key.ID = "key-1"
+			return &key, nil
+		},
+	}
+	s := newTestService(repo)
+
+	key, secret, err := s.Create(context.Background(), "admin-1", " discord bot ", []string{auth.PermPlacementsRead, auth.PermPostsRead}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(secret, auth.APIKeyPrefix) || len(secret) < 40 {
+		t.Errorf("expected a long %s key, got %q", auth.APIKeyPrefix, secret)
+	}
+	if storedHash != hashKey(secret) || strings.Contains(storedHash, secret) {
+		t.Error("expected only the hash of the key to be stored")
+	}
+	if !strings.HasPrefix(secret, stored.Prefix) || len(stored.Prefix) != displayPrefixLength {
+		t.Errorf("expected display prefix of the key, got %q", stored.Prefix)
+	}
+	if stored.Name != "discord bot" || stored.CreatedBy != "admin-1" {
+		t.Errorf("unexpected stored key %+v", stored)
+	}
+	if want := testNow.Add(90 * 24 * time.Hour); !key.ExpiresAt.Equal(want) {
+		t.Errorf("expected default expiry %v, got %v", want, key.ExpiresAt)
+	}
+
+	_, other, _ := s.Create(context.Background(), "admin-1", "script", []string{auth.PermStatsRead}, nil)
+	if other == secret {
+		t.Error("expected a different key every time")
+	}
+}
+
+func TestAPIKeysService_Create_Invalid(t *testing.T) {
+	repo := &mockAPIKeysRepo{
+		CreateKeyFunc: func(key db.APIKey, keyHash string) (*db.APIKey, error) {
+			t.Fatal("expected nothing to be stored")
+			return nil, nil
+		},
+	}
+	s := newTestService(repo)
+
+	past := testNow.Add(-time.Hour)
+	tooLate := testNow.Add(366 * 24 * time.Hour)
+
+	cases := []struct {
+		name      string
+		keyName   string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"empty name", " ", []string{auth.PermPostsRead}, nil},
+		{"long name", strings.Repeat("a", maxNameLength+1), []string{auth.PermPostsRead}, nil},
+		{"no scopes", "bot", nil, nil},
+		{"unknown scope", "bot", []string{"posts:write"}, nil},
+		{"repeated scope", "bot", []string{auth.PermPostsRead, auth.PermPostsRead}, nil},
+		{"expiry in the past", "bot", []string{auth.PermPostsRead}, &past},
+		{"expiry beyond max", "bot", []string{auth.PermPostsRead}, &tooLate},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, _, err := s.Create(context.Background(), "admin-1", c.keyName, c.scopes, c.expiresAt); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestAPIKeysService_VerifyAPIKey(t *testing.T) {
+	recent := testNow.Add(-10 * time.Second)
+	old := testNow.Add(-time.Hour)
+	revoked := testNow.Add(-time.Minute)
+
+	keys := map[string]*db.APIKey{
+		"plk_fresh":   {ID: "fresh", Scopes: []string{auth.PermPostsRead}, ExpiresAt: testNow.Add(time.Hour)},
+		"plk_recent":  {ID: "recent", Scopes: []string{auth.PermStatsRead}, ExpiresAt: testNow.Add(time.Hour), LastUsedAt: &recent},
+		"plk_old":     {ID: "old", Scopes: []string{auth.PermStatsRead}, ExpiresAt: testNow.Add(time.Hour), LastUsedAt: &old},
+		"plk_expired": {ID: "expired", Scopes: []string{auth.PermStatsRead}, ExpiresAt: testNow},
+		"plk_revoked": {ID: "revoked", Scopes: []string{auth.PermStatsRead}, ExpiresAt: testNow.Add(time.Hour), RevokedAt: &revoked},
+	}
+	byHash := map[string]*db.APIKey{}
+	for k, v := range keys {
+		byHash[hashKey(k)] = v
+	}
+
+	var touched []string
+	repo := &mockAPIKeysRepo{
+		GetKeyByHashFunc: func(keyHash string) (*db.APIKey, error) {
+			return byHash[keyHash], nil
+		},
+		TouchKeyFunc: func(id string, at time.Time) error {
+			touched = append(touched, id)
+			return errors.New("db down")
+		},
+	}
+	s := newTestService(repo)
+
+	cases := []struct {
+		key       string
+		wantValid bool
+		wantTouch bool
+	}{
+		{"plk_fresh", true, true},
+		{"plk_recent", true, false},
+		{"plk_old", true, true},
+		{"plk_expired", false, false},
+		{"plk_revoked", false, false},
+		{"plk_unknown", false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.key, func(t *testing.T) {
+			touched = nil
+			p, err := s.VerifyAPIKey(context.Background(), c.key)
+			if !c.wantValid {
+				if !errors.Is(err, auth.ErrInvalidAPIKey) {
+					t.Fatalf("expected ErrInvalidAPIKey, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			want := keys[c.key]
+			if !p.IsAPIKey() || p.ID != want.ID || !p.Can(want.Scopes[0]) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+			if p.Can(auth.PermPostsWrite) || p.Can(auth.PermAPIKeysManage) {
+				t.Error("expected the key to hold only its scopes")
+			}
+			if (len(touched) == 1) != c.wantTouch {
+				t.Errorf("expected touch=%v, got %v", c.wantTouch, touched)
+			}
+		})
+	}
+}
@@ -0,0 +1,767 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+var providerName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+/*
+Config is the complete runtime configuration of the server.
+It is loaded once at startup by Load and passed to every constructor that
+needs a setting; no other package reads the environment directly.
+
+Every field can be set from the YAML file (yaml tag) and overridden by an
+environment variable (env tag).
+*/
+type Config struct {
+	// Env names the deployment environment, e.g. "development" or "production".
+	Env string `yaml:"env" env:"APP_ENV"`
+
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Session   SessionConfig   `yaml:"session"`
+	Security  SecurityConfig  `yaml:"security_headers"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Login     LoginConfig     `yaml:"login"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail"`
+	Account   AccountConfig   `yaml:"account"`
+	SSO       SSOConfig       `yaml:"sso"`
+	APIKeys   APIKeysConfig   `yaml:"api_keys"`
+	Password  PasswordConfig  `yaml:"password"`
+	TwoFactor TwoFactorConfig `yaml:"two_factor"`
+	Profile   ProfileConfig   `yaml:"profile"`
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+}
+
+/*
+ServerConfig holds the HTTP listener settings.
+*/
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"HTTP_ADDR"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// DrainDelay is how long /readyz fails before the listener is closed on shutdown.
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"HTTP_DRAIN_DELAY"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For entry.
+	// Only enable it behind a reverse proxy that sets the header.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" env:"HTTP_TRUST_FORWARDED_FOR"`
+	// MaxBodyBytes bounds JSON request bodies; MaxPostBodyBytes replaces it
+	// for creating and updating posts, whose post_body can be long.
+	MaxBodyBytes     int64 `yaml:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES"`
+	MaxPostBodyBytes int64 `yaml:"max_post_body_bytes" env:"HTTP_MAX_POST_BODY_BYTES"`
+}
+
+/*
+DatabaseConfig holds the PostgreSQL connection and pool settings.
+The pool settings apply to both the primary and the read replica.
+*/
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DB_URL"`
+	// ReplicaURL is an optional read replica used for lag-tolerant reads.
+	ReplicaURL      string        `yaml:"replica_url" env:"DB_REPLICA_URL"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// ConnectRetries is how many times a failed startup ping is retried.
+	ConnectRetries    int           `yaml:"connect_retries" env:"DB_CONNECT_RETRIES"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF"`
+}
+
+/*
+JWTConfig holds the token signing keys and token lifetimes.
+*/
+type JWTConfig struct {
+	// Secret is the legacy HS256 key, required while no asymmetric keys are configured.
+	Secret string `yaml:"secret" env:"SECRET"`
+	// Keys are the asymmetric signing keys; they can only be set in the YAML file.
+	Keys []JWTKeyConfig `yaml:"keys"`
+	// RotationGrace keeps tokens signed by a replaced key valid for this long.
+	RotationGrace time.Duration `yaml:"rotation_grace" env:"JWT_ROTATION_GRACE"`
+	// AcceptHS256 keeps accepting tokens signed with Secret once keys are in use.
+	AcceptHS256   bool          `yaml:"accept_hs256" env:"JWT_ACCEPT_HS256"`
+	UserTokenTTL  time.Duration `yaml:"user_token_ttl" env:"JWT_USER_TOKEN_TTL"`
+	AdminTokenTTL time.Duration `yaml:"admin_token_ttl" env:"JWT_ADMIN_TOKEN_TTL"`
+	// ImpersonationTokenTTL is the lifetime of the tokens super admins use to act as a user.
+	ImpersonationTokenTTL time.Duration `yaml:"impersonation_token_ttl" env:"JWT_IMPERSONATION_TOKEN_TTL"`
+}
+
+/*
+JWTKeyConfig describes one asymmetric signing key stored as a PEM file.
+The key with the latest NotBefore in the past signs new tokens, so adding a
+key with a future NotBefore schedules a rotation.
+*/
+type JWTKeyConfig struct {
+	ID        string    `yaml:"kid"`
+	Algorithm string    `yaml:"algorithm"`
+	File      string    `yaml:"file"`
+	NotBefore time.Time `yaml:"not_before"`
+}
+
+/*
+CORSConfig holds the cross-origin policy for browser clients: the origins
+allowed to call the API, the methods and request headers they may use, the
+response headers their scripts may read, whether cookies and credentials
+are sent, and how long preflight results are cached. Origins may contain
+one "*" wildcard, e.g. "https://*.placementlog.example", but wildcards are
+refused in production while credentials are allowed.
+*/
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+/*
+SecurityConfig holds the security headers added to every response. An empty
+value leaves the header out; X-Content-Type-Options: nosniff is always sent.
+Individual routes may override these, e.g. so avatars can be embedded by
+the frontend.
+*/
+type SecurityConfig struct {
+	HSTS                      string `yaml:"hsts" env:"SECURITY_HSTS"`
+	ContentSecurityPolicy     string `yaml:"content_security_policy" env:"SECURITY_CONTENT_SECURITY_POLICY"`
+	ReferrerPolicy            string `yaml:"referrer_policy" env:"SECURITY_REFERRER_POLICY"`
+	FrameOptions              string `yaml:"frame_options" env:"SECURITY_FRAME_OPTIONS"`
+	CrossOriginResourcePolicy string `yaml:"cross_origin_resource_policy" env:"SECURITY_CROSS_ORIGIN_RESOURCE_POLICY"`
+}
+
+/*
+SessionConfig holds the optional cookie session mode for browser clients.
+When Cookies is set, logins store the token in the HttpOnly cookie
+CookieName instead of returning it in the response body, and the CSRF token
+of the session in the script-readable cookie CSRFCookieName. Requests
+authenticated by the cookie must echo that CSRF token in the X-CSRF-Token
+header unless they are GET, HEAD or OPTIONS. Bearer tokens keep working in
+either mode. SameSite is "strict", "lax" or "none"; "none" requires Secure.
+*/
+type SessionConfig struct {
+	Cookies        bool   `yaml:"cookies" env:"SESSION_COOKIES"`
+	CookieName     string `yaml:"cookie_name" env:"SESSION_COOKIE_NAME"`
+	CSRFCookieName string `yaml:"csrf_cookie_name" env:"SESSION_CSRF_COOKIE_NAME"`
+	// CookieDomain shares the cookies with subdomains, e.g. ".placementlog.example".
+	CookieDomain string `yaml:"cookie_domain" env:"SESSION_COOKIE_DOMAIN"`
+	Secure       bool   `yaml:"secure" env:"SESSION_COOKIE_SECURE"`
+	SameSite     string `yaml:"same_site" env:"SESSION_COOKIE_SAME_SITE"`
+}
+
+/*
+CacheConfig holds the response cache settings for the public read endpoints.
+Entries are invalidated on writes; TTL bounds how stale another instance's
+in-process cache can get.
+*/
+type CacheConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"CACHE_TTL"`
+}
+
+/*
+LoginConfig holds the brute-force protection settings for the login endpoints.
+After FreeAttempts failures on an account each further failure doubles the
+wait before its next attempt, starting at BackoffBase and capped at
+BackoffMax; reaching MaxFailures locks the account for LockoutDuration.
+A client IP is locked for LockoutDuration after IPMaxFailures failures
+across all accounts. Failures older than FailureWindow are forgotten.
+*/
+type LoginConfig struct {
+	FreeAttempts    int           `yaml:"free_attempts" env:"LOGIN_FREE_ATTEMPTS"`
+	MaxFailures     int           `yaml:"max_failures" env:"LOGIN_MAX_FAILURES"`
+	IPMaxFailures   int           `yaml:"ip_max_failures" env:"LOGIN_IP_MAX_FAILURES"`
+	BackoffBase     time.Duration `yaml:"backoff_base" env:"LOGIN_BACKOFF_BASE"`
+	BackoffMax      time.Duration `yaml:"backoff_max" env:"LOGIN_BACKOFF_MAX"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+	FailureWindow   time.Duration `yaml:"failure_window" env:"LOGIN_FAILURE_WINDOW"`
+}
+
+/*
+RateLimitConfig holds the per route group request budgets.
+Each group allows a burst of its Requests and refills at Requests per Window,
+counted per account for authenticated callers and per client IP otherwise.
+A group with zero Requests is not limited. Store is "memory" for a single
+instance or "postgres" to share budgets between replicas.
+*/
+type RateLimitConfig struct {
+	Store          string        `yaml:"store" env:"RATE_LIMIT_STORE"`
+	PublicRequests int           `yaml:"public_requests" env:"RATE_LIMIT_PUBLIC_REQUESTS"`
+	PublicWindow   time.Duration `yaml:"public_window" env:"RATE_LIMIT_PUBLIC_WINDOW"`
+	AuthRequests   int           `yaml:"auth_requests" env:"RATE_LIMIT_AUTH_REQUESTS"`
+	AuthWindow     time.Duration `yaml:"auth_window" env:"RATE_LIMIT_AUTH_WINDOW"`
+	UserRequests   int           `yaml:"user_requests" env:"RATE_LIMIT_USER_REQUESTS"`
+	UserWindow     time.Duration `yaml:"user_window" env:"RATE_LIMIT_USER_WINDOW"`
+	AdminRequests  int           `yaml:"admin_requests" env:"RATE_LIMIT_ADMIN_REQUESTS"`
+	AdminWindow    time.Duration `yaml:"admin_window" env:"RATE_LIMIT_ADMIN_WINDOW"`
+}
+
+/*
+MailConfig selects and configures the outgoing mail sender.
+Driver is "smtp" for real delivery, "file" to write messages to Dir, or
+"log" to print them, which is only meant for local development.
+*/
+type MailConfig struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"`
+}
+
+/*
+AccountConfig holds the registration, email verification and password reset
+settings. LinkBaseURL is the frontend address that the emailed links point
+to, e.g. https://placementlog.example; the frontend posts the token back to
+the API.
+
+RegistrationMode decides how new registrations are checked against the
+student roster:
+  - "open": any well-formed regno can register and log in immediately
+  - "roster": only regnos on the roster can register, and as in "pending"
+    mode the accounts cannot log in until the student confirms the college
+    email listed on the roster or an admin approves them
+  - "pending": accounts cannot log in until an admin approves them or the
+    student confirms the college email listed on the roster
+
+An account whose deletion is requested is purged after DeletionCoolingOff,
+unless the request is cancelled first. The server looks for due accounts
+every DeletionPurgeInterval; zero disables the in-process purge, leaving it
+to "plctl users purge".
+*/
+type AccountConfig struct {
+	RegistrationMode      string        `yaml:"registration_mode" env:"ACCOUNT_REGISTRATION_MODE"`
+	LinkBaseURL           string        `yaml:"link_base_url" env:"ACCOUNT_LINK_BASE_URL"`
+	EmailVerifyTTL        time.Duration `yaml:"email_verify_ttl" env:"ACCOUNT_EMAIL_VERIFY_TTL"`
+	PasswordResetTTL      time.Duration `yaml:"password_reset_ttl" env:"ACCOUNT_PASSWORD_RESET_TTL"`
+	DeletionCoolingOff    time.Duration `yaml:"deletion_cooling_off" env:"ACCOUNT_DELETION_COOLING_OFF"`
+	DeletionPurgeInterval time.Duration `yaml:"deletion_purge_interval" env:"ACCOUNT_DELETION_PURGE_INTERVAL"`
+}
+
+/*
+PasswordConfig holds the password policy and the argon2id cost parameters
+for new hashes. Argon2Memory is in KiB. BlocklistFile names a list of
+breached or common passwords, one per line, that are refused; empty
+disables the blocklist. Raising the cost parameters rehashes existing
+passwords the next time their owners log in.
+*/
+type PasswordConfig struct {
+	MinLength         int    `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MinClasses        int    `yaml:"min_classes" env:"PASSWORD_MIN_CLASSES"`
+	BlocklistFile     string `yaml:"blocklist_file" env:"PASSWORD_BLOCKLIST_FILE"`
+	Argon2Memory      int    `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY"`
+	Argon2Iterations  int    `yaml:"argon2_iterations" env:"PASSWORD_ARGON2_ITERATIONS"`
+	Argon2Parallelism int    `yaml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM"`
+}
+
+/*
+SSOConfig holds the OpenID Connect providers students can log in with, such
+as the college's Google Workspace or Microsoft Entra tenant. Providers can
+only be set in the YAML file. StateTTL bounds the time between starting a
+login and returning from the provider.
+*/
+type SSOConfig struct {
+	StateTTL  time.Duration       `yaml:"state_ttl" env:"SSO_STATE_TTL"`
+	Providers []SSOProviderConfig `yaml:"providers"`
+}
+
+/*
+SSOProviderConfig describes one OpenID Connect provider. Name appears in the
+login URLs (/auth/oidc/{name}/...). Scopes are requested in addition to
+"openid" and default to "email profile".
+
+The regno of a login is read from the email claim with EmailPattern, a
+regular expression with a named group "regno", e.g.
+"^(?P<regno>[0-9]{2}[a-z]{3}[0-9]{4})@college\.example$"; without a pattern
+the email is looked up on the student roster. Emails the provider has not
+marked as verified are refused unless TrustEmail is set, for providers such
+as Microsoft Entra that omit email_verified for managed accounts.
+
+An existing password account is only taken over by its first SSO login when
+AutoLink is set; otherwise the student links the provider while logged in.
+*/
+type SSOProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+	EmailPattern string   `yaml:"email_pattern"`
+	TrustEmail   bool     `yaml:"trust_email"`
+	AutoLink     bool     `yaml:"auto_link"`
+}
+
+/*
+APIKeysConfig holds the lifetimes of API keys: keys created without an
+expiry expire after DefaultTTL, and no key may live longer than MaxTTL.
+*/
+type APIKeysConfig struct {
+	DefaultTTL time.Duration `yaml:"default_ttl" env:"API_KEY_DEFAULT_TTL"`
+	MaxTTL     time.Duration `yaml:"max_ttl" env:"API_KEY_MAX_TTL"`
+}
+
+/*
+TwoFactorConfig holds the TOTP second factor settings.
+Accounts of the RequiredRoles ("user", "admin") must set up a second factor
+before their sessions are admitted to anything else; other accounts may
+enable one voluntarily. Issuer is the service name shown in authenticator
+apps.
+*/
+type TwoFactorConfig struct {
+	RequiredRoles []string `yaml:"required_roles" env:"TWO_FACTOR_REQUIRED_ROLES"`
+	Issuer        string   `yaml:"issuer" env:"TWO_FACTOR_ISSUER"`
+}
+
+/*
+ProfileConfig holds the limits for uploaded avatars. Uploads must be PNG,
+JPEG or GIF images of at most AvatarMaxBytes bytes whose width and height do
+not exceed AvatarMaxDimension pixels.
+*/
+type ProfileConfig struct {
+	AvatarMaxBytes     int64 `yaml:"avatar_max_bytes" env:"PROFILE_AVATAR_MAX_BYTES"`
+	AvatarMaxDimension int   `yaml:"avatar_max_dimension" env:"PROFILE_AVATAR_MAX_DIMENSION"`
+}
+
+/*
+TelemetryConfig holds the trace export settings.
+An empty OTLPEndpoint disables trace export.
+*/
+type TelemetryConfig struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+}
+
+/*
+Default returns the configuration used for every value that is not set in
+the YAML file or the environment.
+*/
+func Default() Config {
+	return Config{
+		Env: "development",
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			MaxBodyBytes:      64 * 1024,
+			MaxPostBodyBytes:  256 * 1024,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:      25,
+			MaxIdleConns:      25,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnMaxIdleTime:   5 * time.Minute,
+			ConnectRetries:    5,
+			ConnectBackoff:    500 * time.Millisecond,
+			ConnectMaxBackoff: 10 * time.Second,
+		},
+		JWT: JWTConfig{
+			RotationGrace:         24 * time.Hour,
+			AcceptHS256:           true,
+			UserTokenTTL:          24 * time.Hour,
+			AdminTokenTTL:         24 * time.Hour,
+			ImpersonationTokenTTL: 15 * time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+			ExposedHeaders: []string{"Link", "ETag", "Last-Modified", "Retry-After", "RateLimit-Limit",
+				"RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-CSRF-Token", "X-Impersonated-By"},
+			AllowCredentials: true,
+			MaxAge:           5 * time.Minute,
+		},
+		Security: SecurityConfig{
+			HSTS:                      "max-age=63072000; includeSubDomains",
+			ContentSecurityPolicy:     "default-src 'none'; frame-ancestors 'none'",
+			ReferrerPolicy:            "no-referrer",
+			FrameOptions:              "DENY",
+			CrossOriginResourcePolicy: "same-origin",
+		},
+		Session: SessionConfig{
+			CookieName:     "placementlog_session",
+			CSRFCookieName: "placementlog_csrf",
+			Secure:         true,
+			SameSite:       "lax",
+		},
+		Cache: CacheConfig{
+			TTL: time.Minute,
+		},
+		Login: LoginConfig{
+			FreeAttempts:    3,
+			MaxFailures:     10,
+			IPMaxFailures:   100,
+			BackoffBase:     time.Second,
+			BackoffMax:      time.Minute,
+			LockoutDuration: 15 * time.Minute,
+			FailureWindow:   15 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Store:          "memory",
+			PublicRequests: 300,
+			PublicWindow:   time.Minute,
+			AuthRequests:   20,
+			AuthWindow:     time.Minute,
+			UserRequests:   120,
+			UserWindow:     time.Minute,
+			AdminRequests:  600,
+			AdminWindow:    time.Minute,
+		},
+		Mail: MailConfig{
+			Driver:   "log",
+			From:     "PlacementLog <no-reply@localhost>",
+			SMTPPort: 587,
+			Dir:      "mail",
+		},
+		Account: AccountConfig{
+			RegistrationMode:      "open",
+			LinkBaseURL:           "http://localhost:3000",
+			EmailVerifyTTL:        24 * time.Hour,
+			PasswordResetTTL:      30 * time.Minute,
+			DeletionCoolingOff:    14 * 24 * time.Hour,
+			DeletionPurgeInterval: time.Hour,
+		},
+		SSO: SSOConfig{
+			StateTTL: 10 * time.Minute,
+		},
+		APIKeys: APIKeysConfig{
+			DefaultTTL: 90 * 24 * time.Hour,
+			MaxTTL:     365 * 24 * time.Hour,
+		},
+		Password: PasswordConfig{
+			MinLength:         10,
+			MinClasses:        2,
+			Argon2Memory:      19 * 1024,
+			Argon2Iterations:  2,
+			Argon2Parallelism: 1,
+		},
+		TwoFactor: TwoFactorConfig{
+			RequiredRoles: []string{"admin"},
+			Issuer:        "PlacementLog",
+		},
+		Profile: ProfileConfig{
+			AvatarMaxBytes:     512 * 1024,
+			AvatarMaxDimension: 1024,
+		},
+	}
+}
+
+/*
+Load builds the configuration and validates it.
+
+Parameters:
+- path: Optional YAML file; when empty, CONFIG_FILE is used, and when that is unset too no file is read
+
+Returns:
+- *Config: The validated configuration
+- error: Any error reading the file, parsing a value, or failing validation
+
+Precedence, lowest to highest:
+1. Default values
+2. The YAML file
+3. The .env file in the working directory
+4. Process environment variables
+
+The .env file never overrides a variable already present in the environment.
+*/
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	_ = godotenv.Load(".env")
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %v", err)
+		}
+
+		if err = yaml.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+	}
+
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+/*
+Validate checks that required values are present and that numeric values
+are usable. All problems are reported together.
+
+Returns:
+- error: A combined error listing every invalid setting, or nil
+*/
+func (c Config) Validate() error {
+	var errs []error
+
+	if len(c.JWT.Keys) == 0 && strings.TrimSpace(c.JWT.Secret) == "" {
+		errs = append(errs, errors.New("jwt secret is required (set SECRET)"))
+	}
+
+	for _, k := range c.JWT.Keys {
+		if k.ID == "" || k.File == "" || k.Algorithm == "" {
+			errs = append(errs, errors.New("jwt keys need kid, algorithm and file"))
+			break
+		}
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database url is required (set DB_URL)"))
+	}
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server address is required (set HTTP_ADDR)"))
+	}
+
+	if c.Server.MaxBodyBytes <= 0 || c.Server.MaxPostBodyBytes <= 0 {
+		errs = append(errs, errors.New("request body limits must be positive"))
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database pool sizes must not be negative"))
+	}
+
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database max idle connections must not exceed max open connections"))
+	}
+
+	if c.Database.ConnectRetries < 0 || c.Database.ConnectBackoff <= 0 || c.Database.ConnectMaxBackoff < c.Database.ConnectBackoff {
+		errs = append(errs, errors.New("database connect retries must not be negative and backoffs must be positive with max >= initial"))
+	}
+
+	if c.JWT.UserTokenTTL <= 0 || c.JWT.AdminTokenTTL <= 0 {
+		errs = append(errs, errors.New("token lifetimes must be positive"))
+	}
+
+	if c.JWT.ImpersonationTokenTTL <= 0 || c.JWT.ImpersonationTokenTTL > time.Hour {
+		errs = append(errs, errors.New("impersonation token lifetime must be positive and at most 1h"))
+	}
+
+	if c.JWT.RotationGrace < 0 {
+		errs = append(errs, errors.New("jwt rotation grace must not be negative"))
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("cors allowed origins are required (set CORS_ALLOWED_ORIGINS)"))
+	}
+
+	if c.IsProduction() && c.CORS.AllowCredentials {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if strings.Contains(origin, "*") {
+				errs = append(errs, errors.New("cors allowed origins must not contain wildcards in production while credentials are allowed"))
+				break
+			}
+		}
+	}
+
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("cors max age must not be negative"))
+	}
+
+	if c.Session.Cookies {
+		errs = append(errs, c.Session.validate(c.CORS.AllowedOrigins, c.IsProduction())...)
+	}
+
+	if c.Login.FreeAttempts < 0 || c.Login.MaxFailures <= c.Login.FreeAttempts || c.Login.IPMaxFailures <= c.Login.FreeAttempts {
+		errs = append(errs, errors.New("login max failures must exceed free attempts"))
+	}
+
+	if c.Login.BackoffBase <= 0 || c.Login.BackoffMax < c.Login.BackoffBase || c.Login.LockoutDuration <= 0 || c.Login.FailureWindow <= 0 {
+		errs = append(errs, errors.New("login backoffs, lockout duration and failure window must be positive with max >= base"))
+	}
+
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+		errs = append(errs, errors.New("rate limit store must be \"memory\" or \"postgres\""))
+	}
+
+	rateLimits := []struct {
+		requests int
+		window   time.Duration
+	}{
+		{c.RateLimit.PublicRequests, c.RateLimit.PublicWindow},
+		{c.RateLimit.AuthRequests, c.RateLimit.AuthWindow},
+		{c.RateLimit.UserRequests, c.RateLimit.UserWindow},
+		{c.RateLimit.AdminRequests, c.RateLimit.AdminWindow},
+	}
+	for _, l := range rateLimits {
+		if l.requests < 0 || (l.requests > 0 && l.window <= 0) {
+			errs = append(errs, errors.New("rate limit requests must not be negative and windows must be positive"))
+			break
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			errs = append(errs, errors.New("smtp host is required for the smtp mail driver (set SMTP_HOST)"))
+		}
+	case "file", "log":
+		if c.IsProduction() {
+			errs = append(errs, errors.New("mail driver must be \"smtp\" in production"))
+		}
+	default:
+		errs = append(errs, errors.New("mail driver must be \"smtp\", \"file\" or \"log\""))
+	}
+
+	switch c.Account.RegistrationMode {
+	case "open", "roster", "pending":
+	default:
+		errs = append(errs, errors.New("registration mode must be \"open\", \"roster\" or \"pending\""))
+	}
+
+	if c.Account.EmailVerifyTTL <= 0 || c.Account.PasswordResetTTL <= 0 {
+		errs = append(errs, errors.New("email verification and password reset lifetimes must be positive"))
+	}
+
+	if c.Account.DeletionCoolingOff < 0 || c.Account.DeletionPurgeInterval < 0 {
+		errs = append(errs, errors.New("account deletion cooling-off and purge interval must not be negative"))
+	}
+
+	errs = append(errs, c.SSO.validate(c.IsProduction())...)
+
+	if c.APIKeys.DefaultTTL <= 0 || c.APIKeys.MaxTTL < c.APIKeys.DefaultTTL {
+		errs = append(errs, errors.New("api key lifetimes must be positive with max >= default"))
+	}
+
+	if c.Password.MinLength < 8 || c.Password.MinLength > 128 || c.Password.MinClasses < 0 || c.Password.MinClasses > 4 {
+		errs = append(errs, errors.New("password min length must be between 8 and 128 and min classes between 0 and 4"))
+	}
+
+	if c.Password.Argon2Iterations < 1 || c.Password.Argon2Parallelism < 1 || c.Password.Argon2Parallelism > 255 ||
+		c.Password.Argon2Memory < 8*c.Password.Argon2Parallelism || c.Password.Argon2Memory > 4*1024*1024 {
+		errs = append(errs, errors.New("argon2 iterations and parallelism (at most 255) must be positive and memory between 8*parallelism KiB and 4 GiB"))
+	}
+
+	for _, role := range c.TwoFactor.RequiredRoles {
+		if role != "user" && role != "admin" {
+			errs = append(errs, errors.New("two-factor required roles must be \"user\" or \"admin\""))
+			break
+		}
+	}
+
+	if c.TwoFactor.Issuer == "" {
+		errs = append(errs, errors.New("two-factor issuer is required (set TWO_FACTOR_ISSUER)"))
+	}
+
+	if c.Profile.AvatarMaxBytes <= 0 || c.Profile.AvatarMaxDimension <= 0 {
+		errs = append(errs, errors.New("avatar max bytes and max dimension must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+/*
+validate checks the cookie session settings. Cookies are sent with
+credentialed cross-origin requests, so every allowed CORS origin must be
+listed explicitly.
+*/
+func (s SessionConfig) validate(origins []string, production bool) []error {
+	var errs []error
+
+	if s.CookieName == "" || s.CSRFCookieName == "" || s.CookieName == s.CSRFCookieName {
+		errs = append(errs, errors.New("session cookie names must be set and differ"))
+	}
+
+	switch s.SameSite {
+	case "strict", "lax":
+	case "none":
+		if !s.Secure {
+			errs = append(errs, errors.New("session cookies with same_site \"none\" must be secure"))
+		}
+	default:
+		errs = append(errs, errors.New("session same_site must be \"strict\", \"lax\" or \"none\""))
+	}
+
+	if production && !s.Secure {
+		errs = append(errs, errors.New("session cookies must be secure in production"))
+	}
+
+	for _, origin := range origins {
+		if strings.Contains(origin, "*") {
+			errs = append(errs, errors.New("cors allowed origins must not contain wildcards when session cookies are enabled"))
+			break
+		}
+	}
+
+	return errs
+}
+
+/*
+IsProduction reports whether the server runs in the production environment.
+*/
+func (c Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+/*
+validate checks the SSO providers: names must be unique URL path segments,
+every provider needs an issuer, client ID and redirect URL, and email
+patterns must compile with a "regno" group. Issuers must use HTTPS in
+production.
+*/
+func (s SSOConfig) validate(production bool) []error {
+	var errs []error
+
+	if s.StateTTL <= 0 {
+		errs = append(errs, errors.New("sso state ttl must be positive"))
+	}
+
+	names := make(map[string]bool)
+	for _, p := range s.Providers {
+		if !providerName.MatchString(p.Name) || names[p.Name] {
+			errs = append(errs, fmt.Errorf("sso provider names must be unique and use only a-z, 0-9 and \"-\" (got %q)", p.Name))
+		}
+		names[p.Name] = true
+
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			errs = append(errs, fmt.Errorf("sso provider %q needs issuer, client_id and redirect_url", p.Name))
+		}
+
+		if production && !strings.HasPrefix(p.Issuer, "https://") {
+			errs = append(errs, fmt.Errorf("sso provider %q issuer must use https in production", p.Name))
+		}
+
+		if p.EmailPattern != "" {
+			re, err := regexp.Compile(p.EmailPattern)
+			if err != nil || re.SubexpIndex("regno") < 0 {
+				errs = append(errs, fmt.Errorf("sso provider %q email_pattern must be a valid regular expression with a (?P<regno>...) group", p.Name))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  addr: ":9000"
+  read_timeout: 7s
+database:
+  url: postgres://from-yaml
+  max_open_conns: 10
+jwt:
+  secret: yaml-secret
+`)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SECRET", "env-secret")
+	t.Setenv("DB_MAX_OPEN_CONNS", "40")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("expected yaml addr :9000, got %q", cfg.Server.Addr)
+	}
+	if cfg.Server.ReadTimeout != 7*time.Second {
+		t.Errorf("expected yaml read timeout 7s, got %v", cfg.Server.ReadTimeout)
+	}
+	if cfg.Server.WriteTimeout != Default().Server.WriteTimeout {
+		t.Errorf("expected default write timeout, got %v", cfg.Server.WriteTimeout)
+	}
+	if cfg.Database.URL != "postgres://from-yaml" {
+		t.Errorf("expected yaml database url, got %q", cfg.Database.URL)
+	}
+	if cfg.JWT.Secret != "env-secret" {
+		t.Errorf("expected env to override yaml secret, got %q", cfg.JWT.Secret)
+	}
+	if cfg.Database.MaxOpenConns != 40 {
+		t.Errorf("expected env max open conns 40, got %d", cfg.Database.MaxOpenConns)
+	}
+	want := []string{"https://a.example", "https://b.example"}
+	if !reflect.DeepEqual(cfg.CORS.AllowedOrigins, want) {
+		t.Errorf("expected origins %v, got %v", want, cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoad_RejectsEmptySecret(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SECRET", "  ")
+	t.Setenv("DB_URL", "postgres://localhost/test")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "jwt secret is required") {
+		t.Errorf("expected jwt secret error, got %v", err)
+	}
+}
+
+func TestLoad_InvalidEnvValue(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SECRET", "secret")
+	t.Setenv("DB_URL", "postgres://localhost/test")
+	t.Setenv("HTTP_READ_TIMEOUT", "fast")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "HTTP_READ_TIMEOUT") {
+		t.Errorf("expected error naming HTTP_READ_TIMEOUT, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.JWT.Secret = "secret"
+	valid.Database.URL = "postgres://localhost/test"
+
+	cases := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"missing db url", func(c *Config) { c.Database.URL = "" }, "database url is required"},
+		{"zero body limit", func(c *Config) { c.Server.MaxPostBodyBytes = 0 }, "request body limits"},
+		{"idle above open", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 5, 10 }, "max idle"},
+		{"backoff above max", func(c *Config) { c.Database.ConnectBackoff = time.Minute }, "backoffs"},
+		{"zero ttl", func(c *Config) { c.JWT.UserTokenTTL = 0 }, "token lifetimes"},
+		{"long impersonation ttl", func(c *Config) { c.JWT.ImpersonationTokenTTL = 2 * time.Hour }, "impersonation token lifetime"},
+		{"unknown rate limit store", func(c *Config) { c.RateLimit.Store = "redis" }, "rate limit store"},
+		{"rate limit without window", func(c *Config) { c.RateLimit.UserWindow = 0 }, "windows must be positive"},
+		{"rate limit disabled", func(c *Config) { c.RateLimit.UserRequests, c.RateLimit.UserWindow = 0, 0 }, ""},
+		{"log mail in production", func(c *Config) { c.Env = "production" }, "mail driver must be"},
+		{"smtp without host", func(c *Config) { c.Mail.Driver = "smtp" }, "smtp host"},
+		{"unknown registration mode", func(c *Config) { c.Account.RegistrationMode = "closed" }, "registration mode"},
+		{"short passwords", func(c *Config) { c.Password.MinLength = 6 }, "password min length"},
+		{"argon2 memory too low", func(c *Config) { c.Password.Argon2Memory = 4 }, "argon2"},
+		{"unknown two-factor role", func(c *Config) { c.TwoFactor.RequiredRoles = []string{"guest"} }, "two-factor required roles"},
+		{"negative deletion cooling-off", func(c *Config) { c.Account.DeletionCoolingOff = -time.Hour }, "cooling-off"},
+		{"zero avatar size", func(c *Config) { c.Profile.AvatarMaxBytes = 0 }, "avatar max bytes"},
+		{"lockout below free attempts", func(c *Config) { c.Login.MaxFailures = 2 }, "login max failures"},
+		{"session cookies with wildcard origins", func(c *Config) {
+			c.Session.Cookies, c.CORS.AllowedOrigins = true, []string{"https://*"}
+		}, "must not contain wildcards"},
+		{"session cookies", func(c *Config) { c.Session.Cookies = true }, ""},
+		{"no cors origins", func(c *Config) { c.CORS.AllowedOrigins = nil }, "cors allowed origins are required"},
+		{"wildcard origins in development", func(c *Config) { c.CORS.AllowedOrigins = []string{"http://*"} }, ""},
+		{"wildcard origins with credentials in production", func(c *Config) {
+			c.Env, c.CORS.AllowedOrigins = "production", []string{"https://*"}
+		}, "wildcards in production"},
+		{"insecure same_site none", func(c *Config) {
+			c.Session.Cookies, c.Session.SameSite, c.Session.Secure = true, "none", false
+		}, "must be secure"},
+		{"api key default above max", func(c *Config) { c.APIKeys.DefaultTTL = 2 * c.APIKeys.MaxTTL }, "api key lifetimes"},
+		{"sso provider", func(c *Config) {
+			c.SSO.Providers = []SSOProviderConfig{{
+				Name: "college", Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: "http://localhost:3000/sso",
+				EmailPattern: `^(?P<regno>[0-9]{2}[a-z]{3}[0-9]{4})@college\.example$`,
+			}}
+		}, ""},
+		{"duplicate sso providers", func(c *Config) {
+			p := SSOProviderConfig{Name: "college", Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: "http://localhost:3000/sso"}
+			c.SSO.Providers = []SSOProviderConfig{p, p}
+		}, "sso provider names must be unique"},
+		{"sso pattern without regno group", func(c *Config) {
+			c.SSO.Providers = []SSOProviderConfig{{
+				Name: "college", Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: "http://localhost:3000/sso",
+				EmailPattern: `^[0-9]{2}[a-z]{3}[0-9]{4}@college\.example$`,
+			}}
+		}, "(?P<regno>...) group"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := valid
+			c.mutate(&cfg)
+			err := cfg.Validate()
+			if c.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/varnit-ta/PlacementLog/internal/config"
+)
+
+// fakeServer stands in for a database server; while down, connections and
+// queries fail.
+type fakeServer struct {
+	name    string
+	down    bool
+	queries int
+}
+
+func (s *fakeServer) Connect(context.Context) (driver.Conn, error) {
+	if s.down {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New(s.name + " is down")}
+	}
+	return &fakeConn{server: s}, nil
+}
+
+func (s *fakeServer) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use a connector") }
+
+// fakeConn answers every query with a single row holding the server name.
+type fakeConn struct{ server *fakeServer }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.server.queries++
+	if c.server.down {
+		return nil, driver.ErrBadConn
+	}
+	return &fakeRows{value: c.server.name}, nil
+}
+
+type fakeRows struct {
+	value string
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"server"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// testDialer opens pools on the given servers, keyed by URL, and records the
+// sleeps instead of waiting.
+type testDialer struct {
+	dialer
+	sleeps []time.Duration
+	clock  time.Time
+}
+
+func newTestDialer(servers map[string]*fakeServer) *testDialer {
+	t := &testDialer{clock: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	t.dialer = dialer{
+		open: func(url string) (*sql.DB, error) {
+			server, ok := servers[url]
+			if !ok {
+				return nil, errors.New("unknown url " + url)
+			}
+			return sql.OpenDB(server), nil
+		},
+		ping:  func(ctx context.Context, conn *sql.DB) error { return conn.PingContext(ctx) },
+		sleep: func(d time.Duration) { t.sleeps = append(t.sleeps, d) },
+		now:   func() time.Time { return t.clock },
+	}
+	return t
+}
+
+func testDBConfig() config.DatabaseConfig {
+	return config.DatabaseConfig{
+		URL:               "primary",
+		ConnectRetries:    5,
+		ConnectBackoff:    time.Second,
+		ConnectMaxBackoff: 3 * time.Second,
+	}
+}
+
+// readFrom runs QueryRead and returns the name of the server that answered.
+func readFrom(t *testing.T, d *DB) string {
+	t.Helper()
+
+	rows, err := d.QueryRead(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatalf("QueryRead: %v", err)
+	}
+	defer rows.Close()
+
+	var server string
+	if !rows.Next() {
+		t.Fatalf("QueryRead returned no rows: %v", rows.Err())
+	}
+	if err := rows.Scan(&server); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	return server
+}
+
+func TestInit_RetriesPingWithBackoff(t *testing.T) {
+	dialer := newTestDialer(map[string]*fakeServer{"primary": {name: "primary"}})
+
+	failures := 4
+	ping := dialer.ping
+	dialer.ping = func(ctx context.Context, conn *sql.DB) error {
+		if failures > 0 {
+			failures--
+			return errors.New("starting up")
+		}
+		return ping(ctx, conn)
+	}
+
+	d, err := dialer.init(testDBConfig())
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer d.Close()
+
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	if len(dialer.sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", dialer.sleeps, want)
+	}
+	for i := range want {
+		if dialer.sleeps[i] != want[i] {
+			t.Fatalf("sleeps = %v, want %v", dialer.sleeps, want)
+		}
+	}
+}
+
+func TestInit_GivesUpAfterRetries(t *testing.T) {
+	dialer := newTestDialer(map[string]*fakeServer{"primary": {name: "primary", down: true}})
+
+	cfg := testDBConfig()
+	cfg.ConnectRetries = 2
+
+	_, err := dialer.init(cfg)
+	if err == nil || !strings.Contains(err.Error(), "error pinging database") {
+		t.Fatalf("init error = %v, want a ping error", err)
+	}
+	if len(dialer.sleeps) != 2 {
+		t.Errorf("slept %d times, want 2", len(dialer.sleeps))
+	}
+}
+
+func TestInit_OpenError(t *testing.T) {
+	dialer := newTestDialer(nil)
+
+	_, err := dialer.init(testDBConfig())
+	if err == nil || !strings.Contains(err.Error(), "error connecting to the database") {
+		t.Fatalf("init error = %v, want a connection error", err)
+	}
+}
+
+func TestQueryRead_UsesReplica(t *testing.T) {
+	dialer := newTestDialer(map[string]*fakeServer{
+		"primary": {name: "primary"},
+		"replica": {name: "replica"},
+	})
+
+	cfg := testDBConfig()
+	cfg.ReplicaURL = "replica"
+
+	d, err := dialer.init(cfg)
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer d.Close()
+
+	if got := readFrom(t, d); got != "replica" {
+		t.Errorf("read from %q, want replica", got)
+	}
+}
+
+func TestQueryRead_FallsBackWhenReplicaQueryFails(t *testing.T) {
+	replica := &fakeServer{name: "replica"}
+	dialer := newTestDialer(map[string]*fakeServer{
+		"primary": {name: "primary"},
+		"replica": replica,
+	})
+
+	cfg := testDBConfig()
+	cfg.ReplicaURL = "replica"
+
+	d, err := dialer.init(cfg)
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer d.Close()
+
+	replica.down = true
+
+	if got := readFrom(t, d); got != "primary" {
+		t.Errorf("read from %q, want primary", got)
+	}
+
+	// The connection error marks the replica down, so reads no longer wait
+	// for it until its retry time.
+	queries := replica.queries
+	if got := readFrom(t, d); got != "primary" || replica.queries != queries {
+		t.Errorf("read from %q with %d replica queries, want primary without trying the replica", got, replica.queries-queries)
+	}
+
+	replica.down = false
+	dialer.clock = dialer.clock.Add(time.Second)
+	if got := readFrom(t, d); got != "replica" {
+		t.Errorf("read from %q after the replica came back, want replica", got)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{driver.ErrBadConn, true},
+		{io.ErrUnexpectedEOF, true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "42P01"}, false},
+		{context.Canceled, false},
+	}
+	for _, c := range cases {
+		if got := isConnectionError(c.err); got != c.want {
+			t.Errorf("isConnectionError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestQueryRead_RetriesReplicaDownAtStartup(t *testing.T) {
+	replica := &fakeServer{name: "replica", down: true}
+	dialer := newTestDialer(map[string]*fakeServer{
+		"primary": {name: "primary"},
+		"replica": replica,
+	})
+
+	cfg := testDBConfig()
+	cfg.ReplicaURL = "replica"
+	cfg.ConnectRetries = 0
+
+	d, err := dialer.init(cfg)
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer d.Close()
+
+	if d.Replica() == nil {
+		t.Fatal("the replica pool was dropped")
+	}
+	if got := readFrom(t, d); got != "primary" {
+		t.Fatalf("read from %q while the replica is down, want primary", got)
+	}
+
+	// A failed retry doubles the wait before the next one.
+	dialer.clock = dialer.clock.Add(time.Second)
+	readFrom(t, d)
+	if d.backoff != 2*time.Second {
+		t.Errorf("backoff = %v after a failed retry, want 2s", d.backoff)
+	}
+
+	// The replica is not pinged again before the retry time.
+	replica.down = false
+	dialer.clock = dialer.clock.Add(time.Second)
+	if got := readFrom(t, d); got != "primary" {
+		t.Errorf("read from %q before the retry time, want primary", got)
+	}
+
+	dialer.clock = dialer.clock.Add(time.Second)
+	if got := readFrom(t, d); got != "replica" {
+		t.Errorf("read from %q after the replica came back, want replica", got)
+	}
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the pg_advisory_lock key that serialises migration runs
+// across server instances and plctl.
+const migrationLock int64 = 0x706c6d6967726174
+
+// querier is satisfied by both *sql.DB and *sql.Conn, so the migration state
+// can be read on the connection that holds the migration lock.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+/*
+migrations returns the versions of all embedded migrations in apply order.
+A migration's version is its file name without the .sql extension,
+e.g. "0001_init". Files are applied in lexical order, so new migrations
+must use the next zero-padded number.
+*/
+func migrations() ([]string, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		versions = append(versions, strings.TrimSuffix(e.Name(), ".sql"))
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn querier) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+
+	return nil
+}
+
+/*
+PendingMigrations lists the embedded migrations that have not been applied yet.
+It only reads: before the first migration run there is no schema_migrations
+table, and every migration is pending. Readiness probes call it, so it must
+not take the locks that DDL would.
+
+Parameters:
+- ctx: Context bounding the query
+- conn: The database connection
+
+Returns:
+- []string: Versions of the pending migrations, in apply order
+- error: Any error that occurred while reading migration state
+*/
+func PendingMigrations(ctx context.Context, conn *sql.DB) ([]string, error) {
+	return pendingMigrations(ctx, conn)
+}
+
+func pendingMigrations(ctx context.Context, conn querier) ([]string, error) {
+	all, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var tracked bool
+	err = conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL;`).Scan(&tracked)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migration state: %v", err)
+	}
+	if !tracked {
+		return all, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("error scanning applied migrations: %v", err)
+		}
+		applied[version] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+
+	pending := []string{}
+	for _, version := range all {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+
+	return pending, nil
+}
+
+/*
+Migrate applies all pending embedded migrations.
+Each migration runs in its own transaction together with the insert into
+schema_migrations, so a failed migration leaves no partial state behind.
+Runs are serialised with a session advisory lock held on a dedicated
+connection, so instances starting together do not apply the same migration
+twice; a run that waits for the lock sees the others' migrations as applied.
+
+Parameters:
+- ctx: Context bounding the migration run, including the wait for the lock
+- db: The database pool; one connection is reserved for the whole run
+
+Returns:
+- error: The first error encountered; migrations after it are not applied
+*/
+func Migrate(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error reserving a migration connection: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLock); err != nil {
+		return fmt.Errorf("error taking the migration lock: %v", err)
+	}
+	defer func() {
+		// The lock belongs to the session; if it cannot be released, drop the
+		// connection instead of returning it to the pool still holding it.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLock); err != nil {
+			log.Printf("error releasing the migration lock: %v\n", err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	pending, err := pendingMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range pending {
+		script, err := migrationFiles.ReadFile("migrations/" + version + ".sql")
+		if err != nil {
+			return fmt.Errorf("error reading migration %s: %v", version, err)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("error starting migration %s: %v", version, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying migration %s: %v", version, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1);`, version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error recording migration %s: %v", version, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("error committing migration %s: %v", version, err)
+		}
+
+		log.Printf("Applied migration %s\n", version)
+	}
+
+	return nil
+}
@@ -1,11 +1,11 @@
--- Placement Log Database Schema
+-- 0001_init: baseline schema
 
 -- Users table
 CREATE TABLE IF NOT EXISTS placement_log_users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     regno VARCHAR(20) UNIQUE NOT NULL, -- Registration number (e.g., 22bcs1234)
     username VARCHAR(255) NOT NULL,    -- User's name (not unique)
-    password_hash VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -14,7 +14,7 @@ CREATE TABLE IF NOT EXISTS placement_log_users (
 CREATE TABLE IF NOT EXISTS placement_log_admins (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     username VARCHAR(255) UNIQUE NOT NULL,
-    password_hash VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -61,6 +61,9 @@ END;
 $$ language 'plpgsql';
 
 -- Apply trigger to all tables
+DROP TRIGGER IF EXISTS update_users_updated_at ON placement_log_users;
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON placement_log_users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_admins_updated_at ON placement_log_admins;
 CREATE TRIGGER update_admins_updated_at BEFORE UPDATE ON placement_log_admins FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-CREATE TRIGGER update_posts_updated_at BEFORE UPDATE ON placement_log_posts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column(); 
\ No newline at end of file
+DROP TRIGGER IF EXISTS update_posts_updated_at ON placement_log_posts;
+CREATE TRIGGER update_posts_updated_at BEFORE UPDATE ON placement_log_posts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,14 @@
+-- 0002_login_events: failed logins, lockouts and unlocks
+
+CREATE TABLE IF NOT EXISTS placement_log_login_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_type VARCHAR(10) NOT NULL,  -- 'user' or 'admin'
+    account VARCHAR(255) NOT NULL,      -- regno or admin username as submitted
+    ip VARCHAR(64) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,       -- 'failed', 'locked', 'ip_locked' or 'unlocked'
+    actor_id UUID,                      -- admin who performed an unlock
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_account ON placement_log_login_events(account_type, account, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_ip ON placement_log_login_events(ip, created_at);
@@ -0,0 +1,10 @@
+-- 0003_rate_limits: token buckets shared between API replicas
+
+CREATE TABLE IF NOT EXISTS placement_log_rate_limits (
+    key VARCHAR(255) PRIMARY KEY,       -- route group and principal, e.g. 'public:ip:203.0.113.7'
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,           -- outcome of the last take
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_updated_at ON placement_log_rate_limits(updated_at);
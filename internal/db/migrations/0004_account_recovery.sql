@@ -0,0 +1,22 @@
+-- 0004_account_recovery: verified email addresses and single-use account tokens
+
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Only verified addresses are stored on the user, so each may belong to one account.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON placement_log_users(email);
+
+-- Email verification and password reset tokens. Only the SHA-256 hash of a
+-- token is stored; the token itself is only ever sent to the user.
+CREATE TABLE IF NOT EXISTS placement_log_user_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES placement_log_users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL,       -- 'verify_email' or 'reset_password'
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    email VARCHAR(255),                 -- address being verified
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON placement_log_user_tokens(user_id, purpose);
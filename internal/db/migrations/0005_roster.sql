@@ -0,0 +1,16 @@
+-- 0005_roster: official student roster and pending registrations
+
+CREATE TABLE IF NOT EXISTS placement_log_roster (
+    regno VARCHAR(20) PRIMARY KEY,      -- Registration number (e.g., 22bcs1234)
+    name VARCHAR(255) NOT NULL,
+    branch VARCHAR(10) NOT NULL,
+    batch VARCHAR(10) NOT NULL,
+    email VARCHAR(255) NOT NULL,        -- College email address
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 'active' or 'pending' (waiting for admin approval or college email confirmation).
+-- Existing accounts stay active.
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON placement_log_users(status);
@@ -0,0 +1,25 @@
+-- 0006_two_factor: TOTP second factors and recovery codes for users and admins
+
+CREATE TABLE IF NOT EXISTS placement_log_two_factor (
+    account_type VARCHAR(10) NOT NULL,  -- 'user' or 'admin'
+    account_id UUID NOT NULL,
+    secret VARCHAR(64) NOT NULL,        -- base32 TOTP secret
+    enabled_at TIMESTAMP,               -- NULL until the first code is confirmed
+    last_step BIGINT NOT NULL DEFAULT 0, -- last accepted time step, to reject replayed codes
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_type, account_id)
+);
+
+-- Single-use recovery codes. Only the SHA-256 hash of a code is stored.
+CREATE TABLE IF NOT EXISTS placement_log_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_type VARCHAR(10) NOT NULL,
+    account_id UUID NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_type, account_id)
+        REFERENCES placement_log_two_factor(account_type, account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_account ON placement_log_recovery_codes(account_type, account_id);
@@ -0,0 +1,8 @@
+-- 0007_admin_management: admin roles and disabled admins
+
+ALTER TABLE placement_log_admins ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'admin';
+ALTER TABLE placement_log_admins ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+
+-- Every admin could register other admins before roles existed; existing
+-- accounts keep that ability.
+UPDATE placement_log_admins SET role = 'super_admin';
@@ -0,0 +1,15 @@
+-- 0008_profiles: self-service profile fields and avatars
+
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS display_name VARCHAR(50);
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS bio TEXT;
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS linkedin_url VARCHAR(255);
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS graduation_year INT;
+
+-- Avatars are small, validated images served from the API, so they are kept
+-- in the database instead of a separate object store.
+CREATE TABLE IF NOT EXISTS placement_log_avatars (
+    user_id UUID PRIMARY KEY REFERENCES placement_log_users(id) ON DELETE CASCADE,
+    content_type VARCHAR(50) NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
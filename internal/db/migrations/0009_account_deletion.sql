@@ -0,0 +1,15 @@
+-- 0009_account_deletion: scheduled account deletion that keeps authored posts
+
+-- Set when the user asks for their account to be deleted; the account is
+-- purged once this time has passed unless the request is cancelled.
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled ON placement_log_users(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Posts outlive their authors: purging an account anonymizes its posts
+-- instead of deleting them.
+ALTER TABLE placement_log_posts ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE placement_log_posts DROP CONSTRAINT IF EXISTS placement_log_posts_user_id_fkey;
+ALTER TABLE placement_log_posts ADD CONSTRAINT placement_log_posts_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES placement_log_users(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- 0010_user_moderation: suspensions, forced password resets and the moderation log
+
+-- A suspension blocks logins and existing tokens until suspended_until;
+-- a suspension without an end is a ban.
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+-- Set by an admin; the account is blocked until the password is reset
+-- through an emailed link.
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Search by name
+CREATE INDEX IF NOT EXISTS idx_users_username ON placement_log_users(LOWER(username));
+
+-- Admin actions on user accounts
+CREATE TABLE IF NOT EXISTS placement_log_moderation_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES placement_log_users(id) ON DELETE CASCADE,
+    admin_id UUID REFERENCES placement_log_admins(id) ON DELETE SET NULL,
+    action VARCHAR(30) NOT NULL,        -- suspend, ban, unsuspend, force_password_reset, verify, unverify
+    reason TEXT,
+    expires_at TIMESTAMP,               -- end of a suspension
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_user ON placement_log_moderation_log(user_id, created_at);
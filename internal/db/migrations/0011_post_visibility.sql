@@ -0,0 +1,15 @@
+-- 0011_post_visibility: per-post choice of how the author is shown
+
+-- 'named' shows the author's display name, 'pseudonym' the author's stable
+-- pseudonym and 'anonymous' nothing. Existing posts stay named.
+ALTER TABLE placement_log_posts ADD COLUMN IF NOT EXISTS author_visibility VARCHAR(20) NOT NULL DEFAULT 'named';
+
+ALTER TABLE placement_log_posts DROP CONSTRAINT IF EXISTS placement_log_posts_author_visibility_check;
+ALTER TABLE placement_log_posts ADD CONSTRAINT placement_log_posts_author_visibility_check
+    CHECK (author_visibility IN ('named', 'pseudonym', 'anonymous'));
+
+-- Assigned at random the first time a user posts under a pseudonym, so it
+-- cannot be derived from the user ID.
+ALTER TABLE placement_log_users ADD COLUMN IF NOT EXISTS pseudonym VARCHAR(50);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_pseudonym ON placement_log_users(pseudonym);
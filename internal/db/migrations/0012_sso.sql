@@ -0,0 +1,31 @@
+-- 0012_sso: OpenID Connect logins and the accounts linked to them
+
+-- In-flight logins, from starting at /auth/oidc/{provider}/start until the
+-- callback. Only the hash of the state is stored; the nonce and the PKCE
+-- code verifier never leave the server.
+CREATE TABLE IF NOT EXISTS placement_log_oidc_states (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(100) NOT NULL,
+    code_verifier VARCHAR(100) NOT NULL,
+    link_user_id UUID REFERENCES placement_log_users(id) ON DELETE CASCADE, -- set when linking a logged-in account
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_states_expires ON placement_log_oidc_states(expires_at);
+
+-- Provider accounts linked to users; a user has at most one per provider.
+CREATE TABLE IF NOT EXISTS placement_log_user_identities (
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,      -- the provider's stable user ID ("sub")
+    user_id UUID NOT NULL REFERENCES placement_log_users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Mapping provider emails to regnos through the roster
+CREATE INDEX IF NOT EXISTS idx_roster_email ON placement_log_roster(LOWER(email));
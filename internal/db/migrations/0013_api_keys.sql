@@ -0,0 +1,16 @@
+-- 0013_api_keys: scoped API keys for integrations and bots
+
+-- Only the SHA-256 hash of a key is stored; the key itself is shown once
+-- when it is created. prefix keeps the first characters to tell keys apart.
+CREATE TABLE IF NOT EXISTS placement_log_api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL,             -- e.g. placements:read, posts:read, stats:read
+    created_by UUID REFERENCES placement_log_admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
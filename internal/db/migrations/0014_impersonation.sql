@@ -0,0 +1,27 @@
+-- 0014_impersonation: admin impersonation of users and its audit log
+
+-- Impersonation tokens issued to super admins; session_id is the token's jti.
+CREATE TABLE IF NOT EXISTS placement_log_impersonations (
+    session_id VARCHAR(64) PRIMARY KEY,
+    admin_id UUID REFERENCES placement_log_admins(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES placement_log_users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    read_only BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_user ON placement_log_impersonations(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_impersonations_admin ON placement_log_impersonations(admin_id, created_at);
+
+-- Every request made with an impersonation token, including refused ones
+CREATE TABLE IF NOT EXISTS placement_log_impersonation_requests (
+    id BIGSERIAL PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL REFERENCES placement_log_impersonations(session_id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_requests_session ON placement_log_impersonation_requests(session_id, created_at);
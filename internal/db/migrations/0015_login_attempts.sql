@@ -0,0 +1,10 @@
+-- 0015_login_attempts: login failure counters shared between API replicas
+
+CREATE TABLE IF NOT EXISTS placement_log_login_attempts (
+    key VARCHAR(300) PRIMARY KEY,       -- 'account:<type>:<account>' or 'ip:<ip>'
+    failures INT NOT NULL DEFAULT 0,
+    last_failure TIMESTAMPTZ NOT NULL DEFAULT 'epoch',
+    blocked_until TIMESTAMPTZ NOT NULL DEFAULT 'epoch'
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure ON placement_log_login_attempts(last_failure);
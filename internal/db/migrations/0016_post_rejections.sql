@@ -0,0 +1,6 @@
+-- 0016_post_rejections: remember that a review rejected a post
+
+-- Set when an admin rejects the post; cleared when it is approved or edited
+-- and so goes back to review. Posts rejected before this migration cannot be
+-- told apart from pending ones and stay pending.
+ALTER TABLE placement_log_posts ADD COLUMN IF NOT EXISTS rejected_at TIMESTAMP;
@@ -1,6 +1,9 @@
 package db
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 /*
 User represents a user in the system.
@@ -11,19 +14,179 @@ type User struct {
 	Regno     string `json:"regno"`
 	CreatedAt string `json:"created_at"`
 	Username  string `json:"username"`
+	Email     string `json:"email,omitempty"`
+	Status    string `json:"status,omitempty"`
+	// Suspension and PasswordResetRequired are only loaded where logins are checked.
+	Suspension            *Suspension `json:"-"`
+	PasswordResetRequired bool        `json:"-"`
+}
+
+// User account states.
+const (
+	UserStatusActive  = "active"
+	UserStatusPending = "pending"
+)
+
+/*
+Suspension is an admin-imposed block on a user account. A suspension without
+an end (Until is nil) is a ban.
+*/
+type Suspension struct {
+	Reason string     `json:"reason"`
+	Since  time.Time  `json:"since"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// ActiveAt reports whether the suspension is in force at t.
+func (s *Suspension) ActiveAt(t time.Time) bool {
+	return s != nil && (s.Until == nil || t.Before(*s.Until))
+}
+
+/*
+UserSummary is a user account as listed to admins. Name, Branch and Batch
+come from the student roster and are empty for regnos that are not on it.
+*/
+type UserSummary struct {
+	ID                    string      `json:"id"`
+	Regno                 string      `json:"regno"`
+	Username              string      `json:"username"`
+	Email                 string      `json:"email,omitempty"`
+	Status                string      `json:"status"`
+	Name                  string      `json:"name,omitempty"`
+	Branch                string      `json:"branch,omitempty"`
+	Batch                 string      `json:"batch,omitempty"`
+	Suspension            *Suspension `json:"suspension,omitempty"`
+	PasswordResetRequired bool        `json:"password_reset_required"`
+	CreatedAt             time.Time   `json:"created_at"`
+}
+
+/*
+UserDetail is a user account as shown to admins, with post counts and the
+history of admin actions on the account, newest first.
+*/
+type UserDetail struct {
+	UserSummary
+	Posts         PostCounts        `json:"posts"`
+	ModerationLog []ModerationEntry `json:"moderation_log"`
+}
+
+/*
+PostCounts counts a user's posts by review state.
+*/
+type PostCounts struct {
+	Total    int `json:"total"`
+	Approved int `json:"approved"`
+	Pending  int `json:"pending"`
+}
+
+/*
+ModerationEntry records an admin action on a user account. AdminUsername is
+empty once the admin has been removed.
+*/
+type ModerationEntry struct {
+	ID            int64      `json:"id"`
+	Action        string     `json:"action"`
+	Reason        string     `json:"reason,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	AdminID       string     `json:"admin_id,omitempty"`
+	AdminUsername string     `json:"admin_username,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Moderation actions recorded in the moderation log.
+const (
+	ModerationSuspend            = "suspend"
+	ModerationBan                = "ban"
+	ModerationUnsuspend          = "unsuspend"
+	ModerationForcePasswordReset = "force_password_reset"
+	ModerationVerify             = "verify"
+	ModerationUnverify           = "unverify"
+)
+
+/*
+Profile is the account of the logged-in user as shown at /users/me.
+DisplayName falls back to the registered name when it has not been set.
+PendingEmail is only set in the response to an edit that changed the email
+address, which is attached once it has been verified.
+*/
+type Profile struct {
+	ID             string `json:"id"`
+	Regno          string `json:"regno"`
+	Username       string `json:"username"`
+	DisplayName    string `json:"display_name"`
+	Email          string `json:"email,omitempty"`
+	PendingEmail   string `json:"pending_email,omitempty"`
+	Bio            string `json:"bio"`
+	LinkedIn       string `json:"linkedin"`
+	GraduationYear int    `json:"graduation_year,omitempty"`
+	AvatarURL      string `json:"avatar_url,omitempty"`
+	// Pseudonym is shown instead of the name on posts made under a pseudonym.
+	Pseudonym string `json:"pseudonym,omitempty"`
+	CreatedAt string `json:"created_at"`
+	// DeletionScheduledAt is when the account will be purged, if its deletion was requested.
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+/*
+AuthorProfile is the public view of a user: it leaves out the regno and
+email address and lists only approved posts.
+*/
+type AuthorProfile struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"display_name"`
+	Bio            string `json:"bio"`
+	LinkedIn       string `json:"linkedin"`
+	GraduationYear int    `json:"graduation_year,omitempty"`
+	AvatarURL      string `json:"avatar_url,omitempty"`
+	Posts          []Post `json:"posts"`
+}
+
+/*
+Avatar is a user's uploaded profile picture.
+*/
+type Avatar struct {
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
 }
 
 /*
 Post represents a placement log post in the system.
 Contains post content, ownership information, and review status.
+UserID is empty once the author's account has been deleted, and in public
+responses for posts that are not named. Author is the name shown for the
+post: the author's display name, their pseudonym, or empty when anonymous.
+AuthorBranch and AuthorBatch come from the roster and are only shown with
+the author's name.
 */
 type Post struct {
-	ID       string          `json:"id"`
-	UserID   string          `json:"user_id"`
-	PostBody json.RawMessage `json:"post_body"`
-	Reviewed bool            `json:"reviewed"`
+	ID               string          `json:"id"`
+	UserID           string          `json:"user_id,omitempty"`
+	PostBody         json.RawMessage `json:"post_body"`
+	Reviewed         bool            `json:"reviewed"`
+	ReviewStatus     string          `json:"review_status"`
+	AuthorVisibility string          `json:"author_visibility,omitempty"`
+	Author           string          `json:"author,omitempty"`
+	AuthorBranch     string          `json:"author_branch,omitempty"`
+	AuthorBatch      string          `json:"author_batch,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
 }
 
+// Review states of a post, derived from Reviewed and the time it was rejected.
+const (
+	ReviewStatusApproved = "approved"
+	ReviewStatusPending  = "pending_review"
+	ReviewStatusRejected = "rejected"
+)
+
+// How the author of a post is shown publicly.
+const (
+	VisibilityNamed     = "named"
+	VisibilityPseudonym = "pseudonym"
+	VisibilityAnonymous = "anonymous"
+)
+
 /*
 Round represents a single round in a placement process.
 Contains the content/description of the round.
@@ -47,10 +210,19 @@ Admin represents an admin user in the system.
 Contains basic admin information for authentication and identification.
 */
 type Admin struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role,omitempty"`
+	Disabled  bool   `json:"disabled,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
 }
 
+// Admin roles. Only super admins may register other admins.
+const (
+	AdminRoleSuper = "super_admin"
+	AdminRoleAdmin = "admin"
+)
+
 /*
 PlacementCompany represents a placement event (company, ctc, date, etc).
 */
@@ -70,3 +242,121 @@ type BranchCount struct {
 	Branch string `json:"branch"`
 	Count  int    `json:"count"`
 }
+
+/*
+LoginEvent records a failed login, an account or IP lockout, or an admin
+unlock. Account is the regno or admin username as typed by the client.
+*/
+type LoginEvent struct {
+	ID          string `json:"id"`
+	AccountType string `json:"account_type"`
+	Account     string `json:"account"`
+	IP          string `json:"ip"`
+	Outcome     string `json:"outcome"`
+	ActorID     string `json:"actor_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+/*
+UserToken is an email verification or password reset token and the user it
+was issued to.
+*/
+type UserToken struct {
+	UserID   string `json:"user_id"`
+	Regno    string `json:"regno"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+/*
+RosterEntry is a student on the official roster imported by admins.
+Registrations are checked against it to stop students from registering
+under someone else's registration number.
+*/
+type RosterEntry struct {
+	Regno      string `json:"regno"`
+	Name       string `json:"name"`
+	Branch     string `json:"branch"`
+	Batch      string `json:"batch"`
+	Email      string `json:"email"`
+	ImportedAt string `json:"imported_at,omitempty"`
+}
+
+/*
+TwoFactor is the TOTP second factor of a user or admin account.
+A factor is only in force once Enabled; until then it is an enrollment
+waiting for its first code.
+*/
+type TwoFactor struct {
+	AccountType string `json:"account_type"`
+	AccountID   string `json:"account_id"`
+	Secret      string `json:"-"`
+	Enabled     bool   `json:"enabled"`
+	LastStep    int64  `json:"-"`
+}
+
+/*
+OIDCState is an in-flight OpenID Connect login. LinkUserID is set when a
+logged-in user is linking a provider account rather than logging in.
+*/
+type OIDCState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   string
+}
+
+/*
+UserIdentity is a provider account linked to a user for single sign-on.
+*/
+type UserIdentity struct {
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	UserID      string     `json:"-"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"linked_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+/*
+APIKey is a named, scoped credential for integrations. Only the hash of the
+key is stored; Prefix holds its first characters for display.
+*/
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+/*
+Impersonation is an impersonation token issued to a super admin, with the
+requests made with it when they are loaded.
+*/
+type Impersonation struct {
+	ID            string                 `json:"id"`
+	AdminID       string                 `json:"admin_id,omitempty"`
+	AdminUsername string                 `json:"admin_username,omitempty"`
+	UserID        string                 `json:"user_id"`
+	Reason        string                 `json:"reason"`
+	ReadOnly      bool                   `json:"read_only"`
+	CreatedAt     time.Time              `json:"created_at"`
+	ExpiresAt     time.Time              `json:"expires_at"`
+	RequestCount  int                    `json:"request_count"`
+	Requests      []ImpersonationRequest `json:"requests,omitempty"`
+}
+
+/*
+ImpersonationRequest is a request made with an impersonation token.
+*/
+type ImpersonationRequest struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
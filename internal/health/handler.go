@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// readinessTimeout bounds the dependency checks performed by Readyz.
+const readinessTimeout = 2 * time.Second
+
+/*
+Pinger is the part of *sql.DB used to check database connectivity.
+*/
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+/*
+MigrationChecker reports the schema migrations that have not been applied yet.
+*/
+type MigrationChecker func(ctx context.Context) ([]string, error)
+
+/*
+HealthHandler serves the liveness and readiness probes used by the container
+orchestrator during rolling deploys.
+*/
+type HealthHandler struct {
+	db         Pinger
+	migrations MigrationChecker
+	draining   atomic.Bool
+}
+
+/*
+readinessResponse describes the state of each dependency checked by Readyz.
+*/
+type readinessResponse struct {
+	Status            string   `json:"status"`
+	Database          string   `json:"database"`
+	PendingMigrations []string `json:"pending_migrations"`
+}
+
+/*
+NewHealthHandler creates a new HealthHandler instance.
+
+Parameters:
+- db: The database connection to ping
+- migrations: Reports pending schema migrations
+
+Returns:
+- *HealthHandler: A new handler instance
+*/
+func NewHealthHandler(db Pinger, migrations MigrationChecker) *HealthHandler {
+	return &HealthHandler{db: db, migrations: migrations}
+}
+
+/*
+SetDraining marks the instance as shutting down.
+From then on Readyz reports 503 so the load balancer stops sending new
+requests while in-flight ones are drained.
+*/
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+/*
+Healthz handles liveness probes.
+It only reports that the process is up and serving HTTP; it never checks
+dependencies, so a database outage does not get the container restarted.
+
+HTTP Method: GET
+Endpoint: /healthz
+
+Response (200 OK):
+
+	{
+	  "status": "ok"
+	}
+*/
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+/*
+Readyz handles readiness probes.
+The instance is ready when it is not draining, the database answers a ping
+and every schema migration has been applied.
+
+HTTP Method: GET
+Endpoint: /readyz
+
+Response (200 OK):
+
+	{
+	  "status": "ready",
+	  "database": "ok",
+	  "pending_migrations": []
+	}
+
+Returns:
+- 200 OK: Instance can receive traffic
+- 503 Service Unavailable: Draining, database unreachable or migrations pending
+*/
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := readinessResponse{
+		Status:            "ready",
+		Database:          "ok",
+		PendingMigrations: []string{},
+	}
+	status := http.StatusOK
+
+	if h.draining.Load() {
+		resp.Status = "draining"
+		status = http.StatusServiceUnavailable
+	}
+
+	if err := h.db.PingContext(ctx); err != nil {
+		resp.Status = "unavailable"
+		resp.Database = err.Error()
+		status = http.StatusServiceUnavailable
+	} else if pending, err := h.migrations(ctx); err != nil {
+		resp.Status = "unavailable"
+		resp.Database = err.Error()
+		status = http.StatusServiceUnavailable
+	} else if len(pending) > 0 {
+		resp.Status = "migrations pending"
+		resp.PendingMigrations = pending
+		status = http.StatusServiceUnavailable
+	}
+
+	utils.WriteJSON(w, resp, status)
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (f fakePinger) PingContext(ctx context.Context) error {
+	return f.err
+}
+
+func noPending(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func TestHealthHandler_Healthz(t *testing.T) {
+	h := NewHealthHandler(fakePinger{err: errors.New("down")}, noPending)
+	rec := httptest.NewRecorder()
+	h.Healthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 even when the database is down, got %d", rec.Code)
+	}
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	cases := []struct {
+		name       string
+		ping       error
+		migrations MigrationChecker
+		draining   bool
+		wantStatus int
+	}{
+		{"ready", nil, noPending, false, http.StatusOK},
+		{"database down", errors.New("connection refused"), noPending, false, http.StatusServiceUnavailable},
+		{"migrations pending", nil, func(ctx context.Context) ([]string, error) {
+			return []string{"0002_next"}, nil
+		}, false, http.StatusServiceUnavailable},
+		{"migration check error", nil, func(ctx context.Context) ([]string, error) {
+			return nil, errors.New("permission denied")
+		}, false, http.StatusServiceUnavailable},
+		{"draining", nil, noPending, true, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := NewHealthHandler(fakePinger{err: c.ping}, c.migrations)
+			if c.draining {
+				h.SetDraining()
+			}
+			rec := httptest.NewRecorder()
+			h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected %d, got %d: %s", c.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
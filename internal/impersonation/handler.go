@@ -0,0 +1,218 @@
+package impersonation
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var errForbidden = errors.New("unauthorized: admin token required")
+
+/*
+ImpersonationHandler handles the admin endpoints for impersonating users
+and reviewing the impersonation audit log.
+*/
+type ImpersonationHandler struct {
+	srv *ImpersonationService
+}
+
+/*
+NewImpersonationHandler creates a new ImpersonationHandler instance with the provided service.
+
+Parameters:
+- srv: The impersonation service
+
+Returns:
+- *ImpersonationHandler: A new handler instance
+*/
+func NewImpersonationHandler(srv *ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{srv: srv}
+}
+
+func writeImpersonationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotSuperAdmin):
+		utils.WriteError(w, err, http.StatusForbidden)
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrImpersonationNotFound):
+		utils.WriteError(w, err, http.StatusNotFound)
+	default:
+		utils.WriteError(w, err)
+	}
+}
+
+/*
+impersonateRequest represents the JSON payload for impersonating a user.
+ReadOnly defaults to true.
+*/
+type impersonateRequest struct {
+	Reason   string `json:"reason"`
+	ReadOnly *bool  `json:"read_only"`
+}
+
+/*
+impersonateResponse carries an impersonation token.
+*/
+type impersonateResponse struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"`
+	UserID    string    `json:"user_id"`
+	ReadOnly  bool      `json:"read_only"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+/*
+Impersonate issues a short-lived token for acting as a user, to see what the
+user sees. The token is returned in the body only, also in cookie session
+mode, and is used as "Authorization: Bearer <token>". Every request made
+with it is recorded, and its responses carry the X-Impersonated-By header.
+Read-only tokens (the default) are refused on anything but GET; no token
+can change the user's password, email, second factor or linked accounts,
+export the account or request its deletion.
+
+HTTP Method: POST
+Endpoint: /admin/users/{id}/impersonation
+
+Headers Required:
+- Authorization: Bearer <super_admin_jwt_token>
+
+Request Body:
+
+	{
+	  "reason": "Ticket #1234: post disappeared",
+	  "read_only": true
+	}
+
+Response (201 Created):
+
+	{
+	  "id": "impersonation_id",
+	  "token": "jwt_token_here",
+	  "user_id": "user_uuid",
+	  "read_only": true,
+	  "expires_at": "2026-01-01T10:15:00Z"
+	}
+
+Returns:
+- 201 Created: The token
+- 400 Bad Request: Missing or too long reason
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin is not a super admin
+- 404 Not Found: No such user
+*/
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := auth.AdminID(r.Context())
+	if !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	var payload impersonateRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	readOnly := payload.ReadOnly == nil || *payload.ReadOnly
+
+	token, imp, err := h.srv.Start(r.Context(), adminID, chi.URLParam(r, "id"), payload.Reason, readOnly)
+	if err != nil {
+		writeImpersonationError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, impersonateResponse{
+		ID:        imp.ID,
+		Token:     token,
+		UserID:    imp.UserID,
+		ReadOnly:  imp.ReadOnly,
+		ExpiresAt: imp.ExpiresAt,
+	}, http.StatusCreated)
+}
+
+/*
+ListImpersonations lists the latest 100 impersonations, optionally of one
+user or by one admin.
+
+HTTP Method: GET
+Endpoint: /admin/impersonations?user_id=<user_id>&admin_id=<admin_id>
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK):
+
+	[
+	  {
+	    "id": "impersonation_id",
+	    "admin_id": "admin_uuid",
+	    "admin_username": "root",
+	    "user_id": "user_uuid",
+	    "reason": "Ticket #1234: post disappeared",
+	    "read_only": true,
+	    "created_at": "2026-01-01T10:00:00Z",
+	    "expires_at": "2026-01-01T10:15:00Z",
+	    "request_count": 4
+	  }
+	]
+
+Returns:
+- 200 OK: The impersonations, newest first
+- 401 Unauthorized: Missing or invalid admin token
+*/
+func (h *ImpersonationHandler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.AdminID(r.Context()); !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	list, err := h.srv.List(r.Context(), Filter{UserID: query.Get("user_id"), AdminID: query.Get("admin_id")})
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, list, http.StatusOK)
+}
+
+/*
+GetImpersonation returns an impersonation with every request made with its
+token, including refused ones.
+
+HTTP Method: GET
+Endpoint: /admin/impersonations/{id}
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK): the fields of the list, plus
+
+	{
+	  "requests": [
+	    {"method": "GET", "path": "/posts/user", "status": 200, "created_at": "2026-01-01T10:01:00Z"}
+	  ]
+	}
+
+Returns:
+- 200 OK: The impersonation
+- 401 Unauthorized: Missing or invalid admin token
+- 404 Not Found: No such impersonation
+*/
+func (h *ImpersonationHandler) GetImpersonation(w http.ResponseWriter, r *http.Request) {
+	if _, ok := auth.AdminID(r.Context()); !ok {
+		utils.WriteError(w, errForbidden, http.StatusUnauthorized)
+		return
+	}
+
+	imp, err := h.srv.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeImpersonationError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, imp, http.StatusOK)
+}
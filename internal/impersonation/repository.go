@@ -0,0 +1,255 @@
+package impersonation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+ImpersonationRepo stores impersonation tokens and the requests made with
+them.
+*/
+type ImpersonationRepo struct {
+	db *sql.DB
+}
+
+/*
+NewImpersonationRepo creates a new ImpersonationRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *ImpersonationRepo: A new repository instance
+*/
+func NewImpersonationRepo(db *sql.DB) *ImpersonationRepo {
+	return &ImpersonationRepo{db: db}
+}
+
+/*
+IsSuperAdmin reports whether an admin is an enabled super admin.
+
+Parameters:
+- ctx: The request context
+- adminID: The admin's ID
+
+Returns:
+- bool: True for enabled super admins; false for other and unknown admins
+- error: Any database error
+*/
+func (r *ImpersonationRepo) IsSuperAdmin(ctx context.Context, adminID string) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.IsSuperAdmin", "SELECT", "placement_log_admins").End()
+
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM placement_log_admins
+			WHERE id::text = $1 AND role = $2 AND disabled_at IS NULL
+		);
+	`, adminID, db.AdminRoleSuper).Scan(&ok)
+
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	return ok, nil
+}
+
+/*
+UserExists reports whether a user account exists.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- bool: Whether the account exists
+- error: Any database error
+*/
+func (r *ImpersonationRepo) UserExists(ctx context.Context, userID string) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.UserExists", "SELECT", "placement_log_users").End()
+
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM placement_log_users WHERE id::text = $1);`, userID).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	return ok, nil
+}
+
+/*
+CreateImpersonation records an issued impersonation token.
+
+Parameters:
+- ctx: The request context
+- imp: The token's session ID, admin, user, reason, mode and expiry
+
+Returns:
+- error: Any database error
+*/
+func (r *ImpersonationRepo) CreateImpersonation(ctx context.Context, imp db.Impersonation) error {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.CreateImpersonation", "INSERT", "placement_log_impersonations").End()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_impersonations (session_id, admin_id, user_id, reason, read_only, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`, imp.ID, imp.AdminID, imp.UserID, imp.Reason, imp.ReadOnly, imp.CreatedAt, imp.ExpiresAt)
+
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+/*
+RecordRequest records a request made with an impersonation token.
+
+Parameters:
+- ctx: The request context
+- sessionID: The token's session ID
+- req: The method, path and response status
+
+Returns:
+- error: Any database error
+*/
+func (r *ImpersonationRepo) RecordRequest(ctx context.Context, sessionID string, req db.ImpersonationRequest) error {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.RecordRequest", "INSERT", "placement_log_impersonation_requests").End()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_impersonation_requests (session_id, method, path, status)
+		VALUES ($1, $2, $3, $4);
+	`, sessionID, req.Method, req.Path, req.Status)
+
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+const impersonationColumns = `
+	i.session_id, COALESCE(i.admin_id::text, ''), COALESCE(a.username, ''), i.user_id, i.reason,
+	i.read_only, i.created_at, i.expires_at,
+	(SELECT COUNT(*) FROM placement_log_impersonation_requests q WHERE q.session_id = i.session_id)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImpersonation(row scanner) (*db.Impersonation, error) {
+	var imp db.Impersonation
+	err := row.Scan(&imp.ID, &imp.AdminID, &imp.AdminUsername, &imp.UserID, &imp.Reason,
+		&imp.ReadOnly, &imp.CreatedAt, &imp.ExpiresAt, &imp.RequestCount)
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+/*
+ListImpersonations returns issued impersonation tokens, newest first.
+
+Parameters:
+- ctx: The request context
+- filter: Limits the list to a user and/or an admin; empty fields match all
+- limit: The maximum number of tokens returned
+
+Returns:
+- []db.Impersonation: The tokens with their request counts
+- error: Any database error
+*/
+func (r *ImpersonationRepo) ListImpersonations(ctx context.Context, filter Filter, limit int) ([]db.Impersonation, error) {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.ListImpersonations", "SELECT", "placement_log_impersonations").End()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT`+impersonationColumns+`
+		FROM placement_log_impersonations i
+		LEFT JOIN placement_log_admins a ON a.id = i.admin_id
+		WHERE ($1 = '' OR i.user_id::text = $1) AND ($2 = '' OR i.admin_id::text = $2)
+		ORDER BY i.created_at DESC
+		LIMIT $3;
+	`, filter.UserID, filter.AdminID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer rows.Close()
+
+	list := []db.Impersonation{}
+	for rows.Next() {
+		imp, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		list = append(list, *imp)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return list, nil
+}
+
+/*
+GetImpersonation returns an impersonation token with every request made
+with it.
+
+Parameters:
+- ctx: The request context
+- id: The token's session ID
+
+Returns:
+- *db.Impersonation: The token and its requests, oldest first, or nil when it does not exist
+- error: Any database error
+*/
+func (r *ImpersonationRepo) GetImpersonation(ctx context.Context, id string) (*db.Impersonation, error) {
+	defer telemetry.TraceQuery(ctx, "ImpersonationRepo.GetImpersonation", "SELECT", "placement_log_impersonations").End()
+
+	imp, err := scanImpersonation(r.db.QueryRowContext(ctx, `
+		SELECT`+impersonationColumns+`
+		FROM placement_log_impersonations i
+		LEFT JOIN placement_log_admins a ON a.id = i.admin_id
+		WHERE i.session_id = $1;
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT method, path, status, created_at
+		FROM placement_log_impersonation_requests
+		WHERE session_id = $1
+		ORDER BY created_at, id;
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer rows.Close()
+
+	imp.Requests = []db.ImpersonationRequest{}
+	for rows.Next() {
+		var req db.ImpersonationRequest
+		if err = rows.Scan(&req.Method, &req.Path, &req.Status, &req.CreatedAt); err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		imp.Requests = append(imp.Requests, req)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return imp, nil
+}
+
+// Ensure ImpersonationRepo implements ImpersonationRepository
+var _ ImpersonationRepository = (*ImpersonationRepo)(nil)
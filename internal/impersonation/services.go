@@ -0,0 +1,240 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+var (
+	// ErrNotSuperAdmin is returned when an admin without the super admin role impersonates a user.
+	ErrNotSuperAdmin = errors.New("only super admins can impersonate users")
+	// ErrUserNotFound is returned when the impersonated user does not exist.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrImpersonationNotFound is returned for unknown impersonation IDs.
+	ErrImpersonationNotFound = errors.New("impersonation not found")
+)
+
+const (
+	// maxReasonLength bounds the reason recorded with an impersonation.
+	maxReasonLength = 500
+	// listLimit is the number of impersonations returned by List.
+	listLimit = 100
+)
+
+// Define ImpersonationRepository interface for testability
+//go:generate mockgen -destination=mock_impersonation_repo.go -package=impersonation . ImpersonationRepository
+
+type ImpersonationRepository interface {
+	IsSuperAdmin(ctx context.Context, adminID string) (bool, error)
+	UserExists(ctx context.Context, userID string) (bool, error)
+	CreateImpersonation(ctx context.Context, imp db.Impersonation) error
+	RecordRequest(ctx context.Context, sessionID string, req db.ImpersonationRequest) error
+	ListImpersonations(ctx context.Context, filter Filter, limit int) ([]db.Impersonation, error)
+	GetImpersonation(ctx context.Context, id string) (*db.Impersonation, error)
+}
+
+/*
+TokenIssuer issues impersonation tokens (see jwt.Manager).
+*/
+type TokenIssuer interface {
+	GenerateImpersonationToken(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error)
+}
+
+/*
+Filter limits the impersonations listed; empty fields match all.
+*/
+type Filter struct {
+	UserID  string
+	AdminID string
+}
+
+/*
+ImpersonationService lets super admins see the site as a user does, for
+support. Impersonation tokens are short-lived, read-only unless asked
+otherwise, carry the admin's ID, and every request made with them is
+recorded.
+*/
+type ImpersonationService struct {
+	repo   ImpersonationRepository
+	tokens TokenIssuer
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+/*
+NewImpersonationService creates a new ImpersonationService instance.
+
+Parameters:
+- repo: The impersonation repository
+- tokens: Issues the impersonation tokens
+- ttl: The lifetime of impersonation tokens
+
+Returns:
+- *ImpersonationService: A new service instance
+*/
+func NewImpersonationService(repo ImpersonationRepository, tokens TokenIssuer, ttl time.Duration) *ImpersonationService {
+	return &ImpersonationService{repo: repo, tokens: tokens, ttl: ttl, now: time.Now}
+}
+
+/*
+Start issues an impersonation token for a user.
+
+Parameters:
+- ctx: The request context
+- adminID: The admin asking to impersonate
+- userID: The user to impersonate
+- reason: Why, e.g. the support ticket; recorded with the impersonation
+- readOnly: Whether the token may only be used for reading
+
+Returns:
+- string: The impersonation token
+- *db.Impersonation: The recorded impersonation
+- error: Any error that occurred
+
+The function:
+1. Checks that the admin is an enabled super admin and the user exists
+2. Issues the token
+3. Records the impersonation before the token is handed out, so no token escapes the audit log
+
+Possible errors:
+- ErrNotSuperAdmin: The admin is not an enabled super admin
+- ErrUserNotFound: No such user
+- "reason is required": Missing or too long reason
+*/
+func (s *ImpersonationService) Start(ctx context.Context, adminID, userID, reason string, readOnly bool) (string, *db.Impersonation, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > maxReasonLength {
+		return "", nil, fmt.Errorf("reason is required and must be at most %d characters", maxReasonLength)
+	}
+
+	ok, err := s.repo.IsSuperAdmin(ctx, adminID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, ErrNotSuperAdmin
+	}
+
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !exists {
+		return "", nil, ErrUserNotFound
+	}
+
+	now := s.now().UTC()
+	token, sessionID, err := s.tokens.GenerateImpersonationToken(userID, adminID, readOnly, s.ttl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	imp := db.Impersonation{
+		ID:        sessionID,
+		AdminID:   adminID,
+		UserID:    userID,
+		Reason:    reason,
+		ReadOnly:  readOnly,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	if err := s.repo.CreateImpersonation(ctx, imp); err != nil {
+		return "", nil, err
+	}
+
+	return token, &imp, nil
+}
+
+/*
+RecordImpersonatedRequest records a request made with an impersonation
+token in the audit log.
+
+Parameters:
+- ctx: The request context
+- req: The token's session ID and the request
+
+Returns:
+- error: Any database error
+*/
+func (s *ImpersonationService) RecordImpersonatedRequest(ctx context.Context, req auth.ImpersonatedRequest) error {
+	return s.repo.RecordRequest(ctx, req.SessionID, db.ImpersonationRequest{
+		Method: req.Method,
+		Path:   req.Path,
+		Status: req.Status,
+	})
+}
+
+/*
+List returns the latest impersonations with their request counts.
+
+Parameters:
+- ctx: The request context
+- filter: Limits the list to a user and/or an admin
+
+Returns:
+- []db.Impersonation: At most 100 impersonations, newest first
+- error: Any database error
+*/
+func (s *ImpersonationService) List(ctx context.Context, filter Filter) ([]db.Impersonation, error) {
+	return s.repo.ListImpersonations(ctx, filter, listLimit)
+}
+
+/*
+Get returns an impersonation with every request made with its token.
+
+Parameters:
+- ctx: The request context
+- id: The impersonation ID (the token's session ID)
+
+Returns:
+- *db.Impersonation: The impersonation and its requests
+- error: ErrImpersonationNotFound or a database error
+*/
+func (s *ImpersonationService) Get(ctx context.Context, id string) (*db.Impersonation, error) {
+	imp, err := s.repo.GetImpersonation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if imp == nil {
+		return nil, ErrImpersonationNotFound
+	}
+
+	return imp, nil
+}
+
+/*
+CheckAccount reports whether an admin may still use the impersonation tokens
+issued to them. It implements auth.AccountChecker for the impersonation
+middleware, so disabling or demoting an admin ends their impersonations
+before the tokens expire.
+
+Parameters:
+- ctx: The request context
+- adminID: The impersonating admin's ID
+
+Returns:
+- error: nil for enabled super admins, auth.ErrImpersonationRevoked or a database error
+*/
+func (s *ImpersonationService) CheckAccount(ctx context.Context, adminID string) error {
+	ok, err := s.repo.IsSuperAdmin(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return auth.ErrImpersonationRevoked
+	}
+
+	return nil
+}
+
+// Ensure ImpersonationService implements auth.ImpersonationAuditor and auth.AccountChecker
+var (
+	_ auth.ImpersonationAuditor = (*ImpersonationService)(nil)
+	_ auth.AccountChecker       = (*ImpersonationService)(nil)
+)
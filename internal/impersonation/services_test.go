@@ -0,0 +1,196 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+type mockImpersonationRepo struct {
+	IsSuperAdminFunc        func(adminID string) (bool, error)
+	UserExistsFunc          func(userID string) (bool, error)
+	CreateImpersonationFunc func(imp db.Impersonation) error
+	RecordRequestFunc       func(sessionID string, req db.ImpersonationRequest) error
+	ListImpersonationsFunc  func(filter Filter, limit int) ([]db.Impersonation, error)
+	GetImpersonationFunc    func(id string) (*db.Impersonation, error)
+}
+
+func (m *mockImpersonationRepo) IsSuperAdmin(ctx context.Context, adminID string) (bool, error) {
+	return m.IsSuperAdminFunc(adminID)
+}
+func (m *mockImpersonationRepo) UserExists(ctx context.Context, userID string) (bool, error) {
+	return m.UserExistsFunc(userID)
+}
+func (m *mockImpersonationRepo) CreateImpersonation(ctx context.Context, imp db.Impersonation) error {
+	return m.CreateImpersonationFunc(imp)
+}
+func (m *mockImpersonationRepo) RecordRequest(ctx context.Context, sessionID string, req db.ImpersonationRequest) error {
+	return m.RecordRequestFunc(sessionID, req)
+}
+func (m *mockImpersonationRepo) ListImpersonations(ctx context.Context, filter Filter, limit int) ([]db.Impersonation, error) {
+	return m.ListImpersonationsFunc(filter, limit)
+}
+func (m *mockImpersonationRepo) GetImpersonation(ctx context.Context, id string) (*db.Impersonation, error) {
+	return m.GetImpersonationFunc(id)
+}
+
+type mockTokenIssuer struct {
+	GenerateImpersonationTokenFunc func(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error)
+}
+
+func (m *mockTokenIssuer) GenerateImpersonationToken(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error) {
+	return m.GenerateImpersonationTokenFunc(userID, adminID, readOnly, ttl)
+}
+
+func newTestRepo() *mockImpersonationRepo {
+	return &mockImpersonationRepo{
+		IsSuperAdminFunc: func(adminID string) (bool, error) { return adminID == "super-1", nil },
+		UserExistsFunc:   func(userID string) (bool, error) { return userID == "user-1", nil },
+	}
+}
+
+func TestImpersonationService_Start(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var created db.Impersonation
+	repo := newTestRepo()
+	repo.CreateImpersonationFunc = func(imp db.Impersonation) error {
+		created = imp
+		return nil
+	}
+	tokens := &mockTokenIssuer{
+		GenerateImpersonationTokenFunc: func(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error) {
+			if userID != "user-1" || adminID != "super-1" || !readOnly || ttl != 15*time.Minute {
+				t.Errorf("unexpected token request %s %s %v %v", userID, adminID, readOnly, ttl)
+			}
+			return "token", "session-1", nil
+		},
+	}
+	s := NewImpersonationService(repo, tokens, 15*time.Minute)
+	s.now = func() time.Time { return now }
+
+	token, imp, err := s.Start(context.Background(), "super-1", "user-1", "  Ticket #1234 ", true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "token" {
+		t.Errorf("expected the issued token, got %q", token)
+	}
+	want := db.Impersonation{
+		ID:        "session-1",
+		AdminID:   "super-1",
+		UserID:    "user-1",
+		Reason:    "Ticket #1234",
+		ReadOnly:  true,
+		CreatedAt: now,
+		ExpiresAt: now.Add(15 * time.Minute),
+	}
+	if created.ID != want.ID || created.AdminID != want.AdminID || created.UserID != want.UserID ||
+		created.Reason != want.Reason || !created.ReadOnly || !created.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("expected %+v to be recorded, got %+v", want, created)
+	}
+	if imp.ID != "session-1" {
+		t.Errorf("expected the recorded impersonation, got %+v", imp)
+	}
+}
+
+func TestImpersonationService_Start_Refused(t *testing.T) {
+	cases := []struct {
+		name    string
+		adminID string
+		userID  string
+		reason  string
+		wantErr error
+	}{
+		{"not a super admin", "admin-1", "user-1", "ticket", ErrNotSuperAdmin},
+		{"unknown user", "super-1", "user-2", "ticket", ErrUserNotFound},
+		{"missing reason", "super-1", "user-1", " ", nil},
+		{"long reason", "super-1", "user-1", strings.Repeat("a", maxReasonLength+1), nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokens := &mockTokenIssuer{
+				GenerateImpersonationTokenFunc: func(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error) {
+					t.Fatal("expected no token to be issued")
+					return "", "", nil
+				},
+			}
+			s := NewImpersonationService(newTestRepo(), tokens, 15*time.Minute)
+
+			_, _, err := s.Start(context.Background(), c.adminID, c.userID, c.reason, true)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if c.wantErr != nil && !errors.Is(err, c.wantErr) {
+				t.Errorf("expected %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestImpersonationService_Start_NotRecorded(t *testing.T) {
+	repo := newTestRepo()
+	repo.CreateImpersonationFunc = func(imp db.Impersonation) error {
+		return errors.New("db down")
+	}
+	tokens := &mockTokenIssuer{
+		GenerateImpersonationTokenFunc: func(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error) {
+			return "token", "session-1", nil
+		},
+	}
+	s := NewImpersonationService(repo, tokens, 15*time.Minute)
+
+	token, _, err := s.Start(context.Background(), "super-1", "user-1", "ticket", false)
+	if err == nil || token != "" {
+		t.Fatalf("expected no token when the impersonation cannot be recorded, got %q %v", token, err)
+	}
+}
+
+func TestImpersonationService_RecordImpersonatedRequest(t *testing.T) {
+	var gotSession string
+	var got db.ImpersonationRequest
+	repo := newTestRepo()
+	repo.RecordRequestFunc = func(sessionID string, req db.ImpersonationRequest) error {
+		gotSession, got = sessionID, req
+		return nil
+	}
+	s := NewImpersonationService(repo, nil, 15*time.Minute)
+
+	err := s.RecordImpersonatedRequest(context.Background(), auth.ImpersonatedRequest{
+		SessionID: "session-1", AdminID: "super-1", UserID: "user-1", Method: "GET", Path: "/posts/user", Status: 200,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotSession != "session-1" || got.Method != "GET" || got.Path != "/posts/user" || got.Status != 200 {
+		t.Errorf("unexpected record %s %+v", gotSession, got)
+	}
+}
+
+func TestImpersonationService_CheckAccount(t *testing.T) {
+	s := NewImpersonationService(newTestRepo(), nil, 15*time.Minute)
+
+	if err := s.CheckAccount(context.Background(), "super-1"); err != nil {
+		t.Errorf("expected an enabled super admin to pass, got %v", err)
+	}
+	if err := s.CheckAccount(context.Background(), "admin-1"); !errors.Is(err, auth.ErrImpersonationRevoked) {
+		t.Errorf("expected ErrImpersonationRevoked, got %v", err)
+	}
+}
+
+func TestImpersonationService_Get(t *testing.T) {
+	repo := newTestRepo()
+	repo.GetImpersonationFunc = func(id string) (*db.Impersonation, error) {
+		return nil, nil
+	}
+	s := NewImpersonationService(repo, nil, 15*time.Minute)
+
+	if _, err := s.Get(context.Background(), "missing"); !errors.Is(err, ErrImpersonationNotFound) {
+		t.Errorf("expected ErrImpersonationNotFound, got %v", err)
+	}
+}
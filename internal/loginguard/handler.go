@@ -0,0 +1,99 @@
+package loginguard
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var (
+	errUnauthorized = errors.New("unauthorized: admin token required")
+	errForbidden    = errors.New("forbidden: " + auth.PermUsersManage + " permission required")
+)
+
+/*
+LoginGuardHandler handles the admin endpoints of the login guard.
+*/
+type LoginGuardHandler struct {
+	guard *LoginGuard
+}
+
+/*
+NewLoginGuardHandler creates a new LoginGuardHandler instance with the provided guard.
+
+Parameters:
+- guard: The login guard
+
+Returns:
+- *LoginGuardHandler: A new handler instance
+*/
+func NewLoginGuardHandler(guard *LoginGuard) *LoginGuardHandler {
+	return &LoginGuardHandler{guard: guard}
+}
+
+/*
+unlockRequest represents the JSON payload for unlocking an account or IP.
+*/
+type unlockRequest struct {
+	AccountType string `json:"account_type"`
+	Account     string `json:"account"`
+	IP          string `json:"ip"`
+}
+
+/*
+Unlock lifts a login backoff or lockout.
+
+HTTP Method: POST
+Endpoint: /admin/login-lockouts/unlock
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body:
+
+	{
+	  "account_type": "user",
+	  "account": "22bcs1234",
+	  "ip": "203.0.113.7"
+	}
+
+Either account (with account_type) or ip may be omitted.
+
+Response (200 OK):
+
+	{
+	  "message": "unlocked"
+	}
+
+Returns:
+- 200 OK: The account and/or IP can log in again
+- 400 Bad Request: Invalid request format or nothing to unlock
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+*/
+func (h *LoginGuardHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	if !principal.Can(auth.PermUsersManage) {
+		utils.WriteError(w, errForbidden, http.StatusForbidden)
+		return
+	}
+
+	var req unlockRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if err := h.guard.Unlock(r.Context(), req.AccountType, req.Account, req.IP, principal.ID); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "unlocked"}, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package loginguard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestLoginGuardHandler_Unlock_RequiresUsersManage(t *testing.T) {
+	g, _, _ := newTestGuard()
+	h := NewLoginGuardHandler(g)
+
+	admin := auth.NewPrincipal("00000000-0000-0000-0000-000000000001", auth.RoleAdmin, "session-1")
+	delegate := auth.NewPrincipal("00000000-0000-0000-0000-000000000002", auth.RoleAdmin, "session-2")
+	delegate.Permissions = slices.DeleteFunc(delegate.Permissions, func(p string) bool { return p == auth.PermUsersManage })
+
+	cases := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"no principal", nil, http.StatusUnauthorized},
+		{"admin without users:manage", delegate, http.StatusForbidden},
+		{"admin", admin, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/login-lockouts/unlock", strings.NewReader(`{"ip":"10.0.0.1"}`))
+			if c.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), c.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			h.Unlock(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected %d, got %d: %s", c.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+LoginEventsRepo stores login security events.
+*/
+type LoginEventsRepo struct {
+	db *sql.DB
+}
+
+/*
+NewLoginEventsRepo creates a new LoginEventsRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *LoginEventsRepo: A new repository instance
+*/
+func NewLoginEventsRepo(db *sql.DB) *LoginEventsRepo {
+	return &LoginEventsRepo{db: db}
+}
+
+/*
+RecordEvent inserts a login event.
+
+Parameters:
+- ctx: The request context
+- event: The event; ID and CreatedAt are assigned by the database and ActorID may be empty
+
+Returns:
+- error: Any error that occurred during insertion
+*/
+func (r *LoginEventsRepo) RecordEvent(ctx context.Context, event db.LoginEvent) error {
+	defer telemetry.TraceQuery(ctx, "LoginEventsRepo.RecordEvent", "INSERT", "placement_log_login_events").End()
+
+	query := `
+		INSERT INTO placement_log_login_events (account_type, account, ip, outcome, actor_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid);
+	`
+
+	_, err := r.db.ExecContext(ctx, query, event.AccountType, event.Account, event.IP, event.Outcome, event.ActorID)
+	if err != nil {
+		return fmt.Errorf("failed to record login event: %v", err)
+	}
+
+	return nil
+}
+
+// Ensure LoginEventsRepo implements EventRecorder
+var _ EventRecorder = (*LoginEventsRepo)(nil)
+
+/*
+PostgresStore keeps the attempt counters in the placement_log_login_attempts
+table, so that every replica sees the same failures and lockouts, admin
+unlocks apply everywhere and lockouts survive restarts.
+Each Update is one transaction that locks the rows of its keys, so
+concurrent attempts on different replicas are counted one after the other.
+*/
+type PostgresStore struct {
+	db *sql.DB
+}
+
+/*
+NewPostgresStore creates a store backed by the given database.
+
+Parameters:
+- db: The primary database connection
+
+Returns:
+- *PostgresStore: A new store instance
+*/
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+/*
+Update locks the counters of keys, creating missing rows, calls fn with them
+and writes them back in the same transaction.
+Rows are locked in key order so that two updates cannot deadlock.
+
+Parameters:
+- ctx: The request context
+- keys: The counter keys
+- fn: Changes the counters, given in the order of keys
+
+Returns:
+- error: Any database error
+*/
+func (s *PostgresStore) Update(ctx context.Context, keys []string, fn func(entries []*Attempts)) error {
+	defer telemetry.TraceQuery(ctx, "PostgresStore.Update", "UPDATE", "placement_log_login_attempts").End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	byKey := make(map[string]*Attempts, len(keys))
+	for _, key := range slices.Sorted(slices.Values(keys)) {
+		if _, ok := byKey[key]; ok {
+			continue
+		}
+
+		// The no-op update makes the upsert lock and return an existing row.
+		var a Attempts
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO placement_log_login_attempts (key) VALUES ($1)
+			ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+			RETURNING failures, last_failure, blocked_until;
+		`, key).Scan(&a.Failures, &a.LastFailure, &a.BlockedUntil)
+		if err != nil {
+			return fmt.Errorf("failed to load login attempts: %v", err)
+		}
+		byKey[key] = &a
+	}
+
+	entries := make([]*Attempts, len(keys))
+	for i, key := range keys {
+		entries[i] = byKey[key]
+	}
+
+	fn(entries)
+
+	for key, a := range byKey {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE placement_log_login_attempts SET failures = $2, last_failure = $3, blocked_until = $4
+			WHERE key = $1;
+		`, key, a.Failures, a.LastFailure, a.BlockedUntil)
+		if err != nil {
+			return fmt.Errorf("failed to save login attempts: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+/*
+Delete removes the counters of keys.
+
+Parameters:
+- ctx: The request context
+- keys: The counter keys
+
+Returns:
+- error: Any database error
+*/
+func (s *PostgresStore) Delete(ctx context.Context, keys ...string) error {
+	defer telemetry.TraceQuery(ctx, "PostgresStore.Delete", "DELETE", "placement_log_login_attempts").End()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM placement_log_login_attempts WHERE key = ANY($1);`, pq.Array(keys))
+	if err != nil {
+		return fmt.Errorf("failed to delete login attempts: %v", err)
+	}
+
+	return nil
+}
+
+/*
+Forget removes the counters that are neither recent nor blocked.
+
+Parameters:
+- ctx: The request context
+- failedBefore: Counters with an older last failure are removed
+- now: Counters still blocked at now are kept
+
+Returns:
+- error: Any database error
+*/
+func (s *PostgresStore) Forget(ctx context.Context, failedBefore, now time.Time) error {
+	defer telemetry.TraceQuery(ctx, "PostgresStore.Forget", "DELETE", "placement_log_login_attempts").End()
+
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM placement_log_login_attempts WHERE last_failure < $1 AND blocked_until <= $2;
+	`, failedBefore, now)
+	if err != nil {
+		return fmt.Errorf("failed to delete stale login attempts: %v", err)
+	}
+
+	return nil
+}
+
+// Ensure PostgresStore implements Store
+var _ Store = (*PostgresStore)(nil)
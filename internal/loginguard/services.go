@@ -0,0 +1,388 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+// Outcomes stored in login events.
+const (
+	OutcomeFailed   = "failed"
+	OutcomeLocked   = "locked"
+	OutcomeIPLocked = "ip_locked"
+	OutcomeUnlocked = "unlocked"
+)
+
+// EventRecorder stores login events; LoginEventsRepo is the production implementation.
+type EventRecorder interface {
+	RecordEvent(ctx context.Context, event db.LoginEvent) error
+}
+
+/*
+BlockedError is returned when a login is attempted while the account or the
+client IP is backing off or locked out. The handlers turn it into
+429 Too Many Requests with a Retry-After header.
+*/
+type BlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// RetryAfterHeader returns the wait as whole seconds, rounded up, for the Retry-After header.
+func (e *BlockedError) RetryAfterHeader() string {
+	return strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+/*
+LoginGuard throttles login attempts per account and per client IP.
+The failure counters are kept in a Store, which PostgresStore shares
+between replicas; every failure and lockout is also recorded through the
+EventRecorder for auditing.
+
+Check admits an attempt and counts it as a failure in the same step, so
+parallel guesses cannot all pass before the first failure is counted; the
+returned Attempt gives the count back when the login succeeds.
+
+All methods are safe on a nil *LoginGuard, which disables throttling.
+*/
+type LoginGuard struct {
+	cfg       config.LoginConfig
+	store     Store
+	events    EventRecorder
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+/*
+NewLoginGuard creates a new LoginGuard.
+
+Parameters:
+- store: Where the failure counters are kept (nil keeps them in memory, for a single instance)
+- events: Where failed logins and lockouts are recorded (nil disables recording)
+- cfg: The backoff and lockout settings
+
+Returns:
+- *LoginGuard: A new guard instance
+*/
+func NewLoginGuard(store Store, events EventRecorder, cfg config.LoginConfig) *LoginGuard {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return &LoginGuard{
+		cfg:    cfg,
+		store:  store,
+		events: events,
+		now:    time.Now,
+	}
+}
+
+func accountKey(accountType, account string) string {
+	return "account:" + accountType + ":" + strings.ToLower(strings.TrimSpace(account))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+/*
+Attempt is a login attempt admitted by Check. It has been counted as a
+failure already; exactly one of Fail, Succeed or Release settles it, and
+the later calls do nothing.
+
+All methods are safe on a nil *Attempt.
+*/
+type Attempt struct {
+	guard         *LoginGuard
+	accountType   string
+	account       string
+	ip            string
+	accountLocked bool
+	ipLocked      bool
+	done          bool
+}
+
+/*
+Check admits a login attempt unless the account or the client IP must wait,
+and counts it as a failure in the same atomic step.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- account: The regno or admin username
+- ip: The client IP
+
+Returns:
+- *Attempt: The admitted attempt; the caller settles it with Fail, Succeed or Release
+- error: A *BlockedError while the account or the IP must wait, or a store error
+
+The function:
+1. Forgets earlier failures that are older than the failure window
+2. Refuses the attempt while the account or the IP is backing off or locked
+3. Otherwise counts the attempt: once the free attempts are used up the account's next attempt is delayed exponentially, and at its failure limit the account or IP is locked for the lockout duration
+*/
+func (g *LoginGuard) Check(ctx context.Context, accountType, account, ip string) (*Attempt, error) {
+	if g == nil {
+		return nil, nil
+	}
+
+	now := g.now()
+	g.sweep(ctx, now)
+
+	attempt := &Attempt{guard: g, accountType: accountType, account: account, ip: ip}
+	var wait time.Duration
+
+	err := g.store.Update(ctx, []string{accountKey(accountType, account), ipKey(ip)}, func(entries []*Attempts) {
+		for _, a := range entries {
+			if g.expired(a, now) {
+				*a = Attempts{}
+			}
+			wait = max(wait, a.BlockedUntil.Sub(now))
+		}
+
+		if wait > 0 {
+			return
+		}
+
+		attempt.accountLocked = g.fail(entries[0], g.cfg.FreeAttempts, g.cfg.MaxFailures, now)
+		// Many students share a campus NAT address, so IPs are only locked out
+		// at their much higher limit and never backed off.
+		attempt.ipLocked = g.fail(entries[1], g.cfg.IPMaxFailures, g.cfg.IPMaxFailures, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if wait > 0 {
+		return nil, &BlockedError{RetryAfter: wait}
+	}
+
+	return attempt, nil
+}
+
+/*
+Fail settles the attempt as a wrong guess: its failure stays counted and a
+"failed" event is recorded, plus a "locked"/"ip_locked" event when it
+reached a lockout.
+
+Parameters:
+- ctx: The request context
+*/
+func (a *Attempt) Fail(ctx context.Context) {
+	if a == nil || a.done {
+		return
+	}
+	a.done = true
+
+	g := a.guard
+	g.record(ctx, a.accountType, a.account, a.ip, OutcomeFailed, "")
+	if a.accountLocked {
+		g.record(ctx, a.accountType, a.account, a.ip, OutcomeLocked, "")
+	}
+	if a.ipLocked {
+		g.record(ctx, a.accountType, a.account, a.ip, OutcomeIPLocked, "")
+	}
+}
+
+/*
+Succeed settles the attempt as a successful login: the failures of the
+account are cleared and the attempt is taken back from the client IP.
+Earlier failures of the IP are kept, so that logging into one's own account
+does not reset a guessing run against other accounts.
+
+Parameters:
+- ctx: The request context
+*/
+func (a *Attempt) Succeed(ctx context.Context) {
+	if a == nil || a.done {
+		return
+	}
+	a.done = true
+
+	g := a.guard
+	if err := g.store.Delete(ctx, accountKey(a.accountType, a.account)); err != nil {
+		log.Printf("loginguard: %v", err)
+	}
+	g.refund(ctx, []string{ipKey(a.ip)}, []int{g.cfg.IPMaxFailures}, []int{g.cfg.IPMaxFailures})
+}
+
+/*
+Release takes the attempt back from the account and the client IP without
+clearing earlier failures. Callers defer it so that attempts which ended in
+neither a wrong guess nor a login, such as a database error, do not count.
+
+Parameters:
+- ctx: The request context
+*/
+func (a *Attempt) Release(ctx context.Context) {
+	if a == nil || a.done {
+		return
+	}
+	a.done = true
+
+	g := a.guard
+	g.refund(ctx,
+		[]string{accountKey(a.accountType, a.account), ipKey(a.ip)},
+		[]int{g.cfg.FreeAttempts, g.cfg.IPMaxFailures},
+		[]int{g.cfg.MaxFailures, g.cfg.IPMaxFailures})
+}
+
+// fail counts one failure and reports whether it triggered a lockout.
+func (g *LoginGuard) fail(a *Attempts, free, limit int, now time.Time) bool {
+	a.Failures++
+	a.LastFailure = now
+
+	switch {
+	case a.Failures >= limit:
+		a.BlockedUntil = now.Add(g.cfg.LockoutDuration)
+		return true
+	case a.Failures > free:
+		a.BlockedUntil = now.Add(g.backoff(a.Failures - free))
+	}
+
+	return false
+}
+
+// refund takes one failure back from each key and recomputes its wait.
+func (g *LoginGuard) refund(ctx context.Context, keys []string, free, limit []int) {
+	err := g.store.Update(ctx, keys, func(entries []*Attempts) {
+		for i, a := range entries {
+			a.Failures = max(a.Failures-1, 0)
+
+			switch {
+			case a.Failures >= limit[i]:
+			case a.Failures > free[i]:
+				a.BlockedUntil = a.LastFailure.Add(g.backoff(a.Failures - free[i]))
+			default:
+				a.BlockedUntil = time.Time{}
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("loginguard: %v", err)
+	}
+}
+
+// backoff returns BackoffBase doubled for every failure past the first, capped at BackoffMax.
+func (g *LoginGuard) backoff(n int) time.Duration {
+	delay := g.cfg.BackoffBase
+	for i := 1; i < n && delay < g.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, g.cfg.BackoffMax)
+}
+
+func (g *LoginGuard) expired(a *Attempts, now time.Time) bool {
+	return now.Sub(a.LastFailure) > g.cfg.FailureWindow && !now.Before(a.BlockedUntil)
+}
+
+// sweep drops forgotten counters at most once per failure window so that
+// guessing many account names cannot grow the store without bound.
+func (g *LoginGuard) sweep(ctx context.Context, now time.Time) {
+	g.mu.Lock()
+	if now.Sub(g.lastSweep) < g.cfg.FailureWindow {
+		g.mu.Unlock()
+		return
+	}
+	g.lastSweep = now
+	g.mu.Unlock()
+
+	if err := g.store.Forget(ctx, now.Add(-g.cfg.FailureWindow), now); err != nil {
+		log.Printf("loginguard: %v", err)
+	}
+}
+
+/*
+Succeed clears the failures of an account outside of a login, e.g. after
+its password was reset through an emailed link.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- account: The regno or admin username
+*/
+func (g *LoginGuard) Succeed(ctx context.Context, accountType, account string) {
+	if g == nil {
+		return
+	}
+
+	if err := g.store.Delete(ctx, accountKey(accountType, account)); err != nil {
+		log.Printf("loginguard: %v", err)
+	}
+}
+
+/*
+Unlock lifts the backoff or lockout of an account and/or a client IP on
+every instance sharing the store.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"; ignored when account is empty
+- account: The regno or admin username to unlock, or empty
+- ip: The client IP to unlock, or empty
+- adminID: The admin performing the unlock, recorded with the event
+
+Returns:
+- error: If neither an account nor an IP is given, or a store error
+*/
+func (g *LoginGuard) Unlock(ctx context.Context, accountType, account, ip, adminID string) error {
+	if account == "" && ip == "" {
+		return fmt.Errorf("account or ip is required")
+	}
+
+	if account != "" && accountType != auth.RoleUser && accountType != auth.RoleAdmin {
+		return fmt.Errorf("account type must be 'user' or 'admin'")
+	}
+
+	if g == nil {
+		return nil
+	}
+
+	var keys []string
+	if account != "" {
+		keys = append(keys, accountKey(accountType, account))
+	}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+
+	if err := g.store.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	g.record(ctx, accountType, account, ip, OutcomeUnlocked, adminID)
+
+	return nil
+}
+
+// record stores an event; failures are logged rather than failing the login.
+func (g *LoginGuard) record(ctx context.Context, accountType, account, ip, outcome, actorID string) {
+	if g.events == nil {
+		return
+	}
+
+	err := g.events.RecordEvent(ctx, db.LoginEvent{
+		AccountType: accountType,
+		Account:     account,
+		IP:          ip,
+		Outcome:     outcome,
+		ActorID:     actorID,
+	})
+	if err != nil {
+		log.Printf("loginguard: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+)
+
+type mockEventRecorder struct {
+	mu     sync.Mutex
+	events []db.LoginEvent
+}
+
+func (m *mockEventRecorder) RecordEvent(ctx context.Context, event db.LoginEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *mockEventRecorder) outcomes() []string {
+	var out []string
+	for _, e := range m.events {
+		out = append(out, e.Outcome)
+	}
+	return out
+}
+
+func newTestGuard() (*LoginGuard, *mockEventRecorder, *time.Time) {
+	events := &mockEventRecorder{}
+	g := NewLoginGuard(nil, events, config.Default().Login)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, events, &now
+}
+
+// fail makes a login attempt that turns out to be a wrong guess.
+func fail(t *testing.T, g *LoginGuard, accountType, account, ip string) {
+	t.Helper()
+	attempt, err := g.Check(context.Background(), accountType, account, ip)
+	if err != nil {
+		t.Fatalf("expected attempt on %s from %s to be admitted, got %v", account, ip, err)
+	}
+	attempt.Fail(context.Background())
+}
+
+// check reports whether an attempt would be admitted, without counting it.
+func check(g *LoginGuard, accountType, account, ip string) error {
+	attempt, err := g.Check(context.Background(), accountType, account, ip)
+	attempt.Release(context.Background())
+	return err
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected BlockedError, got %v", err)
+	}
+	return blocked.RetryAfter
+}
+
+func TestLoginGuard_Backoff(t *testing.T) {
+	g, _, now := newTestGuard()
+
+	for i := 0; i < 3; i++ {
+		fail(t, g, "user", "22bcs1234", "10.0.0.1")
+		if err := check(g, "user", "22bcs1234", "10.0.0.1"); err != nil {
+			t.Fatalf("expected free attempt %d to be allowed, got %v", i+1, err)
+		}
+	}
+
+	fail(t, g, "user", "22BCS1234", "10.0.0.2")
+	if err := check(g, "user", "22bcs9999", "10.0.0.2"); err != nil {
+		t.Errorf("expected IP not to back off below its limit, got %v", err)
+	}
+	if got := retryAfter(t, check(g, "user", "22bcs1234", "10.0.0.3")); got != time.Second {
+		t.Errorf("expected 1s backoff, got %v", got)
+	}
+
+	*now = now.Add(time.Second)
+	fail(t, g, "user", "22bcs1234", "10.0.0.2")
+	if got := retryAfter(t, check(g, "user", "22bcs1234", "10.0.0.3")); got != 2*time.Second {
+		t.Errorf("expected backoff to double to 2s, got %v", got)
+	}
+
+	if err := check(g, "user", "22bcs9999", "10.0.0.3"); err != nil {
+		t.Errorf("expected other accounts to be unaffected, got %v", err)
+	}
+}
+
+func TestLoginGuard_Lockout(t *testing.T) {
+	g, events, now := newTestGuard()
+
+	for i := 0; i < 10; i++ {
+		fail(t, g, "admin", "root", "10.0.0.1")
+		*now = now.Add(time.Minute)
+	}
+
+	if got := retryAfter(t, check(g, "admin", "root", "10.0.0.9")); got != 14*time.Minute {
+		t.Errorf("expected remaining lockout 14m, got %v", got)
+	}
+	if outcomes := events.outcomes(); outcomes[len(outcomes)-1] != OutcomeLocked {
+		t.Errorf("expected lockout event, got %v", outcomes)
+	}
+
+	*now = now.Add(15 * time.Minute)
+	if err := check(g, "admin", "root", "10.0.0.9"); err != nil {
+		t.Errorf("expected lockout to expire, got %v", err)
+	}
+}
+
+// lockIP fails one attempt on each of limit different accounts from ip.
+func lockIP(t *testing.T, g *LoginGuard, now *time.Time, ip string, limit int) {
+	t.Helper()
+	for i := 0; i < limit; i++ {
+		fail(t, g, "user", string(rune('a'+i%26))+string(rune('a'+i/26)), ip)
+		*now = now.Add(time.Second)
+	}
+}
+
+func TestLoginGuard_IPLimit(t *testing.T) {
+	g, events, now := newTestGuard()
+
+	// One failure per account never triggers an account backoff, but the
+	// client IP is locked once it reaches the IP limit.
+	lockIP(t, g, now, "10.0.0.1", 100)
+
+	if err := check(g, "user", "fresh", "10.0.0.1"); err == nil {
+		t.Error("expected locked IP to be blocked for any account")
+	}
+	if err := check(g, "user", "fresh", "10.0.0.2"); err != nil {
+		t.Errorf("expected other IPs to be unaffected, got %v", err)
+	}
+	if outcomes := events.outcomes(); outcomes[len(outcomes)-1] != OutcomeIPLocked {
+		t.Errorf("expected ip lockout event, got %v", outcomes[len(outcomes)-1])
+	}
+}
+
+func TestLoginGuard_ParallelAttempts(t *testing.T) {
+	g, _, _ := newTestGuard()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	admitted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt, err := g.Check(context.Background(), "user", "22bcs1234", "10.0.0.1")
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			admitted++
+			mu.Unlock()
+			attempt.Fail(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	// The free attempts and the one that starts the backoff get through;
+	// the others are refused before any of the admitted ones has failed.
+	if admitted != 4 {
+		t.Errorf("expected 4 parallel guesses to be admitted, got %d", admitted)
+	}
+}
+
+func TestLoginGuard_Release(t *testing.T) {
+	g, events, _ := newTestGuard()
+
+	for i := 0; i < 10; i++ {
+		attempt, err := g.Check(context.Background(), "user", "22bcs1234", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("expected released attempts not to count, got %v on attempt %d", err, i+1)
+		}
+		attempt.Release(context.Background())
+		attempt.Fail(context.Background())
+	}
+
+	if len(events.events) != 0 {
+		t.Errorf("expected a settled attempt to ignore Fail, got events %v", events.outcomes())
+	}
+}
+
+func TestLoginGuard_SucceedAndUnlock(t *testing.T) {
+	g, events, now := newTestGuard()
+
+	for i := 0; i < 5; i++ {
+		fail(t, g, "user", "22bcs1234", "10.0.0.1")
+		*now = now.Add(time.Minute)
+	}
+	attempt, err := g.Check(context.Background(), "user", "22bcs1234", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	attempt.Succeed(context.Background())
+	for i := 0; i < 3; i++ {
+		fail(t, g, "user", "22bcs1234", "10.0.0.2")
+	}
+	if err := check(g, "user", "22bcs1234", "10.0.0.2"); err != nil {
+		t.Errorf("expected success to clear account failures, got %v", err)
+	}
+
+	store := g.store.(*MemoryStore)
+	if ip := store.entries[ipKey("10.0.0.1")]; ip == nil || ip.Failures != 5 {
+		t.Errorf("expected success to keep the IP's earlier failures, got %+v", ip)
+	}
+
+	lockIP(t, g, now, "10.0.0.3", 100)
+	if err := g.Unlock(context.Background(), "", "", "", "admin1"); err == nil {
+		t.Error("expected error when nothing to unlock")
+	}
+	if err := g.Unlock(context.Background(), "user", "", "10.0.0.3", "admin1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := check(g, "user", "22bcs5678", "10.0.0.3"); err != nil {
+		t.Errorf("expected unlocked IP to be allowed, got %v", err)
+	}
+	last := events.events[len(events.events)-1]
+	if last.Outcome != OutcomeUnlocked || last.ActorID != "admin1" {
+		t.Errorf("expected unlock event by admin1, got %+v", last)
+	}
+}
+
+func TestLoginGuard_Nil(t *testing.T) {
+	var g *LoginGuard
+	attempt, err := g.Check(context.Background(), "user", "22bcs1234", "10.0.0.1")
+	if err != nil {
+		t.Errorf("expected nil guard to allow logins, got %v", err)
+	}
+	attempt.Fail(context.Background())
+	attempt.Succeed(context.Background())
+	g.Succeed(context.Background(), "user", "22bcs1234")
+}
@@ -0,0 +1,102 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Attempts are the failure counters of one account or client IP.
+*/
+type Attempts struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+/*
+Store keeps the attempt counters.
+MemoryStore serves a single instance; PostgresStore shares the counters
+between replicas and keeps them across restarts.
+*/
+type Store interface {
+	// Update loads the counters of keys, creating empty ones, calls fn with
+	// them in the order of keys and saves what fn leaves. No other Update
+	// of the same keys runs in between.
+	Update(ctx context.Context, keys []string, fn func(entries []*Attempts)) error
+	// Delete removes the counters of keys.
+	Delete(ctx context.Context, keys ...string) error
+	// Forget removes the counters whose last failure is before failedBefore
+	// and which are not blocked at now.
+	Forget(ctx context.Context, failedBefore, now time.Time) error
+}
+
+/*
+MemoryStore is an in-process Store.
+Each server instance has its own counters, so with N replicas an attacker
+gets N times the attempts; use PostgresStore there.
+*/
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Attempts
+}
+
+/*
+NewMemoryStore creates an empty in-process store.
+
+Returns:
+- *MemoryStore: A new store instance
+*/
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Attempts)}
+}
+
+// Update runs fn on the counters of keys under the store's lock.
+func (s *MemoryStore) Update(ctx context.Context, keys []string, fn func(entries []*Attempts)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]*Attempts, len(keys))
+	for i, key := range keys {
+		a, ok := s.entries[key]
+		if !ok {
+			a = &Attempts{}
+			s.entries[key] = a
+		}
+		entries[i] = a
+	}
+
+	fn(entries)
+
+	return nil
+}
+
+// Delete removes the counters of keys.
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+// Forget removes the counters that are neither recent nor blocked.
+func (s *MemoryStore) Forget(ctx context.Context, failedBefore, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, a := range s.entries {
+		if a.LastFailure.Before(failedBefore) && !now.Before(a.BlockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
@@ -3,15 +3,19 @@ package placements
 import (
 	"net/http"
 
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
 	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
 type PlacementsHandler struct {
-	srv *PlacementsService
+	srv   *PlacementsService
+	cache *cache.Cache
 }
 
-func NewPlacementsHandler(srv *PlacementsService) *PlacementsHandler {
-	return &PlacementsHandler{srv: srv}
+// NewPlacementsHandler creates a PlacementsHandler. The public GET endpoints
+// are served from the cache with ETag / Last-Modified support; pass nil to disable caching.
+func NewPlacementsHandler(srv *PlacementsService, cache *cache.Cache) *PlacementsHandler {
+	return &PlacementsHandler{srv: srv, cache: cache}
 }
 
 type PlacementRequest struct {
@@ -36,7 +40,7 @@ func (h *PlacementsHandler) AddPlacement(w http.ResponseWriter, r *http.Request)
 		utils.WriteError(w, err)
 		return
 	}
-	resp, err := h.srv.AddPlacement(req)
+	resp, err := h.srv.AddPlacement(r.Context(), req)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -46,30 +50,21 @@ func (h *PlacementsHandler) AddPlacement(w http.ResponseWriter, r *http.Request)
 
 // GET /placements (all users)
 func (h *PlacementsHandler) GetAllPlacements(w http.ResponseWriter, r *http.Request) {
-	placementsList, err := h.srv.GetAllPlacements()
-	if err != nil {
-		utils.WriteError(w, err)
-		return
-	}
-	utils.WriteJSON(w, placementsList, http.StatusOK)
+	h.cache.ServeJSON(w, r, allPlacementsCacheKey, func() (any, error) {
+		return h.srv.GetAllPlacements(r.Context())
+	})
 }
 
 // GET /placements/company-branch (public)
 func (h *PlacementsHandler) GetCompanyBranchMap(w http.ResponseWriter, r *http.Request) {
-	result, err := h.srv.GetCompanyBranchMap()
-	if err != nil {
-		utils.WriteError(w, err)
-		return
-	}
-	utils.WriteJSON(w, result, http.StatusOK)
+	h.cache.ServeJSON(w, r, companyBranchCacheKey, func() (any, error) {
+		return h.srv.GetCompanyBranchMap(r.Context())
+	})
 }
 
 // GET /placements/branch-company (public)
 func (h *PlacementsHandler) GetBranchCompanyMap(w http.ResponseWriter, r *http.Request) {
-	result, err := h.srv.GetBranchCompanyMap()
-	if err != nil {
-		utils.WriteError(w, err)
-		return
-	}
-	utils.WriteJSON(w, result, http.StatusOK)
+	h.cache.ServeJSON(w, r, branchCompanyCacheKey, func() (any, error) {
+		return h.srv.GetBranchCompanyMap(r.Context())
+	})
 }
@@ -1,32 +1,42 @@
 package placements
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 type PlacementsRepo struct {
-	db *sql.DB
+	db *db.DB
 }
 
-func NewPlacementsRepo(db *sql.DB) *PlacementsRepo {
+// NewPlacementsRepo creates a PlacementsRepo. Listings and branch/company
+// statistics are read from the replica when one is configured.
+func NewPlacementsRepo(db *db.DB) *PlacementsRepo {
 	return &PlacementsRepo{db: db}
 }
 
-func (r *PlacementsRepo) InsertPlacementCompany(company string, ctc float64, placementDate string) (int, error) {
+func (r *PlacementsRepo) InsertPlacementCompany(ctx context.Context, company string, ctc float64, placementDate string) (int, error) {
+	defer telemetry.TraceQuery(ctx, "PlacementsRepo.InsertPlacementCompany", "INSERT", "placement_companies").End()
+
 	var id int
 	query := `INSERT INTO placement_companies (company, ctc, placement_date) VALUES ($1, $2, $3) RETURNING id`
-	err := r.db.QueryRow(query, company, ctc, placementDate).Scan(&id)
+	err := r.db.QueryRowContext(ctx, query, company, ctc, placementDate).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert placement company: %w", err)
 	}
 	return id, nil
 }
 
-func (r *PlacementsRepo) InsertBranchwiseRecords(placementID int, branchCounts []BranchCount) error {
+func (r *PlacementsRepo) InsertBranchwiseRecords(ctx context.Context, placementID int, branchCounts []BranchCount) error {
+	defer telemetry.TraceQuery(ctx, "PlacementsRepo.InsertBranchwiseRecords", "INSERT", "placement_branchwise_record").End()
+
 	query := `INSERT INTO placement_branchwise_record (placement_id, branch, count) VALUES ($1, $2, $3)`
 	for _, bc := range branchCounts {
-		_, err := r.db.Exec(query, placementID, bc.Branch, bc.Count)
+		_, err := r.db.ExecContext(ctx, query, placementID, bc.Branch, bc.Count)
 		if err != nil {
 			return fmt.Errorf("failed to insert branchwise record: %w", err)
 		}
@@ -34,9 +44,21 @@ func (r *PlacementsRepo) InsertBranchwiseRecords(placementID int, branchCounts [
 	return nil
 }
 
-func (r *PlacementsRepo) GetAllPlacements() ([]PlacementCompany, error) {
+/*
+GetAllPlacements returns every placement with its branch counts, newest first.
+The placements and their branch counts are read in one query, so a request
+holds a single pooled connection however many companies there are.
+*/
+func (r *PlacementsRepo) GetAllPlacements(ctx context.Context) ([]PlacementCompany, error) {
+	defer telemetry.TraceQuery(ctx, "PlacementsRepo.GetAllPlacements", "SELECT", "placement_companies").End()
+
 	placements := []PlacementCompany{}
-	rows, err := r.db.Query(`SELECT id, company, ctc, placement_date, created_at FROM placement_companies ORDER BY placement_date DESC`)
+	rows, err := r.db.QueryRead(ctx, `
+		SELECT pc.id, pc.company, pc.ctc, pc.placement_date, pc.created_at, pbr.branch, pbr.count
+		FROM placement_companies pc
+		LEFT JOIN placement_branchwise_record pbr ON pbr.placement_id = pc.id
+		ORDER BY pc.placement_date DESC, pc.id, pbr.id
+	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch placements: %w", err)
 	}
@@ -44,26 +66,26 @@ func (r *PlacementsRepo) GetAllPlacements() ([]PlacementCompany, error) {
 
 	for rows.Next() {
 		var p PlacementCompany
-		err := rows.Scan(&p.ID, &p.Company, &p.CTC, &p.PlacementDate, &p.CreatedAt)
+		var branch sql.NullString
+		var count sql.NullInt64
+		err := rows.Scan(&p.ID, &p.Company, &p.CTC, &p.PlacementDate, &p.CreatedAt, &branch, &count)
 		if err != nil {
 			return nil, err
 		}
-		branchRows, err := r.db.Query(`SELECT branch, count FROM placement_branchwise_record WHERE placement_id = $1`, p.ID)
-		if err != nil {
-			return nil, err
+
+		// Rows of one placement arrive together; start a new one when the id changes.
+		if n := len(placements); n == 0 || placements[n-1].ID != p.ID {
+			placements = append(placements, p)
 		}
-		var branchCounts []BranchCount
-		for branchRows.Next() {
-			var bc BranchCount
-			if err := branchRows.Scan(&bc.Branch, &bc.Count); err != nil {
-				return nil, err
-			}
-			branchCounts = append(branchCounts, bc)
+		if branch.Valid {
+			last := &placements[len(placements)-1]
+			last.BranchCounts = append(last.BranchCounts, BranchCount{Branch: branch.String, Count: int(count.Int64)})
 		}
-		branchRows.Close()
-		p.BranchCounts = branchCounts
-		placements = append(placements, p)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch placements: %w", err)
+	}
+
 	return placements, nil
 }
 
@@ -82,8 +104,10 @@ type CompanyCount struct {
 	Count   int    `json:"count"`
 }
 
-func (r *PlacementsRepo) GetCompanyBranchMap() ([]CompanyBranch, error) {
-	rows, err := r.db.Query(`
+func (r *PlacementsRepo) GetCompanyBranchMap(ctx context.Context) ([]CompanyBranch, error) {
+	defer telemetry.TraceQuery(ctx, "PlacementsRepo.GetCompanyBranchMap", "SELECT", "placement_companies").End()
+
+	rows, err := r.db.QueryRead(ctx, `
 		SELECT pc.company, pbr.branch, SUM(pbr.count) as total
 		FROM placement_companies pc
 		JOIN placement_branchwise_record pbr ON pc.id = pbr.placement_id
@@ -95,24 +119,31 @@ func (r *PlacementsRepo) GetCompanyBranchMap() ([]CompanyBranch, error) {
 	}
 	defer rows.Close()
 
-	companyMap := make(map[string][]BranchCount)
+	// Rows arrive grouped by company; keeping their order keeps the body,
+	// and so its ETag, identical for identical data.
+	var result []CompanyBranch
 	for rows.Next() {
 		var company, branch string
 		var count int
 		if err := rows.Scan(&company, &branch, &count); err != nil {
 			return nil, err
 		}
-		companyMap[company] = append(companyMap[company], BranchCount{Branch: branch, Count: count})
+		if n := len(result); n == 0 || result[n-1].Company != company {
+			result = append(result, CompanyBranch{Company: company})
+		}
+		last := &result[len(result)-1]
+		last.Branches = append(last.Branches, BranchCount{Branch: branch, Count: count})
 	}
-	var result []CompanyBranch
-	for company, branches := range companyMap {
-		result = append(result, CompanyBranch{Company: company, Branches: branches})
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
 
-func (r *PlacementsRepo) GetBranchCompanyMap() ([]BranchCompany, error) {
-	rows, err := r.db.Query(`
+func (r *PlacementsRepo) GetBranchCompanyMap(ctx context.Context) ([]BranchCompany, error) {
+	defer telemetry.TraceQuery(ctx, "PlacementsRepo.GetBranchCompanyMap", "SELECT", "placement_companies").End()
+
+	rows, err := r.db.QueryRead(ctx, `
 		SELECT pbr.branch, pc.company, SUM(pbr.count) as total
 		FROM placement_companies pc
 		JOIN placement_branchwise_record pbr ON pc.id = pbr.placement_id
@@ -124,18 +155,22 @@ func (r *PlacementsRepo) GetBranchCompanyMap() ([]BranchCompany, error) {
 	}
 	defer rows.Close()
 
-	branchMap := make(map[string][]CompanyCount)
+	// Rows arrive grouped by branch, see GetCompanyBranchMap.
+	var result []BranchCompany
 	for rows.Next() {
 		var branch, company string
 		var count int
 		if err := rows.Scan(&branch, &company, &count); err != nil {
 			return nil, err
 		}
-		branchMap[branch] = append(branchMap[branch], CompanyCount{Company: company, Count: count})
+		if n := len(result); n == 0 || result[n-1].Branch != branch {
+			result = append(result, BranchCompany{Branch: branch})
+		}
+		last := &result[len(result)-1]
+		last.Companies = append(last.Companies, CompanyCount{Company: company, Count: count})
 	}
-	var result []BranchCompany
-	for branch, companies := range branchMap {
-		result = append(result, BranchCompany{Branch: branch, Companies: companies})
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
@@ -1,8 +1,12 @@
 package placements
 
 import (
+	"context"
 	"strings"
 	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 type BranchCount struct {
@@ -45,35 +49,47 @@ func CountBranches(regNos []string) []BranchCount {
 //go:generate mockgen -destination=mock_placements_repo.go -package=placements . PlacementsRepository
 
 type PlacementsRepository interface {
-	InsertPlacementCompany(company string, ctc float64, placementDate string) (int, error)
-	InsertBranchwiseRecords(placementID int, branchCounts []BranchCount) error
-	GetAllPlacements() ([]PlacementCompany, error)
-	GetCompanyBranchMap() ([]CompanyBranch, error)
-	GetBranchCompanyMap() ([]BranchCompany, error)
+	InsertPlacementCompany(ctx context.Context, company string, ctc float64, placementDate string) (int, error)
+	InsertBranchwiseRecords(ctx context.Context, placementID int, branchCounts []BranchCount) error
+	GetAllPlacements(ctx context.Context) ([]PlacementCompany, error)
+	GetCompanyBranchMap(ctx context.Context) ([]CompanyBranch, error)
+	GetBranchCompanyMap(ctx context.Context) ([]BranchCompany, error)
 }
 
+// Cache keys of the public placement endpoints; all of them change when a placement is added.
+const (
+	allPlacementsCacheKey = "placements:all"
+	companyBranchCacheKey = "placements:company-branch"
+	branchCompanyCacheKey = "placements:branch-company"
+)
+
 type PlacementsService struct {
-	repo PlacementsRepository
+	repo  PlacementsRepository
+	cache *cache.Cache
 }
 
-func NewPlacementsService(repo PlacementsRepository) *PlacementsService {
-	return &PlacementsService{repo: repo}
+// NewPlacementsService creates a PlacementsService. The cache is invalidated
+// whenever a placement is added; pass nil to disable caching.
+func NewPlacementsService(repo PlacementsRepository, cache *cache.Cache) *PlacementsService {
+	return &PlacementsService{repo: repo, cache: cache}
 }
 
-func (s *PlacementsService) AddPlacement(req PlacementRequest) (PlacementResponse, error) {
+func (s *PlacementsService) AddPlacement(ctx context.Context, req PlacementRequest) (PlacementResponse, error) {
 	placementDate := req.PlacementDate
 	if placementDate == "" {
 		placementDate = time.Now().Format("2006-01-02")
 	}
 	branchCounts := CountBranches(req.Students)
-	placementID, err := s.repo.InsertPlacementCompany(req.Company, req.CTC, placementDate)
+	placementID, err := s.repo.InsertPlacementCompany(ctx, req.Company, req.CTC, placementDate)
 	if err != nil {
 		return PlacementResponse{}, err
 	}
-	err = s.repo.InsertBranchwiseRecords(placementID, branchCounts)
+	err = s.repo.InsertBranchwiseRecords(ctx, placementID, branchCounts)
 	if err != nil {
 		return PlacementResponse{}, err
 	}
+	telemetry.PlacementsAdded.Inc()
+	s.cache.Invalidate(allPlacementsCacheKey, companyBranchCacheKey, branchCompanyCacheKey)
 	return PlacementResponse{
 		PlacementID:   placementID,
 		Company:       req.Company,
@@ -83,14 +99,14 @@ func (s *PlacementsService) AddPlacement(req PlacementRequest) (PlacementRespons
 	}, nil
 }
 
-func (s *PlacementsService) GetAllPlacements() ([]PlacementCompany, error) {
-	return s.repo.GetAllPlacements()
+func (s *PlacementsService) GetAllPlacements(ctx context.Context) ([]PlacementCompany, error) {
+	return s.repo.GetAllPlacements(ctx)
 }
 
-func (s *PlacementsService) GetCompanyBranchMap() ([]CompanyBranch, error) {
-	return s.repo.GetCompanyBranchMap()
+func (s *PlacementsService) GetCompanyBranchMap(ctx context.Context) ([]CompanyBranch, error) {
+	return s.repo.GetCompanyBranchMap(ctx)
 }
 
-func (s *PlacementsService) GetBranchCompanyMap() ([]BranchCompany, error) {
-	return s.repo.GetBranchCompanyMap()
+func (s *PlacementsService) GetBranchCompanyMap(ctx context.Context) ([]BranchCompany, error) {
+	return s.repo.GetBranchCompanyMap(ctx)
 }
@@ -1,9 +1,13 @@
 package placements
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
 )
 
 type mockPlacementsRepo struct {
@@ -14,19 +18,19 @@ type mockPlacementsRepo struct {
 	GetBranchCompanyMapFunc     func() ([]BranchCompany, error)
 }
 
-func (m *mockPlacementsRepo) InsertPlacementCompany(company string, ctc float64, placementDate string) (int, error) {
+func (m *mockPlacementsRepo) InsertPlacementCompany(ctx context.Context, company string, ctc float64, placementDate string) (int, error) {
 	return m.InsertPlacementCompanyFunc(company, ctc, placementDate)
 }
-func (m *mockPlacementsRepo) InsertBranchwiseRecords(placementID int, branchCounts []BranchCount) error {
+func (m *mockPlacementsRepo) InsertBranchwiseRecords(ctx context.Context, placementID int, branchCounts []BranchCount) error {
 	return m.InsertBranchwiseRecordsFunc(placementID, branchCounts)
 }
-func (m *mockPlacementsRepo) GetAllPlacements() ([]PlacementCompany, error) {
+func (m *mockPlacementsRepo) GetAllPlacements(ctx context.Context) ([]PlacementCompany, error) {
 	return m.GetAllPlacementsFunc()
 }
-func (m *mockPlacementsRepo) GetCompanyBranchMap() ([]CompanyBranch, error) {
+func (m *mockPlacementsRepo) GetCompanyBranchMap(ctx context.Context) ([]CompanyBranch, error) {
 	return m.GetCompanyBranchMapFunc()
 }
-func (m *mockPlacementsRepo) GetBranchCompanyMap() ([]BranchCompany, error) {
+func (m *mockPlacementsRepo) GetBranchCompanyMap(ctx context.Context) ([]BranchCompany, error) {
 	return m.GetBranchCompanyMapFunc()
 }
 
@@ -40,8 +44,8 @@ func TestPlacementsService_AddPlacement(t *testing.T) {
 				return nil
 			},
 		}
-		s := NewPlacementsService(repo)
-		resp, err := s.AddPlacement(PlacementRequest{
+		s := NewPlacementsService(repo, nil)
+		resp, err := s.AddPlacement(context.Background(), PlacementRequest{
 			Company:       "TestCo",
 			CTC:           10.5,
 			PlacementDate: "2024-01-01",
@@ -54,14 +58,39 @@ func TestPlacementsService_AddPlacement(t *testing.T) {
 			t.Errorf("unexpected response: %+v", resp)
 		}
 	})
+	t.Run("invalidates the cached listings", func(t *testing.T) {
+		repo := &mockPlacementsRepo{
+			InsertPlacementCompanyFunc: func(company string, ctc float64, placementDate string) (int, error) {
+				return 1, nil
+			},
+			InsertBranchwiseRecordsFunc: func(placementID int, branchCounts []BranchCount) error {
+				return nil
+			},
+		}
+		store := cache.NewMemoryStore()
+		keys := []string{allPlacementsCacheKey, companyBranchCacheKey, branchCompanyCacheKey}
+		for _, key := range keys {
+			store.Set(key, cache.NewEntry([]byte("[]")), time.Minute)
+		}
+		s := NewPlacementsService(repo, cache.New(store, time.Minute))
+
+		if _, err := s.AddPlacement(context.Background(), PlacementRequest{Company: "TestCo", CTC: 10.5, Students: []string{"22bcs1234"}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, key := range keys {
+			if _, ok := store.Get(key); ok {
+				t.Errorf("expected %s to be invalidated", key)
+			}
+		}
+	})
 	t.Run("placement company insert error", func(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			InsertPlacementCompanyFunc: func(company string, ctc float64, placementDate string) (int, error) {
 				return 0, errors.New("insert error")
 			},
 		}
-		s := NewPlacementsService(repo)
-		_, err := s.AddPlacement(PlacementRequest{Company: "TestCo", CTC: 10.5, Students: []string{"22bcs1234"}})
+		s := NewPlacementsService(repo, nil)
+		_, err := s.AddPlacement(context.Background(), PlacementRequest{Company: "TestCo", CTC: 10.5, Students: []string{"22bcs1234"}})
 		if err == nil || err.Error() != "insert error" {
 			t.Errorf("expected insert error, got %v", err)
 		}
@@ -75,8 +104,8 @@ func TestPlacementsService_AddPlacement(t *testing.T) {
 				return errors.New("branchwise error")
 			},
 		}
-		s := NewPlacementsService(repo)
-		_, err := s.AddPlacement(PlacementRequest{Company: "TestCo", CTC: 10.5, Students: []string{"22bcs1234"}})
+		s := NewPlacementsService(repo, nil)
+		_, err := s.AddPlacement(context.Background(), PlacementRequest{Company: "TestCo", CTC: 10.5, Students: []string{"22bcs1234"}})
 		if err == nil || err.Error() != "branchwise error" {
 			t.Errorf("expected branchwise error, got %v", err)
 		}
@@ -89,8 +118,8 @@ func TestPlacementsService_GetAllPlacements(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetAllPlacementsFunc: func() ([]PlacementCompany, error) { return placements, nil },
 		}
-		s := NewPlacementsService(repo)
-		got, err := s.GetAllPlacements()
+		s := NewPlacementsService(repo, nil)
+		got, err := s.GetAllPlacements(context.Background())
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -102,8 +131,8 @@ func TestPlacementsService_GetAllPlacements(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetAllPlacementsFunc: func() ([]PlacementCompany, error) { return nil, errors.New("db error") },
 		}
-		s := NewPlacementsService(repo)
-		_, err := s.GetAllPlacements()
+		s := NewPlacementsService(repo, nil)
+		_, err := s.GetAllPlacements(context.Background())
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -116,8 +145,8 @@ func TestPlacementsService_GetCompanyBranchMap(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetCompanyBranchMapFunc: func() ([]CompanyBranch, error) { return cb, nil },
 		}
-		s := NewPlacementsService(repo)
-		got, err := s.GetCompanyBranchMap()
+		s := NewPlacementsService(repo, nil)
+		got, err := s.GetCompanyBranchMap(context.Background())
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -129,8 +158,8 @@ func TestPlacementsService_GetCompanyBranchMap(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetCompanyBranchMapFunc: func() ([]CompanyBranch, error) { return nil, errors.New("db error") },
 		}
-		s := NewPlacementsService(repo)
-		_, err := s.GetCompanyBranchMap()
+		s := NewPlacementsService(repo, nil)
+		_, err := s.GetCompanyBranchMap(context.Background())
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -143,8 +172,8 @@ func TestPlacementsService_GetBranchCompanyMap(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetBranchCompanyMapFunc: func() ([]BranchCompany, error) { return bc, nil },
 		}
-		s := NewPlacementsService(repo)
-		got, err := s.GetBranchCompanyMap()
+		s := NewPlacementsService(repo, nil)
+		got, err := s.GetBranchCompanyMap(context.Background())
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -156,8 +185,8 @@ func TestPlacementsService_GetBranchCompanyMap(t *testing.T) {
 		repo := &mockPlacementsRepo{
 			GetBranchCompanyMapFunc: func() ([]BranchCompany, error) { return nil, errors.New("db error") },
 		}
-		s := NewPlacementsService(repo)
-		_, err := s.GetBranchCompanyMap()
+		s := NewPlacementsService(repo, nil)
+		_, err := s.GetBranchCompanyMap(context.Background())
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
 	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
@@ -13,7 +16,8 @@ Provides endpoints for creating, reading, updating, and deleting posts.
 Includes both user and admin-specific operations.
 */
 type PostsHandler struct {
-	srv *PostsService
+	srv   *PostsService
+	cache *cache.Cache
 }
 
 /*
@@ -21,33 +25,43 @@ NewPostsHandler creates a new PostsHandler instance with the provided service.
 
 Parameters:
 - srv: The posts service
+- cache: The response cache for the public post list (nil disables caching)
 
 Returns:
 - *PostsHandler: A new handler instance
 */
-func NewPostsHandler(srv *PostsService) *PostsHandler {
+func NewPostsHandler(srv *PostsService, cache *cache.Cache) *PostsHandler {
 	return &PostsHandler{
-		srv: srv,
+		srv:   srv,
+		cache: cache,
 	}
 }
 
+// errUnauthenticated is returned when a user route runs without a user principal.
+var errUnauthenticated = errors.New("unauthorized: user authentication required")
+
 /*
 createPostRequest represents the JSON payload for creating a new post.
 */
 type createPostRequest struct {
-	PostBody map[string]any `json:"post_body"`
+	PostBody         map[string]any `json:"post_body"`
+	AuthorVisibility string         `json:"author_visibility"`
 }
 
 /*
 updatePostRequest represents the JSON payload for updating an existing post.
 */
 type updatePostRequest struct {
-	PostBody map[string]any `json:"post_body"`
+	PostBody         map[string]any `json:"post_body"`
+	AuthorVisibility string         `json:"author_visibility"`
 }
 
 /*
 AddPost handles post creation requests.
 Creates a new post with reviewed=false, requiring admin approval.
+author_visibility chooses how the author is shown publicly: "named" (the
+default) shows their display name, "pseudonym" a stable pseudonym assigned
+on first use, and "anonymous" nothing.
 
 HTTP Method: POST
 Endpoint: /posts
@@ -62,7 +76,8 @@ Request Body:
 	    "company": "Google",
 	    "role": "Software Engineer",
 	    "rounds": [...]
-	  }
+	  },
+	  "author_visibility": "pseudonym"
 	}
 
 Response (201 Created):
@@ -70,12 +85,20 @@ Response (201 Created):
 	{
 	  "id": "post_id",
 	  "user_id": "user_id",
-	  "post_body": {...}
+	  "post_body": {...},
+	  "reviewed": false,
+	  "review_status": "pending_review",
+	  "author_visibility": "pseudonym",
+	  "author": "QuietOtter42",
+	  "author_branch": "BCE",
+	  "author_batch": "2022",
+	  "created_at": "2026-01-01T10:00:00Z",
+	  "updated_at": "2026-01-01T10:00:00Z"
 	}
 
 Returns:
 - 201 Created: Post created successfully
-- 400 Bad Request: Invalid request format
+- 400 Bad Request: Invalid request format or author visibility
 - 401 Unauthorized: Missing or invalid token
 */
 func (h *PostsHandler) AddPost(w http.ResponseWriter, r *http.Request) {
@@ -86,14 +109,14 @@ func (h *PostsHandler) AddPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userId := r.Header.Get("X-User-ID")
+	userId, ok := auth.UserID(r.Context())
 
-	if userId == "" {
-		utils.WriteError(w, http.ErrNoCookie)
+	if !ok {
+		utils.WriteError(w, errUnauthenticated, http.StatusUnauthorized)
 		return
 	}
 
-	post, err := h.srv.AddPost(userId, req.PostBody)
+	post, err := h.srv.AddPost(r.Context(), userId, req.PostBody, req.AuthorVisibility)
 
 	if err != nil {
 		utils.WriteError(w, err)
@@ -105,17 +128,15 @@ func (h *PostsHandler) AddPost(w http.ResponseWriter, r *http.Request) {
 
 /*
 UpdatePost handles post update requests.
-Users can only update their own posts.
+Users can only update their own posts. Without author_visibility the post
+keeps its current visibility.
 
 HTTP Method: PUT
-Endpoint: /posts?id=<post_id>
+Endpoint: /posts/{id}
 
 Headers Required:
 - Authorization: Bearer <user_jwt_token>
 
-Query Parameters:
-- id: The ID of the post to update
-
 Request Body:
 
 	{
@@ -123,7 +144,8 @@ Request Body:
 	    "company": "Updated Company",
 	    "role": "Updated Role",
 	    "rounds": [...]
-	  }
+	  },
+	  "author_visibility": "anonymous"
 	}
 
 Response (200 OK):
@@ -131,12 +153,17 @@ Response (200 OK):
 	{
 	  "id": "post_id",
 	  "user_id": "user_id",
-	  "post_body": {...}
+	  "post_body": {...},
+	  "reviewed": false,
+	  "review_status": "pending_review",
+	  "author_visibility": "anonymous",
+	  "created_at": "2026-01-01T10:00:00Z",
+	  "updated_at": "2026-01-02T09:30:00Z"
 	}
 
 Returns:
 - 200 OK: Post updated successfully
-- 400 Bad Request: Invalid request format or missing post ID
+- 400 Bad Request: Invalid request format or author visibility
 - 401 Unauthorized: Missing or invalid token
 - 403 Forbidden: User not authorized to update this post
 */
@@ -148,21 +175,14 @@ func (h *PostsHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userId := r.Header.Get("X-User-ID")
-	query := r.URL.Query()
-	postId := query.Get("id")
+	userId, ok := auth.UserID(r.Context())
 
-	if userId == "" {
-		utils.WriteError(w, http.ErrNoCookie)
+	if !ok {
+		utils.WriteError(w, errUnauthenticated, http.StatusUnauthorized)
 		return
 	}
 
-	if postId == "" {
-		utils.WriteError(w, http.ErrMissingFile)
-		return
-	}
-
-	post, err := h.srv.UpdatePost(postId, userId, req.PostBody)
+	post, err := h.srv.UpdatePost(r.Context(), chi.URLParam(r, "id"), userId, req.PostBody, req.AuthorVisibility)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -176,14 +196,11 @@ DeletePost handles post deletion requests.
 Users can only delete their own posts.
 
 HTTP Method: DELETE
-Endpoint: /posts?id=<post_id>
+Endpoint: /posts/{id}
 
 Headers Required:
 - Authorization: Bearer <user_jwt_token>
 
-Query Parameters:
-- id: The ID of the post to delete
-
 Response (200 OK):
 
 	{
@@ -192,26 +209,19 @@ Response (200 OK):
 
 Returns:
 - 200 OK: Post deleted successfully
-- 400 Bad Request: Missing post ID
+- 400 Bad Request: No such post of the user
 - 401 Unauthorized: Missing or invalid token
 - 403 Forbidden: User not authorized to delete this post
 */
 func (h *PostsHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	postId := query.Get("id")
-	userId := r.Header.Get("X-User-ID")
+	userId, ok := auth.UserID(r.Context())
 
-	if postId == "" {
-		utils.WriteError(w, http.ErrMissingFile)
+	if !ok {
+		utils.WriteError(w, errUnauthenticated, http.StatusUnauthorized)
 		return
 	}
 
-	if userId == "" {
-		utils.WriteError(w, http.ErrNoCookie)
-		return
-	}
-
-	err := h.srv.DeletePost(postId, userId)
+	err := h.srv.DeletePost(r.Context(), chi.URLParam(r, "id"), userId)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -223,6 +233,11 @@ func (h *PostsHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 /*
 GetAll handles requests to retrieve all approved posts.
 This endpoint is public and doesn't require authentication.
+"user_id", "author_branch" and "author_batch" are only included for named
+posts; pseudonymous and anonymous posts carry just the author name chosen
+for them.
+The response is cached until a post is approved, rejected, updated or deleted,
+and carries ETag / Last-Modified validators for conditional requests.
 
 HTTP Method: GET
 Endpoint: /posts
@@ -233,38 +248,101 @@ Response (200 OK):
 	{
 	  "id": "post_id",
 	  "user_id": "user_id",
-	  "post_body": {...}
+	  "post_body": {...},
+	  "reviewed": true,
+	  "review_status": "approved",
+	  "author_visibility": "named",
+	  "author": "Jane Doe",
+	  "author_branch": "BCE",
+	  "author_batch": "2022",
+	  "created_at": "2026-01-01T10:00:00Z",
+	  "updated_at": "2026-01-02T09:30:00Z"
+	},
+	{
+	  "id": "post_id",
+	  "post_body": {...},
+	  "reviewed": true,
+	  "review_status": "approved",
+	  "author_visibility": "anonymous",
+	  "created_at": "2026-01-01T10:00:00Z",
+	  "updated_at": "2026-01-01T10:00:00Z"
 	}
 
 ]
 
 Returns:
 - 200 OK: List of all approved posts
+- 304 Not Modified: The client's cached copy (If-None-Match / If-Modified-Since) is current
 - 500 Internal Server Error: Database error
 */
 func (h *PostsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.srv.GetAll()
+	h.cache.ServeJSON(w, r, approvedPostsCacheKey, func() (any, error) {
+		return h.srv.GetAll(r.Context())
+	})
+}
+
+/*
+GetPost handles requests to retrieve a single post.
+Approved posts are public, with the author hidden as in GetAll. Posts
+pending review or rejected are only shown to their author and to admins,
+with review_status "pending_review" or "rejected"; to anyone else they do
+not exist.
+
+HTTP Method: GET
+Endpoint: /posts/{id}
+
+Headers (optional):
+- Authorization: Bearer <user_or_admin_jwt_token>
+
+Response (200 OK):
+
+	{
+	  "id": "post_id",
+	  "user_id": "user_id",
+	  "post_body": {...},
+	  "reviewed": true,
+	  "review_status": "approved",
+	  "author_visibility": "named",
+	  "author": "Jane Doe",
+	  "author_branch": "BCE",
+	  "author_batch": "2022",
+	  "created_at": "2026-01-01T10:00:00Z",
+	  "updated_at": "2026-01-02T09:30:00Z"
+	}
+
+Returns:
+- 200 OK: The post
+- 404 Not Found: No such post, or an unapproved post of another user
+- 500 Internal Server Error: Database error
+*/
+func (h *PostsHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.FromContext(r.Context())
+	viewerId, _ := auth.UserID(r.Context())
+	moderator := principal.IsAdmin() && principal.Can(auth.PermPostsModerate)
+
+	post, err := h.srv.GetPost(r.Context(), chi.URLParam(r, "id"), viewerId, moderator)
+	if errors.Is(err, ErrPostNotFound) {
+		utils.WriteError(w, err, http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		utils.WriteError(w, err)
+		utils.WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	utils.WriteJSON(w, posts, http.StatusOK)
+	utils.WriteJSON(w, post, http.StatusOK)
 }
 
 /*
-GetByUser handles requests to retrieve posts by a specific user.
-Users can only view their own posts.
+GetByUser handles requests to retrieve the posts of the logged-in user.
+The user is taken from the token, so users can only list their own posts.
 
 HTTP Method: GET
-Endpoint: /posts/user?user_id=<user_id>
+Endpoint: /posts/user
 
 Headers Required:
 - Authorization: Bearer <user_jwt_token>
 
-Query Parameters:
-- user_id: The ID of the user whose posts to retrieve
-
 Response (200 OK):
 [
 
@@ -278,26 +356,17 @@ Response (200 OK):
 
 Returns:
 - 200 OK: List of user's approved posts
-- 400 Bad Request: Missing user ID
 - 401 Unauthorized: Missing or invalid token
-- 403 Forbidden: User can only view their own posts
 */
 func (h *PostsHandler) GetByUser(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	requestedUserId := query.Get("user_id")
-	authenticatedUserId := r.Header.Get("X-User-ID")
-
-	if requestedUserId == "" {
-		utils.WriteError(w, http.ErrMissingFile)
-		return
-	}
+	userId, ok := auth.UserID(r.Context())
 
-	if requestedUserId != authenticatedUserId {
-		utils.WriteError(w, errors.New("forbidden: can only access own posts"))
+	if !ok {
+		utils.WriteError(w, errUnauthenticated, http.StatusUnauthorized)
 		return
 	}
 
-	posts, err := h.srv.GetByUser(requestedUserId)
+	posts, err := h.srv.GetByUser(r.Context(), userId)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -308,7 +377,8 @@ func (h *PostsHandler) GetByUser(w http.ResponseWriter, r *http.Request) {
 
 /*
 GetAllPostsForAdmin handles requests to retrieve all posts for admin review.
-Admins can see both approved and pending posts.
+Admins can see both approved and pending posts, including the user ID of
+pseudonymous and anonymous posts.
 
 HTTP Method: GET
 Endpoint: /admin/posts
@@ -323,7 +393,8 @@ Response (200 OK):
 	  "id": "post_id",
 	  "user_id": "user_id",
 	  "post_body": {...},
-	  "reviewed": true/false
+	  "reviewed": true/false,
+	  "author_visibility": "anonymous"
 	}
 
 ]
@@ -334,7 +405,7 @@ Returns:
 - 500 Internal Server Error: Database error
 */
 func (h *PostsHandler) GetAllPostsForAdmin(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.srv.GetAllPostsForAdmin()
+	posts, err := h.srv.GetAllPostsForAdmin(r.Context())
 
 	if err != nil {
 		utils.WriteError(w, err)
@@ -349,13 +420,12 @@ ReviewPost handles post review requests by admins.
 Admins can approve or reject posts.
 
 HTTP Method: PUT
-Endpoint: /admin/posts/review?id=<post_id>&action=<action>
+Endpoint: /admin/posts/{id}/review?action=<action>
 
 Headers Required:
 - Authorization: Bearer <admin_jwt_token>
 
 Query Parameters:
-- id: The ID of the post to review
 - action: Either "approve" or "reject"
 
 Response (200 OK):
@@ -371,11 +441,9 @@ Returns:
 - 500 Internal Server Error: Database error
 */
 func (h *PostsHandler) ReviewPost(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	postId := query.Get("id")
-	action := query.Get("action") // "approve" or "reject"
+	action := r.URL.Query().Get("action") // "approve" or "reject"
 
-	if postId == "" || action == "" {
+	if action == "" {
 		utils.WriteError(w, http.ErrMissingFile)
 		return
 	}
@@ -385,7 +453,7 @@ func (h *PostsHandler) ReviewPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.srv.ReviewPost(postId, action)
+	err := h.srv.ReviewPost(r.Context(), chi.URLParam(r, "id"), action)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -399,14 +467,11 @@ DeletePostAsAdmin handles post deletion requests by admins.
 Admins can delete any post, regardless of ownership.
 
 HTTP Method: DELETE
-Endpoint: /admin/posts?id=<post_id>
+Endpoint: /admin/posts/{id}
 
 Headers Required:
 - Authorization: Bearer <admin_jwt_token>
 
-Query Parameters:
-- id: The ID of the post to delete
-
 Response (200 OK):
 
 	{
@@ -415,20 +480,12 @@ Response (200 OK):
 
 Returns:
 - 200 OK: Post deleted successfully
-- 400 Bad Request: Missing post ID
+- 400 Bad Request: No such post
 - 401 Unauthorized: Missing or invalid admin token
 - 500 Internal Server Error: Database error
 */
 func (h *PostsHandler) DeletePostAsAdmin(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	postId := query.Get("id")
-
-	if postId == "" {
-		utils.WriteError(w, http.ErrMissingFile)
-		return
-	}
-
-	err := h.srv.DeletePostAsAdmin(postId)
+	err := h.srv.DeletePostAsAdmin(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
 		utils.WriteError(w, err)
 		return
@@ -0,0 +1,44 @@
+package posts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestPostsHandler_GetByUser(t *testing.T) {
+	var requested string
+	repo := &mockPostsRepo{
+		GetPostsByUserIdFunc: func(userId string) ([]db.Post, error) {
+			requested = userId
+			return []db.Post{}, nil
+		},
+	}
+	h := NewPostsHandler(NewPostsService(repo, nil), nil)
+
+	t.Run("no user", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.GetByUser(rec, httptest.NewRequest(http.MethodGet, "/posts/user", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("lists the caller's posts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/posts/user?user_id=u2", nil)
+		req = req.WithContext(auth.NewContext(req.Context(), auth.NewPrincipal("u1", auth.RoleUser, "session-1")))
+		rec := httptest.NewRecorder()
+
+		h.GetByUser(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if requested != "u1" {
+			t.Errorf("expected the posts of u1, got %q", requested)
+		}
+	})
+}
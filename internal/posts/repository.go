@@ -1,11 +1,14 @@
 package posts
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 /*
@@ -13,24 +16,85 @@ PostsRepo handles post-related data access operations.
 Provides methods for creating, reading, updating, and deleting posts in the database.
 */
 type PostsRepo struct {
-	db *sql.DB
+	db *db.DB
 }
 
 /*
 NewPostsRepo creates a new PostsRepo instance with the provided database connection.
 
 Parameters:
-- db: The database connection
+- db: The database handle; public listings are read from the replica when one is configured
 
 Returns:
 - *PostsRepo: A new repository instance
 */
-func NewPostsRepo(db *sql.DB) *PostsRepo {
+func NewPostsRepo(db *db.DB) *PostsRepo {
 	return &PostsRepo{
 		db: db,
 	}
 }
 
+/*
+postColumns selects a post joined with its author as "p", "u" and "r" (see
+postJoins), with whether a review rejected it. The author name depends on the post's visibility: the display
+name of named posts, the pseudonym of pseudonymous posts and nothing for
+anonymous ones.
+*/
+const postColumns = `
+	p.id, COALESCE(p.user_id::text, ''), p.post_body, p.reviewed, p.rejected_at IS NOT NULL, p.author_visibility,
+	CASE p.author_visibility
+		WHEN 'named' THEN COALESCE(NULLIF(u.display_name, ''), u.username, '')
+		WHEN 'pseudonym' THEN COALESCE(u.pseudonym, '')
+		ELSE ''
+	END,
+	COALESCE(r.branch, ''), COALESCE(r.batch, ''), p.created_at, p.updated_at`
+
+// postJoins joins a post "p" with its author "u" and the author's roster entry "r".
+const postJoins = `
+		LEFT JOIN placement_log_users u ON u.id = p.user_id
+		LEFT JOIN placement_log_roster r ON r.regno = u.regno`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner, p *db.Post) error {
+	var rejected bool
+	err := row.Scan(&p.ID, &p.UserID, &p.PostBody, &p.Reviewed, &rejected, &p.AuthorVisibility, &p.Author,
+		&p.AuthorBranch, &p.AuthorBatch, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.Reviewed:
+		p.ReviewStatus = db.ReviewStatusApproved
+	case rejected:
+		p.ReviewStatus = db.ReviewStatusRejected
+	default:
+		p.ReviewStatus = db.ReviewStatusPending
+	}
+
+	return nil
+}
+
+func scanPosts(rows *sql.Rows) ([]db.Post, error) {
+	defer rows.Close()
+
+	var posts []db.Post
+
+	for rows.Next() {
+		var p db.Post
+		if err := scanPost(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan posts: %v", err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
 /*
 GetAllPosts retrieves all approved posts from the database.
 Only returns posts that have been reviewed and approved by admins.
@@ -40,35 +104,24 @@ Returns:
 - error: Any error that occurred during retrieval
 
 The function queries the database for posts where reviewed=true.
+The query runs on the read replica when one is configured.
 */
-func (repo PostsRepo) GetAllPosts() ([]db.Post, error) {
+func (repo PostsRepo) GetAllPosts(ctx context.Context) ([]db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.GetAllPosts", "SELECT", "placement_log_posts").End()
+
 	query := `
-		SELECT id, user_id, post_body, reviewed
-		FROM placement_log_posts
-		WHERE reviewed=true;
+		SELECT` + postColumns + `
+		FROM placement_log_posts p` + postJoins + `
+		WHERE p.reviewed=true;
 	`
 
-	rows, err := repo.db.Query(query)
+	rows, err := repo.db.QueryRead(ctx, query)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all the posts: %v", err)
 	}
 
-	defer rows.Close()
-
-	var posts []db.Post
-
-	for rows.Next() {
-		var p db.Post
-		var reviewed bool
-		if err := rows.Scan(&p.ID, &p.UserID, &p.PostBody, &reviewed); err != nil {
-			return nil, fmt.Errorf("failed to scan posts: %v", err)
-		}
-		p.Reviewed = reviewed
-		posts = append(posts, p)
-	}
-
-	return posts, nil
+	return scanPosts(rows)
 }
 
 /*
@@ -82,38 +135,29 @@ Returns:
 The function queries the database for all posts, including the reviewed status.
 Posts are ordered by created_at in descending order (newest first).
 */
-func (repo PostsRepo) GetAllPostsForAdmin() ([]db.Post, error) {
+func (repo PostsRepo) GetAllPostsForAdmin(ctx context.Context) ([]db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.GetAllPostsForAdmin", "SELECT", "placement_log_posts").End()
+
 	query := `
-		SELECT id, user_id, post_body, reviewed
-		FROM placement_log_posts
-		ORDER BY created_at DESC;
+		SELECT` + postColumns + `
+		FROM placement_log_posts p` + postJoins + `
+		ORDER BY p.created_at DESC;
 	`
 
-	rows, err := repo.db.Query(query)
+	rows, err := repo.db.QueryContext(ctx, query)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all posts for admin: %v", err)
 	}
 
-	defer rows.Close()
-
-	var posts []db.Post
-
-	for rows.Next() {
-		var p db.Post
-		if err := rows.Scan(&p.ID, &p.UserID, &p.PostBody, &p.Reviewed); err != nil {
-			return nil, fmt.Errorf("failed to scan posts: %v", err)
-		}
-		posts = append(posts, p)
-	}
-
-	return posts, nil
+	return scanPosts(rows)
 }
 
 /*
 GetPostsByUserId retrieves approved posts for a specific user.
 
 Parameters:
+- ctx: The request context
 - userId: The ID of the user whose posts to retrieve
 
 Returns:
@@ -127,39 +171,65 @@ Possible errors:
 - "all fields are required": Missing user ID
 - "failed to get user posts": Database query error
 */
-func (repo PostsRepo) GetPostsByUserId(userId string) ([]db.Post, error) {
+func (repo PostsRepo) GetPostsByUserId(ctx context.Context, userId string) ([]db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.GetPostsByUserId", "SELECT", "placement_log_posts").End()
+
 	if userId == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
 	query := `
-		SELECT id, user_id, post_body, reviewed
-		FROM placement_log_posts 
-		WHERE user_id=$1 AND reviewed=true;`
+		SELECT` + postColumns + `
+		FROM placement_log_posts p` + postJoins + `
+		WHERE p.user_id=$1 AND p.reviewed=true;`
 
-	rows, err := repo.db.Query(query, userId)
+	rows, err := repo.db.QueryContext(ctx, query, userId)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user posts: %v", err)
 	}
 
-	defer rows.Close()
+	return scanPosts(rows)
+}
 
-	var posts []db.Post
+/*
+GetPost retrieves a single post, whatever its review status.
 
-	for rows.Next() {
-		var p db.Post
-		var reviewed bool
+Parameters:
+- ctx: The request context
+- postId: The ID of the post
 
-		if err := rows.Scan(&p.ID, &p.UserID, &p.PostBody, &reviewed); err != nil {
-			return nil, fmt.Errorf("failed to scan posts: %v", err)
-		}
+Returns:
+- *db.Post: The post, or nil when there is no post with that ID
+- error: Any error that occurred during retrieval
 
-		p.Reviewed = reviewed
-		posts = append(posts, p)
+An ID that is not a valid UUID matches no post.
+*/
+func (repo PostsRepo) GetPost(ctx context.Context, postId string) (*db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.GetPost", "SELECT", "placement_log_posts").End()
+
+	query := `
+		SELECT` + postColumns + `
+		FROM placement_log_posts p` + postJoins + `
+		WHERE p.id = $1;
+	`
+
+	var post db.Post
+	err := scanPost(repo.db.QueryRowContext(ctx, query, postId), &post)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "22P02" {
+		return nil, nil
+	}
+
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
 
-	return posts, nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %v", err)
+	}
+
+	return &post, nil
 }
 
 /*
@@ -167,35 +237,43 @@ AddPost creates a new post in the database.
 The post is created with reviewed=false, requiring admin approval.
 
 Parameters:
+- ctx: The request context
 - userId: The ID of the user creating the post
 - postBody: The post content as JSON
+- visibility: How the author is shown ("named", "pseudonym" or "anonymous")
 
 Returns:
 - *db.Post: The created post
 - error: Any error that occurred during creation
 
 The function:
-1. Validates that user ID and post body are provided
+1. Validates that user ID, post body and visibility are provided
 2. Inserts the new post into the database with reviewed=false
-3. Returns the created post information
+3. Returns the created post information with its author name
 
 Possible errors:
 - "all fields are required": Missing user ID or post body
 - "failed to add post": Database insertion error
 */
-func (repo PostsRepo) AddPost(userId string, postBody json.RawMessage) (*db.Post, error) {
-	if userId == "" || postBody == nil {
+func (repo PostsRepo) AddPost(ctx context.Context, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.AddPost", "INSERT", "placement_log_posts").End()
+
+	if userId == "" || postBody == nil || visibility == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
 	query := `
-		INSERT INTO placement_log_posts (user_id, post_body, reviewed)
-		VALUES ($1, $2, false)
-		RETURNING id, user_id, post_body, reviewed;
+		WITH p AS (
+			INSERT INTO placement_log_posts (user_id, post_body, reviewed, author_visibility)
+			VALUES ($1, $2, false, $3)
+			RETURNING *
+		)
+		SELECT` + postColumns + `
+		FROM p` + postJoins + `;
 	`
 
 	var post db.Post
-	err := repo.db.QueryRow(query, userId, postBody).Scan(&post.ID, &post.UserID, &post.PostBody, &post.Reviewed)
+	err := scanPost(repo.db.QueryRowContext(ctx, query, userId, postBody, visibility), &post)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to add post: %v", err)
@@ -209,9 +287,11 @@ UpdatePost updates an existing post in the database.
 Users can only update their own posts. When updated, the post needs re-review.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to update
 - userId: The ID of the user updating the post
 - postBody: The updated post content as JSON
+- visibility: The new author visibility, or empty to keep the current one
 
 Returns:
 - *db.Post: The updated post
@@ -219,8 +299,8 @@ Returns:
 
 The function:
 1. Validates that post ID, user ID, and post body are provided
-2. Updates the post in the database (sets reviewed=false)
-3. Returns the updated post information
+2. Updates the post in the database (sets reviewed=false and clears a rejection)
+3. Returns the updated post information with its author name
 
 Possible errors:
 - "all fields are required": Missing required parameters
@@ -229,20 +309,27 @@ Possible errors:
 
 Note: When a post is updated, it needs to be reviewed again by an admin.
 */
-func (repo PostsRepo) UpdatePost(postId string, userId string, postBody json.RawMessage) (*db.Post, error) {
+func (repo PostsRepo) UpdatePost(ctx context.Context, postId string, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.UpdatePost", "UPDATE", "placement_log_posts").End()
+
 	if postId == "" || userId == "" || postBody == nil {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
 	query := `
-		UPDATE placement_log_posts 
-		SET post_body = $1, reviewed = false
-		WHERE id = $2 AND user_id = $3
-		RETURNING id, user_id, post_body, reviewed;
+		WITH p AS (
+			UPDATE placement_log_posts
+			SET post_body = $1, reviewed = false, rejected_at = NULL,
+				author_visibility = COALESCE(NULLIF($4, ''), author_visibility)
+			WHERE id = $2 AND user_id = $3
+			RETURNING *
+		)
+		SELECT` + postColumns + `
+		FROM p` + postJoins + `;
 	`
 
 	var post db.Post
-	err := repo.db.QueryRow(query, postBody, postId, userId).Scan(&post.ID, &post.UserID, &post.PostBody, &post.Reviewed)
+	err := scanPost(repo.db.QueryRowContext(ctx, query, postBody, postId, userId, visibility), &post)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("post not found or unauthorized")
@@ -260,6 +347,7 @@ DeletePost deletes a post from the database.
 Users can only delete their own posts.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to delete
 - userId: The ID of the user deleting the post
 
@@ -276,7 +364,9 @@ Possible errors:
 - "failed to delete post": Database deletion error
 - "no post found with given ID or unauthorized": Post doesn't exist or user doesn't own it
 */
-func (repo PostsRepo) DeletePost(postId string, userId string) error {
+func (repo PostsRepo) DeletePost(ctx context.Context, postId string, userId string) error {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.DeletePost", "DELETE", "placement_log_posts").End()
+
 	if postId == "" || userId == "" {
 		return fmt.Errorf("post ID and user ID are required")
 	}
@@ -286,7 +376,7 @@ func (repo PostsRepo) DeletePost(postId string, userId string) error {
 		WHERE id = $1 AND user_id = $2;
 	`
 
-	result, err := repo.db.Exec(query, postId, userId)
+	result, err := repo.db.ExecContext(ctx, query, postId, userId)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %v", err)
@@ -309,6 +399,7 @@ DeletePostAsAdmin deletes any post from the database (admin operation).
 Admins can delete any post, regardless of ownership.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to delete
 
 Returns:
@@ -324,7 +415,9 @@ Possible errors:
 - "failed to delete post": Database deletion error
 - "no post found with given ID": Post doesn't exist
 */
-func (repo PostsRepo) DeletePostAsAdmin(postId string) error {
+func (repo PostsRepo) DeletePostAsAdmin(ctx context.Context, postId string) error {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.DeletePostAsAdmin", "DELETE", "placement_log_posts").End()
+
 	if postId == "" {
 		return fmt.Errorf("post ID is required")
 	}
@@ -334,7 +427,7 @@ func (repo PostsRepo) DeletePostAsAdmin(postId string) error {
 		WHERE id = $1;
 	`
 
-	result, err := repo.db.Exec(query, postId)
+	result, err := repo.db.ExecContext(ctx, query, postId)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %v", err)
@@ -357,6 +450,7 @@ ReviewPost reviews a post (admin operation).
 Admins can approve or reject posts by updating the reviewed status.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to review
 - action: The review action ("approve" or "reject")
 
@@ -375,9 +469,12 @@ Possible errors:
 - "no post found with given ID": Post doesn't exist
 
 Note: When a post is approved (reviewed=true), it becomes visible to the public.
-When a post is rejected (reviewed=false), it remains hidden from public view.
+When a post is rejected (reviewed=false), it remains hidden from public view and
+rejected_at records the rejection, so its review_status is "rejected".
 */
-func (repo PostsRepo) ReviewPost(postId string, action string) error {
+func (repo PostsRepo) ReviewPost(ctx context.Context, postId string, action string) error {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.ReviewPost", "UPDATE", "placement_log_posts").End()
+
 	if postId == "" || action == "" {
 		return fmt.Errorf("post ID and action are required")
 	}
@@ -393,12 +490,12 @@ func (repo PostsRepo) ReviewPost(postId string, action string) error {
 	}
 
 	query := `
-		UPDATE placement_log_posts 
-		SET reviewed = $1
+		UPDATE placement_log_posts
+		SET reviewed = $1, rejected_at = CASE WHEN $1 THEN NULL ELSE CURRENT_TIMESTAMP END
 		WHERE id = $2;
 	`
 
-	result, err := repo.db.Exec(query, reviewed, postId)
+	result, err := repo.db.ExecContext(ctx, query, reviewed, postId)
 
 	if err != nil {
 		return fmt.Errorf("failed to review post: %v", err)
@@ -416,5 +513,50 @@ func (repo PostsRepo) ReviewPost(postId string, action string) error {
 	return nil
 }
 
+/*
+SetPseudonym assigns a pseudonym to a user unless they already have one.
+
+Parameters:
+- ctx: The request context
+- userId: The ID of the user
+- candidate: The pseudonym to assign when the user has none yet
+
+Returns:
+- string: The user's pseudonym, which is the existing one if it was already set
+- error: Any error that occurred
+
+Possible errors:
+- errPseudonymTaken: The candidate belongs to another user
+- "user not found": No such user
+- "failed to set pseudonym": Database update error
+*/
+func (repo PostsRepo) SetPseudonym(ctx context.Context, userId, candidate string) (string, error) {
+	defer telemetry.TraceQuery(ctx, "PostsRepo.SetPseudonym", "UPDATE", "placement_log_users").End()
+
+	query := `
+		UPDATE placement_log_users
+		SET pseudonym = COALESCE(pseudonym, $2)
+		WHERE id = $1
+		RETURNING pseudonym;
+	`
+
+	var pseudonym string
+	err := repo.db.QueryRowContext(ctx, query, userId, candidate).Scan(&pseudonym)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return "", errPseudonymTaken
+	}
+
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not found")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to set pseudonym: %v", err)
+	}
+
+	return pseudonym, nil
+}
+
 // Ensure PostsRepo implements PostsRepository
 var _ PostsRepository = (*PostsRepo)(nil)
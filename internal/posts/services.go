@@ -1,46 +1,177 @@
 package posts
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
 )
 
 // Define PostsRepository interface for testability
 //go:generate mockgen -destination=mock_posts_repo.go -package=posts . PostsRepository
 
 type PostsRepository interface {
-	AddPost(userId string, postBody json.RawMessage) (*db.Post, error)
-	UpdatePost(postId, userId string, postBody json.RawMessage) (*db.Post, error)
-	DeletePost(postId, userId string) error
-	DeletePostAsAdmin(postId string) error
-	GetAllPosts() ([]db.Post, error)
-	GetAllPostsForAdmin() ([]db.Post, error)
-	GetPostsByUserId(userId string) ([]db.Post, error)
-	ReviewPost(postId, action string) error
+	AddPost(ctx context.Context, userId string, postBody json.RawMessage, visibility string) (*db.Post, error)
+	UpdatePost(ctx context.Context, postId, userId string, postBody json.RawMessage, visibility string) (*db.Post, error)
+	DeletePost(ctx context.Context, postId, userId string) error
+	DeletePostAsAdmin(ctx context.Context, postId string) error
+	GetAllPosts(ctx context.Context) ([]db.Post, error)
+	GetAllPostsForAdmin(ctx context.Context) ([]db.Post, error)
+	GetPost(ctx context.Context, postId string) (*db.Post, error)
+	GetPostsByUserId(ctx context.Context, userId string) ([]db.Post, error)
+	ReviewPost(ctx context.Context, postId, action string) error
+	SetPseudonym(ctx context.Context, userId, candidate string) (string, error)
 }
 
+var (
+	// ErrPostNotFound is returned for unknown posts and for unapproved posts of other users.
+	ErrPostNotFound = errors.New("post not found")
+	// errPseudonymTaken is returned by SetPseudonym when another user already has the candidate.
+	errPseudonymTaken = errors.New("pseudonym already taken")
+)
+
+// maxPseudonymAttempts bounds the random pseudonyms tried before giving up.
+const maxPseudonymAttempts = 5
+
+// Words random pseudonyms are made of, e.g. "QuietOtter42".
+var (
+	pseudonymAdjectives = []string{
+		"Quiet", "Brave", "Curious", "Swift", "Calm", "Clever", "Bold", "Gentle",
+		"Lucky", "Patient", "Steady", "Bright", "Humble", "Eager", "Witty", "Keen",
+	}
+	pseudonymAnimals = []string{
+		"Otter", "Falcon", "Panda", "Heron", "Lynx", "Koala", "Badger", "Dolphin",
+		"Fox", "Owl", "Tiger", "Raven", "Gecko", "Bison", "Crane", "Marten",
+	}
+)
+
 /*
 PostsService handles post-related business logic.
 Provides methods for creating, reading, updating, and deleting posts.
 Includes both user and admin-specific operations.
 */
 type PostsService struct {
-	repo PostsRepository
+	repo  PostsRepository
+	cache *cache.Cache
 }
 
+// approvedPostsCacheKey identifies the cached GET /posts response.
+const approvedPostsCacheKey = "posts:approved"
+
 /*
 NewPostsService creates a new PostsService instance with the provided repository.
 
 Parameters:
 - repo: The posts repository
+- cache: The response cache invalidated when the set of approved posts changes (nil disables caching)
 
 Returns:
 - *PostsService: A new service instance
 */
-func NewPostsService(repo PostsRepository) *PostsService {
-	return &PostsService{repo: repo}
+func NewPostsService(repo PostsRepository, cache *cache.Cache) *PostsService {
+	return &PostsService{repo: repo, cache: cache}
+}
+
+/*
+InvalidateApproved drops the cached list of approved posts. Other services
+call it when something shown in the list changes outside of this package,
+such as an author's display name.
+*/
+func (s *PostsService) InvalidateApproved() {
+	s.cache.Invalidate(approvedPostsCacheKey)
+}
+
+/*
+validateVisibility checks the author visibility requested for a post.
+Empty is accepted and left to the caller: new posts default to named,
+updates keep the current visibility.
+*/
+func validateVisibility(visibility string) error {
+	switch visibility {
+	case "", db.VisibilityNamed, db.VisibilityPseudonym, db.VisibilityAnonymous:
+		return nil
+	default:
+		return fmt.Errorf("author_visibility must be one of named, pseudonym or anonymous")
+	}
+}
+
+func randomWord(words []string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+	if err != nil {
+		return "", err
+	}
+	return words[n.Int64()], nil
+}
+
+/*
+randomPseudonym returns a random pseudonym such as "QuietOtter42". It is
+drawn from crypto/rand so that it cannot be derived from the user.
+*/
+func randomPseudonym() (string, error) {
+	adjective, err := randomWord(pseudonymAdjectives)
+	if err != nil {
+		return "", err
+	}
+
+	animal, err := randomWord(pseudonymAnimals)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(90))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%s%d", adjective, animal, n.Int64()+10), nil
+}
+
+/*
+ensurePseudonym makes sure a user has a pseudonym before they post under it.
+A user keeps the same pseudonym for all their posts; it is assigned at
+random the first time, retrying when the name is already taken.
+
+Parameters:
+- ctx: The request context
+- userId: The ID of the user
+
+Returns:
+- error: Any error that occurred
+*/
+func (s *PostsService) ensurePseudonym(ctx context.Context, userId string) error {
+	for range maxPseudonymAttempts {
+		candidate, err := randomPseudonym()
+		if err != nil {
+			return fmt.Errorf("failed to generate pseudonym: %v", err)
+		}
+
+		_, err = s.repo.SetPseudonym(ctx, userId, candidate)
+		if errors.Is(err, errPseudonymTaken) {
+			continue
+		}
+		return err
+	}
+
+	return fmt.Errorf("could not assign a pseudonym, please try again")
+}
+
+/*
+publicPost hides the author of a post that is not named, including their
+branch and batch. Admins and the author themselves see the post unchanged.
+*/
+func publicPost(p db.Post) db.Post {
+	if p.AuthorVisibility != db.VisibilityNamed {
+		p.UserID = ""
+		p.AuthorBranch = ""
+		p.AuthorBatch = ""
+	}
+	return p
 }
 
 /*
@@ -48,31 +179,55 @@ AddPost creates a new post for a user.
 The post is created with reviewed=false, requiring admin approval.
 
 Parameters:
+- ctx: The request context
 - userId: The ID of the user creating the post
 - postBody: The post content as a map
+- visibility: How the author is shown publicly: "named" (default), "pseudonym" or "anonymous"
 
 Returns:
 - *db.Post: The created post
 - error: Any error that occurred during creation
 
 The function:
-1. Validates that user ID is provided
-2. Marshals the post body to JSON
-3. Creates the post in the database with reviewed=false
-4. Returns the created post information
+1. Validates that user ID and visibility are provided
+2. Assigns the user a pseudonym when posting under one for the first time
+3. Marshals the post body to JSON
+4. Creates the post in the database with reviewed=false
+5. Returns the created post information
 */
-func (s *PostsService) AddPost(userId string, postBody map[string]any) (*db.Post, error) {
+func (s *PostsService) AddPost(ctx context.Context, userId string, postBody map[string]any, visibility string) (*db.Post, error) {
 	if userId == "" {
 		return nil, fmt.Errorf("user ID is required")
 	}
 
+	if err := validateVisibility(visibility); err != nil {
+		return nil, err
+	}
+
+	if visibility == "" {
+		visibility = db.VisibilityNamed
+	}
+
 	bytes, err := json.Marshal(postBody)
 
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling post bytes: %v", err)
 	}
 
-	return s.repo.AddPost(userId, json.RawMessage(bytes))
+	if visibility == db.VisibilityPseudonym {
+		if err = s.ensurePseudonym(ctx, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	post, err := s.repo.AddPost(ctx, userId, json.RawMessage(bytes), visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry.PostsSubmitted.Inc()
+
+	return post, nil
 }
 
 /*
@@ -80,9 +235,11 @@ UpdatePost updates an existing post.
 Users can only update their own posts.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to update
 - userId: The ID of the user updating the post
 - postBody: The updated post content as a map
+- visibility: The new author visibility, or empty to keep the current one
 
 Returns:
 - *db.Post: The updated post
@@ -90,23 +247,42 @@ Returns:
 
 The function:
 1. Validates that post ID and user ID are provided
-2. Marshals the post body to JSON
-3. Updates the post in the database (sets reviewed=false)
-4. Returns the updated post information
+2. Assigns the user a pseudonym when switching to one for the first time
+3. Marshals the post body to JSON
+4. Updates the post in the database (sets reviewed=false)
+5. Returns the updated post information
 
 Note: When a post is updated, it needs to be reviewed again by an admin.
 */
-func (s *PostsService) UpdatePost(postId string, userId string, postBody map[string]any) (*db.Post, error) {
+func (s *PostsService) UpdatePost(ctx context.Context, postId string, userId string, postBody map[string]any, visibility string) (*db.Post, error) {
 	if postId == "" || userId == "" {
 		return nil, fmt.Errorf("post ID and user ID are required")
 	}
 
+	if err := validateVisibility(visibility); err != nil {
+		return nil, err
+	}
+
 	bytes, err := json.Marshal(postBody)
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling post bytes: %v", err)
 	}
 
-	return s.repo.UpdatePost(postId, userId, json.RawMessage(bytes))
+	if visibility == db.VisibilityPseudonym {
+		if err = s.ensurePseudonym(ctx, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	post, err := s.repo.UpdatePost(ctx, postId, userId, json.RawMessage(bytes), visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	// An updated post goes back to review, so it may have left the public list.
+	s.cache.Invalidate(approvedPostsCacheKey)
+
+	return post, nil
 }
 
 /*
@@ -114,6 +290,7 @@ DeletePost deletes a post.
 Users can only delete their own posts.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to delete
 - userId: The ID of the user deleting the post
 
@@ -125,11 +302,18 @@ The function:
 2. Deletes the post from the database (only if owned by the user)
 3. Returns any error that occurred
 */
-func (s *PostsService) DeletePost(postId string, userId string) error {
+func (s *PostsService) DeletePost(ctx context.Context, postId string, userId string) error {
 	if postId == "" || userId == "" {
 		return fmt.Errorf("post ID and user ID are required")
 	}
-	return s.repo.DeletePost(postId, userId)
+
+	if err := s.repo.DeletePost(ctx, postId, userId); err != nil {
+		return err
+	}
+
+	s.cache.Invalidate(approvedPostsCacheKey)
+
+	return nil
 }
 
 /*
@@ -137,6 +321,7 @@ DeletePostAsAdmin deletes any post (admin operation).
 Admins can delete any post, regardless of ownership.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to delete
 
 Returns:
@@ -147,11 +332,18 @@ The function:
 2. Deletes the post from the database
 3. Returns any error that occurred
 */
-func (s *PostsService) DeletePostAsAdmin(postId string) error {
+func (s *PostsService) DeletePostAsAdmin(ctx context.Context, postId string) error {
 	if postId == "" {
 		return fmt.Errorf("post ID is required")
 	}
-	return s.repo.DeletePostAsAdmin(postId)
+
+	if err := s.repo.DeletePostAsAdmin(ctx, postId); err != nil {
+		return err
+	}
+
+	s.cache.Invalidate(approvedPostsCacheKey)
+
+	return nil
 }
 
 /*
@@ -163,14 +355,75 @@ Returns:
 - error: Any error that occurred during retrieval
 
 The function retrieves only posts that have been reviewed and approved by admins.
+The user ID of pseudonymous and anonymous posts is removed, so only the
+author name chosen for the post is public.
 */
-func (s *PostsService) GetAll() ([]db.Post, error) {
-	return s.repo.GetAllPosts()
+func (s *PostsService) GetAll(ctx context.Context) ([]db.Post, error) {
+	posts, err := s.repo.GetAllPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		posts[i] = publicPost(posts[i])
+	}
+
+	return posts, nil
+}
+
+/*
+GetPost retrieves a single post as seen by the caller.
+
+Parameters:
+- ctx: The request context
+- postId: The ID of the post
+- viewerId: The ID of the calling user, or empty for anyone else
+- moderator: Whether the caller may moderate posts
+
+Returns:
+- *db.Post: The post
+- error: Any error that occurred during retrieval
+
+The function:
+1. Retrieves the post, whatever its review status
+2. Returns it unchanged to moderators and to its author
+3. Returns approved posts to everyone else with the author hidden as in GetAll
+
+Possible errors:
+- ErrPostNotFound: No such post, or a pending or rejected post that the caller may not see
+*/
+func (s *PostsService) GetPost(ctx context.Context, postId, viewerId string, moderator bool) (*db.Post, error) {
+	if postId == "" {
+		return nil, fmt.Errorf("post ID is required")
+	}
+
+	post, err := s.repo.GetPost(ctx, postId)
+	if err != nil {
+		return nil, err
+	}
+
+	if post == nil {
+		return nil, ErrPostNotFound
+	}
+
+	if moderator || (viewerId != "" && viewerId == post.UserID) {
+		return post, nil
+	}
+
+	// Pending and rejected posts of other users are reported missing rather than forbidden,
+	// so that their existence does not leak.
+	if !post.Reviewed {
+		return nil, ErrPostNotFound
+	}
+
+	public := publicPost(*post)
+	return &public, nil
 }
 
 /*
 GetAllPostsForAdmin retrieves all posts for admin review.
-Admins can see both approved and pending posts.
+Admins can see both approved and pending posts, with the real author of
+pseudonymous and anonymous posts for moderation.
 
 Returns:
 - []db.Post: List of all posts (approved and pending)
@@ -178,8 +431,8 @@ Returns:
 
 The function retrieves all posts regardless of their review status.
 */
-func (s *PostsService) GetAllPostsForAdmin() ([]db.Post, error) {
-	return s.repo.GetAllPostsForAdmin()
+func (s *PostsService) GetAllPostsForAdmin(ctx context.Context) ([]db.Post, error) {
+	return s.repo.GetAllPostsForAdmin(ctx)
 }
 
 /*
@@ -187,6 +440,7 @@ GetByUser retrieves posts by a specific user.
 Only returns approved posts for the specified user.
 
 Parameters:
+- ctx: The request context
 - userId: The ID of the user whose posts to retrieve
 
 Returns:
@@ -195,8 +449,8 @@ Returns:
 
 The function retrieves only posts that belong to the specified user and have been approved.
 */
-func (s *PostsService) GetByUser(userId string) ([]db.Post, error) {
-	return s.repo.GetPostsByUserId(userId)
+func (s *PostsService) GetByUser(ctx context.Context, userId string) ([]db.Post, error) {
+	return s.repo.GetPostsByUserId(ctx, userId)
 }
 
 /*
@@ -204,6 +458,7 @@ ReviewPost reviews a post (admin operation).
 Admins can approve or reject posts.
 
 Parameters:
+- ctx: The request context
 - postId: The ID of the post to review
 - action: The review action ("approve" or "reject")
 
@@ -218,9 +473,24 @@ The function:
 Note: When a post is approved, it becomes visible to the public.
 When a post is rejected, it remains hidden from public view.
 */
-func (s *PostsService) ReviewPost(postId string, action string) error {
+func (s *PostsService) ReviewPost(ctx context.Context, postId string, action string) error {
 	if postId == "" || action == "" {
 		return fmt.Errorf("post ID and action are required")
 	}
-	return s.repo.ReviewPost(postId, action)
+
+	if err := s.repo.ReviewPost(ctx, postId, action); err != nil {
+		return err
+	}
+
+	// Approving publishes the post and rejecting may unpublish it.
+	s.cache.Invalidate(approvedPostsCacheKey)
+
+	switch action {
+	case "approve":
+		telemetry.PostsApproved.Inc()
+	case "reject":
+		telemetry.PostsRejected.Inc()
+	}
+
+	return nil
 }
@@ -1,78 +1,92 @@
 package posts
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/cache"
 )
 
 type mockPostsRepo struct {
-	AddPostFunc             func(userId string, postBody json.RawMessage) (*db.Post, error)
-	UpdatePostFunc          func(postId, userId string, postBody json.RawMessage) (*db.Post, error)
+	AddPostFunc             func(userId string, postBody json.RawMessage, visibility string) (*db.Post, error)
+	UpdatePostFunc          func(postId, userId string, postBody json.RawMessage, visibility string) (*db.Post, error)
 	DeletePostFunc          func(postId, userId string) error
 	DeletePostAsAdminFunc   func(postId string) error
 	GetAllPostsFunc         func() ([]db.Post, error)
 	GetAllPostsForAdminFunc func() ([]db.Post, error)
+	GetPostFunc             func(postId string) (*db.Post, error)
 	GetPostsByUserIdFunc    func(userId string) ([]db.Post, error)
 	ReviewPostFunc          func(postId, action string) error
+	SetPseudonymFunc        func(userId, candidate string) (string, error)
 }
 
-func (m *mockPostsRepo) AddPost(userId string, postBody json.RawMessage) (*db.Post, error) {
-	return m.AddPostFunc(userId, postBody)
+func (m *mockPostsRepo) AddPost(ctx context.Context, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+	return m.AddPostFunc(userId, postBody, visibility)
 }
-func (m *mockPostsRepo) UpdatePost(postId, userId string, postBody json.RawMessage) (*db.Post, error) {
-	return m.UpdatePostFunc(postId, userId, postBody)
+func (m *mockPostsRepo) UpdatePost(ctx context.Context, postId, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+	return m.UpdatePostFunc(postId, userId, postBody, visibility)
 }
-func (m *mockPostsRepo) DeletePost(postId, userId string) error {
+func (m *mockPostsRepo) DeletePost(ctx context.Context, postId, userId string) error {
 	return m.DeletePostFunc(postId, userId)
 }
-func (m *mockPostsRepo) DeletePostAsAdmin(postId string) error {
+func (m *mockPostsRepo) DeletePostAsAdmin(ctx context.Context, postId string) error {
 	return m.DeletePostAsAdminFunc(postId)
 }
-func (m *mockPostsRepo) GetAllPosts() ([]db.Post, error) {
+func (m *mockPostsRepo) GetAllPosts(ctx context.Context) ([]db.Post, error) {
 	return m.GetAllPostsFunc()
 }
-func (m *mockPostsRepo) GetAllPostsForAdmin() ([]db.Post, error) {
+func (m *mockPostsRepo) GetAllPostsForAdmin(ctx context.Context) ([]db.Post, error) {
 	return m.GetAllPostsForAdminFunc()
 }
-func (m *mockPostsRepo) GetPostsByUserId(userId string) ([]db.Post, error) {
+func (m *mockPostsRepo) GetPost(ctx context.Context, postId string) (*db.Post, error) {
+	return m.GetPostFunc(postId)
+}
+func (m *mockPostsRepo) GetPostsByUserId(ctx context.Context, userId string) ([]db.Post, error) {
 	return m.GetPostsByUserIdFunc(userId)
 }
-func (m *mockPostsRepo) ReviewPost(postId, action string) error {
+func (m *mockPostsRepo) ReviewPost(ctx context.Context, postId, action string) error {
 	return m.ReviewPostFunc(postId, action)
 }
+func (m *mockPostsRepo) SetPseudonym(ctx context.Context, userId, candidate string) (string, error) {
+	return m.SetPseudonymFunc(userId, candidate)
+}
 
 func TestPostsService_AddPost(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockPostsRepo{
-			AddPostFunc: func(userId string, postBody json.RawMessage) (*db.Post, error) {
-				return &db.Post{ID: "1", UserID: userId, PostBody: postBody, Reviewed: false}, nil
+			AddPostFunc: func(userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+				return &db.Post{ID: "1", UserID: userId, PostBody: postBody, Reviewed: false, AuthorVisibility: visibility}, nil
 			},
 		}
-		s := NewPostsService(repo)
+		s := NewPostsService(repo, nil)
 		postBody := map[string]any{"company": "TestCo", "role": "Engineer"}
-		post, err := s.AddPost("user1", postBody)
+		post, err := s.AddPost(context.Background(), "user1", postBody, "")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
 		if post.UserID != "user1" {
 			t.Errorf("expected user1, got %s", post.UserID)
 		}
+		if post.AuthorVisibility != db.VisibilityNamed {
+			t.Errorf("expected posts to be named by default, got %q", post.AuthorVisibility)
+		}
 	})
 	t.Run("missing userId", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		_, err := s.AddPost("", map[string]any{"company": "TestCo"})
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		_, err := s.AddPost(context.Background(), "", map[string]any{"company": "TestCo"}, "")
 		if err == nil || err.Error() != "user ID is required" {
 			t.Errorf("expected user ID is required error, got %v", err)
 		}
 	})
 	t.Run("marshal error", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		_, err := s.AddPost("user1", map[string]any{"bad": func() {}})
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		_, err := s.AddPost(context.Background(), "user1", map[string]any{"bad": func() {}}, "")
 		if err == nil || !strings.Contains(err.Error(), "error marshalling post bytes") {
 			t.Errorf("expected marshalling error, got %v", err)
 		}
@@ -82,13 +96,13 @@ func TestPostsService_AddPost(t *testing.T) {
 func TestPostsService_UpdatePost(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockPostsRepo{
-			UpdatePostFunc: func(postId, userId string, postBody json.RawMessage) (*db.Post, error) {
+			UpdatePostFunc: func(postId, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
 				return &db.Post{ID: postId, UserID: userId, PostBody: postBody, Reviewed: false}, nil
 			},
 		}
-		s := NewPostsService(repo)
+		s := NewPostsService(repo, nil)
 		postBody := map[string]any{"company": "TestCo"}
-		post, err := s.UpdatePost("p1", "u1", postBody)
+		post, err := s.UpdatePost(context.Background(), "p1", "u1", postBody, "")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -97,19 +111,19 @@ func TestPostsService_UpdatePost(t *testing.T) {
 		}
 	})
 	t.Run("missing postId or userId", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		_, err := s.UpdatePost("", "u1", map[string]any{})
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		_, err := s.UpdatePost(context.Background(), "", "u1", map[string]any{}, "")
 		if err == nil || err.Error() != "post ID and user ID are required" {
 			t.Errorf("expected post ID and user ID are required error, got %v", err)
 		}
-		_, err = s.UpdatePost("p1", "", map[string]any{})
+		_, err = s.UpdatePost(context.Background(), "p1", "", map[string]any{}, "")
 		if err == nil || err.Error() != "post ID and user ID are required" {
 			t.Errorf("expected post ID and user ID are required error, got %v", err)
 		}
 	})
 	t.Run("marshal error", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		_, err := s.UpdatePost("p1", "u1", map[string]any{"bad": func() {}})
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		_, err := s.UpdatePost(context.Background(), "p1", "u1", map[string]any{"bad": func() {}}, "")
 		if err == nil || !strings.Contains(err.Error(), "error marshalling post bytes") {
 			t.Errorf("expected marshalling error, got %v", err)
 		}
@@ -121,19 +135,19 @@ func TestPostsService_DeletePost(t *testing.T) {
 		repo := &mockPostsRepo{
 			DeletePostFunc: func(postId, userId string) error { return nil },
 		}
-		s := NewPostsService(repo)
-		err := s.DeletePost("p1", "u1")
+		s := NewPostsService(repo, nil)
+		err := s.DeletePost(context.Background(), "p1", "u1")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
 	})
 	t.Run("missing postId or userId", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		err := s.DeletePost("", "u1")
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		err := s.DeletePost(context.Background(), "", "u1")
 		if err == nil || err.Error() != "post ID and user ID are required" {
 			t.Errorf("expected post ID and user ID are required error, got %v", err)
 		}
-		err = s.DeletePost("p1", "")
+		err = s.DeletePost(context.Background(), "p1", "")
 		if err == nil || err.Error() != "post ID and user ID are required" {
 			t.Errorf("expected post ID and user ID are required error, got %v", err)
 		}
@@ -143,8 +157,8 @@ func TestPostsService_DeletePost(t *testing.T) {
 		repo := &mockPostsRepo{
 			DeletePostFunc: func(postId, userId string) error { return errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		err := s.DeletePost("p1", "u1")
+		s := NewPostsService(repo, nil)
+		err := s.DeletePost(context.Background(), "p1", "u1")
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -156,15 +170,15 @@ func TestPostsService_DeletePostAsAdmin(t *testing.T) {
 		repo := &mockPostsRepo{
 			DeletePostAsAdminFunc: func(postId string) error { return nil },
 		}
-		s := NewPostsService(repo)
-		err := s.DeletePostAsAdmin("p1")
+		s := NewPostsService(repo, nil)
+		err := s.DeletePostAsAdmin(context.Background(), "p1")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
 	})
 	t.Run("missing postId", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		err := s.DeletePostAsAdmin("")
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		err := s.DeletePostAsAdmin(context.Background(), "")
 		if err == nil || err.Error() != "post ID is required" {
 			t.Errorf("expected post ID is required error, got %v", err)
 		}
@@ -174,8 +188,8 @@ func TestPostsService_DeletePostAsAdmin(t *testing.T) {
 		repo := &mockPostsRepo{
 			DeletePostAsAdminFunc: func(postId string) error { return errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		err := s.DeletePostAsAdmin("p1")
+		s := NewPostsService(repo, nil)
+		err := s.DeletePostAsAdmin(context.Background(), "p1")
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -188,8 +202,8 @@ func TestPostsService_GetAll(t *testing.T) {
 		repo := &mockPostsRepo{
 			GetAllPostsFunc: func() ([]db.Post, error) { return posts, nil },
 		}
-		s := NewPostsService(repo)
-		got, err := s.GetAll()
+		s := NewPostsService(repo, nil)
+		got, err := s.GetAll(context.Background())
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -202,22 +216,125 @@ func TestPostsService_GetAll(t *testing.T) {
 		repo := &mockPostsRepo{
 			GetAllPostsFunc: func() ([]db.Post, error) { return nil, errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		_, err := s.GetAll()
+		s := NewPostsService(repo, nil)
+		_, err := s.GetAll(context.Background())
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
 	})
 }
 
+func TestPostsService_AddPost_Visibility(t *testing.T) {
+	t.Run("invalid visibility", func(t *testing.T) {
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		_, err := s.AddPost(context.Background(), "user1", map[string]any{}, "hidden")
+		if err == nil || !strings.Contains(err.Error(), "author_visibility") {
+			t.Errorf("expected an invalid visibility error, got %v", err)
+		}
+	})
+	t.Run("pseudonym assigned with retries", func(t *testing.T) {
+		var candidates []string
+		repo := &mockPostsRepo{
+			SetPseudonymFunc: func(userId, candidate string) (string, error) {
+				candidates = append(candidates, candidate)
+				if len(candidates) < 3 {
+					return "", errPseudonymTaken
+				}
+				return candidate, nil
+			},
+			AddPostFunc: func(userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+				if len(candidates) != 3 {
+					t.Errorf("expected the pseudonym to be assigned before posting, got %d attempts", len(candidates))
+				}
+				return &db.Post{ID: "1", UserID: userId, AuthorVisibility: visibility, Author: candidates[len(candidates)-1]}, nil
+			},
+		}
+		s := NewPostsService(repo, nil)
+		post, err := s.AddPost(context.Background(), "user1", map[string]any{}, db.VisibilityPseudonym)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if post.Author == "" || post.AuthorVisibility != db.VisibilityPseudonym {
+			t.Errorf("unexpected post: %+v", post)
+		}
+	})
+	t.Run("pseudonyms exhausted", func(t *testing.T) {
+		attempts := 0
+		repo := &mockPostsRepo{
+			SetPseudonymFunc: func(userId, candidate string) (string, error) {
+				attempts++
+				return "", errPseudonymTaken
+			},
+		}
+		s := NewPostsService(repo, nil)
+		if _, err := s.AddPost(context.Background(), "user1", map[string]any{}, db.VisibilityPseudonym); err == nil || attempts != maxPseudonymAttempts {
+			t.Errorf("expected failure after %d attempts, got %v after %d", maxPseudonymAttempts, err, attempts)
+		}
+	})
+}
+
+func TestPostsService_UpdatePost_KeepsVisibility(t *testing.T) {
+	var got string
+	repo := &mockPostsRepo{
+		UpdatePostFunc: func(postId, userId string, postBody json.RawMessage, visibility string) (*db.Post, error) {
+			got = visibility
+			return &db.Post{ID: postId}, nil
+		},
+	}
+	s := NewPostsService(repo, nil)
+	if _, err := s.UpdatePost(context.Background(), "p1", "u1", map[string]any{}, ""); err != nil || got != "" {
+		t.Errorf("expected the visibility to be left unchanged, got %v %q", err, got)
+	}
+	if _, err := s.UpdatePost(context.Background(), "p1", "u1", map[string]any{}, db.VisibilityAnonymous); err != nil || got != db.VisibilityAnonymous {
+		t.Errorf("expected anonymous, got %v %q", err, got)
+	}
+}
+
+func TestPostsService_GetAll_HidesAuthors(t *testing.T) {
+	repo := &mockPostsRepo{
+		GetAllPostsFunc: func() ([]db.Post, error) {
+			return []db.Post{
+				{ID: "1", UserID: "u1", AuthorVisibility: db.VisibilityNamed, Author: "Jane", AuthorBranch: "BCE"},
+				{ID: "2", UserID: "u1", AuthorVisibility: db.VisibilityPseudonym, Author: "QuietOtter42", AuthorBranch: "BCE"},
+				{ID: "3", UserID: "u1", AuthorVisibility: db.VisibilityAnonymous},
+			}, nil
+		},
+	}
+	got, err := NewPostsService(repo, nil).GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got[0].UserID != "u1" || got[1].UserID != "" || got[2].UserID != "" {
+		t.Errorf("expected user IDs only on named posts, got %+v", got)
+	}
+	if got[0].AuthorBranch != "BCE" || got[1].AuthorBranch != "" {
+		t.Errorf("expected the branch only on named posts, got %+v", got)
+	}
+	if got[1].Author != "QuietOtter42" {
+		t.Errorf("expected the pseudonym to be kept, got %q", got[1].Author)
+	}
+
+	body, _ := json.Marshal(got[2])
+	if strings.Contains(string(body), "user_id") {
+		t.Errorf("expected no user_id in anonymous posts, got %s", body)
+	}
+}
+
+func TestRandomPseudonym(t *testing.T) {
+	name, err := randomPseudonym()
+	if err != nil || len(name) < 5 || len(name) > 50 {
+		t.Errorf("unexpected pseudonym %q: %v", name, err)
+	}
+}
+
 func TestPostsService_GetAllPostsForAdmin(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		posts := []db.Post{{ID: "1"}, {ID: "2"}}
 		repo := &mockPostsRepo{
 			GetAllPostsForAdminFunc: func() ([]db.Post, error) { return posts, nil },
 		}
-		s := NewPostsService(repo)
-		got, err := s.GetAllPostsForAdmin()
+		s := NewPostsService(repo, nil)
+		got, err := s.GetAllPostsForAdmin(context.Background())
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -230,8 +347,68 @@ func TestPostsService_GetAllPostsForAdmin(t *testing.T) {
 		repo := &mockPostsRepo{
 			GetAllPostsForAdminFunc: func() ([]db.Post, error) { return nil, errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		_, err := s.GetAllPostsForAdmin()
+		s := NewPostsService(repo, nil)
+		_, err := s.GetAllPostsForAdmin(context.Background())
+		if err == nil || err.Error() != "db error" {
+			t.Errorf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestPostsService_GetPost(t *testing.T) {
+	approved := db.Post{ID: "1", UserID: "u1", Reviewed: true, AuthorVisibility: db.VisibilityAnonymous,
+		AuthorBranch: "BCE", AuthorBatch: "2022"}
+	pending := db.Post{ID: "2", UserID: "u1", AuthorVisibility: db.VisibilityNamed, Author: "Jane"}
+	repo := &mockPostsRepo{
+		GetPostFunc: func(postId string) (*db.Post, error) {
+			switch postId {
+			case approved.ID:
+				p := approved
+				return &p, nil
+			case pending.ID:
+				p := pending
+				return &p, nil
+			}
+			return nil, nil
+		},
+	}
+	s := NewPostsService(repo, nil)
+
+	tests := []struct {
+		name      string
+		postId    string
+		viewerId  string
+		moderator bool
+		want      *db.Post
+		wantErr   error
+	}{
+		{name: "approved post for anyone", postId: "1",
+			want: &db.Post{ID: "1", Reviewed: true, AuthorVisibility: db.VisibilityAnonymous}},
+		{name: "approved post for its author", postId: "1", viewerId: "u1", want: &approved},
+		{name: "approved post for a moderator", postId: "1", moderator: true, want: &approved},
+		{name: "pending post for anyone", postId: "2", wantErr: ErrPostNotFound},
+		{name: "pending post for another user", postId: "2", viewerId: "u2", wantErr: ErrPostNotFound},
+		{name: "pending post for its author", postId: "2", viewerId: "u1", want: &pending},
+		{name: "pending post for a moderator", postId: "2", moderator: true, want: &pending},
+		{name: "unknown post", postId: "3", moderator: true, wantErr: ErrPostNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetPost(context.Background(), tt.postId, tt.viewerId, tt.moderator)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("repo error", func(t *testing.T) {
+		repo := &mockPostsRepo{
+			GetPostFunc: func(postId string) (*db.Post, error) { return nil, errors.New("db error") },
+		}
+		_, err := NewPostsService(repo, nil).GetPost(context.Background(), "1", "", false)
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -244,8 +421,8 @@ func TestPostsService_GetByUser(t *testing.T) {
 		repo := &mockPostsRepo{
 			GetPostsByUserIdFunc: func(userId string) ([]db.Post, error) { return posts, nil },
 		}
-		s := NewPostsService(repo)
-		got, err := s.GetByUser("u1")
+		s := NewPostsService(repo, nil)
+		got, err := s.GetByUser(context.Background(), "u1")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -258,8 +435,8 @@ func TestPostsService_GetByUser(t *testing.T) {
 		repo := &mockPostsRepo{
 			GetPostsByUserIdFunc: func(userId string) ([]db.Post, error) { return nil, errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		_, err := s.GetByUser("u1")
+		s := NewPostsService(repo, nil)
+		_, err := s.GetByUser(context.Background(), "u1")
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
@@ -271,19 +448,33 @@ func TestPostsService_ReviewPost(t *testing.T) {
 		repo := &mockPostsRepo{
 			ReviewPostFunc: func(postId, action string) error { return nil },
 		}
-		s := NewPostsService(repo)
-		err := s.ReviewPost("p1", "approve")
+		s := NewPostsService(repo, nil)
+		err := s.ReviewPost(context.Background(), "p1", "approve")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
 	})
+	t.Run("invalidates public post cache", func(t *testing.T) {
+		store := cache.NewMemoryStore()
+		store.Set(approvedPostsCacheKey, cache.NewEntry([]byte("stale")), time.Minute)
+		repo := &mockPostsRepo{
+			ReviewPostFunc: func(postId, action string) error { return nil },
+		}
+		s := NewPostsService(repo, cache.New(store, time.Minute))
+		if err := s.ReviewPost(context.Background(), "p1", "approve"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, ok := store.Get(approvedPostsCacheKey); ok {
+			t.Error("expected approval to invalidate the cached post list")
+		}
+	})
 	t.Run("missing postId or action", func(t *testing.T) {
-		s := NewPostsService(&mockPostsRepo{})
-		err := s.ReviewPost("", "approve")
+		s := NewPostsService(&mockPostsRepo{}, nil)
+		err := s.ReviewPost(context.Background(), "", "approve")
 		if err == nil || err.Error() != "post ID and action are required" {
 			t.Errorf("expected post ID and action are required error, got %v", err)
 		}
-		err = s.ReviewPost("p1", "")
+		err = s.ReviewPost(context.Background(), "p1", "")
 		if err == nil || err.Error() != "post ID and action are required" {
 			t.Errorf("expected post ID and action are required error, got %v", err)
 		}
@@ -293,10 +484,43 @@ func TestPostsService_ReviewPost(t *testing.T) {
 		repo := &mockPostsRepo{
 			ReviewPostFunc: func(postId, action string) error { return errors.New("db error") },
 		}
-		s := NewPostsService(repo)
-		err := s.ReviewPost("p1", "approve")
+		s := NewPostsService(repo, nil)
+		err := s.ReviewPost(context.Background(), "p1", "approve")
 		if err == nil || err.Error() != "db error" {
 			t.Errorf("expected db error, got %v", err)
 		}
 	})
 }
+
+// fakeRow scans fixed values into the destinations of scanPost.
+type fakeRow []any
+
+func (r fakeRow) Scan(dest ...any) error {
+	for i, v := range r {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func TestScanPost_ReviewStatus(t *testing.T) {
+	cases := []struct {
+		reviewed, rejected bool
+		want               string
+	}{
+		{true, false, db.ReviewStatusApproved},
+		{false, false, db.ReviewStatusPending},
+		{false, true, db.ReviewStatusRejected},
+	}
+	for _, c := range cases {
+		row := fakeRow{"p1", "u1", json.RawMessage(`{}`), c.reviewed, c.rejected, db.VisibilityNamed, "Jane",
+			"", "", time.Time{}, time.Time{}}
+
+		var p db.Post
+		if err := scanPost(row, &p); err != nil {
+			t.Fatalf("scanPost: %v", err)
+		}
+		if p.ReviewStatus != c.want {
+			t.Errorf("reviewed=%v rejected=%v: expected %q, got %q", c.reviewed, c.rejected, c.want, p.ReviewStatus)
+		}
+	}
+}
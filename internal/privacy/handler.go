@@ -0,0 +1,176 @@
+package privacy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var errUnauthorized = errors.New("unauthorized: user authentication required")
+
+/*
+PrivacyHandler handles the personal data export and account deletion
+endpoints of the logged-in user.
+*/
+type PrivacyHandler struct {
+	srv *PrivacyService
+}
+
+/*
+NewPrivacyHandler creates a new PrivacyHandler instance with the provided service.
+
+Parameters:
+- srv: The privacy service
+
+Returns:
+- *PrivacyHandler: A new handler instance
+*/
+func NewPrivacyHandler(srv *PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{srv: srv}
+}
+
+type deletionRequest struct {
+	Password string `json:"password"`
+}
+
+/*
+Export downloads everything stored about the logged-in user as a ZIP archive
+of JSON files, with the avatar image if there is one.
+
+HTTP Method: GET
+Endpoint: /users/me/export
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Returns:
+- 200 OK: The archive (application/zip)
+- 401 Unauthorized: Missing or invalid user token
+- 404 Not Found: The account no longer exists
+*/
+func (h *PrivacyHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	// Build the archive first so that an error can still be reported as JSON.
+	var buf bytes.Buffer
+	err := h.srv.Export(r.Context(), userID, &buf)
+	if errors.Is(err, ErrUserNotFound) {
+		utils.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("placementlog-export-%s.zip", time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+/*
+RequestDeletion schedules the deletion of the logged-in user's account after
+the cooling-off period (ACCOUNT_DELETION_COOLING_OFF). Asking again keeps the
+original time.
+
+HTTP Method: POST
+Endpoint: /users/me/deletion
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Request Body:
+
+	{
+	  "password": "current password"
+	}
+
+Response (202 Accepted):
+
+	{
+	  "purge_after": "2026-01-15T00:00:00Z"
+	}
+
+Returns:
+- 202 Accepted: Deletion scheduled
+- 400 Bad Request: Missing password
+- 401 Unauthorized: Missing or invalid user token
+- 403 Forbidden: Wrong password
+- 429 Too Many Requests: Too many wrong passwords; see Retry-After
+*/
+func (h *PrivacyHandler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	var payload deletionRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	at, err := h.srv.RequestDeletion(r.Context(), userID, payload.Password, utils.ClientIP(r))
+	var blocked *loginguard.BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		w.Header().Set("Retry-After", blocked.RetryAfterHeader())
+		utils.WriteError(w, err, http.StatusTooManyRequests)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		utils.WriteError(w, errors.New("password is wrong"), http.StatusForbidden)
+	case errors.Is(err, ErrUserNotFound):
+		utils.WriteError(w, err, http.StatusNotFound)
+	case err != nil:
+		utils.WriteError(w, err)
+	default:
+		utils.WriteJSON(w, map[string]time.Time{"purge_after": at.UTC()}, http.StatusAccepted)
+	}
+}
+
+/*
+CancelDeletion cancels the scheduled deletion of the logged-in user's account.
+
+HTTP Method: DELETE
+Endpoint: /users/me/deletion
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Returns:
+- 200 OK: Deletion cancelled
+- 401 Unauthorized: Missing or invalid user token
+- 404 Not Found: No deletion is scheduled
+*/
+func (h *PrivacyHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	err := h.srv.CancelDeletion(r.Context(), userID)
+	if errors.Is(err, ErrNoDeletionScheduled) {
+		utils.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "account deletion cancelled"}, http.StatusOK)
+}
@@ -0,0 +1,333 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+exportSections are the queries behind the files of a data export. Each
+returns a single JSON document for the user ID in $1.
+*/
+var exportSections = []struct {
+	name  string
+	query string
+}{
+	{"profile", `
+		SELECT row_to_json(t) FROM (
+			SELECT id, regno, username, display_name, pseudonym, email, email_verified_at, bio,
+				linkedin_url, graduation_year, status, suspended_at, suspended_until, suspension_reason,
+				password_reset_required, created_at, updated_at, deletion_scheduled_at
+			FROM placement_log_users
+			WHERE id = $1
+		) t;`},
+	{"posts", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT id, post_body, reviewed, author_visibility, created_at, updated_at
+			FROM placement_log_posts
+			WHERE user_id = $1
+		) t;`},
+	{"roster", `
+		SELECT COALESCE(json_agg(t), '[]') FROM (
+			SELECT r.regno, r.name, r.branch, r.batch, r.email, r.imported_at
+			FROM placement_log_roster r
+			JOIN placement_log_users u ON u.regno = r.regno
+			WHERE u.id = $1
+		) t;`},
+	// Client IPs are left out: failed logins on an account may come from someone else.
+	// Password confirmations are recorded under "password:<id>" rather than the regno.
+	{"login_events", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT e.outcome, e.created_at
+			FROM placement_log_login_events e
+			JOIN placement_log_users u ON LOWER(e.account) IN (u.regno, 'password:' || u.id::text)
+			WHERE u.id = $1 AND e.account_type = 'user'
+		) t;`},
+	{"moderation_log", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT action, reason, expires_at, created_at
+			FROM placement_log_moderation_log
+			WHERE user_id = $1
+		) t;`},
+	{"two_factor", `
+		SELECT COALESCE(json_agg(t), '[]') FROM (
+			SELECT f.enabled_at, f.created_at,
+				(SELECT COUNT(*) FROM placement_log_recovery_codes c
+				 WHERE c.account_type = f.account_type AND c.account_id = f.account_id AND c.used_at IS NULL) AS unused_recovery_codes
+			FROM placement_log_two_factor f
+			WHERE f.account_type = 'user' AND f.account_id = $1
+		) t;`},
+	{"sso_identities", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT provider, subject, email, created_at, last_login_at
+			FROM placement_log_user_identities
+			WHERE user_id = $1
+		) t;`},
+	// Like the moderation log, admins are left out; the reason is included.
+	{"impersonations", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT i.reason, i.read_only, i.created_at, i.expires_at,
+				(SELECT COUNT(*) FROM placement_log_impersonation_requests q WHERE q.session_id = i.session_id) AS request_count
+			FROM placement_log_impersonations i
+			WHERE i.user_id = $1
+		) t;`},
+}
+
+/*
+PrivacyRepo handles data exports and the deletion of user accounts.
+*/
+type PrivacyRepo struct {
+	db *sql.DB
+}
+
+/*
+NewPrivacyRepo creates a new PrivacyRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *PrivacyRepo: A new repository instance
+*/
+func NewPrivacyRepo(db *sql.DB) *PrivacyRepo {
+	return &PrivacyRepo{db: db}
+}
+
+/*
+ExportData collects everything stored about a user, one JSON document per
+export section. Secrets such as password hashes, TOTP secrets and token
+hashes are left out.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- map[string]json.RawMessage: The documents by section name
+- error: ErrUserNotFound or a database error
+*/
+func (r *PrivacyRepo) ExportData(ctx context.Context, userID string) (map[string]json.RawMessage, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.ExportData", "SELECT", "placement_log_users").End()
+
+	data := make(map[string]json.RawMessage, len(exportSections))
+
+	for _, section := range exportSections {
+		var doc []byte
+		err := r.db.QueryRowContext(ctx, section.query, userID).Scan(&doc)
+
+		if section.name == "profile" && (err == sql.ErrNoRows || (err == nil && doc == nil)) {
+			return nil, ErrUserNotFound
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %v", section.name, err)
+		}
+
+		data[section.name] = doc
+	}
+
+	return data, nil
+}
+
+/*
+GetAvatar returns the user's avatar for the export.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.Avatar: The avatar, or nil when the user has none
+- error: Any database error
+*/
+func (r *PrivacyRepo) GetAvatar(ctx context.Context, userID string) (*db.Avatar, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.GetAvatar", "SELECT", "placement_log_avatars").End()
+
+	var a db.Avatar
+	err := r.db.QueryRowContext(ctx, `
+		SELECT content_type, data, updated_at FROM placement_log_avatars WHERE user_id = $1;
+	`, userID).Scan(&a.ContentType, &a.Data, &a.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &a, nil
+}
+
+/*
+ScheduleDeletion marks an account for deletion. A deletion that is already
+scheduled keeps its original time.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- at: When the account is to be purged
+
+Returns:
+- time.Time: The scheduled purge time
+- error: ErrUserNotFound or a database error
+*/
+func (r *PrivacyRepo) ScheduleDeletion(ctx context.Context, userID string, at time.Time) (time.Time, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.ScheduleDeletion", "UPDATE", "placement_log_users").End()
+
+	var scheduled time.Time
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE placement_log_users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+		WHERE id = $1
+		RETURNING deletion_scheduled_at;
+	`, userID, at).Scan(&scheduled)
+
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrUserNotFound
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule deletion: %v", err)
+	}
+
+	return scheduled, nil
+}
+
+/*
+CancelDeletion clears a scheduled deletion.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- bool: Whether a deletion was scheduled
+- error: Any database error
+*/
+func (r *PrivacyRepo) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.CancelDeletion", "UPDATE", "placement_log_users").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_users SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deletion_scheduled_at IS NOT NULL;
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+/*
+DueDeletions lists accounts whose cooling-off period has ended.
+
+Parameters:
+- ctx: The request context
+- now: The current time
+- limit: The maximum number of IDs returned
+
+Returns:
+- []string: The user IDs, longest overdue first
+- error: Any database error
+*/
+func (r *PrivacyRepo) DueDeletions(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.DueDeletions", "SELECT", "placement_log_users").End()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM placement_log_users
+		WHERE deletion_scheduled_at <= $1
+		ORDER BY deletion_scheduled_at
+		LIMIT $2;
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due deletions: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+/*
+PurgeUser deletes an account whose deletion is due, in one transaction.
+Authored posts are kept and lose their author (the foreign key sets user_id
+to NULL); tokens, the avatar and the moderation log are removed with the user, and the second
+factor and login events are removed explicitly because they are not linked
+by foreign keys.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- now: The current time; an account whose deletion was cancelled or is not yet due is left alone
+
+Returns:
+- bool: Whether the account was purged
+- error: Any database error; nothing is deleted on error
+*/
+func (r *PrivacyRepo) PurgeUser(ctx context.Context, userID string, now time.Time) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "PrivacyRepo.PurgeUser", "DELETE", "placement_log_users").End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	var regno string
+	err = tx.QueryRowContext(ctx, `
+		SELECT regno FROM placement_log_users
+		WHERE id = $1 AND deletion_scheduled_at <= $2
+		FOR UPDATE;
+	`, userID, now).Scan(&regno)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`DELETE FROM placement_log_login_events WHERE account_type = 'user' AND LOWER(account) IN ($1, 'password:' || $2);`, []any{regno, userID}},
+		{`DELETE FROM placement_log_two_factor WHERE account_type = 'user' AND account_id = $1;`, []any{userID}},
+		{`DELETE FROM placement_log_users WHERE id = $1;`, []any{userID}},
+	}
+
+	for _, step := range steps {
+		if _, err = tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return false, fmt.Errorf("failed to purge user: %v", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to purge user: %v", err)
+	}
+
+	return true, nil
+}
+
+// Ensure PrivacyRepo implements PrivacyRepository
+var _ PrivacyRepository = (*PrivacyRepo)(nil)
@@ -0,0 +1,331 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+)
+
+// purgeBatchSize bounds the accounts purged per database round trip.
+const purgeBatchSize = 100
+
+var (
+	// ErrUserNotFound is returned when the account no longer exists.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNoDeletionScheduled is returned when cancelling a deletion that was never requested.
+	ErrNoDeletionScheduled = errors.New("no account deletion is scheduled")
+)
+
+// avatarExtensions names the avatar file in an export by content type.
+var avatarExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+}
+
+// Define PrivacyRepository interface for testability
+//go:generate mockgen -destination=mock_privacy_repo.go -package=privacy . PrivacyRepository
+
+type PrivacyRepository interface {
+	ExportData(ctx context.Context, userID string) (map[string]json.RawMessage, error)
+	GetAvatar(ctx context.Context, userID string) (*db.Avatar, error)
+	ScheduleDeletion(ctx context.Context, userID string, at time.Time) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID string) (bool, error)
+	DueDeletions(ctx context.Context, now time.Time, limit int) ([]string, error)
+	PurgeUser(ctx context.Context, userID string, now time.Time) (bool, error)
+}
+
+/*
+PasswordChecker verifies the current password of a logged-in user,
+throttling repeated wrong passwords.
+*/
+type PasswordChecker interface {
+	CheckPassword(ctx context.Context, userID, pass, ip string) (*db.User, error)
+}
+
+/*
+PrivacyService handles personal data exports and account deletion.
+*/
+type PrivacyService struct {
+	repo      PrivacyRepository
+	passwords PasswordChecker
+	mailer    mail.Sender
+	account   config.AccountConfig
+	// onPurge is called after accounts are purged; may be nil.
+	onPurge func()
+	now     func() time.Time
+}
+
+/*
+NewPrivacyService creates a new PrivacyService instance.
+
+Parameters:
+- repo: The privacy repository
+- passwords: Verifies the password before a deletion is scheduled (may be nil when only purging)
+- mailer: Sends the deletion notice (may be nil when only purging)
+- account: The deletion cooling-off period and link base URL
+- onPurge: Called after accounts are purged, so cached post listings showing them are dropped (may be nil)
+
+Returns:
+- *PrivacyService: A new service instance
+*/
+func NewPrivacyService(repo PrivacyRepository, passwords PasswordChecker, mailer mail.Sender, account config.AccountConfig, onPurge func()) *PrivacyService {
+	return &PrivacyService{repo: repo, passwords: passwords, mailer: mailer, account: account, onPurge: onPurge, now: time.Now}
+}
+
+/*
+Export writes everything stored about a user as a ZIP archive: one JSON file
+per section (profile, posts, roster, login_events, moderation_log,
+two_factor, sso_identities, impersonations), the avatar image if there is
+one, and export.json describing the archive.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- w: Receives the archive
+
+Returns:
+- error: ErrUserNotFound, a database error or a write error
+*/
+func (s *PrivacyService) Export(ctx context.Context, userID string, w io.Writer) error {
+	data, err := s.repo.ExportData(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	avatar, err := s.repo.GetAvatar(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	archive := zip.NewWriter(w)
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []string
+	for _, name := range names {
+		var pretty bytes.Buffer
+		if err = json.Indent(&pretty, data[name], "", "  "); err != nil {
+			return fmt.Errorf("error formatting %s: %v", name, err)
+		}
+
+		file := name + ".json"
+		if err = writeZipFile(archive, file, now, pretty.Bytes()); err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	if avatar != nil {
+		file := "avatar." + avatarExtensions[avatar.ContentType]
+		if err = writeZipFile(archive, file, now, avatar.Data); err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	manifest, err := json.MarshalIndent(map[string]any{
+		"user_id":      userID,
+		"generated_at": now,
+		"files":        files,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	if err = writeZipFile(archive, "export.json", now, manifest); err != nil {
+		return err
+	}
+
+	if err = archive.Close(); err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	return nil
+}
+
+func writeZipFile(archive *zip.Writer, name string, modified time.Time, content []byte) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	if _, err = f.Write(content); err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	return nil
+}
+
+/*
+RequestDeletion schedules the deletion of the logged-in user's account.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- pass: The user's current password
+- ip: The client IP, used to throttle repeated wrong passwords
+
+Returns:
+- time.Time: When the account will be purged
+- error: Any error that occurred
+
+The function:
+1. Verifies the password
+2. Schedules the purge after the cooling-off period; asking again keeps the first time
+3. Emails a notice to the verified address, if there is one
+
+Until the purge the user can still log in and cancel the deletion; the author
+page and avatar are hidden meanwhile. The purge deletes the account and keeps
+its posts without an author.
+
+Possible errors:
+- *loginguard.BlockedError: Too many wrong passwords
+- auth.ErrInvalidCredentials: Wrong password
+*/
+func (s *PrivacyService) RequestDeletion(ctx context.Context, userID, pass, ip string) (time.Time, error) {
+	if pass == "" {
+		return time.Time{}, fmt.Errorf("password is required")
+	}
+
+	user, err := s.passwords.CheckPassword(ctx, userID, pass, ip)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	at, err := s.repo.ScheduleDeletion(ctx, userID, s.now().Add(s.account.DeletionCoolingOff))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user.Email != "" {
+		go func() {
+			if err := s.sendDeletionNotice(user, at); err != nil {
+				log.Printf("privacy: deletion notice for %s: %v", user.ID, err)
+			}
+		}()
+	}
+
+	return at, nil
+}
+
+func (s *PrivacyService) sendDeletionNotice(user *db.User, at time.Time) error {
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Your PlacementLog account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nThe account %s is scheduled for deletion on %s. Your posts will stay on PlacementLog without your name.\n\nIf you did not ask for this, or changed your mind, log in at %s before then and cancel the deletion from your profile.\n",
+			user.Username, user.Regno, at.UTC().Format("2 January 2006 15:04 MST"), s.account.LinkBaseURL),
+	})
+}
+
+/*
+CancelDeletion cancels the scheduled deletion of the logged-in user's account.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- error: ErrNoDeletionScheduled or a database error
+*/
+func (s *PrivacyService) CancelDeletion(ctx context.Context, userID string) error {
+	cancelled, err := s.repo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !cancelled {
+		return ErrNoDeletionScheduled
+	}
+
+	return nil
+}
+
+/*
+PurgeDue purges every account whose cooling-off period has ended and calls
+onPurge if any account was purged.
+
+Parameters:
+- ctx: Cancels the purge between queries
+
+Returns:
+- int: The number of accounts purged
+- error: The first error; accounts purged before it stay purged
+*/
+func (s *PrivacyService) PurgeDue(ctx context.Context) (int, error) {
+	purged := 0
+
+	defer func() {
+		if purged > 0 && s.onPurge != nil {
+			s.onPurge()
+		}
+	}()
+
+	for {
+		now := s.now()
+
+		ids, err := s.repo.DueDeletions(ctx, now, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, id := range ids {
+			ok, err := s.repo.PurgeUser(ctx, id, now)
+			if err != nil {
+				return purged, err
+			}
+			if ok {
+				purged++
+			}
+		}
+
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+/*
+RunPurger calls PurgeDue every interval until ctx is cancelled. Errors are
+logged and retried at the next interval.
+
+Parameters:
+- ctx: Stops the purger when cancelled
+- interval: The time between runs
+*/
+func (s *PrivacyService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := s.PurgeDue(ctx)
+		if n > 0 {
+			log.Printf("privacy: purged %d deleted accounts", n)
+		}
+		if err != nil {
+			log.Printf("privacy: purging deleted accounts: %v", err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+)
+
+type mockPrivacyRepo struct {
+	ExportDataFunc       func(userID string) (map[string]json.RawMessage, error)
+	GetAvatarFunc        func(userID string) (*db.Avatar, error)
+	ScheduleDeletionFunc func(userID string, at time.Time) (time.Time, error)
+	CancelDeletionFunc   func(userID string) (bool, error)
+	DueDeletionsFunc     func(now time.Time, limit int) ([]string, error)
+	PurgeUserFunc        func(userID string, now time.Time) (bool, error)
+}
+
+func (m *mockPrivacyRepo) ExportData(ctx context.Context, userID string) (map[string]json.RawMessage, error) {
+	return m.ExportDataFunc(userID)
+}
+func (m *mockPrivacyRepo) GetAvatar(ctx context.Context, userID string) (*db.Avatar, error) {
+	return m.GetAvatarFunc(userID)
+}
+func (m *mockPrivacyRepo) ScheduleDeletion(ctx context.Context, userID string, at time.Time) (time.Time, error) {
+	return m.ScheduleDeletionFunc(userID, at)
+}
+func (m *mockPrivacyRepo) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	return m.CancelDeletionFunc(userID)
+}
+func (m *mockPrivacyRepo) DueDeletions(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return m.DueDeletionsFunc(now, limit)
+}
+func (m *mockPrivacyRepo) PurgeUser(ctx context.Context, userID string, now time.Time) (bool, error) {
+	return m.PurgeUserFunc(userID, now)
+}
+
+type mockPasswordChecker struct {
+	password string
+}
+
+func (m *mockPasswordChecker) CheckPassword(ctx context.Context, userID, pass, ip string) (*db.User, error) {
+	if pass != m.password {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return &db.User{ID: userID, Regno: "22bcs1234", Username: "Jane"}, nil
+}
+
+var testNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestService(repo PrivacyRepository) *PrivacyService {
+	s := NewPrivacyService(repo, &mockPasswordChecker{password: "secret"}, mail.NewLogSender("test@example.com"), config.Default().Account, nil)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestPrivacyService_Export(t *testing.T) {
+	repo := &mockPrivacyRepo{
+		ExportDataFunc: func(userID string) (map[string]json.RawMessage, error) {
+			return map[string]json.RawMessage{
+				"profile": json.RawMessage(`{"id":"u1","regno":"22bcs1234"}`),
+				"posts":   json.RawMessage(`[]`),
+			}, nil
+		},
+		GetAvatarFunc: func(userID string) (*db.Avatar, error) {
+			return &db.Avatar{ContentType: "image/png", Data: []byte("png")}, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := newTestService(repo).Export(context.Background(), "u1", &buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive, got %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range archive.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"profile.json", "posts.json", "avatar.png", "export.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the archive, got %v", name, archive.File)
+		}
+	}
+
+	var profile map[string]string
+	if err = json.Unmarshal([]byte(files["profile.json"]), &profile); err != nil || profile["regno"] != "22bcs1234" {
+		t.Errorf("unexpected profile.json %q", files["profile.json"])
+	}
+}
+
+func TestPrivacyService_Export_UserNotFound(t *testing.T) {
+	repo := &mockPrivacyRepo{
+		ExportDataFunc: func(userID string) (map[string]json.RawMessage, error) {
+			return nil, ErrUserNotFound
+		},
+	}
+
+	if err := newTestService(repo).Export(context.Background(), "u1", io.Discard); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestPrivacyService_RequestDeletion(t *testing.T) {
+	var scheduled time.Time
+	repo := &mockPrivacyRepo{
+		ScheduleDeletionFunc: func(userID string, at time.Time) (time.Time, error) {
+			scheduled = at
+			return at, nil
+		},
+	}
+	s := newTestService(repo)
+
+	if _, err := s.RequestDeletion(context.Background(), "u1", "wrong", "127.0.0.1"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if !scheduled.IsZero() {
+		t.Fatal("expected no deletion to be scheduled after a wrong password")
+	}
+
+	at, err := s.RequestDeletion(context.Background(), "u1", "secret", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := testNow.Add(config.Default().Account.DeletionCoolingOff); !at.Equal(want) || !scheduled.Equal(want) {
+		t.Errorf("expected purge at %v, got %v", want, at)
+	}
+}
+
+func TestPrivacyService_CancelDeletion(t *testing.T) {
+	repo := &mockPrivacyRepo{
+		CancelDeletionFunc: func(userID string) (bool, error) {
+			return false, nil
+		},
+	}
+
+	if err := newTestService(repo).CancelDeletion(context.Background(), "u1"); !errors.Is(err, ErrNoDeletionScheduled) {
+		t.Errorf("expected ErrNoDeletionScheduled, got %v", err)
+	}
+}
+
+func TestPrivacyService_PurgeDue(t *testing.T) {
+	calls := 0
+	repo := &mockPrivacyRepo{
+		DueDeletionsFunc: func(now time.Time, limit int) ([]string, error) {
+			calls++
+			if calls == 1 {
+				ids := make([]string, limit)
+				for i := range ids {
+					ids[i] = "full-batch"
+				}
+				return ids, nil
+			}
+			return []string{"a", "cancelled"}, nil
+		},
+		PurgeUserFunc: func(userID string, now time.Time) (bool, error) {
+			if !now.Equal(testNow) {
+				t.Errorf("expected purge at %v, got %v", testNow, now)
+			}
+			return userID != "cancelled", nil
+		},
+	}
+
+	s := newTestService(repo)
+	purges := 0
+	s.onPurge = func() { purges++ }
+
+	n, err := s.PurgeDue(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if calls != 2 || n != purgeBatchSize+1 {
+		t.Errorf("expected %d purged in 2 batches, got %d in %d", purgeBatchSize+1, n, calls)
+	}
+	if purges != 1 {
+		t.Errorf("expected the purge to be reported once, got %d", purges)
+	}
+
+	repo.DueDeletionsFunc = func(now time.Time, limit int) ([]string, error) { return nil, nil }
+	if _, err = s.PurgeDue(context.Background()); err != nil || purges != 1 {
+		t.Errorf("expected no report when nothing was purged, got %v and %d reports", err, purges)
+	}
+}
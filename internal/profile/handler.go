@@ -0,0 +1,315 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// multipartOverhead is the room left for multipart headers and boundaries
+// on top of the avatar size limit.
+const multipartOverhead = 16 << 10
+
+var (
+	errUnauthorized = errors.New("unauthorized: user authentication required")
+	// errImpersonatedEmail keeps impersonation tokens from attaching an
+	// address, which would let the admin take the account over by resetting
+	// its password.
+	errImpersonatedEmail = errors.New("forbidden: the email address cannot be changed while impersonating a user")
+)
+
+/*
+ProfileHandler handles the /users endpoints: the logged-in user's own
+profile and avatar, and the public author pages.
+*/
+type ProfileHandler struct {
+	srv            *ProfileService
+	avatarMaxBytes int64
+}
+
+/*
+NewProfileHandler creates a new ProfileHandler instance with the provided service.
+
+Parameters:
+- srv: The profile service
+
+Returns:
+- *ProfileHandler: A new handler instance
+*/
+func NewProfileHandler(srv *ProfileService) *ProfileHandler {
+	return &ProfileHandler{srv: srv, avatarMaxBytes: srv.cfg.AvatarMaxBytes}
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrAvatarNotFound) {
+		utils.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	utils.WriteError(w, err)
+}
+
+/*
+GetMe returns the profile of the logged-in user.
+
+HTTP Method: GET
+Endpoint: /users/me
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Response (200 OK):
+
+	{
+	  "id": "user_uuid",
+	  "regno": "22bcs1234",
+	  "username": "Jane Doe",
+	  "display_name": "Jane",
+	  "email": "jane@example.edu",
+	  "bio": "",
+	  "linkedin": "https://www.linkedin.com/in/jane-doe",
+	  "graduation_year": 2026,
+	  "avatar_url": "/users/user_uuid/avatar?v=1767225600",
+	  "created_at": "2025-01-01T00:00:00Z"
+	}
+
+Returns:
+- 200 OK: The profile
+- 401 Unauthorized: Missing or invalid user token
+*/
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.srv.GetProfile(r.Context(), userID)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, profile, http.StatusOK)
+}
+
+/*
+UpdateMe edits the profile of the logged-in user. Only the fields present
+in the request are changed; "" (or 0 for graduation_year) clears a field.
+
+HTTP Method: PATCH
+Endpoint: /users/me
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Request Body:
+
+	{
+	  "display_name": "Jane",
+	  "email": "jane@example.edu",
+	  "bio": "CSE 2026",
+	  "linkedin": "linkedin.com/in/jane-doe",
+	  "graduation_year": 2026
+	}
+
+A new email address is not attached right away: a verification link is sent
+to it and the response shows it as "pending_email" until the link is used.
+Impersonation tokens cannot change the email address.
+
+Returns:
+- 200 OK: The updated profile
+- 400 Bad Request: Invalid field
+- 401 Unauthorized: Missing or invalid user token
+- 403 Forbidden: "email" sent with an impersonation token
+*/
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	var update ProfileUpdate
+	if err := utils.ReadJSON(r, &update); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if principal, _ := auth.FromContext(r.Context()); principal.IsImpersonated() && update.Email != nil {
+		utils.WriteError(w, errImpersonatedEmail, http.StatusForbidden)
+		return
+	}
+
+	profile, err := h.srv.UpdateProfile(r.Context(), userID, update)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, profile, http.StatusOK)
+}
+
+/*
+UploadAvatar replaces the avatar of the logged-in user.
+
+HTTP Method: PUT
+Endpoint: /users/me/avatar
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+- Content-Type: image/png, image/jpeg or image/gif with the image as the body, or multipart/form-data with the image in the "avatar" field
+
+Returns:
+- 200 OK: Avatar stored; the response holds the new avatar_url
+- 400 Bad Request: Not a PNG, JPEG or GIF image, or larger than PROFILE_AVATAR_MAX_DIMENSION pixels
+- 401 Unauthorized: Missing or invalid user token
+- 413 Request Entity Too Large: Larger than PROFILE_AVATAR_MAX_BYTES
+*/
+func (h *ProfileHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	data, err := h.readAvatar(w, r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.WriteError(w, errors.New("avatar is too large"), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if err = h.srv.SetAvatar(r.Context(), userID, data); err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	profile, err := h.srv.GetProfile(r.Context(), userID)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"avatar_url": profile.AvatarURL}, http.StatusOK)
+}
+
+func (h *ProfileHandler) readAvatar(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType != "multipart/form-data" {
+		return io.ReadAll(http.MaxBytesReader(w, r.Body, h.avatarMaxBytes))
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.avatarMaxBytes+multipartOverhead)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, errors.New("avatar field is required")
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, file)
+	return buf.Bytes(), err
+}
+
+/*
+DeleteAvatar removes the avatar of the logged-in user.
+
+HTTP Method: DELETE
+Endpoint: /users/me/avatar
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Returns:
+- 204 No Content: Avatar removed (or there was none)
+- 401 Unauthorized: Missing or invalid user token
+*/
+func (h *ProfileHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.srv.DeleteAvatar(r.Context(), userID); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetAuthor returns the public page of an author: the public profile fields
+and the author's approved posts, newest first. The regno and email address
+are never shown.
+
+HTTP Method: GET
+Endpoint: /users/{id}
+
+Response (200 OK):
+
+	{
+	  "id": "user_uuid",
+	  "display_name": "Jane",
+	  "bio": "CSE 2026",
+	  "linkedin": "https://www.linkedin.com/in/jane-doe",
+	  "graduation_year": 2026,
+	  "avatar_url": "/users/user_uuid/avatar?v=1767225600",
+	  "posts": [...]
+	}
+
+Returns:
+- 200 OK: The author page
+- 404 Not Found: No such active user
+*/
+func (h *ProfileHandler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.srv.GetAuthorProfile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, profile, http.StatusOK)
+}
+
+/*
+GetAvatar serves a user's avatar. Avatar URLs carry a version that changes
+on every upload, so the image may be cached for a long time.
+
+HTTP Method: GET
+Endpoint: /users/{id}/avatar
+
+Returns:
+- 200 OK: The image
+- 304 Not Modified: The client's copy is current
+- 404 Not Found: No such user or no avatar
+*/
+func (h *ProfileHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	avatar, err := h.srv.GetAvatar(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, "", avatar.UpdatedAt, bytes.NewReader(avatar.Data))
+}
@@ -0,0 +1,54 @@
+package profile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestProfileHandler_UpdateMe_Impersonated(t *testing.T) {
+	repo := &mockProfileRepo{
+		GetProfileFunc: func(userID string) (*db.Profile, error) {
+			return &db.Profile{ID: userID, Email: "jane@college.edu"}, nil
+		},
+		UpdateProfileFunc: func(userID string, update ProfileUpdate) error { return nil },
+	}
+
+	cases := []struct {
+		name         string
+		impersonator string
+		body         string
+		wantStatus   int
+		wantAttached string
+	}{
+		{"own session changes email", "", `{"email": "jane@example.com"}`, http.StatusOK, "jane@example.com"},
+		{"impersonation cannot change email", "admin-1", `{"email": "admin@example.com"}`, http.StatusForbidden, ""},
+		{"impersonation cannot sneak email in", "admin-1", `{"display_name": "Jane", "email": "admin@example.com"}`, http.StatusForbidden, ""},
+		{"impersonation changes display name", "admin-1", `{"display_name": "Jane"}`, http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			emails := &mockEmailChanger{}
+			h := NewProfileHandler(newTestService(repo, emails))
+
+			principal := auth.NewPrincipal("u1", auth.RoleUser, "session-1")
+			principal.ImpersonatorID = c.impersonator
+			req := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(c.body))
+			req = req.WithContext(auth.NewContext(req.Context(), principal))
+			rec := httptest.NewRecorder()
+
+			h.UpdateMe(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", c.wantStatus, rec.Code, rec.Body.String())
+			}
+			if emails.attached != c.wantAttached {
+				t.Errorf("expected %q to be attached, got %q", c.wantAttached, emails.attached)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+ProfileRepo handles the profile fields and avatars of users.
+*/
+type ProfileRepo struct {
+	db *db.DB
+}
+
+/*
+NewProfileRepo creates a new ProfileRepo instance with the provided database connection.
+
+Parameters:
+- db: The database handle; the posts on author pages are read from the replica when one is configured
+
+Returns:
+- *ProfileRepo: A new repository instance
+*/
+func NewProfileRepo(db *db.DB) *ProfileRepo {
+	return &ProfileRepo{db: db}
+}
+
+/*
+GetProfile returns the profile of an active user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.Profile: The profile
+- error: ErrUserNotFound or a database error
+*/
+func (r *ProfileRepo) GetProfile(ctx context.Context, userID string) (*db.Profile, error) {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.GetProfile", "SELECT", "placement_log_users").End()
+
+	var p db.Profile
+	var avatarAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT u.id, u.regno, u.username, COALESCE(NULLIF(u.display_name, ''), u.username),
+			COALESCE(u.email, ''), COALESCE(u.bio, ''), COALESCE(u.linkedin_url, ''),
+			COALESCE(u.graduation_year, 0), a.updated_at, COALESCE(u.pseudonym, ''), u.created_at, u.deletion_scheduled_at
+		FROM placement_log_users u
+		LEFT JOIN placement_log_avatars a ON a.user_id = u.id
+		WHERE u.id = $1 AND u.status = $2;
+	`, userID, db.UserStatusActive).Scan(&p.ID, &p.Regno, &p.Username, &p.DisplayName,
+		&p.Email, &p.Bio, &p.LinkedIn, &p.GraduationYear, &avatarAt, &p.Pseudonym, &p.CreatedAt, &p.DeletionScheduledAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	p.AvatarURL = avatarURL(p.ID, avatarAt)
+
+	return &p, nil
+}
+
+/*
+UpdateProfile writes the fields set in update. An empty string or a zero
+graduation year clears the field.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- update: The validated changes; the email address is not written here
+
+Returns:
+- error: ErrUserNotFound or a database error
+*/
+func (r *ProfileRepo) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) error {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.UpdateProfile", "UPDATE", "placement_log_users").End()
+
+	sets := []string{"updated_at = CURRENT_TIMESTAMP"}
+	args := []any{userID}
+
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = NULLIF($%d, %s)", column, len(args), zeroLiteral(value)))
+	}
+
+	if update.DisplayName != nil {
+		set("display_name", *update.DisplayName)
+	}
+	if update.Bio != nil {
+		set("bio", *update.Bio)
+	}
+	if update.LinkedIn != nil {
+		set("linkedin_url", *update.LinkedIn)
+	}
+	if update.GraduationYear != nil {
+		set("graduation_year", *update.GraduationYear)
+	}
+
+	res, err := r.db.ExecContext(ctx, `UPDATE placement_log_users SET `+strings.Join(sets, ", ")+` WHERE id = $1;`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func zeroLiteral(value any) string {
+	if _, ok := value.(int); ok {
+		return "0"
+	}
+	return "''"
+}
+
+/*
+GetAuthorProfile returns the public profile of an active user with their
+approved named posts, newest first; posts made under a pseudonym or
+anonymously are never linked to the author. Accounts scheduled for deletion
+are hidden. The posts are read from the replica when one is configured.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.AuthorProfile: The public profile
+- error: ErrUserNotFound or a database error
+*/
+func (r *ProfileRepo) GetAuthorProfile(ctx context.Context, userID string) (*db.AuthorProfile, error) {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.GetAuthorProfile", "SELECT", "placement_log_users").End()
+
+	var p db.AuthorProfile
+	var avatarAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT u.id, COALESCE(NULLIF(u.display_name, ''), u.username), COALESCE(u.bio, ''),
+			COALESCE(u.linkedin_url, ''), COALESCE(u.graduation_year, 0), a.updated_at
+		FROM placement_log_users u
+		LEFT JOIN placement_log_avatars a ON a.user_id = u.id
+		WHERE u.id = $1 AND u.status = $2 AND u.deletion_scheduled_at IS NULL;
+	`, userID, db.UserStatusActive).Scan(&p.ID, &p.DisplayName, &p.Bio, &p.LinkedIn, &p.GraduationYear, &avatarAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	p.AvatarURL = avatarURL(p.ID, avatarAt)
+
+	rows, err := r.db.QueryRead(ctx, `
+		SELECT p.id, p.user_id, p.post_body, p.reviewed, p.author_visibility,
+			COALESCE(r.branch, ''), COALESCE(r.batch, ''), p.created_at, p.updated_at
+		FROM placement_log_posts p
+		JOIN placement_log_users u ON u.id = p.user_id
+		LEFT JOIN placement_log_roster r ON r.regno = u.regno
+		WHERE p.user_id = $1 AND p.reviewed = true AND p.author_visibility = $2
+		ORDER BY p.created_at DESC;
+	`, userID, db.VisibilityNamed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author posts: %v", err)
+	}
+	defer rows.Close()
+
+	p.Posts = []db.Post{}
+	for rows.Next() {
+		var post db.Post
+		err = rows.Scan(&post.ID, &post.UserID, &post.PostBody, &post.Reviewed, &post.AuthorVisibility,
+			&post.AuthorBranch, &post.AuthorBatch, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan posts: %v", err)
+		}
+		post.Author = p.DisplayName
+		post.ReviewStatus = db.ReviewStatusApproved
+		p.Posts = append(p.Posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get author posts: %v", err)
+	}
+
+	return &p, nil
+}
+
+/*
+SetAvatar stores a user's avatar, replacing the previous one.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- contentType: The validated image type
+- data: The image
+
+Returns:
+- error: Any database error
+*/
+func (r *ProfileRepo) SetAvatar(ctx context.Context, userID, contentType string, data []byte) error {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.SetAvatar", "INSERT", "placement_log_avatars").End()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_avatars (user_id, content_type, data)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			content_type = EXCLUDED.content_type, data = EXCLUDED.data, updated_at = CURRENT_TIMESTAMP;
+	`, userID, contentType, data)
+	if err != nil {
+		return fmt.Errorf("failed to store avatar: %v", err)
+	}
+
+	return nil
+}
+
+/*
+GetAvatar returns the avatar of an active user that is not scheduled for deletion.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.Avatar: The avatar
+- error: ErrAvatarNotFound or a database error
+*/
+func (r *ProfileRepo) GetAvatar(ctx context.Context, userID string) (*db.Avatar, error) {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.GetAvatar", "SELECT", "placement_log_avatars").End()
+
+	var a db.Avatar
+	err := r.db.QueryRowContext(ctx, `
+		SELECT a.content_type, a.data, a.updated_at
+		FROM placement_log_avatars a
+		JOIN placement_log_users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND u.status = $2 AND u.deletion_scheduled_at IS NULL;
+	`, userID, db.UserStatusActive).Scan(&a.ContentType, &a.Data, &a.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrAvatarNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &a, nil
+}
+
+/*
+DeleteAvatar removes a user's avatar. Removing a missing avatar is not an error.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- error: Any database error
+*/
+func (r *ProfileRepo) DeleteAvatar(ctx context.Context, userID string) error {
+	defer telemetry.TraceQuery(ctx, "ProfileRepo.DeleteAvatar", "DELETE", "placement_log_avatars").End()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM placement_log_avatars WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("failed to delete avatar: %v", err)
+	}
+
+	return nil
+}
+
+// avatarURL links to the avatar endpoint; the version changes on every upload
+// so that clients can cache the image indefinitely.
+func avatarURL(userID string, updatedAt sql.NullTime) string {
+	if !updatedAt.Valid {
+		return ""
+	}
+	return fmt.Sprintf("/users/%s/avatar?v=%d", userID, updatedAt.Time.Unix())
+}
+
+// Ensure ProfileRepo implements ProfileRepository
+var _ ProfileRepository = (*ProfileRepo)(nil)
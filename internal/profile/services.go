@@ -0,0 +1,334 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// Limits of the free-text profile fields, in characters.
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 500
+)
+
+var (
+	// ErrUserNotFound is returned for unknown, malformed or inactive user IDs.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrAvatarNotFound is returned when a user has no avatar.
+	ErrAvatarNotFound = errors.New("avatar not found")
+)
+
+// avatarTypes maps the image formats accepted for avatars to their content types.
+var avatarTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// Define ProfileRepository interface for testability
+//go:generate mockgen -destination=mock_profile_repo.go -package=profile . ProfileRepository
+
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, userID string) (*db.Profile, error)
+	UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) error
+	GetAuthorProfile(ctx context.Context, userID string) (*db.AuthorProfile, error)
+	SetAvatar(ctx context.Context, userID, contentType string, data []byte) error
+	GetAvatar(ctx context.Context, userID string) (*db.Avatar, error)
+	DeleteAvatar(ctx context.Context, userID string) error
+}
+
+/*
+EmailChanger starts the verification of a new email address; the address is
+attached to the account once the emailed link is used.
+*/
+type EmailChanger interface {
+	AttachEmail(ctx context.Context, userID, email string) error
+}
+
+/*
+ProfileUpdate holds the fields of a profile edit. Nil fields are left
+unchanged; an empty string or a zero graduation year clears the field.
+*/
+type ProfileUpdate struct {
+	DisplayName    *string `json:"display_name"`
+	Email          *string `json:"email"`
+	Bio            *string `json:"bio"`
+	LinkedIn       *string `json:"linkedin"`
+	GraduationYear *int    `json:"graduation_year"`
+}
+
+/*
+ProfileService handles the self-service profile of users and the public
+author pages.
+*/
+type ProfileService struct {
+	repo   ProfileRepository
+	emails EmailChanger
+	cfg    config.ProfileConfig
+	// onRename is called after a display name changes; may be nil.
+	onRename func()
+	now      func() time.Time
+}
+
+/*
+NewProfileService creates a new ProfileService instance.
+
+Parameters:
+- repo: The profile repository
+- emails: Verifies new email addresses before they are attached
+- cfg: The avatar limits
+- onRename: Called after a display name changes, so cached post listings showing it are dropped (may be nil)
+
+Returns:
+- *ProfileService: A new service instance
+*/
+func NewProfileService(repo ProfileRepository, emails EmailChanger, cfg config.ProfileConfig, onRename func()) *ProfileService {
+	return &ProfileService{repo: repo, emails: emails, cfg: cfg, onRename: onRename, now: time.Now}
+}
+
+/*
+GetProfile returns the profile of the logged-in user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.Profile: The profile
+- error: ErrUserNotFound or a database error
+*/
+func (s *ProfileService) GetProfile(ctx context.Context, userID string) (*db.Profile, error) {
+	return s.repo.GetProfile(ctx, userID)
+}
+
+/*
+UpdateProfile edits the profile of the logged-in user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- update: The fields to change
+
+Returns:
+- *db.Profile: The updated profile; PendingEmail is set when a new address awaits verification
+- error: Any error that occurred
+
+The function:
+1. Validates and normalizes every field that is set
+2. Sends a verification link when the email address changes; the current address stays until the link is used
+3. Writes the other fields
+4. Calls onRename when the display name was set
+
+Possible errors:
+- Validation errors naming the invalid field
+- Email errors from the verification, e.g. an address already in use
+*/
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*db.Profile, error) {
+	if err := s.normalize(&update); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingEmail := ""
+	if update.Email != nil && !strings.EqualFold(strings.TrimSpace(*update.Email), current.Email) {
+		if err = s.emails.AttachEmail(ctx, userID, *update.Email); err != nil {
+			return nil, err
+		}
+		pendingEmail, _ = mail.ValidateAddress(*update.Email)
+	}
+
+	if err = s.repo.UpdateProfile(ctx, userID, update); err != nil {
+		return nil, err
+	}
+
+	if update.DisplayName != nil && s.onRename != nil {
+		s.onRename()
+	}
+
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.PendingEmail = pendingEmail
+
+	return profile, nil
+}
+
+func (s *ProfileService) normalize(update *ProfileUpdate) error {
+	if update.DisplayName != nil {
+		name := strings.Join(strings.Fields(*update.DisplayName), " ")
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return fmt.Errorf("display name must be at most %d characters", maxDisplayNameLength)
+		}
+		if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return fmt.Errorf("display name must not contain control characters")
+		}
+		update.DisplayName = &name
+	}
+
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return fmt.Errorf("bio must be at most %d characters", maxBioLength)
+		}
+		update.Bio = &bio
+	}
+
+	if update.LinkedIn != nil {
+		link, err := normalizeLinkedIn(*update.LinkedIn)
+		if err != nil {
+			return err
+		}
+		update.LinkedIn = &link
+	}
+
+	if update.GraduationYear != nil && *update.GraduationYear != 0 {
+		year, latest := *update.GraduationYear, s.now().Year()+6
+		if year < 1950 || year > latest {
+			return fmt.Errorf("graduation year must be between 1950 and %d", latest)
+		}
+	}
+
+	return nil
+}
+
+/*
+normalizeLinkedIn accepts a LinkedIn member profile URL, with or without the
+scheme, and returns it in the form https://www.linkedin.com/in/<name>.
+*/
+func normalizeLinkedIn(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	invalid := fmt.Errorf("linkedin must be a profile URL like https://www.linkedin.com/in/your-name")
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return "", invalid
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host != "linkedin.com" && !strings.HasSuffix(host, ".linkedin.com") {
+		return "", invalid
+	}
+
+	parts := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	if len(parts) != 2 || parts[0] != "in" || parts[1] == "" {
+		return "", invalid
+	}
+
+	return "https://www.linkedin.com/in/" + parts[1], nil
+}
+
+/*
+SetAvatar validates and stores the avatar of the logged-in user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- data: The uploaded image
+
+Returns:
+- error: Any error that occurred
+
+Possible errors:
+- "avatar must be at most N bytes": The upload is too large
+- "avatar must be a PNG, JPEG or GIF image": Unknown or corrupt image
+- "avatar must be at most NxN pixels": The image is too large
+*/
+func (s *ProfileService) SetAvatar(ctx context.Context, userID string, data []byte) error {
+	if int64(len(data)) > s.cfg.AvatarMaxBytes {
+		return fmt.Errorf("avatar must be at most %d bytes", s.cfg.AvatarMaxBytes)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	contentType, ok := avatarTypes[format]
+	if err != nil || !ok {
+		return fmt.Errorf("avatar must be a PNG, JPEG or GIF image")
+	}
+
+	if cfg.Width > s.cfg.AvatarMaxDimension || cfg.Height > s.cfg.AvatarMaxDimension {
+		return fmt.Errorf("avatar must be at most %dx%d pixels", s.cfg.AvatarMaxDimension, s.cfg.AvatarMaxDimension)
+	}
+
+	return s.repo.SetAvatar(ctx, userID, contentType, data)
+}
+
+/*
+GetAvatar returns a user's avatar.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.Avatar: The avatar
+- error: ErrAvatarNotFound or a database error
+*/
+func (s *ProfileService) GetAvatar(ctx context.Context, userID string) (*db.Avatar, error) {
+	if !utils.IsUUID(userID) {
+		return nil, ErrAvatarNotFound
+	}
+
+	return s.repo.GetAvatar(ctx, userID)
+}
+
+/*
+DeleteAvatar removes the avatar of the logged-in user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- error: Any database error
+*/
+func (s *ProfileService) DeleteAvatar(ctx context.Context, userID string) error {
+	return s.repo.DeleteAvatar(ctx, userID)
+}
+
+/*
+GetAuthorProfile returns the public page of an author.
+
+Parameters:
+- ctx: The request context
+- userID: The author's user ID
+
+Returns:
+- *db.AuthorProfile: The public profile with the author's approved posts
+- error: ErrUserNotFound or a database error
+*/
+func (s *ProfileService) GetAuthorProfile(ctx context.Context, userID string) (*db.AuthorProfile, error) {
+	if !utils.IsUUID(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.repo.GetAuthorProfile(ctx, userID)
+}
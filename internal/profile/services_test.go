@@ -0,0 +1,166 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+)
+
+type mockProfileRepo struct {
+	GetProfileFunc       func(userID string) (*db.Profile, error)
+	UpdateProfileFunc    func(userID string, update ProfileUpdate) error
+	GetAuthorProfileFunc func(userID string) (*db.AuthorProfile, error)
+	SetAvatarFunc        func(userID, contentType string, data []byte) error
+	GetAvatarFunc        func(userID string) (*db.Avatar, error)
+	DeleteAvatarFunc     func(userID string) error
+}
+
+func (m *mockProfileRepo) GetProfile(ctx context.Context, userID string) (*db.Profile, error) {
+	return m.GetProfileFunc(userID)
+}
+func (m *mockProfileRepo) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) error {
+	return m.UpdateProfileFunc(userID, update)
+}
+func (m *mockProfileRepo) GetAuthorProfile(ctx context.Context, userID string) (*db.AuthorProfile, error) {
+	return m.GetAuthorProfileFunc(userID)
+}
+func (m *mockProfileRepo) SetAvatar(ctx context.Context, userID, contentType string, data []byte) error {
+	return m.SetAvatarFunc(userID, contentType, data)
+}
+func (m *mockProfileRepo) GetAvatar(ctx context.Context, userID string) (*db.Avatar, error) {
+	return m.GetAvatarFunc(userID)
+}
+func (m *mockProfileRepo) DeleteAvatar(ctx context.Context, userID string) error {
+	return m.DeleteAvatarFunc(userID)
+}
+
+type mockEmailChanger struct {
+	attached string
+}
+
+func (m *mockEmailChanger) AttachEmail(ctx context.Context, userID, email string) error {
+	m.attached = email
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
+
+func newTestService(repo ProfileRepository, emails EmailChanger) *ProfileService {
+	s := NewProfileService(repo, emails, config.Default().Profile, nil)
+	s.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestProfileService_UpdateProfile(t *testing.T) {
+	var written ProfileUpdate
+	repo := &mockProfileRepo{
+		GetProfileFunc: func(userID string) (*db.Profile, error) {
+			return &db.Profile{ID: userID, Email: "jane@college.edu"}, nil
+		},
+		UpdateProfileFunc: func(userID string, update ProfileUpdate) error {
+			written = update
+			return nil
+		},
+	}
+	emails := &mockEmailChanger{}
+	s := newTestService(repo, emails)
+	renames := 0
+	s.onRename = func() { renames++ }
+
+	profile, err := s.UpdateProfile(context.Background(), "u1", ProfileUpdate{
+		DisplayName:    strPtr("  Jane   Doe "),
+		Email:          strPtr("Jane@Example.com"),
+		LinkedIn:       strPtr("linkedin.com/in/jane-doe/"),
+		GraduationYear: intPtr(2026),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if *written.DisplayName != "Jane Doe" || *written.LinkedIn != "https://www.linkedin.com/in/jane-doe" || written.Bio != nil {
+		t.Errorf("unexpected update %+v", written)
+	}
+	if emails.attached != "Jane@Example.com" || profile.PendingEmail != "jane@example.com" {
+		t.Errorf("expected verification of the new address, got %q / %q", emails.attached, profile.PendingEmail)
+	}
+	if renames != 1 {
+		t.Errorf("expected the display name change to be reported once, got %d", renames)
+	}
+
+	emails.attached = ""
+	if _, err = s.UpdateProfile(context.Background(), "u1", ProfileUpdate{Email: strPtr("JANE@college.edu")}); err != nil || emails.attached != "" {
+		t.Errorf("expected the current address to be left alone, got %v %q", err, emails.attached)
+	}
+	if renames != 1 {
+		t.Errorf("expected no rename report without a display name, got %d", renames)
+	}
+}
+
+func TestProfileService_UpdateProfile_Invalid(t *testing.T) {
+	repo := &mockProfileRepo{
+		GetProfileFunc: func(userID string) (*db.Profile, error) {
+			t.Fatal("expected invalid update to be rejected before the repository")
+			return nil, nil
+		},
+	}
+	s := newTestService(repo, &mockEmailChanger{})
+
+	cases := []struct {
+		update ProfileUpdate
+		want   string
+	}{
+		{ProfileUpdate{DisplayName: strPtr(strings.Repeat("a", 51))}, "display name"},
+		{ProfileUpdate{Bio: strPtr(strings.Repeat("a", 501))}, "bio"},
+		{ProfileUpdate{LinkedIn: strPtr("https://evil.example/in/jane")}, "linkedin"},
+		{ProfileUpdate{LinkedIn: strPtr("https://www.linkedin.com/company/acme")}, "linkedin"},
+		{ProfileUpdate{GraduationYear: intPtr(2040)}, "graduation year"},
+	}
+	for _, c := range cases {
+		if _, err := s.UpdateProfile(context.Background(), "u1", c.update); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("expected %s error, got %v", c.want, err)
+		}
+	}
+}
+
+func TestProfileService_SetAvatar(t *testing.T) {
+	var stored string
+	repo := &mockProfileRepo{
+		SetAvatarFunc: func(userID, contentType string, data []byte) error {
+			stored = contentType
+			return nil
+		},
+	}
+	s := newTestService(repo, nil)
+
+	encode := func(size int) []byte {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewGray(image.Rect(0, 0, size, size)))
+		return buf.Bytes()
+	}
+
+	if err := s.SetAvatar(context.Background(), "u1", encode(64)); err != nil || stored != "image/png" {
+		t.Errorf("expected PNG avatar to be stored, got %v %q", err, stored)
+	}
+	if err := s.SetAvatar(context.Background(), "u1", encode(2000)); err == nil || !strings.Contains(err.Error(), "pixels") {
+		t.Errorf("expected oversized image to be rejected, got %v", err)
+	}
+	if err := s.SetAvatar(context.Background(), "u1", []byte("<svg xmlns='http://www.w3.org/2000/svg'/>")); err == nil {
+		t.Error("expected non-image upload to be rejected")
+	}
+}
+
+func TestProfileService_GetAuthorProfile_MalformedID(t *testing.T) {
+	s := newTestService(&mockProfileRepo{}, nil)
+
+	if _, err := s.GetAuthorProfile(context.Background(), "not-a-uuid"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package roster
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// maxRosterSize bounds the size of an uploaded roster file.
+const maxRosterSize = 10 << 20
+
+var (
+	errUnauthorized = errors.New("unauthorized: admin token required")
+	errForbidden    = errors.New("forbidden: " + auth.PermUsersManage + " permission required")
+)
+
+/*
+RosterHandler handles the admin endpoints for the student roster and
+pending registrations.
+*/
+type RosterHandler struct {
+	srv *RosterService
+}
+
+/*
+NewRosterHandler creates a new RosterHandler instance with the provided service.
+
+Parameters:
+- srv: The roster service
+
+Returns:
+- *RosterHandler: A new handler instance
+*/
+func NewRosterHandler(srv *RosterService) *RosterHandler {
+	return &RosterHandler{srv: srv}
+}
+
+/*
+canManageUsers reports whether the caller may manage users. Otherwise it
+refuses the request with 401 when there is no principal and 403 when the
+principal lacks the users:manage permission.
+*/
+func canManageUsers(w http.ResponseWriter, r *http.Request) bool {
+	principal, ok := auth.FromContext(r.Context())
+	switch {
+	case !ok:
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+	case !principal.Can(auth.PermUsersManage):
+		utils.WriteError(w, errForbidden, http.StatusForbidden)
+	default:
+		return true
+	}
+	return false
+}
+
+/*
+ImportRoster handles uploads of the official student roster.
+
+HTTP Method: POST
+Endpoint: /admin/roster/import
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+- Content-Type: text/csv
+
+Request Body:
+
+	regno,name,branch,batch,email
+	22bcs1234,Jane Doe,CSE,2026,jane.doe@college.edu
+
+Response (200 OK):
+
+	{
+	  "imported": 1
+	}
+
+Returns:
+- 200 OK: All rows imported
+- 400 Bad Request: Invalid file; the error lists the first invalid lines and nothing is imported
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+*/
+func (h *RosterHandler) ImportRoster(w http.ResponseWriter, r *http.Request) {
+	if !canManageUsers(w, r) {
+		return
+	}
+
+	n, err := h.srv.ImportCSV(r.Context(), http.MaxBytesReader(w, r.Body, maxRosterSize))
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]int{"imported": n}, http.StatusOK)
+}
+
+/*
+GetPendingRegistrations lists registrations waiting for verification.
+
+HTTP Method: GET
+Endpoint: /admin/registrations/pending
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK):
+
+	[
+	  {
+	    "user_id": "user_uuid",
+	    "regno": "22bcs1234",
+	    "username": "Jane",
+	    "created_at": "2026-01-01T00:00:00Z",
+	    "on_roster": true,
+	    "roster_name": "Jane Doe",
+	    "roster_email": "jane.doe@college.edu"
+	  }
+	]
+
+Returns:
+- 200 OK: Pending registrations, oldest first
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+*/
+func (h *RosterHandler) GetPendingRegistrations(w http.ResponseWriter, r *http.Request) {
+	if !canManageUsers(w, r) {
+		return
+	}
+
+	pending, err := h.srv.GetPendingRegistrations(r.Context())
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, pending, http.StatusOK)
+}
+
+/*
+ReviewRegistration approves or rejects a pending registration.
+
+HTTP Method: PUT
+Endpoint: /admin/registrations/review?id=<user_id>&action=<approve|reject>
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK):
+
+	{
+	  "message": "registration approved"
+	}
+
+Returns:
+- 200 OK: Registration approved (account activated) or rejected (account deleted)
+- 400 Bad Request: Missing parameters, invalid action or no such pending registration
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+*/
+func (h *RosterHandler) ReviewRegistration(w http.ResponseWriter, r *http.Request) {
+	if !canManageUsers(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	action := query.Get("action")
+
+	if err := h.srv.ReviewRegistration(r.Context(), query.Get("id"), action); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "registration " + action + "d"}, http.StatusOK)
+}
@@ -0,0 +1,46 @@
+package roster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestRosterHandler_RequiresUsersManage(t *testing.T) {
+	repo := &mockRosterRepo{
+		GetPendingRegistrationsFunc: func() ([]PendingRegistration, error) { return nil, nil },
+	}
+	h := NewRosterHandler(NewRosterService(repo))
+
+	admin := auth.NewPrincipal("admin-1", auth.RoleAdmin, "session-1")
+	delegate := auth.NewPrincipal("admin-2", auth.RoleAdmin, "session-2")
+	delegate.Permissions = slices.DeleteFunc(delegate.Permissions, func(p string) bool { return p == auth.PermUsersManage })
+
+	cases := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"no principal", nil, http.StatusUnauthorized},
+		{"admin without users:manage", delegate, http.StatusForbidden},
+		{"admin", admin, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/registrations/pending", nil)
+			if c.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), c.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			h.GetPendingRegistrations(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected %d, got %d: %s", c.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
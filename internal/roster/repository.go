@@ -0,0 +1,255 @@
+package roster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+PendingRegistration is a registration waiting for verification, together
+with the roster entry of its regno when there is one.
+*/
+type PendingRegistration struct {
+	UserID      string `json:"user_id"`
+	Regno       string `json:"regno"`
+	Username    string `json:"username"`
+	CreatedAt   string `json:"created_at"`
+	OnRoster    bool   `json:"on_roster"`
+	RosterName  string `json:"roster_name,omitempty"`
+	RosterEmail string `json:"roster_email,omitempty"`
+}
+
+/*
+RosterRepo handles the student roster and pending registrations.
+*/
+type RosterRepo struct {
+	db *sql.DB
+}
+
+/*
+NewRosterRepo creates a new RosterRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *RosterRepo: A new repository instance
+*/
+func NewRosterRepo(db *sql.DB) *RosterRepo {
+	return &RosterRepo{db: db}
+}
+
+/*
+ImportEntries inserts roster entries, replacing the details of regnos that
+are already on the roster. All entries are written in one transaction.
+
+Parameters:
+- ctx: The request context
+- entries: The validated entries
+
+Returns:
+- int: The number of entries written
+- error: Any error that occurred; nothing is written on error
+*/
+func (r *RosterRepo) ImportEntries(ctx context.Context, entries []db.RosterEntry) (int, error) {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.ImportEntries", "INSERT", "placement_log_roster").End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO placement_log_roster (regno, name, branch, batch, email)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (regno) DO UPDATE SET
+			name = EXCLUDED.name, branch = EXCLUDED.branch, batch = EXCLUDED.batch,
+			email = EXCLUDED.email, imported_at = CURRENT_TIMESTAMP;
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("db error: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err = stmt.ExecContext(ctx, e.Regno, e.Name, e.Branch, e.Batch, e.Email); err != nil {
+			return 0, fmt.Errorf("failed to import %s: %v", e.Regno, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to import roster: %v", err)
+	}
+
+	return len(entries), nil
+}
+
+/*
+GetEntry returns the roster entry of a regno.
+
+Parameters:
+- ctx: The request context
+- regno: The lowercased registration number
+
+Returns:
+- *db.RosterEntry: The entry, or nil when the regno is not on the roster
+- error: Any database error
+*/
+func (r *RosterRepo) GetEntry(ctx context.Context, regno string) (*db.RosterEntry, error) {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.GetEntry", "SELECT", "placement_log_roster").End()
+
+	var e db.RosterEntry
+	err := r.db.QueryRowContext(ctx, `
+		SELECT regno, name, branch, batch, email, imported_at
+		FROM placement_log_roster
+		WHERE regno = $1;
+	`, regno).Scan(&e.Regno, &e.Name, &e.Branch, &e.Batch, &e.Email, &e.ImportedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &e, nil
+}
+
+/*
+GetEntryByEmail returns the roster entry with the given college email,
+compared case-insensitively.
+
+Parameters:
+- ctx: The request context
+- email: The email address
+
+Returns:
+- *db.RosterEntry: The entry, or nil when no student on the roster has the address
+- error: Any database error
+*/
+func (r *RosterRepo) GetEntryByEmail(ctx context.Context, email string) (*db.RosterEntry, error) {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.GetEntryByEmail", "SELECT", "placement_log_roster").End()
+
+	var e db.RosterEntry
+	err := r.db.QueryRowContext(ctx, `
+		SELECT regno, name, branch, batch, email, imported_at
+		FROM placement_log_roster
+		WHERE LOWER(email) = LOWER($1)
+		LIMIT 1;
+	`, email).Scan(&e.Regno, &e.Name, &e.Branch, &e.Batch, &e.Email, &e.ImportedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &e, nil
+}
+
+/*
+GetPendingRegistrations lists the registrations waiting for verification,
+oldest first.
+
+Returns:
+- []PendingRegistration: The pending registrations
+- error: Any database error
+*/
+func (r *RosterRepo) GetPendingRegistrations(ctx context.Context) ([]PendingRegistration, error) {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.GetPendingRegistrations", "SELECT", "placement_log_users").End()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, u.regno, u.username, u.created_at,
+			r.regno IS NOT NULL, COALESCE(r.name, ''), COALESCE(r.email, '')
+		FROM placement_log_users u
+		LEFT JOIN placement_log_roster r ON r.regno = u.regno
+		WHERE u.status = $1
+		ORDER BY u.created_at;
+	`, db.UserStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending registrations: %v", err)
+	}
+	defer rows.Close()
+
+	pending := []PendingRegistration{}
+	for rows.Next() {
+		var p PendingRegistration
+		if err = rows.Scan(&p.UserID, &p.Regno, &p.Username, &p.CreatedAt, &p.OnRoster, &p.RosterName, &p.RosterEmail); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+/*
+ApproveRegistration activates a pending registration.
+
+Parameters:
+- ctx: The request context
+- userID: The pending user's ID
+
+Returns:
+- error: "pending registration not found" when there is no such pending user
+*/
+func (r *RosterRepo) ApproveRegistration(ctx context.Context, userID string) error {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.ApproveRegistration", "UPDATE", "placement_log_users").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_users SET status = $2
+		WHERE id = $1 AND status = $3;
+	`, userID, db.UserStatusActive, db.UserStatusPending)
+
+	return checkPendingUpdated(res, err)
+}
+
+/*
+RejectRegistration deletes a pending registration so the regno can be
+registered again by its owner.
+
+Parameters:
+- ctx: The request context
+- userID: The pending user's ID
+
+Returns:
+- error: "pending registration not found" when there is no such pending user
+*/
+func (r *RosterRepo) RejectRegistration(ctx context.Context, userID string) error {
+	defer telemetry.TraceQuery(ctx, "RosterRepo.RejectRegistration", "DELETE", "placement_log_users").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM placement_log_users
+		WHERE id = $1 AND status = $2;
+	`, userID, db.UserStatusPending)
+
+	return checkPendingUpdated(res, err)
+}
+
+func checkPendingUpdated(res sql.Result, err error) error {
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("pending registration not found")
+	}
+
+	return nil
+}
+
+// Ensure RosterRepo implements RosterRepository
+var _ RosterRepository = (*RosterRepo)(nil)
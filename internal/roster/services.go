@@ -0,0 +1,204 @@
+package roster
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// maxReportedErrors caps the row errors returned for a rejected import.
+const maxReportedErrors = 20
+
+// rosterColumns are the required CSV header names, in any order.
+var rosterColumns = []string{"regno", "name", "branch", "batch", "email"}
+
+// Define RosterRepository interface for testability
+//go:generate mockgen -destination=mock_roster_repo.go -package=roster . RosterRepository
+
+type RosterRepository interface {
+	ImportEntries(ctx context.Context, entries []db.RosterEntry) (int, error)
+	GetPendingRegistrations(ctx context.Context) ([]PendingRegistration, error)
+	ApproveRegistration(ctx context.Context, userID string) error
+	RejectRegistration(ctx context.Context, userID string) error
+}
+
+/*
+RosterService handles roster imports and the review of pending registrations.
+*/
+type RosterService struct {
+	repo RosterRepository
+}
+
+/*
+NewRosterService creates a new RosterService instance with the provided repository.
+
+Parameters:
+- repo: The roster repository
+
+Returns:
+- *RosterService: A new service instance
+*/
+func NewRosterService(repo RosterRepository) *RosterService {
+	return &RosterService{repo: repo}
+}
+
+/*
+ImportCSV imports the official roster from CSV.
+
+Parameters:
+- ctx: The request context
+- r: CSV with a header row naming the columns regno, name, branch, batch and email (any order; extra columns are ignored)
+
+Returns:
+- int: The number of entries imported
+- error: Any error that occurred
+
+The function:
+1. Maps the header columns
+2. Validates every row: regno format, non-empty name/branch/batch, a bare email address, no duplicate regnos
+3. Rejects the whole file if any row is invalid, listing the first problems by line
+4. Otherwise upserts all entries in one transaction
+*/
+func (s *RosterService) ImportCSV(ctx context.Context, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, fmt.Errorf("roster file is empty")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading roster: %v", err)
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range rosterColumns {
+		if _, ok := index[col]; !ok {
+			return 0, fmt.Errorf("roster header must include %s", strings.Join(rosterColumns, ", "))
+		}
+	}
+
+	var entries []db.RosterEntry
+	var errs []error
+	seen := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return 0, fmt.Errorf("error reading roster: %v", err)
+		}
+
+		field := func(col string) string {
+			return strings.TrimSpace(record[index[col]])
+		}
+
+		entry, rowErr := parseEntry(field)
+		if rowErr == nil {
+			if first, dup := seen[entry.Regno]; dup {
+				rowErr = fmt.Errorf("duplicate regno %s (first on line %d)", entry.Regno, first)
+			}
+			seen[entry.Regno] = line
+		}
+
+		if rowErr != nil {
+			if len(errs) < maxReportedErrors {
+				errs = append(errs, fmt.Errorf("line %d: %v", line, rowErr))
+			}
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(errs) > 0 {
+		return 0, fmt.Errorf("roster rejected: %w", errors.Join(errs...))
+	}
+
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("roster has no entries")
+	}
+
+	return s.repo.ImportEntries(ctx, entries)
+}
+
+func parseEntry(field func(col string) string) (db.RosterEntry, error) {
+	regno, ok := utils.NormalizeRegno(field("regno"))
+	if !ok {
+		return db.RosterEntry{}, fmt.Errorf("not a valid registration number %q", field("regno"))
+	}
+
+	email, err := mail.ValidateAddress(field("email"))
+	if err != nil {
+		return db.RosterEntry{}, fmt.Errorf("%v %q", err, field("email"))
+	}
+
+	entry := db.RosterEntry{
+		Regno:  regno,
+		Name:   field("name"),
+		Branch: strings.ToUpper(field("branch")),
+		Batch:  field("batch"),
+		Email:  email,
+	}
+
+	if entry.Name == "" || entry.Branch == "" || entry.Batch == "" {
+		return db.RosterEntry{}, fmt.Errorf("name, branch and batch are required")
+	}
+
+	return entry, nil
+}
+
+/*
+GetPendingRegistrations lists the registrations waiting for verification.
+
+Returns:
+- []PendingRegistration: The pending registrations, oldest first
+- error: Any error that occurred during retrieval
+*/
+func (s *RosterService) GetPendingRegistrations(ctx context.Context) ([]PendingRegistration, error) {
+	return s.repo.GetPendingRegistrations(ctx)
+}
+
+/*
+ReviewRegistration approves or rejects a pending registration.
+
+Parameters:
+- ctx: The request context
+- userID: The pending user's ID
+- action: "approve" activates the account, "reject" deletes it
+
+Returns:
+- error: Any error that occurred
+
+Possible errors:
+- "user ID is required": Missing user ID
+- "invalid action: must be 'approve' or 'reject'": Unknown action
+- "pending registration not found": No such pending user
+*/
+func (s *RosterService) ReviewRegistration(ctx context.Context, userID, action string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	switch action {
+	case "approve":
+		return s.repo.ApproveRegistration(ctx, userID)
+	case "reject":
+		return s.repo.RejectRegistration(ctx, userID)
+	default:
+		return fmt.Errorf("invalid action: must be 'approve' or 'reject'")
+	}
+}
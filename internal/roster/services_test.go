@@ -0,0 +1,122 @@
+package roster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+)
+
+type mockRosterRepo struct {
+	ImportEntriesFunc           func(entries []db.RosterEntry) (int, error)
+	GetPendingRegistrationsFunc func() ([]PendingRegistration, error)
+	ApproveRegistrationFunc     func(userID string) error
+	RejectRegistrationFunc      func(userID string) error
+}
+
+func (m *mockRosterRepo) ImportEntries(ctx context.Context, entries []db.RosterEntry) (int, error) {
+	return m.ImportEntriesFunc(entries)
+}
+func (m *mockRosterRepo) GetPendingRegistrations(ctx context.Context) ([]PendingRegistration, error) {
+	return m.GetPendingRegistrationsFunc()
+}
+func (m *mockRosterRepo) ApproveRegistration(ctx context.Context, userID string) error {
+	return m.ApproveRegistrationFunc(userID)
+}
+func (m *mockRosterRepo) RejectRegistration(ctx context.Context, userID string) error {
+	return m.RejectRegistrationFunc(userID)
+}
+
+func TestRosterService_ImportCSV(t *testing.T) {
+	var imported []db.RosterEntry
+	repo := &mockRosterRepo{
+		ImportEntriesFunc: func(entries []db.RosterEntry) (int, error) {
+			imported = entries
+			return len(entries), nil
+		},
+	}
+	s := NewRosterService(repo)
+
+	n, err := s.ImportCSV(context.Background(), strings.NewReader("Email,RegNo,Name,Branch,Batch,Phone\n"+
+		"Jane.Doe@College.edu, 22BCS1234 ,Jane Doe,cse,2026,555\n"+
+		"john@college.edu,22bec0042,John Roe,ECE,2026,\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries imported, got %d", n)
+	}
+	want := db.RosterEntry{Regno: "22bcs1234", Name: "Jane Doe", Branch: "CSE", Batch: "2026", Email: "jane.doe@college.edu"}
+	if imported[0] != want {
+		t.Errorf("expected %+v, got %+v", want, imported[0])
+	}
+}
+
+func TestRosterService_ImportCSV_Rejected(t *testing.T) {
+	repo := &mockRosterRepo{
+		ImportEntriesFunc: func(entries []db.RosterEntry) (int, error) {
+			t.Fatal("expected nothing to be imported")
+			return 0, nil
+		},
+	}
+	s := NewRosterService(repo)
+
+	cases := []struct {
+		name    string
+		csv     string
+		wantErr []string
+	}{
+		{"empty", "", []string{"roster file is empty"}},
+		{"missing column", "regno,name,branch,email\n", []string{"header must include"}},
+		{"no rows", "regno,name,branch,batch,email\n", []string{"no entries"}},
+		{"invalid rows", "regno,name,branch,batch,email\n" +
+			"22bcs1234,Jane,CSE,2026,jane@college.edu\n" +
+			"bogus,Bob,CSE,2026,bob@college.edu\n" +
+			"22bcs1235,Ann,CSE,2026,Ann <ann@college.edu>\n" +
+			"22BCS1234,Jane Again,CSE,2026,jane2@college.edu\n" +
+			"22bcs1236,,CSE,2026,x@college.edu\n",
+			[]string{"line 3: not a valid registration number", "line 4: not a valid email address", "line 5: duplicate regno 22bcs1234 (first on line 2)", "line 6: name, branch and batch are required"}},
+	}
+
+	for _, c := range cases {
+		_, err := s.ImportCSV(context.Background(), strings.NewReader(c.csv))
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+			continue
+		}
+		for _, want := range c.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected error containing %q, got %v", c.name, want, err)
+			}
+		}
+	}
+}
+
+func TestRosterService_ReviewRegistration(t *testing.T) {
+	var approved, rejected string
+	repo := &mockRosterRepo{
+		ApproveRegistrationFunc: func(userID string) error {
+			approved = userID
+			return nil
+		},
+		RejectRegistrationFunc: func(userID string) error {
+			rejected = userID
+			return nil
+		},
+	}
+	s := NewRosterService(repo)
+
+	if err := s.ReviewRegistration(context.Background(), "u1", "approve"); err != nil || approved != "u1" {
+		t.Errorf("expected u1 to be approved, got %q %v", approved, err)
+	}
+	if err := s.ReviewRegistration(context.Background(), "u2", "reject"); err != nil || rejected != "u2" {
+		t.Errorf("expected u2 to be rejected, got %q %v", rejected, err)
+	}
+	if err := s.ReviewRegistration(context.Background(), "u3", "ban"); err == nil {
+		t.Error("expected invalid action error")
+	}
+	if err := s.ReviewRegistration(context.Background(), "", "approve"); err == nil {
+		t.Error("expected missing user ID error")
+	}
+}
@@ -0,0 +1,368 @@
+package sso
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	userauth "github.com/varnit-ta/PlacementLog/internal/userAuth"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/oidc"
+	"github.com/varnit-ta/PlacementLog/pkg/session"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var errUnauthorized = errors.New("unauthorized: user authentication required")
+
+const (
+	// bindingCookieName is the HttpOnly cookie binding a login to the browser that started it.
+	bindingCookieName = "placementlog_oidc_binding"
+	// bindingCookiePath limits the binding cookie to the SSO endpoints.
+	bindingCookiePath = "/auth/oidc"
+)
+
+/*
+CookieOptions are the attributes of the browser binding cookie; they follow
+the session cookie settings.
+*/
+type CookieOptions struct {
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+/*
+SSOHandler handles single sign-on HTTP requests.
+*/
+type SSOHandler struct {
+	srv      *SSOService
+	sessions *session.Manager
+	cookie   CookieOptions
+}
+
+/*
+callbackRequest represents the JSON payload of a login callback.
+*/
+type callbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+/*
+loginResponse is the response of a completed login; it matches the response
+of /auth/login.
+*/
+type loginResponse struct {
+	UserID    string `json:"userid"`
+	Regno     string `json:"regno"`
+	Username  string `json:"username"`
+	Token     string `json:"token,omitempty"`
+	Status    string `json:"status,omitempty"`
+	TwoFactor string `json:"two_factor,omitempty"`
+	Linked    bool   `json:"linked,omitempty"`
+}
+
+/*
+NewSSOHandler creates a new SSOHandler instance with the provided service.
+
+Parameters:
+- srv: The SSO service
+- sessions: Sets the session cookies of logins in cookie session mode (nil when disabled)
+- cookie: The attributes of the browser binding cookie
+
+Returns:
+- *SSOHandler: A new handler instance
+*/
+func NewSSOHandler(srv *SSOService, sessions *session.Manager, cookie CookieOptions) *SSOHandler {
+	return &SSOHandler{srv: srv, sessions: sessions, cookie: cookie}
+}
+
+/*
+setBinding stores the browser binding of a login in an HttpOnly cookie that
+expires with the login state; an empty binding removes the cookie.
+*/
+func (h *SSOHandler) setBinding(w http.ResponseWriter, binding string) {
+	c := &http.Cookie{
+		Name:     bindingCookieName,
+		Value:    binding,
+		Path:     bindingCookiePath,
+		Domain:   h.cookie.Domain,
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.cookie.SameSite,
+	}
+
+	if binding == "" {
+		c.MaxAge = -1
+	} else {
+		c.Expires = time.Now().Add(h.srv.stateTTL)
+	}
+
+	http.SetCookie(w, c)
+}
+
+func writeSSOError(w http.ResponseWriter, err error) {
+	var suspended *auth.SuspendedError
+	switch {
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrNotLinked):
+		utils.WriteError(w, err, http.StatusNotFound)
+	case errors.Is(err, ErrInvalidState), errors.Is(err, ErrBrowserMismatch), errors.Is(err, oidc.ErrInvalidIDToken):
+		utils.WriteError(w, err, http.StatusUnauthorized)
+	case errors.Is(err, ErrLinkRequired), errors.Is(err, ErrIdentityTaken), errors.Is(err, ErrAlreadyLinked):
+		utils.WriteError(w, err, http.StatusConflict)
+	case errors.Is(err, ErrEmailNotAllowed), errors.Is(err, ErrWrongStudent), errors.Is(err, ErrNotOnRoster),
+		errors.Is(err, userauth.ErrAccountPending), errors.Is(err, auth.ErrPasswordResetRequired), errors.As(err, &suspended):
+		utils.WriteError(w, err, http.StatusForbidden)
+	default:
+		utils.WriteError(w, err)
+	}
+}
+
+/*
+Providers lists the configured SSO providers.
+
+HTTP Method: GET
+Endpoint: /auth/oidc/providers
+
+Response (200 OK):
+
+	{
+	  "providers": ["college"]
+	}
+*/
+func (h *SSOHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, map[string][]string{"providers": h.srv.Providers()}, http.StatusOK)
+}
+
+/*
+Start begins an SSO login. The client sends the browser to the returned
+URL; the provider then redirects it to the configured redirect URL with
+"state" and "code" query parameters, which the client posts to the callback.
+The response sets an HttpOnly cookie binding the login to this browser; the
+callback is refused without it, so clients on another origin must send both
+requests with credentials.
+
+HTTP Method: POST
+Endpoint: /auth/oidc/{provider}/start
+
+Response (200 OK):
+
+	{
+	  "authorization_url": "https://accounts.google.com/o/oauth2/v2/auth?..."
+	}
+
+Returns:
+- 200 OK: The authorization URL
+- 404 Not Found: Unknown provider
+*/
+func (h *SSOHandler) Start(w http.ResponseWriter, r *http.Request) {
+	url, binding, err := h.srv.Start(r.Context(), chi.URLParam(r, "provider"), "")
+	if err != nil {
+		writeSSOError(w, err)
+		return
+	}
+
+	h.setBinding(w, binding)
+
+	utils.WriteJSON(w, map[string]string{"authorization_url": url}, http.StatusOK)
+}
+
+/*
+Callback completes an SSO login, or the linking started at
+/users/me/oidc/{provider}/link.
+
+HTTP Method: POST
+Endpoint: /auth/oidc/{provider}/callback
+
+Request Body:
+
+	{
+	  "state": "state from the redirect",
+	  "code": "code from the redirect"
+	}
+
+Response (200 OK, or 201 Created for a new account):
+
+	{
+	  "userid": "user_id",
+	  "regno": "22bcs1234",
+	  "username": "John Doe",
+	  "token": "jwt_token_here"
+	}
+
+As on /auth/login, "two_factor" is set when the token is a challenge token,
+and in cookie session mode the token is set as a cookie. "linked" is true
+when the provider account was linked to an existing account. A new account
+waiting for verification is answered with 202 Accepted, "status": "pending"
+and no token.
+
+Returns:
+- 200 OK: Logged in
+- 201 Created: Registered and logged in
+- 202 Accepted: Registered; the account is pending verification
+- 400 Bad Request: Missing state or code, or the provider refused the code
+- 401 Unauthorized: Unknown, used or expired state, no binding cookie of the browser that started the login, or an invalid ID token
+- 403 Forbidden: The email does not belong to the student, the regno is not on the roster, or the account is pending, suspended or must reset its password
+- 404 Not Found: Unknown provider
+- 409 Conflict: A password account has the regno and must link the provider first, or the provider account is linked elsewhere
+*/
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var payload callbackRequest
+
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	binding := ""
+	if c, err := r.Cookie(bindingCookieName); err == nil {
+		binding = c.Value
+	}
+
+	result, err := h.srv.Callback(r.Context(), chi.URLParam(r, "provider"), payload.State, payload.Code, binding)
+	if binding != "" {
+		h.setBinding(w, "")
+	}
+	if err != nil {
+		writeSSOError(w, err)
+		return
+	}
+
+	resp := loginResponse{
+		UserID:    result.User.ID,
+		Regno:     result.User.Regno,
+		Username:  result.User.Username,
+		TwoFactor: result.SecondFactor,
+		Linked:    result.Linked,
+	}
+
+	if result.User.Status == db.UserStatusPending {
+		resp.Status = result.User.Status
+		utils.WriteJSON(w, resp, http.StatusAccepted)
+		return
+	}
+
+	if resp.Token, err = h.sessions.Issue(w, result.Token); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Created {
+		status = http.StatusCreated
+	}
+
+	utils.WriteJSON(w, resp, status)
+}
+
+/*
+Link starts linking a provider account to the logged-in user, after which
+the user can log in through the provider. It continues like a login: the
+browser goes to the returned URL and the client posts the state and code to
+/auth/oidc/{provider}/callback. The provider account's email must map to
+the user's regno. As on /auth/oidc/{provider}/start, the response sets the
+browser binding cookie.
+
+HTTP Method: POST
+Endpoint: /users/me/oidc/{provider}/link
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Response (200 OK):
+
+	{
+	  "authorization_url": "https://accounts.google.com/o/oauth2/v2/auth?..."
+	}
+
+Returns:
+- 200 OK: The authorization URL
+- 401 Unauthorized: Missing or invalid user token
+- 404 Not Found: Unknown provider
+*/
+func (h *SSOHandler) Link(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	url, binding, err := h.srv.Start(r.Context(), chi.URLParam(r, "provider"), userID)
+	if err != nil {
+		writeSSOError(w, err)
+		return
+	}
+
+	h.setBinding(w, binding)
+
+	utils.WriteJSON(w, map[string]string{"authorization_url": url}, http.StatusOK)
+}
+
+/*
+Identities lists the provider accounts linked to the logged-in user.
+
+HTTP Method: GET
+Endpoint: /users/me/oidc
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Response (200 OK):
+
+	[
+	  {
+	    "provider": "college",
+	    "email": "22bcs1234@college.example",
+	    "linked_at": "2026-01-01T10:00:00Z",
+	    "last_login_at": "2026-01-02T09:00:00Z"
+	  }
+	]
+*/
+func (h *SSOHandler) Identities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.srv.Identities(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, identities, http.StatusOK)
+}
+
+/*
+Unlink removes the logged-in user's account of a provider. The password
+still works afterwards; accounts created through SSO can set one with a
+password reset.
+
+HTTP Method: DELETE
+Endpoint: /users/me/oidc/{provider}
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Returns:
+- 200 OK: Unlinked
+- 401 Unauthorized: Missing or invalid user token
+- 404 Not Found: Unknown provider, or no account of it is linked
+*/
+func (h *SSOHandler) Unlink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.srv.Unlink(r.Context(), userID, chi.URLParam(r, "provider")); err != nil {
+		writeSSOError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "provider unlinked"}, http.StatusOK)
+}
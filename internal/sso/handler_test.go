@@ -0,0 +1,155 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/oidc"
+)
+
+/*
+newHandlerTest wires an SSO handler to a test service whose states are kept
+in memory, so logins go through Start and Callback like in a browser.
+*/
+func newHandlerTest(users ...*db.User) (http.Handler, *ssoTest) {
+	tc := newSSOTest(studentClaims(), users...)
+	tc.provider.autoLink = true
+
+	states := map[string]db.OIDCState{}
+	tc.repo.CreateStateFunc = func(stateHash string, state db.OIDCState, ttl time.Duration) error {
+		states[stateHash] = state
+		return nil
+	}
+	tc.repo.ConsumeStateFunc = func(stateHash string) (*db.OIDCState, error) {
+		st, ok := states[stateHash]
+		if !ok {
+			return nil, nil
+		}
+		delete(states, stateHash)
+		return &st, nil
+	}
+
+	var nonce, challenge string
+	tc.provider.idp = &mockIdentityProvider{
+		AuthCodeURLFunc: func(ctx context.Context, state, n, c string) (string, error) {
+			nonce, challenge = n, c
+			return "https://idp.example/authorize?state=" + state, nil
+		},
+		ExchangeFunc: func(ctx context.Context, code, verifier, n string) (*oidc.Claims, error) {
+			if code != "code" || n != nonce || oidc.CodeChallenge(verifier) != challenge {
+				return nil, errors.New("code exchange failed: invalid_grant")
+			}
+			return studentClaims(), nil
+		},
+	}
+
+	h := NewSSOHandler(tc.s, nil, CookieOptions{Secure: true, SameSite: http.SameSiteLaxMode})
+	r := chi.NewRouter()
+	r.Post("/auth/oidc/{provider}/start", h.Start)
+	r.Post("/auth/oidc/{provider}/callback", h.Callback)
+
+	return r, tc
+}
+
+// startLogin starts a login and returns the state and the binding cookie.
+func startLogin(t *testing.T, r http.Handler) (string, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/oidc/college/start", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data struct {
+			AuthorizationURL string `json:"authorization_url"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	_, state, _ := strings.Cut(body.Data.AuthorizationURL, "state=")
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == bindingCookieName {
+			if !c.HttpOnly || !c.Secure || c.Path != bindingCookiePath || c.Value == "" {
+				t.Fatalf("unexpected binding cookie %+v", c)
+			}
+			return state, c
+		}
+	}
+	t.Fatal("start: no binding cookie set")
+	return "", nil
+}
+
+func callback(r http.Handler, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/oidc/college/callback",
+		strings.NewReader(`{"state": "`+state+`", "code": "code"}`))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSSOHandler_BrowserBinding(t *testing.T) {
+	t.Run("same browser", func(t *testing.T) {
+		r, _ := newHandlerTest()
+		state, cookie := startLogin(t, r)
+
+		rec := callback(r, state, cookie)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		cleared := false
+		for _, c := range rec.Result().Cookies() {
+			cleared = cleared || (c.Name == bindingCookieName && c.MaxAge < 0)
+		}
+		if !cleared {
+			t.Error("expected the binding cookie to be cleared")
+		}
+	})
+
+	t.Run("no binding cookie", func(t *testing.T) {
+		r, _ := newHandlerTest()
+		state, _ := startLogin(t, r)
+
+		if rec := callback(r, state, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("another browser's cookie", func(t *testing.T) {
+		r, _ := newHandlerTest()
+		attackerState, _ := startLogin(t, r)
+		_, victimCookie := startLogin(t, r)
+
+		if rec := callback(r, attackerState, victimCookie); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestSSOHandler_PasswordResetRequired(t *testing.T) {
+	r, _ := newHandlerTest(&db.User{ID: testUserID, Regno: "22bcs1234", Status: db.UserStatusActive, PasswordResetRequired: true})
+	state, cookie := startLogin(t, r)
+
+	rec := callback(r, state, cookie)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), auth.ErrPasswordResetRequired.Error()) || strings.Contains(rec.Body.String(), "token") {
+		t.Errorf("expected the password reset error and no token, got %s", rec.Body.String())
+	}
+}
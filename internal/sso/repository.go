@@ -0,0 +1,332 @@
+package sso
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+SSORepo stores in-flight OpenID Connect logins and the provider accounts
+linked to users.
+*/
+type SSORepo struct {
+	db *sql.DB
+}
+
+/*
+NewSSORepo creates a new SSORepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *SSORepo: A new repository instance
+*/
+func NewSSORepo(db *sql.DB) *SSORepo {
+	return &SSORepo{db: db}
+}
+
+// userColumns are the user fields needed to decide whether a login is allowed.
+const userColumns = `
+	u.id, u.regno, u.created_at, u.username, u.status,
+	u.suspended_at, u.suspended_until, COALESCE(u.suspension_reason, ''), u.password_reset_required`
+
+func scanUser(row *sql.Row) (*db.User, error) {
+	var user db.User
+	var suspendedAt sql.NullTime
+	var suspension db.Suspension
+
+	err := row.Scan(
+		&user.ID,
+		&user.Regno,
+		&user.CreatedAt,
+		&user.Username,
+		&user.Status,
+		&suspendedAt,
+		&suspension.Until,
+		&suspension.Reason,
+		&user.PasswordResetRequired,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	if suspendedAt.Valid {
+		suspension.Since = suspendedAt.Time
+		user.Suspension = &suspension
+	}
+
+	return &user, nil
+}
+
+/*
+CreateState stores a new in-flight login and removes expired ones.
+
+Parameters:
+- ctx: The request context
+- stateHash: Hex SHA-256 of the state sent to the provider and the browser binding
+- state: The provider, nonce, PKCE code verifier and, when linking, the user
+- ttl: How long the login may take
+
+Returns:
+- error: Any database error
+*/
+func (r *SSORepo) CreateState(ctx context.Context, stateHash string, state db.OIDCState, ttl time.Duration) error {
+	defer telemetry.TraceQuery(ctx, "SSORepo.CreateState", "INSERT", "placement_log_oidc_states").End()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM placement_log_oidc_states WHERE expires_at < CURRENT_TIMESTAMP;`); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_oidc_states (state_hash, provider, nonce, code_verifier, link_user_id, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, CURRENT_TIMESTAMP + make_interval(secs => $6));
+	`, stateHash, state.Provider, state.Nonce, state.CodeVerifier, state.LinkUserID, ttl.Seconds())
+
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+/*
+ConsumeState deletes an in-flight login and returns it, so every state can
+be used once.
+
+Parameters:
+- ctx: The request context
+- stateHash: Hex SHA-256 of the state returned by the provider and the browser binding
+
+Returns:
+- *db.OIDCState: The login, or nil when the state is unknown, used or expired
+- error: Any database error
+*/
+func (r *SSORepo) ConsumeState(ctx context.Context, stateHash string) (*db.OIDCState, error) {
+	defer telemetry.TraceQuery(ctx, "SSORepo.ConsumeState", "DELETE", "placement_log_oidc_states").End()
+
+	var state db.OIDCState
+	var linkUserID sql.NullString
+	var expired bool
+
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM placement_log_oidc_states
+		WHERE state_hash = $1
+		RETURNING provider, nonce, code_verifier, link_user_id, expires_at <= CURRENT_TIMESTAMP;
+	`, stateHash).Scan(&state.Provider, &state.Nonce, &state.CodeVerifier, &linkUserID, &expired)
+
+	if err == sql.ErrNoRows || (err == nil && expired) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	state.LinkUserID = linkUserID.String
+
+	return &state, nil
+}
+
+/*
+GetUserByIdentity returns the user a provider account is linked to.
+
+Parameters:
+- ctx: The request context
+- provider: The provider name
+- subject: The provider's user ID
+
+Returns:
+- *db.User: The user, or nil when the provider account is not linked
+- error: Any database error
+*/
+func (r *SSORepo) GetUserByIdentity(ctx context.Context, provider, subject string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "SSORepo.GetUserByIdentity", "SELECT", "placement_log_user_identities").End()
+
+	return scanUser(r.db.QueryRowContext(ctx, `
+		SELECT`+userColumns+`
+		FROM placement_log_user_identities i
+		JOIN placement_log_users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2;
+	`, provider, subject))
+}
+
+/*
+GetUserByRegno returns the user with a registration number.
+
+Parameters:
+- ctx: The request context
+- regno: The lowercased registration number
+
+Returns:
+- *db.User: The user, or nil when no account has the regno
+- error: Any database error
+*/
+func (r *SSORepo) GetUserByRegno(ctx context.Context, regno string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "SSORepo.GetUserByRegno", "SELECT", "placement_log_users").End()
+
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT`+userColumns+` FROM placement_log_users u WHERE u.regno = $1;`, regno))
+}
+
+/*
+GetUser returns a user by ID.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.User: The user, or nil when it does not exist
+- error: Any database error
+*/
+func (r *SSORepo) GetUser(ctx context.Context, userID string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "SSORepo.GetUser", "SELECT", "placement_log_users").End()
+
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT`+userColumns+` FROM placement_log_users u WHERE u.id = $1;`, userID))
+}
+
+/*
+LinkIdentity links a provider account to a user.
+
+Parameters:
+- ctx: The request context
+- identity: The provider, subject, user and email
+
+Returns:
+- error: Any error that occurred
+
+Possible errors:
+- ErrIdentityTaken: The provider account is linked to another user
+- ErrAlreadyLinked: The user already has an account of this provider
+*/
+func (r *SSORepo) LinkIdentity(ctx context.Context, identity db.UserIdentity) error {
+	defer telemetry.TraceQuery(ctx, "SSORepo.LinkIdentity", "INSERT", "placement_log_user_identities").End()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4);
+	`, identity.Provider, identity.Subject, identity.UserID, identity.Email)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		if pqErr.Constraint == "placement_log_user_identities_pkey" {
+			return ErrIdentityTaken
+		}
+		return ErrAlreadyLinked
+	}
+
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+/*
+TouchIdentity records a login through a linked provider account and keeps
+its email current.
+
+Parameters:
+- ctx: The request context
+- provider: The provider name
+- subject: The provider's user ID
+- email: The email from the latest login
+
+Returns:
+- error: Any database error
+*/
+func (r *SSORepo) TouchIdentity(ctx context.Context, provider, subject, email string) error {
+	defer telemetry.TraceQuery(ctx, "SSORepo.TouchIdentity", "UPDATE", "placement_log_user_identities").End()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_user_identities
+		SET last_login_at = CURRENT_TIMESTAMP, email = $3
+		WHERE provider = $1 AND subject = $2;
+	`, provider, subject, email)
+
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+/*
+UnlinkIdentity removes the provider account of a user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- provider: The provider name
+
+Returns:
+- error: ErrNotLinked when the user has no account of the provider, or a database error
+*/
+func (r *SSORepo) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	defer telemetry.TraceQuery(ctx, "SSORepo.UnlinkIdentity", "DELETE", "placement_log_user_identities").End()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM placement_log_user_identities WHERE user_id = $1 AND provider = $2;`, userID, provider)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotLinked
+	}
+
+	return nil
+}
+
+/*
+ListIdentities returns the provider accounts linked to a user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- []db.UserIdentity: The linked accounts, oldest first
+- error: Any database error
+*/
+func (r *SSORepo) ListIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error) {
+	defer telemetry.TraceQuery(ctx, "SSORepo.ListIdentities", "SELECT", "placement_log_user_identities").End()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT provider, email, created_at, last_login_at
+		FROM placement_log_user_identities
+		WHERE user_id = $1
+		ORDER BY created_at;
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer rows.Close()
+
+	identities := []db.UserIdentity{}
+	for rows.Next() {
+		i := db.UserIdentity{UserID: userID}
+		if err = rows.Scan(&i.Provider, &i.Email, &i.CreatedAt, &i.LastLoginAt); err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		identities = append(identities, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return identities, nil
+}
+
+// Ensure SSORepo implements SSORepository
+var _ SSORepository = (*SSORepo)(nil)
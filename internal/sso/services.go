@@ -0,0 +1,560 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	userauth "github.com/varnit-ta/PlacementLog/internal/userAuth"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/oidc"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var (
+	// ErrUnknownProvider is returned for provider names that are not configured.
+	ErrUnknownProvider = errors.New("unknown sso provider")
+	// ErrInvalidState is returned when the callback's state is unknown, used or expired.
+	ErrInvalidState = errors.New("invalid or expired login state")
+	// ErrBrowserMismatch is returned when the callback comes without the binding set by Start.
+	ErrBrowserMismatch = errors.New("the login must be completed in the browser that started it")
+	// ErrEmailNotAllowed is returned when the provider email does not belong to a student.
+	ErrEmailNotAllowed = errors.New("this account's email does not belong to a student")
+	// ErrLinkRequired is returned when an unlinked provider account matches an existing account.
+	ErrLinkRequired = errors.New("an account with this registration number already exists; log in with your password and link the provider from your account settings")
+	// ErrWrongStudent is returned when a user tries to link another student's provider account.
+	ErrWrongStudent = errors.New("the provider account belongs to another student")
+	// ErrIdentityTaken is returned when the provider account is linked to another user.
+	ErrIdentityTaken = errors.New("this provider account is already linked to another user")
+	// ErrAlreadyLinked is returned when the user already has an account of the provider.
+	ErrAlreadyLinked = errors.New("an account of this provider is already linked; unlink it first")
+	// ErrNotLinked is returned when unlinking a provider that is not linked.
+	ErrNotLinked = errors.New("no account of this provider is linked")
+	// ErrNotOnRoster is returned when the roster registration mode refuses a new account.
+	ErrNotOnRoster = errors.New("registration number is not on the student roster")
+)
+
+// Define SSORepository interface for testability
+//go:generate mockgen -destination=mock_sso_repo.go -package=sso . SSORepository
+
+type SSORepository interface {
+	CreateState(ctx context.Context, stateHash string, state db.OIDCState, ttl time.Duration) error
+	ConsumeState(ctx context.Context, stateHash string) (*db.OIDCState, error)
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*db.User, error)
+	GetUserByRegno(ctx context.Context, regno string) (*db.User, error)
+	GetUser(ctx context.Context, userID string) (*db.User, error)
+	LinkIdentity(ctx context.Context, identity db.UserIdentity) error
+	TouchIdentity(ctx context.Context, provider, subject, email string) error
+	UnlinkIdentity(ctx context.Context, userID, provider string) error
+	ListIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error)
+}
+
+/*
+IdentityProvider is an OpenID Connect provider (see oidc.Provider).
+*/
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error)
+}
+
+/*
+TokenIssuer issues the token of a login, together with its second factor
+state (see userauth.TokenIssuer).
+*/
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, accountType, accountID string) (string, string, error)
+}
+
+/*
+RosterLookup finds roster entries by regno or college email; both return
+nil when there is no such entry.
+*/
+type RosterLookup interface {
+	GetEntry(ctx context.Context, regno string) (*db.RosterEntry, error)
+	GetEntryByEmail(ctx context.Context, email string) (*db.RosterEntry, error)
+}
+
+/*
+AccountCreator creates user accounts (see userauth.UserAuthRepo.Register).
+*/
+type AccountCreator interface {
+	Register(ctx context.Context, regno, username, pass, status string) (*db.User, error)
+}
+
+/*
+provider is a configured provider with its email rules.
+*/
+type provider struct {
+	idp        IdentityProvider
+	pattern    *regexp.Regexp
+	trustEmail bool
+	autoLink   bool
+}
+
+/*
+LoginResult is the outcome of a completed SSO login.
+*/
+type LoginResult struct {
+	// Token is empty while a new account is pending verification.
+	Token string
+	// SecondFactor is the second factor state of Token (see TokenIssuer).
+	SecondFactor string
+	User         *db.User
+	// Created is set when the login registered a new account.
+	Created bool
+	// Linked is set when the login linked the provider account to an existing account.
+	Linked bool
+}
+
+/*
+SSOService implements single sign-on through OpenID Connect providers.
+Provider accounts are mapped to students by the regno derived from their
+email, and are linked to user accounts, so later logins find the account by
+the provider's stable subject even if the email changes.
+*/
+type SSOService struct {
+	repo      SSORepository
+	providers map[string]*provider
+	names     []string
+	tokens    TokenIssuer
+	roster    RosterLookup
+	accounts  AccountCreator
+	account   config.AccountConfig
+	stateTTL  time.Duration
+	now       func() time.Time
+}
+
+/*
+NewSSOService creates a new SSOService instance for the configured providers.
+
+Parameters:
+- repo: The SSO repository
+- tokens: Issues the tokens of logins, with a challenge when a second factor is due
+- roster: The student roster, used to map emails and to check new accounts
+- accounts: Creates the accounts of students logging in for the first time
+- cfg: The providers and the login state lifetime
+- account: The registration mode applied to new accounts
+
+Returns:
+- *SSOService: A new service instance
+*/
+func NewSSOService(repo SSORepository, tokens TokenIssuer, roster RosterLookup, accounts AccountCreator, cfg config.SSOConfig, account config.AccountConfig) *SSOService {
+	s := &SSOService{
+		repo:      repo,
+		providers: make(map[string]*provider),
+		tokens:    tokens,
+		roster:    roster,
+		accounts:  accounts,
+		account:   account,
+		stateTTL:  cfg.StateTTL,
+		now:       time.Now,
+	}
+
+	for _, p := range cfg.Providers {
+		scopes := p.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{"email", "profile"}
+		}
+
+		pr := &provider{
+			idp: oidc.NewProvider(oidc.Config{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  p.RedirectURL,
+				Scopes:       scopes,
+			}),
+			trustEmail: p.TrustEmail,
+			autoLink:   p.AutoLink,
+		}
+		// Patterns are checked by config.Validate.
+		if p.EmailPattern != "" {
+			pr.pattern = regexp.MustCompile(p.EmailPattern)
+		}
+
+		s.providers[p.Name] = pr
+		s.names = append(s.names, p.Name)
+	}
+
+	return s
+}
+
+/*
+Providers returns the names of the configured providers, for login buttons.
+*/
+func (s *SSOService) Providers() []string {
+	if s.names == nil {
+		return []string{}
+	}
+	return s.names
+}
+
+/*
+stateKey is the key a login state is stored under: the hash of the state
+and of the browser binding of the login. Without the binding the state can
+neither be found nor consumed.
+*/
+func stateKey(state, binding string) string {
+	sum := sha256.Sum256([]byte(binding + ":" + state))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Start begins a login or, with linkUserID, the linking of a provider account
+to a logged-in user.
+
+Parameters:
+- ctx: Bounds the discovery request to the provider
+- name: The provider name
+- linkUserID: The user linking the provider, or empty for a login
+
+Returns:
+- string: The authorization URL to send the browser to
+- string: The browser binding, kept by the browser that started the login and required by Callback
+- error: Any error that occurred
+
+The function:
+1. Generates a random state, nonce, PKCE code verifier and browser binding
+2. Builds the authorization URL with the S256 code challenge
+3. Stores the nonce and verifier under the hash of the state and binding until the callback
+
+The binding keeps a state and code obtained in one browser from completing
+the login in another, which would log the victim into the attacker's
+account (login CSRF).
+
+Possible errors:
+- ErrUnknownProvider: The provider is not configured
+*/
+func (s *SSOService) Start(ctx context.Context, name, linkUserID string) (string, string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	var values [4]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return "", "", err
+		}
+		values[i] = v
+	}
+	state, nonce, verifier, binding := values[0], values[1], values[2], values[3]
+
+	url, err := p.idp.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.repo.CreateState(ctx, stateKey(state, binding), db.OIDCState{
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+	}, s.stateTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	return url, binding, nil
+}
+
+/*
+Callback completes a login started by Start with the state and code the
+provider passed to the redirect URL.
+
+Parameters:
+- ctx: Bounds the requests to the provider
+- name: The provider name
+- state: The state from the redirect
+- code: The authorization code from the redirect
+- binding: The browser binding returned by Start, from the browser completing the login
+
+Returns:
+- *LoginResult: The token and the user
+- error: Any error that occurred
+
+The function:
+1. Consumes the state of this browser, so it cannot be replayed
+2. Redeems the code with the PKCE verifier and verifies the ID token
+3. Maps the provider email to a regno with the provider's email pattern or the roster
+4. When linking, links the provider account to the user that started it, who must have that regno
+5. Otherwise finds the user linked to the provider account, links an existing account with the regno when allowed, or registers a new one
+6. Rejects pending and suspended accounts and accounts that must reset their password, and issues a token with "user" role (a challenge token when a second factor is due)
+
+An existing account is only linked on login when the provider has auto_link
+set, and new accounts follow the registration mode. Accounts that must reset
+their password are refused as on the password login, since their tokens are
+refused too until the reset.
+
+Possible errors:
+- ErrUnknownProvider, ErrInvalidState: Unknown provider, or unknown, used or expired state
+- ErrBrowserMismatch: No browser binding
+- oidc.ErrInvalidIDToken (wrapped): The ID token failed verification
+- ErrEmailNotAllowed: The email is unverified or does not map to a student
+- ErrLinkRequired: An account with the regno exists but is not linked
+- ErrWrongStudent, ErrIdentityTaken, ErrAlreadyLinked: Linking was refused
+- ErrNotOnRoster: Roster registration mode and the regno is not on the roster
+- userauth.ErrAccountPending, *auth.SuspendedError, auth.ErrPasswordResetRequired (wrapped): The account may not log in
+*/
+func (s *SSOService) Callback(ctx context.Context, name, state, code, binding string) (*LoginResult, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	if state == "" || code == "" {
+		return nil, fmt.Errorf("state and code are required")
+	}
+
+	if binding == "" {
+		return nil, ErrBrowserMismatch
+	}
+
+	st, err := s.repo.ConsumeState(ctx, stateKey(state, binding))
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Provider != name {
+		return nil, ErrInvalidState
+	}
+
+	claims, err := p.idp.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("sso login failed: %w", err)
+	}
+
+	email, regno, err := s.student(ctx, p, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := db.UserIdentity{Provider: name, Subject: claims.Subject, Email: email}
+	result := &LoginResult{}
+
+	if st.LinkUserID != "" {
+		result.User, err = s.link(ctx, st.LinkUserID, regno, identity)
+		result.Linked = true
+	} else {
+		result.User, result.Created, result.Linked, err = s.findOrCreate(ctx, p, regno, claims, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.User.Status == db.UserStatusPending {
+		if result.Created {
+			return result, nil
+		}
+		return nil, fmt.Errorf("sso login failed: %w", userauth.ErrAccountPending)
+	}
+
+	if result.User.Suspension.ActiveAt(s.now()) {
+		return nil, fmt.Errorf("sso login failed: %w", &auth.SuspendedError{Reason: result.User.Suspension.Reason, Until: result.User.Suspension.Until})
+	}
+
+	if result.User.PasswordResetRequired {
+		return nil, fmt.Errorf("sso login failed: %w", auth.ErrPasswordResetRequired)
+	}
+
+	result.Token, result.SecondFactor, err = s.tokens.IssueToken(ctx, auth.RoleUser, result.User.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err = s.repo.TouchIdentity(ctx, name, claims.Subject, email); err != nil {
+		log.Printf("sso: recording login of %s: %v", result.User.ID, err)
+	}
+
+	return result, nil
+}
+
+/*
+student returns the email of a provider account and the regno it maps to.
+*/
+func (s *SSOService) student(ctx context.Context, p *provider, claims *oidc.Claims) (string, string, error) {
+	email := claims.Email
+	if email == "" && p.trustEmail {
+		email = claims.PreferredUsername
+	}
+
+	if email == "" || (!claims.EmailVerified && !p.trustEmail) {
+		return "", "", ErrEmailNotAllowed
+	}
+
+	if p.pattern != nil {
+		m := p.pattern.FindStringSubmatch(email)
+		if m == nil {
+			return "", "", ErrEmailNotAllowed
+		}
+		regno, ok := utils.NormalizeRegno(m[p.pattern.SubexpIndex("regno")])
+		if !ok {
+			return "", "", ErrEmailNotAllowed
+		}
+		return email, regno, nil
+	}
+
+	entry, err := s.roster.GetEntryByEmail(ctx, email)
+	if err != nil {
+		return "", "", err
+	}
+	if entry == nil {
+		return "", "", ErrEmailNotAllowed
+	}
+
+	return email, entry.Regno, nil
+}
+
+/*
+link links a provider account to the logged-in user that started linking.
+*/
+func (s *SSOService) link(ctx context.Context, userID, regno string, identity db.UserIdentity) (*db.User, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidState
+	}
+
+	if user.Regno != regno {
+		return nil, ErrWrongStudent
+	}
+
+	identity.UserID = user.ID
+	if err = s.repo.LinkIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+/*
+findOrCreate returns the user of a provider login, linking or registering
+the account on first use.
+
+Returns:
+- *db.User: The user
+- bool: Whether the account was registered
+- bool: Whether an existing account was linked
+- error: Any error that occurred
+*/
+func (s *SSOService) findOrCreate(ctx context.Context, p *provider, regno string, claims *oidc.Claims, identity db.UserIdentity) (*db.User, bool, bool, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil || user != nil {
+		return user, false, false, err
+	}
+
+	if user, err = s.repo.GetUserByRegno(ctx, regno); err != nil {
+		return nil, false, false, err
+	}
+
+	if user != nil {
+		if !p.autoLink {
+			return nil, false, false, ErrLinkRequired
+		}
+		identity.UserID = user.ID
+		if err = s.repo.LinkIdentity(ctx, identity); err != nil {
+			return nil, false, false, err
+		}
+		return user, false, true, nil
+	}
+
+	if user, err = s.register(ctx, regno, claims, identity.Email); err != nil {
+		return nil, false, false, err
+	}
+
+	identity.UserID = user.ID
+	if err = s.repo.LinkIdentity(ctx, identity); err != nil {
+		return nil, false, false, err
+	}
+
+	return user, true, false, nil
+}
+
+/*
+register creates the account of a student's first SSO login according to
+the registration mode. The account gets a random password nobody knows; the
+student can set one through a password reset. In "pending" mode the account
+is active right away when the provider email is the college email on the
+roster, as it is after confirming that email.
+*/
+func (s *SSOService) register(ctx context.Context, regno string, claims *oidc.Claims, email string) (*db.User, error) {
+	entry, err := s.roster.GetEntry(ctx, regno)
+	if err != nil {
+		return nil, err
+	}
+
+	status := db.UserStatusActive
+	switch s.account.RegistrationMode {
+	case "roster":
+		if entry == nil {
+			return nil, ErrNotOnRoster
+		}
+	case "pending":
+		if entry == nil || !strings.EqualFold(entry.Email, email) {
+			status = db.UserStatusPending
+		}
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" && entry != nil {
+		name = entry.Name
+	}
+	if name == "" {
+		name = regno
+	}
+
+	pass, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.accounts.Register(ctx, regno, name, pass, status)
+	if err != nil {
+		return nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	return user, nil
+}
+
+/*
+Unlink removes a linked provider account.
+
+Parameters:
+- ctx: The request context
+- userID: The authenticated user's ID
+- name: The provider name
+
+Returns:
+- error: ErrUnknownProvider, ErrNotLinked or a database error
+*/
+func (s *SSOService) Unlink(ctx context.Context, userID, name string) error {
+	if _, ok := s.providers[name]; !ok {
+		return ErrUnknownProvider
+	}
+	return s.repo.UnlinkIdentity(ctx, userID, name)
+}
+
+/*
+Identities lists the provider accounts linked to a user.
+
+Parameters:
+- ctx: The request context
+- userID: The authenticated user's ID
+
+Returns:
+- []db.UserIdentity: The linked accounts
+- error: Any database error
+*/
+func (s *SSOService) Identities(ctx context.Context, userID string) ([]db.UserIdentity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
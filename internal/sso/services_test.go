@@ -0,0 +1,435 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	userauth "github.com/varnit-ta/PlacementLog/internal/userAuth"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/oidc"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+type mockSSORepo struct {
+	CreateStateFunc       func(stateHash string, state db.OIDCState, ttl time.Duration) error
+	ConsumeStateFunc      func(stateHash string) (*db.OIDCState, error)
+	GetUserByIdentityFunc func(provider, subject string) (*db.User, error)
+	GetUserByRegnoFunc    func(regno string) (*db.User, error)
+	GetUserFunc           func(userID string) (*db.User, error)
+	LinkIdentityFunc      func(identity db.UserIdentity) error
+	UnlinkIdentityFunc    func(userID, provider string) error
+	ListIdentitiesFunc    func(userID string) ([]db.UserIdentity, error)
+}
+
+func (m *mockSSORepo) CreateState(ctx context.Context, stateHash string, state db.OIDCState, ttl time.Duration) error {
+	return m.CreateStateFunc(stateHash, state, ttl)
+}
+func (m *mockSSORepo) ConsumeState(ctx context.Context, stateHash string) (*db.OIDCState, error) {
+	return m.ConsumeStateFunc(stateHash)
+}
+func (m *mockSSORepo) GetUserByIdentity(ctx context.Context, provider, subject string) (*db.User, error) {
+	return m.GetUserByIdentityFunc(provider, subject)
+}
+func (m *mockSSORepo) GetUserByRegno(ctx context.Context, regno string) (*db.User, error) {
+	return m.GetUserByRegnoFunc(regno)
+}
+func (m *mockSSORepo) GetUser(ctx context.Context, userID string) (*db.User, error) {
+	return m.GetUserFunc(userID)
+}
+func (m *mockSSORepo) LinkIdentity(ctx context.Context, identity db.UserIdentity) error {
+	return m.LinkIdentityFunc(identity)
+}
+func (m *mockSSORepo) TouchIdentity(ctx context.Context, provider, subject, email string) error {
+	return nil
+}
+func (m *mockSSORepo) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	return m.UnlinkIdentityFunc(userID, provider)
+}
+func (m *mockSSORepo) ListIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error) {
+	return m.ListIdentitiesFunc(userID)
+}
+
+type mockIdentityProvider struct {
+	AuthCodeURLFunc func(ctx context.Context, state, nonce, challenge string) (string, error)
+	ExchangeFunc    func(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error)
+}
+
+func (m *mockIdentityProvider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	return m.AuthCodeURLFunc(ctx, state, nonce, challenge)
+}
+func (m *mockIdentityProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error) {
+	return m.ExchangeFunc(ctx, code, verifier, nonce)
+}
+
+type mockTokenIssuer struct{}
+
+func (mockTokenIssuer) IssueToken(ctx context.Context, accountType, accountID string) (string, string, error) {
+	return "token-" + accountID, "", nil
+}
+
+type mockRoster struct {
+	entries []db.RosterEntry
+}
+
+func (m *mockRoster) GetEntry(ctx context.Context, regno string) (*db.RosterEntry, error) {
+	for _, e := range m.entries {
+		if e.Regno == regno {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockRoster) GetEntryByEmail(ctx context.Context, email string) (*db.RosterEntry, error) {
+	for _, e := range m.entries {
+		if e.Email == email {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+type mockAccounts struct {
+	registered *db.User
+}
+
+func (m *mockAccounts) Register(ctx context.Context, regno, username, pass, status string) (*db.User, error) {
+	if pass == "" {
+		return nil, errors.New("all fields are required")
+	}
+	m.registered = &db.User{ID: "new-user", Regno: regno, Username: username, Status: status}
+	return m.registered, nil
+}
+
+var collegePattern = regexp.MustCompile(`^(?P<regno>[0-9]{2}[a-z]{3}[0-9]{4})@college\.example$`)
+
+func studentClaims() *oidc.Claims {
+	return &oidc.Claims{Subject: "subject-1", Email: "22bcs1234@college.example", EmailVerified: true, Name: "Test Student"}
+}
+
+/*
+ssoTest is a service with one provider, "college", whose logins all
+complete with the given claims. Linked identities are recorded, and the
+repository holds the given users.
+*/
+type ssoTest struct {
+	s        *SSOService
+	repo     *mockSSORepo
+	provider *provider
+	accounts *mockAccounts
+	users    []*db.User
+	linked   []db.UserIdentity
+	state    db.OIDCState
+}
+
+func newSSOTest(claims *oidc.Claims, users ...*db.User) *ssoTest {
+	tc := &ssoTest{accounts: &mockAccounts{}, users: users, state: db.OIDCState{Provider: "college", Nonce: "nonce", CodeVerifier: "verifier"}}
+
+	tc.repo = &mockSSORepo{
+		ConsumeStateFunc: func(stateHash string) (*db.OIDCState, error) {
+			if stateHash != stateKey("state", "binding") {
+				return nil, nil
+			}
+			st := tc.state
+			return &st, nil
+		},
+		GetUserByIdentityFunc: func(provider, subject string) (*db.User, error) {
+			for _, i := range tc.linked {
+				if i.Provider == provider && i.Subject == subject {
+					return tc.findUser(func(u *db.User) bool { return u.ID == i.UserID }), nil
+				}
+			}
+			return nil, nil
+		},
+		GetUserByRegnoFunc: func(regno string) (*db.User, error) {
+			return tc.findUser(func(u *db.User) bool { return u.Regno == regno }), nil
+		},
+		GetUserFunc: func(userID string) (*db.User, error) {
+			return tc.findUser(func(u *db.User) bool { return u.ID == userID }), nil
+		},
+		LinkIdentityFunc: func(identity db.UserIdentity) error {
+			tc.linked = append(tc.linked, identity)
+			return nil
+		},
+	}
+
+	tc.provider = &provider{
+		idp: &mockIdentityProvider{
+			ExchangeFunc: func(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error) {
+				if code != "code" || verifier != "verifier" || nonce != "nonce" {
+					return nil, errors.New("code exchange failed: invalid_grant")
+				}
+				return claims, nil
+			},
+		},
+		pattern: collegePattern,
+	}
+
+	tc.s = &SSOService{
+		repo:      tc.repo,
+		providers: map[string]*provider{"college": tc.provider},
+		tokens:    mockTokenIssuer{},
+		roster:    &mockRoster{},
+		accounts:  tc.accounts,
+		account:   config.AccountConfig{RegistrationMode: "open"},
+		stateTTL:  10 * time.Minute,
+		now:       time.Now,
+	}
+
+	return tc
+}
+
+// findUser looks through the given users and the account registered by the test.
+func (tc *ssoTest) findUser(match func(u *db.User) bool) *db.User {
+	for _, u := range append(tc.users, tc.accounts.registered) {
+		if u != nil && match(u) {
+			return u
+		}
+	}
+	return nil
+}
+
+func TestSSOService_Start(t *testing.T) {
+	var stored db.OIDCState
+	var storedHash, gotState, gotChallenge string
+
+	s := &SSOService{
+		repo: &mockSSORepo{
+			CreateStateFunc: func(stateHash string, state db.OIDCState, ttl time.Duration) error {
+				storedHash, stored = stateHash, state
+				return nil
+			},
+		},
+		providers: map[string]*provider{"college": {idp: &mockIdentityProvider{
+			AuthCodeURLFunc: func(ctx context.Context, state, nonce, challenge string) (string, error) {
+				gotState, gotChallenge = state, challenge
+				return "https://idp.example/authorize?state=" + url.QueryEscape(state), nil
+			},
+		}}},
+		stateTTL: 10 * time.Minute,
+	}
+
+	authURL, binding, err := s.Start(context.Background(), "college", testUserID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if authURL == "" || binding == "" || storedHash != stateKey(gotState, binding) || storedHash == gotState {
+		t.Errorf("expected only the hash of the state and binding to be stored, got %q for %q", storedHash, gotState)
+	}
+	if strings.Contains(authURL, binding) {
+		t.Errorf("expected the binding to stay out of the authorization URL, got %s", authURL)
+	}
+	if stored.Provider != "college" || stored.LinkUserID != testUserID || stored.Nonce == "" ||
+		gotChallenge != oidc.CodeChallenge(stored.CodeVerifier) {
+		t.Errorf("unexpected stored state %+v with challenge %q", stored, gotChallenge)
+	}
+
+	if _, _, err = s.Start(context.Background(), "other", ""); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestSSOService_Callback_State(t *testing.T) {
+	tc := newSSOTest(studentClaims())
+
+	if _, err := tc.s.Callback(context.Background(), "college", "forged", "code", "binding"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for an unknown state, got %v", err)
+	}
+
+	if _, err := tc.s.Callback(context.Background(), "college", "state", "code", ""); !errors.Is(err, ErrBrowserMismatch) {
+		t.Errorf("expected ErrBrowserMismatch without a binding, got %v", err)
+	}
+
+	// A state and code obtained in another browser do not match this browser's binding.
+	if _, err := tc.s.Callback(context.Background(), "college", "state", "code", "victim-binding"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for another browser's binding, got %v", err)
+	}
+
+	tc.state.Provider = "other"
+	tc.s.providers["other"] = tc.provider
+	if _, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for another provider's state, got %v", err)
+	}
+}
+
+func TestSSOService_Callback_NewAccount(t *testing.T) {
+	tc := newSSOTest(studentClaims())
+
+	result, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !result.Created || result.Token != "token-new-user" || result.User.Regno != "22bcs1234" || result.User.Username != "Test Student" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(tc.linked) != 1 || tc.linked[0].UserID != "new-user" || tc.linked[0].Subject != "subject-1" {
+		t.Errorf("expected the provider account to be linked, got %+v", tc.linked)
+	}
+
+	// The next login finds the account through the link.
+	result, err = tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+	if err != nil || result.Created || result.User.ID != "new-user" {
+		t.Errorf("expected a login to the linked account, got %+v %v", result, err)
+	}
+}
+
+func TestSSOService_Callback_Email(t *testing.T) {
+	cases := []struct {
+		name       string
+		claims     *oidc.Claims
+		noPattern  bool
+		trustEmail bool
+		wantErr    error
+		wantRegno  string
+	}{
+		{"pattern", studentClaims(), false, false, nil, "22bcs1234"},
+		{"unverified", &oidc.Claims{Subject: "s", Email: "22bcs1234@college.example"}, false, false, ErrEmailNotAllowed, ""},
+		{"trusted unverified", &oidc.Claims{Subject: "s", PreferredUsername: "22bcs1234@college.example"}, false, true, nil, "22bcs1234"},
+		{"other domain", &oidc.Claims{Subject: "s", Email: "22bcs1234@gmail.com", EmailVerified: true}, false, false, ErrEmailNotAllowed, ""},
+		{"roster", &oidc.Claims{Subject: "s", Email: "jane@college.example", EmailVerified: true}, true, false, nil, "22bcs4321"},
+		{"not on roster", &oidc.Claims{Subject: "s", Email: "john@college.example", EmailVerified: true}, true, false, ErrEmailNotAllowed, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := newSSOTest(c.claims)
+			tc.s.roster = &mockRoster{entries: []db.RosterEntry{{Regno: "22bcs4321", Name: "Jane", Email: "jane@college.example"}}}
+			tc.provider.trustEmail = c.trustEmail
+			if c.noPattern {
+				tc.provider.pattern = nil
+			}
+
+			result, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+			if err == nil && result.User.Regno != c.wantRegno {
+				t.Errorf("expected regno %s, got %s", c.wantRegno, result.User.Regno)
+			}
+		})
+	}
+}
+
+func TestSSOService_Callback_ExistingAccount(t *testing.T) {
+	existing := &db.User{ID: testUserID, Regno: "22bcs1234", Username: "Test Student", Status: db.UserStatusActive}
+
+	tc := newSSOTest(studentClaims(), existing)
+	if _, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding"); !errors.Is(err, ErrLinkRequired) {
+		t.Errorf("expected ErrLinkRequired, got %v", err)
+	}
+	if len(tc.linked) != 0 || tc.accounts.registered != nil {
+		t.Errorf("expected the password account to be left alone, got %+v", tc.linked)
+	}
+
+	tc.provider.autoLink = true
+	result, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Linked || result.Created || result.User.ID != testUserID || len(tc.linked) != 1 {
+		t.Errorf("expected the account to be linked automatically, got %+v", result)
+	}
+}
+
+func TestSSOService_Callback_Link(t *testing.T) {
+	cases := []struct {
+		name    string
+		user    *db.User
+		wantErr error
+	}{
+		{"own account", &db.User{ID: testUserID, Regno: "22bcs1234", Status: db.UserStatusActive}, nil},
+		{"another student's account", &db.User{ID: testUserID, Regno: "22bcs9999", Status: db.UserStatusActive}, ErrWrongStudent},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := newSSOTest(studentClaims(), c.user)
+			tc.state.LinkUserID = testUserID
+
+			result, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+			if err == nil && (!result.Linked || result.Token == "" || len(tc.linked) != 1 || tc.linked[0].UserID != testUserID) {
+				t.Errorf("expected the provider account to be linked, got %+v %+v", result, tc.linked)
+			}
+			if err != nil && len(tc.linked) != 0 {
+				t.Errorf("expected nothing to be linked, got %+v", tc.linked)
+			}
+		})
+	}
+}
+
+func TestSSOService_Callback_RegistrationMode(t *testing.T) {
+	roster := []db.RosterEntry{{Regno: "22bcs1234", Name: "Test Student", Email: "22bcs1234@college.example"}}
+
+	cases := []struct {
+		name       string
+		mode       string
+		roster     []db.RosterEntry
+		wantErr    error
+		wantStatus string
+	}{
+		{"roster mode on roster", "roster", roster, nil, db.UserStatusActive},
+		{"roster mode not on roster", "roster", nil, ErrNotOnRoster, ""},
+		{"pending mode with roster email", "pending", roster, nil, db.UserStatusActive},
+		{"pending mode with other email", "pending", []db.RosterEntry{{Regno: "22bcs1234", Email: "old@college.example"}}, nil, db.UserStatusPending},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := newSSOTest(studentClaims())
+			tc.s.account.RegistrationMode = c.mode
+			tc.s.roster = &mockRoster{entries: c.roster}
+
+			result, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if result.User.Status != c.wantStatus || (result.Token == "") != (c.wantStatus == db.UserStatusPending) {
+				t.Errorf("expected a %s account, got %+v", c.wantStatus, result)
+			}
+		})
+	}
+}
+
+func TestSSOService_Callback_BlockedAccounts(t *testing.T) {
+	now := time.Now()
+	until := now.Add(time.Hour)
+
+	cases := []struct {
+		name    string
+		user    *db.User
+		wantErr error
+	}{
+		{"pending", &db.User{ID: testUserID, Regno: "22bcs1234", Status: db.UserStatusPending}, userauth.ErrAccountPending},
+		{"suspended", &db.User{ID: testUserID, Regno: "22bcs1234", Status: db.UserStatusActive,
+			Suspension: &db.Suspension{Reason: "spam", Since: now, Until: &until}}, nil},
+		{"password reset required", &db.User{ID: testUserID, Regno: "22bcs1234", Status: db.UserStatusActive,
+			PasswordResetRequired: true}, auth.ErrPasswordResetRequired},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := newSSOTest(studentClaims(), c.user)
+			tc.provider.autoLink = true
+
+			_, err := tc.s.Callback(context.Background(), "college", "state", "code", "binding")
+			var suspended *auth.SuspendedError
+			if c.wantErr != nil && !errors.Is(err, c.wantErr) {
+				t.Errorf("expected %v, got %v", c.wantErr, err)
+			}
+			if c.wantErr == nil && !errors.As(err, &suspended) {
+				t.Errorf("expected a suspension error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+/*
+StatsHandler handles the site statistics endpoint.
+*/
+type StatsHandler struct {
+	srv *StatsService
+}
+
+/*
+NewStatsHandler creates a new StatsHandler instance with the provided service.
+
+Parameters:
+- srv: The stats service
+
+Returns:
+- *StatsHandler: A new handler instance
+*/
+func NewStatsHandler(srv *StatsService) *StatsHandler {
+	return &StatsHandler{srv: srv}
+}
+
+/*
+GetStats returns counts of users, posts and placements, and the moderation
+actions of the last "days" days (default 30). The route admits admins and
+API keys with the stats:read scope.
+
+HTTP Method: GET
+Endpoint: /admin/stats?days=30
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token or api_key>
+
+Response (200 OK):
+
+	{
+	  "users": {"total": 120, "active": 110, "pending": 8, "suspended": 2},
+	  "posts": {"total": 45, "approved": 40, "pending_review": 5},
+	  "placements": 12,
+	  "moderation_actions": {"suspend": 2, "verify": 6},
+	  "days": 30,
+	  "since": "2026-01-01T00:00:00Z"
+	}
+
+Returns:
+- 200 OK: The statistics
+- 400 Bad Request: days is not a number between 1 and 365
+- 401 Unauthorized: Missing or invalid credentials
+- 403 Forbidden: The caller lacks the stats:read permission
+*/
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	days := DefaultDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			utils.WriteError(w, errors.New("days must be a number"))
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.srv.GetStats(r.Context(), days)
+	if errors.Is(err, ErrInvalidDays) {
+		utils.WriteError(w, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, stats, http.StatusOK)
+}
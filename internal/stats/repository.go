@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+StatsRepo computes the site statistics from the users, posts, placements
+and moderation log tables. The counts tolerate replication lag, so they are
+read from the replica when one is configured.
+*/
+type StatsRepo struct {
+	db *db.DB
+}
+
+/*
+NewStatsRepo creates a new StatsRepo instance with the provided database connection.
+
+Parameters:
+- db: The database handle; statistics are read from the replica when one is configured
+
+Returns:
+- *StatsRepo: A new repository instance
+*/
+func NewStatsRepo(db *db.DB) *StatsRepo {
+	return &StatsRepo{db: db}
+}
+
+/*
+GetStats returns the current counts and the moderation actions taken since
+a time.
+
+Parameters:
+- ctx: The request context
+- since: Start of the window for the moderation action counts
+
+Returns:
+- *Stats: The statistics, without the window fields
+- error: Any database error
+*/
+func (r *StatsRepo) GetStats(ctx context.Context, since time.Time) (*Stats, error) {
+	defer telemetry.TraceQuery(ctx, "StatsRepo.GetStats", "SELECT", "placement_log_users").End()
+
+	stats := Stats{ModerationActions: map[string]int{}}
+
+	counts, err := r.db.QueryRead(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'active'),
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COUNT(*) FILTER (WHERE suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > CURRENT_TIMESTAMP)),
+			(SELECT COUNT(*) FROM placement_log_posts),
+			(SELECT COUNT(*) FROM placement_log_posts WHERE reviewed),
+			(SELECT COUNT(*) FROM placement_companies)
+		FROM placement_log_users;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer counts.Close()
+
+	// The aggregate always returns exactly one row.
+	if !counts.Next() {
+		return nil, fmt.Errorf("db error: %v", counts.Err())
+	}
+	err = counts.Scan(
+		&stats.Users.Total,
+		&stats.Users.Active,
+		&stats.Users.Pending,
+		&stats.Users.Suspended,
+		&stats.Posts.Total,
+		&stats.Posts.Approved,
+		&stats.Placements,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	counts.Close()
+	stats.Posts.PendingReview = stats.Posts.Total - stats.Posts.Approved
+
+	rows, err := r.db.QueryRead(ctx, `
+		SELECT action, COUNT(*)
+		FROM placement_log_moderation_log
+		WHERE created_at >= $1
+		GROUP BY action;
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var action string
+		var n int
+		if err = rows.Scan(&action, &n); err != nil {
+			return nil, fmt.Errorf("db error: %v", err)
+		}
+		stats.ModerationActions[action] = n
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &stats, nil
+}
+
+// Ensure StatsRepo implements StatsRepository
+var _ StatsRepository = (*StatsRepo)(nil)
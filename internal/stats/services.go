@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultDays is the moderation window when none is given.
+	DefaultDays = 30
+	// MaxDays bounds the moderation window.
+	MaxDays = 365
+)
+
+// ErrInvalidDays is returned for a moderation window outside 1 to MaxDays days.
+var ErrInvalidDays = fmt.Errorf("days must be between 1 and %d", MaxDays)
+
+// Define StatsRepository interface for testability
+//go:generate mockgen -destination=mock_stats_repo.go -package=stats . StatsRepository
+
+type StatsRepository interface {
+	GetStats(ctx context.Context, since time.Time) (*Stats, error)
+}
+
+/*
+UserCounts counts user accounts by state. Suspended accounts are also
+counted under their status.
+*/
+type UserCounts struct {
+	Total     int `json:"total"`
+	Active    int `json:"active"`
+	Pending   int `json:"pending"`
+	Suspended int `json:"suspended"`
+}
+
+/*
+PostCounts counts posts by review state.
+*/
+type PostCounts struct {
+	Total         int `json:"total"`
+	Approved      int `json:"approved"`
+	PendingReview int `json:"pending_review"`
+}
+
+/*
+Stats is an overview of the site for admins and reporting integrations.
+*/
+type Stats struct {
+	Users      UserCounts `json:"users"`
+	Posts      PostCounts `json:"posts"`
+	Placements int        `json:"placements"`
+	// ModerationActions counts the moderation log entries of the window by action.
+	ModerationActions map[string]int `json:"moderation_actions"`
+	Days              int            `json:"days"`
+	Since             time.Time      `json:"since"`
+}
+
+/*
+StatsService provides the site statistics.
+*/
+type StatsService struct {
+	repo StatsRepository
+	now  func() time.Time
+}
+
+/*
+NewStatsService creates a new StatsService instance with the provided repository.
+
+Parameters:
+- repo: The stats repository
+
+Returns:
+- *StatsService: A new service instance
+*/
+func NewStatsService(repo StatsRepository) *StatsService {
+	return &StatsService{repo: repo, now: time.Now}
+}
+
+/*
+GetStats returns the site statistics.
+
+Parameters:
+- ctx: The request context
+- days: The number of days of moderation actions to count, between 1 and MaxDays
+
+Returns:
+- *Stats: The statistics
+- error: ErrInvalidDays or a database error
+*/
+func (s *StatsService) GetStats(ctx context.Context, days int) (*Stats, error) {
+	if days < 1 || days > MaxDays {
+		return nil, ErrInvalidDays
+	}
+
+	since := s.now().UTC().AddDate(0, 0, -days)
+
+	stats, err := s.repo.GetStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Days = days
+	stats.Since = since
+
+	return stats, nil
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockStatsRepo struct {
+	GetStatsFunc func(since time.Time) (*Stats, error)
+}
+
+func (m *mockStatsRepo) GetStats(ctx context.Context, since time.Time) (*Stats, error) {
+	return m.GetStatsFunc(since)
+}
+
+func TestStatsService_GetStats(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	var gotSince time.Time
+	repo := &mockStatsRepo{
+		GetStatsFunc: func(since time.Time) (*Stats, error) {
+			gotSince = since
+			return &Stats{Placements: 4, ModerationActions: map[string]int{"ban": 1}}, nil
+		},
+	}
+	s := NewStatsService(repo)
+	s.now = func() time.Time { return now }
+
+	stats, err := s.GetStats(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if !gotSince.Equal(want) || !stats.Since.Equal(want) {
+		t.Errorf("expected window from %v, got %v", want, gotSince)
+	}
+	if stats.Days != 30 || stats.Placements != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	for _, days := range []int{0, -1, MaxDays + 1} {
+		if _, err := s.GetStats(context.Background(), days); !errors.Is(err, ErrInvalidDays) {
+			t.Errorf("days=%d: expected ErrInvalidDays, got %v", days, err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package twofactor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/session"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var errUnauthorized = errors.New("unauthorized: authentication required")
+
+/*
+TwoFactorHandler handles the second factor endpoints of users and admins.
+They are reachable with challenge tokens, which every other authenticated
+route refuses.
+*/
+type TwoFactorHandler struct {
+	srv      *TwoFactorService
+	sessions *session.Manager
+}
+
+/*
+NewTwoFactorHandler creates a new TwoFactorHandler instance with the provided service.
+
+Parameters:
+- srv: The second factor service
+- sessions: Replaces the session cookies in cookie session mode (nil when disabled)
+
+Returns:
+- *TwoFactorHandler: A new handler instance
+*/
+func NewTwoFactorHandler(srv *TwoFactorService, sessions *session.Manager) *TwoFactorHandler {
+	return &TwoFactorHandler{srv: srv, sessions: sessions}
+}
+
+/*
+codeRequest represents the JSON payload carrying a TOTP or recovery code.
+*/
+type codeRequest struct {
+	Code string `json:"code"`
+}
+
+/*
+principalAndCode reads the caller and the submitted code, writing the error
+response itself when either is missing.
+*/
+func principalAndCode(w http.ResponseWriter, r *http.Request) (*auth.Principal, string, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	var req codeRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, err)
+		return nil, "", false
+	}
+
+	if req.Code == "" {
+		utils.WriteError(w, errors.New("code is required"))
+		return nil, "", false
+	}
+
+	return principal, req.Code, true
+}
+
+/*
+writeCodeError maps second factor errors to responses: 429 with Retry-After
+while wrong codes are throttled, 401 for a wrong code, otherwise 400.
+*/
+func writeCodeError(w http.ResponseWriter, err error) {
+	var blocked *loginguard.BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		w.Header().Set("Retry-After", blocked.RetryAfterHeader())
+		utils.WriteError(w, err, http.StatusTooManyRequests)
+	case errors.Is(err, ErrInvalidCode):
+		utils.WriteError(w, err, http.StatusUnauthorized)
+	default:
+		utils.WriteError(w, err)
+	}
+}
+
+/*
+Enroll starts setting up TOTP for the caller.
+
+HTTP Method: POST
+Endpoint: /auth/2fa/enroll
+
+Headers Required:
+- Authorization: Bearer <jwt_token> (a full session or an "enroll" challenge token)
+
+Response (200 OK):
+
+	{
+	  "secret": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP",
+	  "uri": "otpauth://totp/PlacementLog:22bcs1234?algorithm=SHA1&digits=6&issuer=PlacementLog&period=30&secret=..."
+	}
+
+The client shows uri as a QR code and then confirms with the first code.
+
+Returns:
+- 200 OK: Enrollment started
+- 400 Bad Request: Two-factor authentication is already enabled
+- 401 Unauthorized: Missing or invalid token
+*/
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.srv.Enroll(r.Context(), principal)
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, enrollment, http.StatusOK)
+}
+
+/*
+Confirm enables TOTP with the first code from the authenticator app.
+
+HTTP Method: POST
+Endpoint: /auth/2fa/confirm
+
+Request Body:
+
+	{
+	  "code": "123456"
+	}
+
+Response (200 OK):
+
+	{
+	  "recovery_codes": ["abcd-efgh-ijkl-mnop", "..."],
+	  "token": "jwt_token_here"
+	}
+
+The recovery codes are only shown once. The token replaces the caller's
+token and carries a verified second factor; in cookie session mode it
+replaces the session cookie and is not returned.
+
+Returns:
+- 200 OK: Two-factor authentication enabled
+- 400 Bad Request: No enrollment in progress
+- 401 Unauthorized: Missing or invalid token, or wrong code
+- 429 Too Many Requests: Too many wrong codes; Retry-After gives the wait in seconds
+*/
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	principal, code, ok := principalAndCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, token, err := h.srv.Confirm(r.Context(), principal, code, utils.ClientIP(r))
+	if err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	resp := map[string]any{"recovery_codes": codes}
+	if token, err = h.sessions.Issue(w, token); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if token != "" {
+		resp["token"] = token
+	}
+
+	utils.WriteJSON(w, resp, http.StatusOK)
+}
+
+/*
+Verify completes a login with a TOTP code or a recovery code.
+
+HTTP Method: POST
+Endpoint: /auth/2fa/verify
+
+Headers Required:
+- Authorization: Bearer <jwt_token> (the "pending" challenge token from login)
+
+Request Body:
+
+	{
+	  "code": "123456"
+	}
+
+Response (200 OK):
+
+	{
+	  "token": "jwt_token_here"
+	}
+
+In cookie session mode the token replaces the session cookie and the
+response body is empty ({}).
+
+Returns:
+- 200 OK: A token for the full session
+- 401 Unauthorized: Missing or invalid token, or wrong code
+- 429 Too Many Requests: Too many wrong codes; Retry-After gives the wait in seconds
+*/
+func (h *TwoFactorHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	principal, code, ok := principalAndCode(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := h.srv.Verify(r.Context(), principal, code, utils.ClientIP(r))
+	if err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	resp := map[string]string{}
+	if token, err = h.sessions.Issue(w, token); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if token != "" {
+		resp["token"] = token
+	}
+
+	utils.WriteJSON(w, resp, http.StatusOK)
+}
+
+/*
+RegenerateRecoveryCodes replaces the caller's recovery codes.
+
+HTTP Method: POST
+Endpoint: /auth/2fa/recovery-codes
+
+Request Body:
+
+	{
+	  "code": "123456"
+	}
+
+Response (200 OK):
+
+	{
+	  "recovery_codes": ["abcd-efgh-ijkl-mnop", "..."]
+	}
+
+Returns:
+- 200 OK: New recovery codes; the old ones no longer work
+- 401 Unauthorized: Missing or invalid token, or wrong code
+- 403 Forbidden: The session has not completed its second factor
+- 429 Too Many Requests: Too many wrong codes
+*/
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	principal, code, ok := principalAndCode(w, r)
+	if !ok {
+		return
+	}
+
+	if principal.NeedsSecondFactor() {
+		utils.WriteError(w, errors.New("forbidden: two-factor authentication required"), http.StatusForbidden)
+		return
+	}
+
+	codes, err := h.srv.RegenerateRecoveryCodes(r.Context(), principal, code, utils.ClientIP(r))
+	if err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string][]string{"recovery_codes": codes}, http.StatusOK)
+}
+
+/*
+Disable turns TOTP off for the caller.
+
+HTTP Method: POST
+Endpoint: /auth/2fa/disable
+
+Request Body:
+
+	{
+	  "code": "123456"
+	}
+
+Returns:
+- 200 OK: Two-factor authentication disabled
+- 400 Bad Request: The caller's role requires two-factor authentication
+- 401 Unauthorized: Missing or invalid token, or wrong code
+- 403 Forbidden: The session has not completed its second factor
+- 429 Too Many Requests: Too many wrong codes
+*/
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	principal, code, ok := principalAndCode(w, r)
+	if !ok {
+		return
+	}
+
+	if principal.NeedsSecondFactor() {
+		utils.WriteError(w, errors.New("forbidden: two-factor authentication required"), http.StatusForbidden)
+		return
+	}
+
+	if err := h.srv.Disable(r.Context(), principal, code, utils.ClientIP(r)); err != nil {
+		writeCodeError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "two-factor authentication disabled"}, http.StatusOK)
+}
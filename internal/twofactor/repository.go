@@ -0,0 +1,312 @@
+package twofactor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+/*
+TwoFactorRepo stores TOTP secrets and recovery codes of users and admins.
+*/
+type TwoFactorRepo struct {
+	db *sql.DB
+}
+
+/*
+NewTwoFactorRepo creates a new TwoFactorRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *TwoFactorRepo: A new repository instance
+*/
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{db: db}
+}
+
+/*
+GetFactor returns the second factor of an account.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+
+Returns:
+- *db.TwoFactor: The factor, or nil when the account has none
+- error: Any database error
+*/
+func (r *TwoFactorRepo) GetFactor(ctx context.Context, accountType, accountID string) (*db.TwoFactor, error) {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.GetFactor", "SELECT", "placement_log_two_factor").End()
+
+	f := db.TwoFactor{AccountType: accountType, AccountID: accountID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT secret, enabled_at IS NOT NULL, last_step
+		FROM placement_log_two_factor
+		WHERE account_type = $1 AND account_id = $2;
+	`, accountType, accountID).Scan(&f.Secret, &f.Enabled, &f.LastStep)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &f, nil
+}
+
+/*
+AccountName returns the name shown for the account in authenticator apps:
+the regno of a user or the username of an admin.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+
+Returns:
+- string: The account name
+- error: "account not found" or any database error
+*/
+func (r *TwoFactorRepo) AccountName(ctx context.Context, accountType, accountID string) (string, error) {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.AccountName", "SELECT", "placement_log_users").End()
+
+	query := `SELECT regno FROM placement_log_users WHERE id = $1;`
+	if accountType == auth.RoleAdmin {
+		query = `SELECT username FROM placement_log_admins WHERE id = $1;`
+	}
+
+	var name string
+	err := r.db.QueryRowContext(ctx, query, accountID).Scan(&name)
+
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("account not found")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("db error: %v", err)
+	}
+
+	return name, nil
+}
+
+/*
+SaveSecret starts an enrollment, replacing any earlier unconfirmed one.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+- secret: The new base32 TOTP secret
+
+Returns:
+- error: "two-factor authentication is already enabled" when the account has an enabled factor
+*/
+func (r *TwoFactorRepo) SaveSecret(ctx context.Context, accountType, accountID, secret string) error {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.SaveSecret", "INSERT", "placement_log_two_factor").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO placement_log_two_factor (account_type, account_id, secret)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_type, account_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_step = 0, created_at = CURRENT_TIMESTAMP
+			WHERE placement_log_two_factor.enabled_at IS NULL;
+	`, accountType, accountID, secret)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	return nil
+}
+
+/*
+Enable puts an enrollment in force and replaces the account's recovery codes.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+- step: The time step of the confirmed code, so it cannot be replayed
+- codeHashes: SHA-256 hashes of the new recovery codes
+
+Returns:
+- error: "no two-factor enrollment in progress" when there is nothing to enable
+*/
+func (r *TwoFactorRepo) Enable(ctx context.Context, accountType, accountID string, step int64, codeHashes []string) error {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.Enable", "UPDATE", "placement_log_two_factor").End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE placement_log_two_factor SET enabled_at = CURRENT_TIMESTAMP, last_step = $3
+		WHERE account_type = $1 AND account_id = $2 AND enabled_at IS NULL;
+	`, accountType, accountID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	} else if n == 0 {
+		return fmt.Errorf("no two-factor enrollment in progress")
+	}
+
+	if err = replaceRecoveryCodes(ctx, tx, accountType, accountID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, accountType, accountID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM placement_log_recovery_codes WHERE account_type = $1 AND account_id = $2;
+	`, accountType, accountID); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %v", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO placement_log_recovery_codes (account_type, account_id, code_hash)
+			VALUES ($1, $2, $3);
+		`, accountType, accountID, hash); err != nil {
+			return fmt.Errorf("failed to store recovery codes: %v", err)
+		}
+	}
+
+	return nil
+}
+
+/*
+UseStep records the time step of an accepted code. It fails when the step is
+not later than the last accepted one, so each code works only once.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+- step: The time step of the code
+
+Returns:
+- bool: Whether the step was recorded
+- error: Any database error
+*/
+func (r *TwoFactorRepo) UseStep(ctx context.Context, accountType, accountID string, step int64) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.UseStep", "UPDATE", "placement_log_two_factor").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_two_factor SET last_step = $3
+		WHERE account_type = $1 AND account_id = $2 AND enabled_at IS NOT NULL AND last_step < $3;
+	`, accountType, accountID, step)
+
+	return affected(res, err)
+}
+
+/*
+UseRecoveryCode marks an unused recovery code as used.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+- codeHash: The SHA-256 hash of the code
+
+Returns:
+- bool: Whether an unused code matched
+- error: Any database error
+*/
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, accountType, accountID, codeHash string) (bool, error) {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.UseRecoveryCode", "UPDATE", "placement_log_recovery_codes").End()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE placement_log_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE account_type = $1 AND account_id = $2 AND code_hash = $3 AND used_at IS NULL;
+	`, accountType, accountID, codeHash)
+
+	return affected(res, err)
+}
+
+/*
+RegenerateRecoveryCodes replaces the recovery codes of an enabled factor.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+- codeHashes: SHA-256 hashes of the new codes
+
+Returns:
+- error: Any database error
+*/
+func (r *TwoFactorRepo) RegenerateRecoveryCodes(ctx context.Context, accountType, accountID string, codeHashes []string) error {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.RegenerateRecoveryCodes", "INSERT", "placement_log_recovery_codes").End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err = replaceRecoveryCodes(ctx, tx, accountType, accountID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+Disable removes the account's second factor and its recovery codes.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+
+Returns:
+- error: Any database error
+*/
+func (r *TwoFactorRepo) Disable(ctx context.Context, accountType, accountID string) error {
+	defer telemetry.TraceQuery(ctx, "TwoFactorRepo.Disable", "DELETE", "placement_log_two_factor").End()
+
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM placement_log_two_factor WHERE account_type = $1 AND account_id = $2;
+	`, accountType, accountID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+
+	return nil
+}
+
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("db error: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+// Ensure TwoFactorRepo implements TwoFactorRepository
+var _ TwoFactorRepository = (*TwoFactorRepo)(nil)
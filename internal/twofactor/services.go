@@ -0,0 +1,388 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/pkg/totp"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes an account gets at a time.
+	recoveryCodeCount = 10
+	// codeSkew is how many 30 second steps of clock drift are tolerated.
+	codeSkew = 1
+)
+
+// ErrInvalidCode is returned for a wrong, expired or replayed code.
+var ErrInvalidCode = errors.New("invalid two-factor code")
+
+// Define TwoFactorRepository interface for testability
+//go:generate mockgen -destination=mock_twofactor_repo.go -package=twofactor . TwoFactorRepository
+
+type TwoFactorRepository interface {
+	GetFactor(ctx context.Context, accountType, accountID string) (*db.TwoFactor, error)
+	AccountName(ctx context.Context, accountType, accountID string) (string, error)
+	SaveSecret(ctx context.Context, accountType, accountID, secret string) error
+	Enable(ctx context.Context, accountType, accountID string, step int64, codeHashes []string) error
+	UseStep(ctx context.Context, accountType, accountID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, accountType, accountID, codeHash string) (bool, error)
+	RegenerateRecoveryCodes(ctx context.Context, accountType, accountID string, codeHashes []string) error
+	Disable(ctx context.Context, accountType, accountID string) error
+}
+
+/*
+Enrollment is a started TOTP enrollment. URI is the otpauth:// provisioning
+URI that clients render as a QR code; Secret is shown for manual entry.
+*/
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+/*
+TwoFactorService handles TOTP enrollment, verification and recovery codes,
+and decides which tokens logins receive.
+*/
+type TwoFactorService struct {
+	repo   TwoFactorRepository
+	tokens *jwt.Manager
+	guard  *loginguard.LoginGuard
+	cfg    config.TwoFactorConfig
+	now    func() time.Time
+}
+
+/*
+NewTwoFactorService creates a new TwoFactorService instance.
+
+Parameters:
+- repo: The second factor repository
+- tokens: The token manager used to issue step-up tokens
+- guard: Throttles wrong codes like failed logins (nil disables throttling)
+- cfg: The roles that require a second factor and the issuer name
+
+Returns:
+- *TwoFactorService: A new service instance
+*/
+func NewTwoFactorService(repo TwoFactorRepository, tokens *jwt.Manager, guard *loginguard.LoginGuard, cfg config.TwoFactorConfig) *TwoFactorService {
+	return &TwoFactorService{repo: repo, tokens: tokens, guard: guard, cfg: cfg, now: time.Now}
+}
+
+func (s *TwoFactorService) required(role string) bool {
+	return slices.Contains(s.cfg.RequiredRoles, role)
+}
+
+/*
+IssueToken issues the token for a login whose password has been verified.
+
+Parameters:
+- ctx: The request context
+- accountType: "user" or "admin"
+- accountID: The user or admin ID
+
+Returns:
+- string: The token
+- string: The second factor state of the token, empty for a full session
+- error: Any error that occurred
+
+Accounts with a second factor get a challenge token in the
+auth.SecondFactorPending state, and accounts whose role requires one they
+have not set up get one in the auth.SecondFactorEnroll state. Challenge
+tokens are short-lived and only admitted to the second factor endpoints.
+*/
+func (s *TwoFactorService) IssueToken(ctx context.Context, accountType, accountID string) (string, string, error) {
+	factor, err := s.repo.GetFactor(ctx, accountType, accountID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var state string
+	switch {
+	case factor != nil && factor.Enabled:
+		state = auth.SecondFactorPending
+	case s.required(accountType):
+		state = auth.SecondFactorEnroll
+	default:
+		token, err := s.tokens.GenerateJwtToken(accountID, accountType)
+		return token, "", err
+	}
+
+	token, err := s.tokens.GenerateChallengeToken(accountID, accountType, state)
+	return token, state, err
+}
+
+/*
+Enroll starts setting up a TOTP second factor for the caller.
+
+Parameters:
+- ctx: The request context
+- p: The authenticated principal
+
+Returns:
+- *Enrollment: The new secret and its provisioning URI
+- error: Any error that occurred
+
+Possible errors:
+- "two-factor authentication is already enabled": Disable it first to enroll a new device
+*/
+func (s *TwoFactorService) Enroll(ctx context.Context, p *auth.Principal) (*Enrollment, error) {
+	name, err := s.repo.AccountName(ctx, p.Role, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.SaveSecret(ctx, p.Role, p.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{Secret: secret, URI: totp.ProvisioningURI(s.cfg.Issuer, name, secret)}, nil
+}
+
+/*
+Confirm completes an enrollment with the first code from the authenticator app.
+
+Parameters:
+- ctx: The request context
+- p: The authenticated principal
+- code: The current TOTP code
+- ip: The client IP, used to throttle wrong codes
+
+Returns:
+- []string: The recovery codes, shown to the user once
+- string: A step-up token for the now verified session
+- error: Any error that occurred
+
+Possible errors:
+- "no two-factor enrollment in progress": Enroll was not called, or the factor is already enabled
+- ErrInvalidCode: Wrong code
+- *loginguard.BlockedError: Too many wrong codes
+*/
+func (s *TwoFactorService) Confirm(ctx context.Context, p *auth.Principal, code, ip string) ([]string, string, error) {
+	factor, err := s.repo.GetFactor(ctx, p.Role, p.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if factor == nil || factor.Enabled {
+		return nil, "", fmt.Errorf("no two-factor enrollment in progress")
+	}
+
+	attempt, err := s.guard.Check(ctx, p.Role, guardKey(p), ip)
+	if err != nil {
+		return nil, "", err
+	}
+	defer attempt.Release(ctx)
+
+	step, ok := totp.Validate(factor.Secret, code, s.now(), codeSkew)
+	if !ok {
+		attempt.Fail(ctx)
+		return nil, "", ErrInvalidCode
+	}
+	attempt.Succeed(ctx)
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = s.repo.Enable(ctx, p.Role, p.ID, step, hashes); err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.tokens.GenerateStepUpToken(p.ID, p.Role, auth.SecondFactorVerified)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return codes, token, nil
+}
+
+/*
+Verify completes a login with a TOTP code or a recovery code.
+
+Parameters:
+- ctx: The request context
+- p: The authenticated principal, normally holding a challenge token
+- code: A TOTP code or an unused recovery code
+- ip: The client IP, used to throttle wrong codes
+
+Returns:
+- string: A step-up token with the role's full lifetime
+- error: Any error that occurred
+
+Possible errors:
+- ErrInvalidCode: Wrong, replayed or used code, or no second factor enabled
+- *loginguard.BlockedError: Too many wrong codes
+*/
+func (s *TwoFactorService) Verify(ctx context.Context, p *auth.Principal, code, ip string) (string, error) {
+	if err := s.checkCode(ctx, p, code, ip); err != nil {
+		return "", err
+	}
+
+	return s.tokens.GenerateStepUpToken(p.ID, p.Role, auth.SecondFactorVerified)
+}
+
+/*
+RegenerateRecoveryCodes replaces the caller's recovery codes.
+
+Parameters:
+- ctx: The request context
+- p: The authenticated principal
+- code: A current TOTP code or an unused recovery code
+- ip: The client IP, used to throttle wrong codes
+
+Returns:
+- []string: The new recovery codes; the old ones stop working
+- error: Any error that occurred
+*/
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, p *auth.Principal, code, ip string) ([]string, error) {
+	if err := s.checkCode(ctx, p, code, ip); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.RegenerateRecoveryCodes(ctx, p.Role, p.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+/*
+Disable removes the caller's second factor.
+
+Parameters:
+- ctx: The request context
+- p: The authenticated principal
+- code: A current TOTP code or an unused recovery code
+- ip: The client IP, used to throttle wrong codes
+
+Returns:
+- error: Any error that occurred
+
+Possible errors:
+- "two-factor authentication is required for this account": The role requires a second factor
+*/
+func (s *TwoFactorService) Disable(ctx context.Context, p *auth.Principal, code, ip string) error {
+	if s.required(p.Role) {
+		return fmt.Errorf("two-factor authentication is required for this account")
+	}
+
+	if err := s.checkCode(ctx, p, code, ip); err != nil {
+		return err
+	}
+
+	return s.repo.Disable(ctx, p.Role, p.ID)
+}
+
+/*
+checkCode accepts a TOTP code of the caller's enabled factor, which must be
+newer than the last accepted one, or an unused recovery code. Wrong codes
+count as failed logins of the account.
+*/
+func (s *TwoFactorService) checkCode(ctx context.Context, p *auth.Principal, code, ip string) error {
+	attempt, err := s.guard.Check(ctx, p.Role, guardKey(p), ip)
+	if err != nil {
+		return err
+	}
+	defer attempt.Release(ctx)
+
+	ok, err := s.matchCode(ctx, p, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		attempt.Fail(ctx)
+		return ErrInvalidCode
+	}
+
+	attempt.Succeed(ctx)
+	return nil
+}
+
+func (s *TwoFactorService) matchCode(ctx context.Context, p *auth.Principal, code string) (bool, error) {
+	factor, err := s.repo.GetFactor(ctx, p.Role, p.ID)
+	if err != nil || factor == nil || !factor.Enabled {
+		return false, err
+	}
+
+	if step, ok := totp.Validate(factor.Secret, code, s.now(), codeSkew); ok {
+		return s.repo.UseStep(ctx, p.Role, p.ID, step)
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+
+	return s.repo.UseRecoveryCode(ctx, p.Role, p.ID, hashRecoveryCode(normalized))
+}
+
+// guardKey is the account name under which wrong codes are throttled.
+func guardKey(p *auth.Principal) string {
+	return "2fa:" + p.ID
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+/*
+newRecoveryCodes generates a fresh set of recovery codes.
+
+Returns:
+- []string: The codes, formatted as xxxx-xxxx-xxxx-xxxx
+- []string: Their hashes, which is all the database stores
+- error: If the random source fails
+*/
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery codes: %v", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode lowercases a code and strips separators.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
+
+func hashRecoveryCode(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
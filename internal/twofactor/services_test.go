@@ -0,0 +1,214 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/config"
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/pkg/totp"
+)
+
+// memoryRepo keeps factors and recovery codes in maps.
+type memoryRepo struct {
+	factors map[string]*db.TwoFactor
+	codes   map[string]map[string]bool
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{factors: make(map[string]*db.TwoFactor), codes: make(map[string]map[string]bool)}
+}
+
+func (m *memoryRepo) GetFactor(ctx context.Context, accountType, accountID string) (*db.TwoFactor, error) {
+	if f, ok := m.factors[accountType+accountID]; ok {
+		factor := *f
+		return &factor, nil
+	}
+	return nil, nil
+}
+func (m *memoryRepo) AccountName(ctx context.Context, accountType, accountID string) (string, error) {
+	return "name-" + accountID, nil
+}
+func (m *memoryRepo) SaveSecret(ctx context.Context, accountType, accountID, secret string) error {
+	if f, ok := m.factors[accountType+accountID]; ok && f.Enabled {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	m.factors[accountType+accountID] = &db.TwoFactor{AccountType: accountType, AccountID: accountID, Secret: secret}
+	return nil
+}
+func (m *memoryRepo) Enable(ctx context.Context, accountType, accountID string, step int64, codeHashes []string) error {
+	f := m.factors[accountType+accountID]
+	f.Enabled, f.LastStep = true, step
+	return m.RegenerateRecoveryCodes(context.Background(), accountType, accountID, codeHashes)
+}
+func (m *memoryRepo) UseStep(ctx context.Context, accountType, accountID string, step int64) (bool, error) {
+	f := m.factors[accountType+accountID]
+	if step <= f.LastStep {
+		return false, nil
+	}
+	f.LastStep = step
+	return true, nil
+}
+func (m *memoryRepo) UseRecoveryCode(ctx context.Context, accountType, accountID, codeHash string) (bool, error) {
+	unused, ok := m.codes[accountType+accountID][codeHash]
+	if !ok || !unused {
+		return false, nil
+	}
+	m.codes[accountType+accountID][codeHash] = false
+	return true, nil
+}
+func (m *memoryRepo) RegenerateRecoveryCodes(ctx context.Context, accountType, accountID string, codeHashes []string) error {
+	m.codes[accountType+accountID] = make(map[string]bool)
+	for _, h := range codeHashes {
+		m.codes[accountType+accountID][h] = true
+	}
+	return nil
+}
+func (m *memoryRepo) Disable(ctx context.Context, accountType, accountID string) error {
+	delete(m.factors, accountType+accountID)
+	delete(m.codes, accountType+accountID)
+	return nil
+}
+
+func newTestService(t *testing.T, cfg config.TwoFactorConfig) (*TwoFactorService, *jwt.Manager, *time.Time) {
+	t.Helper()
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	s := NewTwoFactorService(newMemoryRepo(), tokens, loginguard.NewLoginGuard(nil, nil, config.Default().Login), cfg)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, tokens, &now
+}
+
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func stateOf(t *testing.T, tokens *jwt.Manager, token string) string {
+	t.Helper()
+	claims, err := tokens.ParseToken(token)
+	if err != nil {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+	return claims.MFA
+}
+
+func TestTwoFactorService_EnrollAndLogin(t *testing.T) {
+	s, tokens, now := newTestService(t, config.Default().TwoFactor)
+
+	// Admins must enroll; students without a factor get a full session.
+	token, state, err := s.IssueToken(context.Background(), auth.RoleAdmin, "a1")
+	if err != nil || state != auth.SecondFactorEnroll || stateOf(t, tokens, token) != auth.SecondFactorEnroll {
+		t.Fatalf("expected enroll challenge for admin, got %q %v", state, err)
+	}
+	if _, state, _ = s.IssueToken(context.Background(), auth.RoleUser, "u1"); state != "" {
+		t.Errorf("expected full session for student, got %q", state)
+	}
+
+	admin := &auth.Principal{ID: "a1", Role: auth.RoleAdmin, SecondFactor: auth.SecondFactorEnroll}
+	enrollment, err := s.Enroll(context.Background(), admin)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(enrollment.URI, "PlacementLog:name-a1") || !strings.Contains(enrollment.URI, enrollment.Secret) {
+		t.Errorf("unexpected provisioning uri %q", enrollment.URI)
+	}
+
+	if _, _, err = s.Confirm(context.Background(), admin, "000000", "10.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected wrong code to be rejected, got %v", err)
+	}
+
+	codes, token, err := s.Confirm(context.Background(), admin, currentCode(t, enrollment.Secret, *now), "10.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(codes) != recoveryCodeCount || stateOf(t, tokens, token) != auth.SecondFactorVerified {
+		t.Errorf("expected %d recovery codes and a verified token, got %v", recoveryCodeCount, codes)
+	}
+	if _, err = s.Enroll(context.Background(), admin); err == nil {
+		t.Error("expected a second enrollment to be rejected")
+	}
+
+	// The next login is challenged for a code.
+	if _, state, _ = s.IssueToken(context.Background(), auth.RoleAdmin, "a1"); state != auth.SecondFactorPending {
+		t.Errorf("expected pending challenge, got %q", state)
+	}
+	pending := &auth.Principal{ID: "a1", Role: auth.RoleAdmin, SecondFactor: auth.SecondFactorPending}
+
+	if _, err = s.Verify(context.Background(), pending, currentCode(t, enrollment.Secret, *now), "10.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected the code used to confirm to be rejected as a replay, got %v", err)
+	}
+
+	*now = now.Add(totp.Period)
+	token, err = s.Verify(context.Background(), pending, currentCode(t, enrollment.Secret, *now), "10.0.0.1")
+	if err != nil || stateOf(t, tokens, token) != auth.SecondFactorVerified {
+		t.Fatalf("expected verified token, got %v", err)
+	}
+
+	// Recovery codes work once, with any case and separators.
+	recovery := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	if _, err = s.Verify(context.Background(), pending, recovery, "10.0.0.1"); err != nil {
+		t.Errorf("expected recovery code to be accepted, got %v", err)
+	}
+	if _, err = s.Verify(context.Background(), pending, codes[0], "10.0.0.1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected used recovery code to be rejected, got %v", err)
+	}
+
+	if err = s.Disable(context.Background(), admin, codes[1], "10.0.0.1"); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("expected admins to be unable to disable their second factor, got %v", err)
+	}
+}
+
+func TestTwoFactorService_OptionalForStudents(t *testing.T) {
+	s, _, now := newTestService(t, config.Default().TwoFactor)
+	student := &auth.Principal{ID: "u1", Role: auth.RoleUser}
+
+	enrollment, err := s.Enroll(context.Background(), student)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, _, err = s.Confirm(context.Background(), student, currentCode(t, enrollment.Secret, *now), "10.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, state, _ := s.IssueToken(context.Background(), auth.RoleUser, "u1"); state != auth.SecondFactorPending {
+		t.Errorf("expected enrolled student to be challenged, got %q", state)
+	}
+
+	*now = now.Add(totp.Period)
+	if err = s.Disable(context.Background(), student, currentCode(t, enrollment.Secret, *now), "10.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, state, _ := s.IssueToken(context.Background(), auth.RoleUser, "u1"); state != "" {
+		t.Errorf("expected full session after disabling, got %q", state)
+	}
+}
+
+func TestTwoFactorService_Throttled(t *testing.T) {
+	s, _, now := newTestService(t, config.Default().TwoFactor)
+	student := &auth.Principal{ID: "u1", Role: auth.RoleUser}
+	enrollment, _ := s.Enroll(context.Background(), student)
+	if _, _, err := s.Confirm(context.Background(), student, currentCode(t, enrollment.Secret, *now), "10.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var blocked *loginguard.BlockedError
+	for i := 0; i < 10; i++ {
+		if _, err := s.Verify(context.Background(), student, "000000", "10.0.0.1"); errors.As(err, &blocked) {
+			return
+		}
+	}
+	t.Error("expected repeated wrong codes to be throttled")
+}
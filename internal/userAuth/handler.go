@@ -1,8 +1,13 @@
 package userauth
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/session"
 	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
@@ -11,7 +16,8 @@ UserAuthHandler handles user authentication HTTP requests.
 Provides endpoints for user login, registration, and logout.
 */
 type UserAuthHandler struct {
-	srv *UserAuthService
+	srv      *UserAuthService
+	sessions *session.Manager
 }
 
 /*
@@ -35,7 +41,11 @@ type responsePayload struct {
 	UserID   string `json:"userid"`
 	Regno    string `json:"regno"`
 	Username string `json:"username"`
-	Token    string `json:"token"`
+	Token    string `json:"token,omitempty"`
+	Status   string `json:"status,omitempty"`
+	// TwoFactor is set when Token is a challenge token: "pending" asks for a
+	// code at /auth/2fa/verify, "enroll" for setting up TOTP at /auth/2fa/enroll.
+	TwoFactor string `json:"two_factor,omitempty"`
 }
 
 /*
@@ -43,13 +53,15 @@ NewUserAuthHandler creates a new UserAuthHandler instance with the provided serv
 
 Parameters:
 - srv: The user authentication service
+- sessions: Sets the session cookies of logins in cookie session mode (nil when disabled)
 
 Returns:
 - *UserAuthHandler: A new handler instance
 */
-func NewUserAuthHandler(srv *UserAuthService) *UserAuthHandler {
+func NewUserAuthHandler(srv *UserAuthService, sessions *session.Manager) *UserAuthHandler {
 	return &UserAuthHandler{
-		srv: srv,
+		srv:      srv,
+		sessions: sessions,
 	}
 }
 
@@ -76,10 +88,19 @@ Response (200 OK):
 	  "token": "jwt_token_here"
 	}
 
+When the account has a second factor, the token is a short-lived challenge
+token and the response adds "two_factor": "pending"; the client exchanges it
+for a full token at /auth/2fa/verify.
+
+In cookie session mode the token is set as an HttpOnly cookie instead of
+being returned, and the CSRF token is returned in the X-CSRF-Token header.
+
 Returns:
 - 200 OK: Successful login with token
 - 400 Bad Request: Invalid request format
 - 401 Unauthorized: Invalid credentials
+- 403 Forbidden: The registration is pending verification
+- 429 Too Many Requests: Too many failed attempts; Retry-After gives the wait in seconds
 */
 func (h *UserAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var payload loginRequestPayload
@@ -89,17 +110,23 @@ func (h *UserAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, user, err := h.srv.Login(payload.Regno, payload.Password)
+	token, secondFactor, user, err := h.srv.Login(r.Context(), payload.Regno, payload.Password, utils.ClientIP(r))
 	if err != nil {
-		utils.WriteError(w, err)
+		writeLoginError(w, err)
+		return
+	}
+
+	if token, err = h.sessions.Issue(w, token); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	resp := responsePayload{
-		UserID:   user.ID,
-		Regno:    user.Regno,
-		Username: user.Username,
-		Token:    token,
+		UserID:    user.ID,
+		Regno:     user.Regno,
+		Username:  user.Username,
+		Token:     token,
+		TwoFactor: secondFactor,
 	}
 
 	utils.WriteJSON(w, resp, http.StatusOK)
@@ -129,9 +156,22 @@ Response (201 Created):
 	  "token": "jwt_token_here"
 	}
 
+Response (202 Accepted), when the registration mode is "pending":
+
+	{
+	  "userid": "user_id",
+	  "regno": "22bcs1234",
+	  "username": "John Doe",
+	  "status": "pending"
+	}
+
+In cookie session mode the token of a 201 response is set as a cookie, as
+on login.
+
 Returns:
 - 201 Created: Successful registration with token
-- 400 Bad Request: Invalid request format
+- 202 Accepted: Registration waits for admin approval or confirmation from the roster email; no token is issued
+- 400 Bad Request: Invalid request format, or the regno is not on the roster
 - 409 Conflict: Username already exists
 */
 func (h *UserAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -142,19 +182,30 @@ func (h *UserAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, userId, err := h.srv.Register(payload.Regno, payload.Username, payload.Password)
+	token, user, err := h.srv.Register(r.Context(), payload.Regno, payload.Username, payload.Password)
 	if err != nil {
 		utils.WriteError(w, err)
 		return
 	}
 
 	resp := responsePayload{
-		UserID:   userId,
-		Regno:    payload.Regno,
-		Username: payload.Username,
+		UserID:   user.ID,
+		Regno:    user.Regno,
+		Username: user.Username,
 		Token:    token,
 	}
 
+	if user.Status == db.UserStatusPending {
+		resp.Status = user.Status
+		utils.WriteJSON(w, resp, http.StatusAccepted)
+		return
+	}
+
+	if resp.Token, err = h.sessions.Issue(w, token); err != nil {
+		utils.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	utils.WriteJSON(w, resp, http.StatusCreated)
 }
 
@@ -176,7 +227,251 @@ Response (200 OK):
 	}
 
 Note: The client should remove the JWT token from local storage after calling this endpoint.
+In cookie session mode the session cookies are cleared.
 */
 func (h *UserAuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.sessions.Clear(w)
+
 	utils.WriteJSON(w, map[string]string{"message": "logged out successfully"}, http.StatusOK)
 }
+
+/*
+writeLoginError maps login errors to responses: 429 with Retry-After while
+throttled, 401 for bad credentials, 403 for pending, suspended and
+reset-required accounts and 400 otherwise.
+*/
+func writeLoginError(w http.ResponseWriter, err error) {
+	var blocked *loginguard.BlockedError
+	var suspended *auth.SuspendedError
+	switch {
+	case errors.As(err, &blocked):
+		w.Header().Set("Retry-After", blocked.RetryAfterHeader())
+		utils.WriteError(w, err, http.StatusTooManyRequests)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		utils.WriteError(w, err, http.StatusUnauthorized)
+	case errors.Is(err, ErrAccountPending), errors.Is(err, auth.ErrPasswordResetRequired):
+		utils.WriteError(w, err, http.StatusForbidden)
+	case errors.As(err, &suspended):
+		utils.WriteError(w, err, http.StatusForbidden)
+	default:
+		utils.WriteError(w, err)
+	}
+}
+
+/*
+emailRequest represents the JSON payload for attaching an email or requesting a password reset.
+*/
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+/*
+tokenRequest represents the JSON payload for verifying an email or resetting a password.
+*/
+type tokenRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+/*
+AttachEmail handles requests to add an email address to the account.
+A verification link is emailed to the address; it is attached once the link is used.
+
+HTTP Method: POST
+Endpoint: /auth/email
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Request Body:
+
+	{
+	  "email": "student@example.edu"
+	}
+
+Returns:
+- 202 Accepted: Verification email sent
+- 400 Bad Request: Invalid address or address already in use
+- 401 Unauthorized: Missing or invalid user token
+*/
+func (h *UserAuthHandler) AttachEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errors.New("unauthorized: user authentication required"), http.StatusUnauthorized)
+		return
+	}
+
+	var payload emailRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if err := h.srv.AttachEmail(r.Context(), userID, payload.Email); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "verification email sent"}, http.StatusAccepted)
+}
+
+/*
+VerifyEmail handles email verification with the token from the emailed link.
+
+HTTP Method: POST
+Endpoint: /auth/email/verify
+
+Request Body:
+
+	{
+	  "token": "token_from_link"
+	}
+
+Response (200 OK):
+
+	{
+	  "email": "student@example.edu"
+	}
+
+Returns:
+- 200 OK: Address verified and attached
+- 400 Bad Request: Invalid, used or expired token
+*/
+func (h *UserAuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload tokenRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	email, err := h.srv.VerifyEmail(r.Context(), payload.Token)
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"email": email}, http.StatusOK)
+}
+
+/*
+ForgotPassword handles password reset requests.
+The response is the same whether or not the address belongs to an account.
+
+HTTP Method: POST
+Endpoint: /auth/password/forgot
+
+Request Body:
+
+	{
+	  "email": "student@example.edu"
+	}
+
+Returns:
+- 202 Accepted: A reset link is emailed if the address is verified on an account
+- 400 Bad Request: Malformed address
+*/
+func (h *UserAuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload emailRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if err := h.srv.RequestPasswordReset(r.Context(), payload.Email); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "if the address belongs to an account, a reset link has been sent"}, http.StatusAccepted)
+}
+
+/*
+ResetPassword handles setting a new password with the token from a reset link.
+
+HTTP Method: POST
+Endpoint: /auth/password/reset
+
+Request Body:
+
+	{
+	  "token": "token_from_link",
+	  "password": "new_password"
+	}
+
+Returns:
+- 200 OK: Password changed
+- 400 Bad Request: Missing fields or invalid, used or expired token
+*/
+func (h *UserAuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload tokenRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if err := h.srv.ResetPassword(r.Context(), payload.Token, payload.Password); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, map[string]string{"message": "password has been reset"}, http.StatusOK)
+}
+
+/*
+changePasswordRequest represents the JSON payload for password changes.
+*/
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+/*
+ChangePassword handles password changes by the logged-in user.
+
+HTTP Method: POST
+Endpoint: /users/me/password
+
+Headers Required:
+- Authorization: Bearer <user_jwt_token>
+
+Request Body:
+
+	{
+	  "current_password": "old password",
+	  "new_password": "new password"
+	}
+
+Returns:
+- 200 OK: Password changed
+- 400 Bad Request: Missing field or the new password violates the password policy
+- 401 Unauthorized: Missing or invalid user token
+- 403 Forbidden: The current password is wrong
+- 429 Too Many Requests: Too many wrong current passwords; Retry-After gives the wait in seconds
+*/
+func (h *UserAuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		utils.WriteError(w, errors.New("unauthorized: user authentication required"), http.StatusUnauthorized)
+		return
+	}
+
+	var payload changePasswordRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	err := h.srv.ChangePassword(r.Context(), userID, payload.CurrentPassword, payload.NewPassword, utils.ClientIP(r))
+	var blocked *loginguard.BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		w.Header().Set("Retry-After", blocked.RetryAfterHeader())
+		utils.WriteError(w, err, http.StatusTooManyRequests)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		utils.WriteError(w, errors.New("current password is wrong"), http.StatusForbidden)
+	case err != nil:
+		utils.WriteError(w, err)
+	default:
+		utils.WriteJSON(w, map[string]string{"message": "password changed"}, http.StatusOK)
+	}
+}
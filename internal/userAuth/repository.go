@@ -1,14 +1,18 @@
 package userauth
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"regexp"
-	"strings"
+	"log"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/varnit-ta/PlacementLog/internal/db"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
 /*
@@ -16,7 +20,8 @@ UserAuthRepo handles user authentication data access operations.
 Provides methods for user login and registration with database interactions.
 */
 type UserAuthRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	hasher *password.Hasher
 }
 
 /*
@@ -24,13 +29,15 @@ NewUserAuthRepo creates a new UserAuthRepo instance with the provided database c
 
 Parameters:
 - db: The database connection
+- hasher: Hashes and verifies passwords
 
 Returns:
 - *UserAuthRepo: A new repository instance
 */
-func NewUserAuthRepo(db *sql.DB) *UserAuthRepo {
+func NewUserAuthRepo(db *sql.DB, hasher *password.Hasher) *UserAuthRepo {
 	return &UserAuthRepo{
-		db: db,
+		db:     db,
+		hasher: hasher,
 	}
 }
 
@@ -38,6 +45,7 @@ func NewUserAuthRepo(db *sql.DB) *UserAuthRepo {
 Login validates user credentials against the database.
 
 Parameters:
+- ctx: The request context
 - username: The user's username (registration number format: 22bcs1234)
 - pass: The user's password
 
@@ -48,67 +56,156 @@ Returns:
 The function:
 1. Validates the username format using regex (22bcs1234 pattern)
 2. Queries the database for the user
-3. Compares the provided password with the hashed password using bcrypt
-4. Returns user information upon successful authentication
+3. Verifies the provided password against the stored hash
+4. Rehashes the password if the stored hash is bcrypt or uses older argon2id parameters
+5. Returns user information upon successful authentication
 
 Possible errors:
 - "all fields are required": Missing username or password
 - "not a valid registration number": Invalid username format
-- "no such user exists": User not found in database
-- "incorrect password": Password doesn't match
+- auth.ErrInvalidCredentials: User not found or password doesn't match
 */
-func (repo UserAuthRepo) Login(regno, pass string) (*db.User, error) {
+func (repo UserAuthRepo) Login(ctx context.Context, regno, pass string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.Login", "SELECT", "placement_log_users").End()
+
 	if regno == "" || pass == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
 	// Standardize regno to lowercase for case-insensitive login
-	regno = strings.ToLower(regno)
-
-	re := regexp.MustCompile(`^\d{2}[a-z]{3}\d{4}$`)
+	regno, ok := utils.NormalizeRegno(regno)
 
-	if !re.MatchString(regno) {
+	if !ok {
 		return nil, fmt.Errorf("not a valid registration number")
 	}
 
 	queryString := `
-		SELECT id, regno, password, created_at, username
+		SELECT id, regno, password, created_at, username, status,
+			suspended_at, suspended_until, COALESCE(suspension_reason, ''), password_reset_required
 		FROM placement_log_users
 		WHERE regno=$1;
 	`
 
 	var user db.User
 	var hashedPass string
+	var suspendedAt sql.NullTime
+	var suspension db.Suspension
 
-	err := repo.db.QueryRow(queryString, regno).Scan(
+	err := repo.db.QueryRowContext(ctx, queryString, regno).Scan(
 		&user.ID,
 		&user.Regno,
 		&hashedPass,
 		&user.CreatedAt,
 		&user.Username,
+		&user.Status,
+		&suspendedAt,
+		&suspension.Until,
+		&suspension.Reason,
+		&user.PasswordResetRequired,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no such user exists")
+		repo.hasher.CompareDummy(pass)
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	match, rehash, err := repo.hasher.Verify(pass, hashedPass)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying pass: %v", err)
+	}
+
+	if !match {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if rehash {
+		repo.rehash(ctx, user.ID, pass, hashedPass)
+	}
+
+	if suspendedAt.Valid {
+		suspension.Since = suspendedAt.Time
+		user.Suspension = &suspension
+	}
+
+	return &user, nil
+}
+
+/*
+VerifyPassword checks the current password of a logged-in user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- pass: The password to check
+
+Returns:
+- *db.User: The user, when the password matches
+- error: auth.ErrInvalidCredentials on a mismatch, or a database error
+*/
+func (repo UserAuthRepo) VerifyPassword(ctx context.Context, userID, pass string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.VerifyPassword", "SELECT", "placement_log_users").End()
+
+	var user db.User
+	var hashedPass string
+
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT id, regno, password, created_at, username, status, COALESCE(email, '')
+		FROM placement_log_users
+		WHERE id = $1;
+	`, userID).Scan(&user.ID, &user.Regno, &hashedPass, &user.CreatedAt, &user.Username, &user.Status, &user.Email)
+
+	if err == sql.ErrNoRows {
+		repo.hasher.CompareDummy(pass)
+		return nil, auth.ErrInvalidCredentials
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("db error: %v", err)
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(pass)); err != nil {
-		return nil, fmt.Errorf("incorrect password")
+	match, _, err := repo.hasher.Verify(pass, hashedPass)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying pass: %v", err)
+	}
+
+	if !match {
+		return nil, auth.ErrInvalidCredentials
 	}
 
 	return &user, nil
 }
 
+/*
+rehash replaces an outdated password hash after a successful login.
+The update only applies if the stored hash is still the one that was
+verified, so it cannot undo a concurrent password change. Failures are
+logged and do not affect the login.
+*/
+func (repo UserAuthRepo) rehash(ctx context.Context, userID, pass, oldHash string) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.rehash", "UPDATE", "placement_log_users").End()
+
+	hashedPass, err := repo.hasher.Hash(pass)
+	if err == nil {
+		_, err = repo.db.ExecContext(ctx, `UPDATE placement_log_users SET password = $2 WHERE id = $1 AND password = $3;`, userID, hashedPass, oldHash)
+	}
+
+	if err != nil {
+		log.Printf("userauth: rehashing password of %s: %v", userID, err)
+	}
+}
+
 /*
 Register creates a new user account in the database.
 
 Parameters:
+- ctx: The request context
 - username: The user's username (registration number format: 22bcs1234)
 - pass: The user's password
+- status: db.UserStatusActive, or db.UserStatusPending until the registration is verified
 
 Returns:
 - *db.User: The newly created user information
@@ -116,7 +213,7 @@ Returns:
 
 The function:
 1. Validates the username format using regex (22bcs1234 pattern)
-2. Hashes the password using bcrypt with default cost
+2. Hashes the password with argon2id
 3. Inserts the new user into the database
 4. Returns the created user information
 
@@ -127,39 +224,40 @@ Possible errors:
 - "error hashing pass": Password hashing failed
 - "failed to insert user": Database insertion failed
 */
-func (repo UserAuthRepo) Register(regno, username, pass string) (*db.User, error) {
+func (repo UserAuthRepo) Register(ctx context.Context, regno, username, pass, status string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.Register", "INSERT", "placement_log_users").End()
+
 	if regno == "" || username == "" || pass == "" {
 		return nil, fmt.Errorf("all fields are required")
 	}
 
 	// Convert the input to lowercase BEFORE validation
-	regno = strings.ToLower(regno)
-
-	re := regexp.MustCompile(`^\d{2}[a-z]{3}\d{4}$`)
+	regno, ok := utils.NormalizeRegno(regno)
 
-	if !re.MatchString(regno) {
+	if !ok {
 		return nil, fmt.Errorf("not a valid registration number")
 	}
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	hashedPass, err := repo.hasher.Hash(pass)
 
 	if err != nil {
 		return nil, fmt.Errorf("error hashing pass: %v", err)
 	}
 
 	queryString := `
-		INSERT INTO placement_log_users (regno, password, username)
-		VALUES ($1, $2, $3)
-		RETURNING id, regno, created_at, username;
+		INSERT INTO placement_log_users (regno, password, username, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, regno, created_at, username, status;
 	`
 
 	var user db.User
 
-	err = repo.db.QueryRow(queryString, regno, hashedPass, username).Scan(
+	err = repo.db.QueryRowContext(ctx, queryString, regno, hashedPass, username, status).Scan(
 		&user.ID,
 		&user.Regno,
 		&user.CreatedAt,
 		&user.Username,
+		&user.Status,
 	)
 
 	if err != nil {
@@ -172,5 +270,261 @@ func (repo UserAuthRepo) Register(regno, username, pass string) (*db.User, error
 	return &user, nil
 }
 
+/*
+CreateToken stores the hash of a new email verification or password reset
+token. Earlier unused tokens of the same purpose are revoked, so only the
+most recently sent link works.
+
+Parameters:
+- ctx: The request context
+- userID: The user the token belongs to
+- purpose: purposeVerifyEmail or purposeResetPassword
+- tokenHash: Hex SHA-256 of the token
+- email: The address being verified, or empty
+- ttl: How long the token stays valid
+
+Returns:
+- error: Any error that occurred during insertion
+*/
+func (repo UserAuthRepo) CreateToken(ctx context.Context, userID, purpose, tokenHash, email string, ttl time.Duration) error {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.CreateToken", "INSERT", "placement_log_user_tokens").End()
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE placement_log_user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+	`, userID, purpose)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO placement_log_user_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), CURRENT_TIMESTAMP + make_interval(secs => $5));
+	`, userID, purpose, tokenHash, email, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to create token: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+/*
+FindToken looks up an unused, unexpired token without consuming it, so a
+request can be validated against the token's user before the token is spent.
+
+Parameters:
+- ctx: The request context
+- purpose: The purpose the token must have been issued for
+- tokenHash: Hex SHA-256 of the submitted token
+
+Returns:
+- *db.UserToken: The user, their name and the email the token was issued for
+- error: Any error that occurred during the query
+
+Possible errors:
+- "invalid or expired token": Unknown, already used, revoked or expired token
+*/
+func (repo UserAuthRepo) FindToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.FindToken", "SELECT", "placement_log_user_tokens").End()
+
+	query := `
+		SELECT t.user_id, u.regno, u.username, COALESCE(t.email, '')
+		FROM placement_log_user_tokens t
+		JOIN placement_log_users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL
+			AND t.expires_at > CURRENT_TIMESTAMP;
+	`
+
+	var token db.UserToken
+	err := repo.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(&token.UserID, &token.Regno, &token.Username, &token.Email)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired token")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &token, nil
+}
+
+/*
+ConsumeToken marks a token as used and returns what it was issued for.
+The check and the update are one statement, so a token can only be used once
+even when submitted concurrently.
+
+Parameters:
+- ctx: The request context
+- purpose: The purpose the token must have been issued for
+- tokenHash: Hex SHA-256 of the submitted token
+
+Returns:
+- *db.UserToken: The user and email the token was issued for
+- error: Any error that occurred during the update
+
+Possible errors:
+- "invalid or expired token": Unknown, already used, revoked or expired token
+*/
+func (repo UserAuthRepo) ConsumeToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.ConsumeToken", "UPDATE", "placement_log_user_tokens").End()
+
+	query := `
+		UPDATE placement_log_user_tokens t SET used_at = CURRENT_TIMESTAMP
+		FROM placement_log_users u
+		WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL
+			AND t.expires_at > CURRENT_TIMESTAMP AND u.id = t.user_id
+		RETURNING t.user_id, u.regno, COALESCE(t.email, '');
+	`
+
+	var token db.UserToken
+	err := repo.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(&token.UserID, &token.Regno, &token.Email)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired token")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &token, nil
+}
+
+/*
+SetEmail stores a verified email address on the user.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- email: The verified address
+
+Returns:
+- error: Any error that occurred during the update
+
+Possible errors:
+- "email already in use": Another account verified this address
+*/
+func (repo UserAuthRepo) SetEmail(ctx context.Context, userID, email string) error {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.SetEmail", "UPDATE", "placement_log_users").End()
+
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE placement_log_users SET email = $2, email_verified_at = CURRENT_TIMESTAMP
+		WHERE id = $1;
+	`, userID, email)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return fmt.Errorf("email already in use")
+		}
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return nil
+}
+
+/*
+GetUserByEmail looks up the user with the given verified email address.
+
+Parameters:
+- ctx: The request context
+- email: The lowercased address
+
+Returns:
+- *db.User: The user, or nil when no account has verified this address
+- error: Any database error
+*/
+func (repo UserAuthRepo) GetUserByEmail(ctx context.Context, email string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.GetUserByEmail", "SELECT", "placement_log_users").End()
+
+	var user db.User
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT id, regno, created_at, username, email
+		FROM placement_log_users
+		WHERE email = $1;
+	`, email).Scan(&user.ID, &user.Regno, &user.CreatedAt, &user.Username, &user.Email)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	return &user, nil
+}
+
+/*
+UpdatePassword replaces the user's password, revokes their outstanding
+password reset tokens and clears a reset required by an admin.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- pass: The new password
+
+Returns:
+- error: Any error that occurred during hashing or the update
+*/
+func (repo UserAuthRepo) UpdatePassword(ctx context.Context, userID, pass string) error {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.UpdatePassword", "UPDATE", "placement_log_users").End()
+
+	hashedPass, err := repo.hasher.Hash(pass)
+	if err != nil {
+		return fmt.Errorf("error hashing pass: %v", err)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `UPDATE placement_log_users SET password = $2, password_reset_required = FALSE WHERE id = $1;`, userID, hashedPass); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE placement_log_user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+	`, userID, purposeResetPassword)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+/*
+ActivateUser moves a pending registration to the active state.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- error: Any error that occurred during the update
+*/
+func (repo UserAuthRepo) ActivateUser(ctx context.Context, userID string) error {
+	defer telemetry.TraceQuery(ctx, "UserAuthRepo.ActivateUser", "UPDATE", "placement_log_users").End()
+
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE placement_log_users SET status = $2
+		WHERE id = $1 AND status = $3;
+	`, userID, db.UserStatusActive, db.UserStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to activate user: %v", err)
+	}
+
+	return nil
+}
+
 // Ensure UserAuthRepo implements UserAuthRepository
 var _ UserAuthRepository = (*UserAuthRepo)(nil)
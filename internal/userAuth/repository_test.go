@@ -1,9 +1,11 @@
 package userauth
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/varnit-ta/PlacementLog/internal/db"
 )
@@ -13,15 +15,47 @@ type mockDB struct{}
 // Add methods as needed to satisfy the sql.DB interface for the test, or just mock the repository methods directly.
 
 type mockUserAuthRepo struct {
-	LoginFunc    func(regno, pass string) (*db.User, error)
-	RegisterFunc func(regno, username, pass string) (*db.User, error)
+	LoginFunc          func(regno, pass string) (*db.User, error)
+	RegisterFunc       func(regno, username, pass, status string) (*db.User, error)
+	CreateTokenFunc    func(userID, purpose, tokenHash, email string, ttl time.Duration) error
+	FindTokenFunc      func(purpose, tokenHash string) (*db.UserToken, error)
+	ConsumeTokenFunc   func(purpose, tokenHash string) (*db.UserToken, error)
+	SetEmailFunc       func(userID, email string) error
+	GetUserByEmailFunc func(email string) (*db.User, error)
+	UpdatePasswordFunc func(userID, pass string) error
+	ActivateUserFunc   func(userID string) error
+	VerifyPasswordFunc func(userID, pass string) (*db.User, error)
 }
 
-func (m *mockUserAuthRepo) Login(regno, pass string) (*db.User, error) {
+func (m *mockUserAuthRepo) Login(ctx context.Context, regno, pass string) (*db.User, error) {
 	return m.LoginFunc(regno, pass)
 }
-func (m *mockUserAuthRepo) Register(regno, username, pass string) (*db.User, error) {
-	return m.RegisterFunc(regno, username, pass)
+func (m *mockUserAuthRepo) Register(ctx context.Context, regno, username, pass, status string) (*db.User, error) {
+	return m.RegisterFunc(regno, username, pass, status)
+}
+func (m *mockUserAuthRepo) CreateToken(ctx context.Context, userID, purpose, tokenHash, email string, ttl time.Duration) error {
+	return m.CreateTokenFunc(userID, purpose, tokenHash, email, ttl)
+}
+func (m *mockUserAuthRepo) FindToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error) {
+	return m.FindTokenFunc(purpose, tokenHash)
+}
+func (m *mockUserAuthRepo) ConsumeToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error) {
+	return m.ConsumeTokenFunc(purpose, tokenHash)
+}
+func (m *mockUserAuthRepo) SetEmail(ctx context.Context, userID, email string) error {
+	return m.SetEmailFunc(userID, email)
+}
+func (m *mockUserAuthRepo) GetUserByEmail(ctx context.Context, email string) (*db.User, error) {
+	return m.GetUserByEmailFunc(email)
+}
+func (m *mockUserAuthRepo) UpdatePassword(ctx context.Context, userID, pass string) error {
+	return m.UpdatePasswordFunc(userID, pass)
+}
+func (m *mockUserAuthRepo) ActivateUser(ctx context.Context, userID string) error {
+	return m.ActivateUserFunc(userID)
+}
+func (m *mockUserAuthRepo) VerifyPassword(ctx context.Context, userID, pass string) (*db.User, error) {
+	return m.VerifyPasswordFunc(userID, pass)
 }
 
 func TestUserAuthRepo_Login_TableDriven(t *testing.T) {
@@ -49,7 +83,7 @@ func TestUserAuthRepo_Login_TableDriven(t *testing.T) {
 		{"22bcs9999", "password", ""},
 	}
 	for _, c := range cases {
-		_, err := repo.Login(c.regno, c.pass)
+		_, err := repo.Login(context.Background(), c.regno, c.pass)
 		if c.wantErr == "" && err != nil {
 			t.Errorf("Login(%q, %q) unexpected error: %v", c.regno, c.pass, err)
 		}
@@ -61,7 +95,7 @@ func TestUserAuthRepo_Login_TableDriven(t *testing.T) {
 
 func TestUserAuthRepo_Register_TableDriven(t *testing.T) {
 	repo := &mockUserAuthRepo{
-		RegisterFunc: func(regno, username, pass string) (*db.User, error) {
+		RegisterFunc: func(regno, username, pass, status string) (*db.User, error) {
 			if regno == "" || username == "" || pass == "" {
 				return nil, errors.New("all fields are required")
 			}
@@ -80,7 +114,7 @@ func TestUserAuthRepo_Register_TableDriven(t *testing.T) {
 		{"22bcs9999", "user", "password", ""},
 	}
 	for _, c := range cases {
-		_, err := repo.Register(c.regno, c.username, c.pass)
+		_, err := repo.Register(context.Background(), c.regno, c.username, c.pass, db.UserStatusActive)
 		if c.wantErr == "" && err != nil {
 			t.Errorf("Register(%q, %q, %q) unexpected error: %v", c.regno, c.username, c.pass, err)
 		}
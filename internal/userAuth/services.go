@@ -1,18 +1,65 @@
 package userauth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/varnit-ta/PlacementLog/internal/config"
 	"github.com/varnit-ta/PlacementLog/internal/db"
-	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
 )
 
+// Purposes of single-use account tokens.
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+)
+
+// ErrAccountPending is returned when a pending registration tries to log in.
+var ErrAccountPending = errors.New("account pending verification")
+
 // Define UserAuthRepository interface for testability
 //go:generate mockgen -destination=mock_userauth_repo.go -package=userauth . UserAuthRepository
 
 type UserAuthRepository interface {
-	Login(regno, pass string) (*db.User, error)
-	Register(regno, username, pass string) (*db.User, error)
+	Login(ctx context.Context, regno, pass string) (*db.User, error)
+	Register(ctx context.Context, regno, username, pass, status string) (*db.User, error)
+	CreateToken(ctx context.Context, userID, purpose, tokenHash, email string, ttl time.Duration) error
+	FindToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error)
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (*db.UserToken, error)
+	SetEmail(ctx context.Context, userID, email string) error
+	GetUserByEmail(ctx context.Context, email string) (*db.User, error)
+	UpdatePassword(ctx context.Context, userID, pass string) error
+	ActivateUser(ctx context.Context, userID string) error
+	VerifyPassword(ctx context.Context, userID, pass string) (*db.User, error)
+}
+
+/*
+TokenIssuer issues the token of a login whose password has been verified,
+together with its second factor state (see auth.SecondFactorPending and
+auth.SecondFactorEnroll); the state is empty for a full session.
+*/
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, accountType, accountID string) (string, string, error)
+}
+
+/*
+RosterLookup finds the official roster entry of a registration number.
+It returns nil when the regno is not on the roster.
+*/
+type RosterLookup interface {
+	GetEntry(ctx context.Context, regno string) (*db.RosterEntry, error)
 }
 
 /*
@@ -20,7 +67,14 @@ UserAuthService handles user authentication business logic.
 Provides methods for user login and registration with JWT token generation.
 */
 type UserAuthService struct {
-	repo UserAuthRepository
+	repo    UserAuthRepository
+	tokens  TokenIssuer
+	guard   *loginguard.LoginGuard
+	mailer  mail.Sender
+	roster  RosterLookup
+	policy  *password.Policy
+	account config.AccountConfig
+	now     func() time.Time
 }
 
 /*
@@ -28,78 +82,507 @@ NewUserAuthService creates a new UserAuthService instance with the provided repo
 
 Parameters:
 - repo: The user authentication repository
+- tokens: Issues the tokens of logins, with a challenge when a second factor is due
+- guard: The login throttle (nil disables throttling)
+- mailer: Sends verification and password reset emails
+- roster: The student roster registrations are checked against
+- policy: The rules for new passwords (nil accepts any password)
+- account: Registration mode, token lifetimes and the base URL of emailed links
 
 Returns:
 - *UserAuthService: A new service instance
 */
-func NewUserAuthService(repo UserAuthRepository) *UserAuthService {
-	return &UserAuthService{repo: repo}
+func NewUserAuthService(repo UserAuthRepository, tokens TokenIssuer, guard *loginguard.LoginGuard, mailer mail.Sender, roster RosterLookup, policy *password.Policy, account config.AccountConfig) *UserAuthService {
+	return &UserAuthService{repo: repo, tokens: tokens, guard: guard, mailer: mailer, roster: roster, policy: policy, account: account, now: time.Now}
 }
 
 /*
 Login authenticates a user with the provided credentials and generates a JWT token.
 
 Parameters:
+- ctx: The request context
 - username: The user's username (registration number format: 22bcs1234)
 - password: The user's password
+- ip: The client IP, used to throttle repeated failures
 
 Returns:
 - string: JWT token for the authenticated user
-- string: User ID of the authenticated user
+- string: The second factor state of the token; a challenge token only reaches the /auth/2fa endpoints
+- *db.User: The authenticated user
 - error: Any error that occurred during authentication
 
 The function:
-1. Validates the user credentials against the database
-2. Generates a JWT token with "user" role
-3. Returns the token and user ID upon successful authentication
+1. Rejects the attempt while the account or IP is backing off or locked out
+2. Validates the user credentials against the database
+3. Records a failure, or clears the account's failures on success
+4. Rejects registrations that are still pending verification, suspended accounts and accounts that must reset their password
+5. Issues a JWT token with "user" role, or a challenge token when a second factor is due
+6. Returns the token and user upon successful authentication
+
+Possible errors:
+- *loginguard.BlockedError: Too many recent failures
+- auth.ErrInvalidCredentials (wrapped): Unknown regno or wrong password
+- ErrAccountPending (wrapped): The registration has not been verified yet
+- *auth.SuspendedError (wrapped): An admin suspended or banned the account
+- auth.ErrPasswordResetRequired (wrapped): An admin requires a new password, set through a reset link
 */
-func (s *UserAuthService) Login(regno, password string) (string, *db.User, error) {
-	user, err := s.repo.Login(regno, password)
+func (s *UserAuthService) Login(ctx context.Context, regno, password, ip string) (string, string, *db.User, error) {
+	attempt, err := s.guard.Check(ctx, auth.RoleUser, regno, ip)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer attempt.Release(ctx)
+
+	user, err := s.repo.Login(ctx, regno, password)
+
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		attempt.Fail(ctx)
+	}
 
 	if err != nil {
-		return "", nil, fmt.Errorf("login failed: %w", err)
+		return "", "", nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	attempt.Succeed(ctx)
+
+	if user.Status == db.UserStatusPending {
+		return "", "", nil, fmt.Errorf("login failed: %w", ErrAccountPending)
+	}
+
+	if user.Suspension.ActiveAt(s.now()) {
+		return "", "", nil, fmt.Errorf("login failed: %w", &auth.SuspendedError{Reason: user.Suspension.Reason, Until: user.Suspension.Until})
+	}
+
+	if user.PasswordResetRequired {
+		return "", "", nil, fmt.Errorf("login failed: %w", auth.ErrPasswordResetRequired)
 	}
 
-	token, err := jwt.GenerateJwtToken(user.ID, "user")
+	token, secondFactor, err := s.tokens.IssueToken(ctx, auth.RoleUser, user.ID)
 
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+		return "", "", nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	return token, user, nil
+	return token, secondFactor, user, nil
 }
 
 /*
 Register creates a new user account and generates a JWT token.
 
 Parameters:
+- ctx: The request context
 - username: The user's username (registration number format: 22bcs1234)
-- password: The user's password
+- pass: The user's password
 
 Returns:
-- string: JWT token for the newly registered user
-- string: User ID of the newly registered user
+- string: JWT token for the newly registered user; empty while the account is pending
+- *db.User: The new user, whose Status tells whether it is active or pending
 - error: Any error that occurred during registration
 
 The function:
-1. Validates the username format (registration number)
-2. Hashes the password using bcrypt
-3. Creates a new user in the database
-4. Generates a JWT token with "user" role
-5. Returns the token and user ID upon successful registration
+1. Checks the password against the password policy
+2. Checks the regno against the roster according to the registration mode
+3. Hashes the password and creates the user in the database
+4. For pending accounts on the roster, emails a confirmation link to the roster address
+5. Otherwise issues a JWT token with "user" role (an enrollment challenge when students must set up a second factor)
+
+In "open" mode any regno is accepted and the account is active at once.
+"roster" mode requires a roster entry and "pending" mode accepts any regno;
+in both the account stays pending until the student confirms the college
+email on the roster or an admin approves it, since knowing a regno proves
+nothing about who registers it.
+
+Possible errors:
+- Password policy violations, e.g. "password must be at least 10 characters"
+- "registration number is not on the student roster": Roster mode and unknown regno
+*/
+func (s *UserAuthService) Register(ctx context.Context, regno, name, pass string) (string, *db.User, error) {
+	if err := s.policy.Check(pass, regno, name); err != nil {
+		return "", nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	status := db.UserStatusActive
+	var entry *db.RosterEntry
+
+	if s.account.RegistrationMode != "open" {
+		normalized, ok := utils.NormalizeRegno(regno)
+		if !ok {
+			return "", nil, fmt.Errorf("registration failed: not a valid registration number")
+		}
+
+		var err error
+		if entry, err = s.roster.GetEntry(ctx, normalized); err != nil {
+			return "", nil, fmt.Errorf("registration failed: %w", err)
+		}
+
+		if entry == nil && s.account.RegistrationMode == "roster" {
+			return "", nil, fmt.Errorf("registration failed: registration number is not on the student roster")
+		}
+
+		status = db.UserStatusPending
+	}
+
+	user, err := s.repo.Register(ctx, regno, name, pass, status)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	if user.Status == db.UserStatusPending {
+		if entry != nil {
+			if err = s.sendRegistrationConfirmation(ctx, user, entry); err != nil {
+				log.Printf("userauth: registration confirmation for %s: %v", user.ID, err)
+			}
+		}
+		return "", user, nil
+	}
+
+	token, _, err := s.tokens.IssueToken(ctx, auth.RoleUser, user.ID)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return token, user, nil
+}
+
+/*
+sendRegistrationConfirmation emails a verification link to the college
+address on the roster. Using the link verifies that address and activates
+the pending account.
+*/
+func (s *UserAuthService) sendRegistrationConfirmation(ctx context.Context, user *db.User, entry *db.RosterEntry) error {
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.CreateToken(ctx, user.ID, purposeVerifyEmail, hash, entry.Email, s.account.EmailVerifyTTL); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      entry.Email,
+		Subject: "Confirm your PlacementLog registration",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone registered a PlacementLog account for %s. If it was you, open this link to confirm it:\n\n%s/verify-email?token=%s\n\nThe link expires in %s. If it was not you, ignore this email; the account stays locked until an administrator reviews it.\n",
+			entry.Name, entry.Regno, s.account.LinkBaseURL, token, s.account.EmailVerifyTTL),
+	})
+}
+
+/*
+newAccountToken generates a random single-use token for an emailed link.
+
+Returns:
+- string: The token sent to the user
+- string: Its hash, which is all the database stores
+- error: If the random source fails
+*/
+func newAccountToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("error generating token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashAccountToken(token), nil
+}
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+AttachEmail starts verification of an email address for the user.
+The address is only stored on the account once the emailed link is used.
+
+Parameters:
+- ctx: The request context
+- userID: The authenticated user's ID
+- email: The address to attach
+
+Returns:
+- error: Any error that occurred
+
+The function:
+1. Validates the address
+2. Rejects addresses already verified by another account
+3. Stores a hashed verification token, revoking earlier ones
+4. Emails the verification link
+
+Possible errors:
+- "not a valid email address": Malformed address
+- "email already in use": Another account verified this address
+*/
+func (s *UserAuthService) AttachEmail(ctx context.Context, userID, email string) error {
+	email, err := mail.ValidateAddress(email)
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.ID != userID {
+		return fmt.Errorf("email already in use")
+	}
+
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.CreateToken(ctx, userID, purposeVerifyEmail, hash, email, s.account.EmailVerifyTTL); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Verify your PlacementLog email address",
+		Body: fmt.Sprintf("Open this link to verify your email address:\n\n%s/verify-email?token=%s\n\nThe link expires in %s. If you did not request this, ignore this email.\n",
+			s.account.LinkBaseURL, token, s.account.EmailVerifyTTL),
+	})
+}
+
+/*
+VerifyEmail completes email verification with the token from the emailed link.
+
+Parameters:
+- ctx: The request context
+- token: The token from the link
+
+Returns:
+- string: The verified address
+- error: Any error that occurred
+
+When the address is the college email on the roster for the account's
+regno, a pending registration is activated as well.
+
+Possible errors:
+- "invalid or expired token": Unknown, used, revoked or expired token
+- "email already in use": Another account verified the address in the meantime
+*/
+func (s *UserAuthService) VerifyEmail(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("token is required")
+	}
+
+	t, err := s.repo.ConsumeToken(ctx, purposeVerifyEmail, hashAccountToken(token))
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.repo.SetEmail(ctx, t.UserID, t.Email); err != nil {
+		return "", err
+	}
+
+	if s.roster != nil {
+		entry, err := s.roster.GetEntry(ctx, t.Regno)
+		if err != nil {
+			return "", err
+		}
+		if entry != nil && entry.Email == t.Email {
+			if err = s.repo.ActivateUser(ctx, t.UserID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return t.Email, nil
+}
+
+/*
+RequestPasswordReset emails a password reset link to the account that
+verified the given address.
+It returns nil whether or not such an account exists, and the token is
+created and mailed in the background, so neither the response nor its
+timing reveals which addresses are registered.
+
+Parameters:
+- ctx: The request context
+- email: The address entered on the forgot password form
+
+Returns:
+- error: Only for malformed addresses and database errors
+*/
+func (s *UserAuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	email, err := mail.ValidateAddress(email)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil || user == nil {
+		return err
+	}
+
+	// The email is sent after the response, so it must outlive the request.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.SendPasswordReset(ctx, user); err != nil {
+			log.Printf("userauth: password reset for %s: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+/*
+SendPasswordReset emails a password reset link to a user's verified address.
+Admins use it to make a user choose a new password.
+
+Parameters:
+- ctx: The request context
+- user: The user; Email must be set
+
+Returns:
+- error: Any error creating the token or sending the email
+*/
+func (s *UserAuthService) SendPasswordReset(ctx context.Context, user *db.User) error {
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.CreateToken(ctx, user.ID, purposeResetPassword, hash, "", s.account.PasswordResetTTL); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your PlacementLog password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to choose a new password for %s:\n\n%s/reset-password?token=%s\n\nThe link can be used once and expires in %s. If you did not request a reset, ignore this email; your password is unchanged.\n",
+			user.Username, user.Regno, s.account.LinkBaseURL, token, s.account.PasswordResetTTL),
+	})
+}
+
+/*
+ResetPassword sets a new password with the token from a reset link.
+
+Parameters:
+- ctx: The request context
+- token: The token from the link
+- pass: The new password
+
+Returns:
+- error: Any error that occurred
+
+The function:
+1. Looks up the token, which fails if it was used, revoked or has expired
+2. Checks the new password against the password policy, including the user's registration number and name
+3. Consumes the token; a rejected password leaves it usable for another attempt
+4. Stores the new password hash and revokes other reset tokens
+5. Lifts any login lockout on the account
 */
-func (s *UserAuthService) Register(regno, name, password string) (string, string, error) {
-	user, err := s.repo.Register(regno, name, password)
+func (s *UserAuthService) ResetPassword(ctx context.Context, token, pass string) error {
+	if token == "" || pass == "" {
+		return fmt.Errorf("all fields are required")
+	}
+
+	hash := hashAccountToken(token)
+
+	found, err := s.repo.FindToken(ctx, purposeResetPassword, hash)
+	if err != nil {
+		return err
+	}
+
+	if err = s.policy.Check(pass, found.Regno, found.Username); err != nil {
+		return err
+	}
 
+	t, err := s.repo.ConsumeToken(ctx, purposeResetPassword, hash)
 	if err != nil {
-		return "", "", fmt.Errorf("registration failed: %w", err)
+		return err
 	}
 
-	token, err := jwt.GenerateJwtToken(user.ID, "user")
+	if err = s.repo.UpdatePassword(ctx, t.UserID, pass); err != nil {
+		return err
+	}
+
+	s.guard.Succeed(ctx, auth.RoleUser, t.Regno)
+
+	return nil
+}
+
+/*
+CheckPassword verifies the current password of a logged-in user before a
+sensitive change such as a new password or account deletion.
 
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- pass: The password to check
+- ip: The client IP, used to throttle repeated wrong passwords
+
+Returns:
+- *db.User: The user, when the password matches
+- error: Any error that occurred
+
+Wrong passwords are throttled under their own key, so guesses here do not
+lock the account out of logging in.
+
+Possible errors:
+- *loginguard.BlockedError: Too many wrong passwords
+- auth.ErrInvalidCredentials: The password is wrong
+*/
+func (s *UserAuthService) CheckPassword(ctx context.Context, userID, pass, ip string) (*db.User, error) {
+	guardKey := "password:" + userID
+	attempt, err := s.guard.Check(ctx, auth.RoleUser, guardKey, ip)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
+	}
+	defer attempt.Release(ctx)
+
+	user, err := s.repo.VerifyPassword(ctx, userID, pass)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		attempt.Fail(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempt.Succeed(ctx)
+
+	return user, nil
+}
+
+/*
+ChangePassword replaces the password of a logged-in user after checking the
+current one.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- current: The current password
+- pass: The new password
+- ip: The client IP, used to throttle repeated wrong passwords
+
+Returns:
+- error: Any error that occurred
+
+The function:
+1. Verifies the current password as CheckPassword does
+2. Checks the new password against the password policy
+3. Stores the new password hash and revokes outstanding reset tokens
+
+Possible errors:
+- *loginguard.BlockedError: Too many wrong current passwords
+- auth.ErrInvalidCredentials: The current password is wrong
+- Password policy violations
+*/
+func (s *UserAuthService) ChangePassword(ctx context.Context, userID, current, pass, ip string) error {
+	if current == "" || pass == "" {
+		return fmt.Errorf("all fields are required")
+	}
+
+	user, err := s.CheckPassword(ctx, userID, current, ip)
+	if err != nil {
+		return err
+	}
+
+	if err = s.policy.Check(pass, user.Regno, user.Username); err != nil {
+		return err
 	}
 
-	return token, user.ID, nil
+	return s.repo.UpdatePassword(ctx, userID, pass)
 }
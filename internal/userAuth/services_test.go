@@ -1,23 +1,37 @@
 package userauth
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/varnit-ta/PlacementLog/internal/config"
 	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/internal/loginguard"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+	"github.com/varnit-ta/PlacementLog/pkg/mail"
+	"github.com/varnit-ta/PlacementLog/pkg/password"
 )
 
-type fakeUserAuthRepo struct{}
+type fakeUserAuthRepo struct{ mockUserAuthRepo }
 
-func (f *fakeUserAuthRepo) Login(regno, pass string) (*db.User, error) {
+func (f *fakeUserAuthRepo) Login(ctx context.Context, regno, pass string) (*db.User, error) {
 	return &db.User{ID: "11111111-1111-1111-1111-111111111111", Regno: regno, Username: "testuser"}, nil
 }
-func (f *fakeUserAuthRepo) Register(regno, username, pass string) (*db.User, error) {
+func (f *fakeUserAuthRepo) Register(ctx context.Context, regno, username, pass, status string) (*db.User, error) {
 	return nil, nil // not used in this test
 }
 
 func TestUserAuthService_Login(t *testing.T) {
-	s := NewUserAuthService(&fakeUserAuthRepo{})
-	token, user, err := s.Login("22bcs1234", "password")
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	s := NewUserAuthService(&fakeUserAuthRepo{}, tokens, nil, nil, nil, nil, config.AccountConfig{})
+	token, _, user, err := s.Login(context.Background(), "22bcs1234", "password", "127.0.0.1")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -28,3 +42,390 @@ func TestUserAuthService_Login(t *testing.T) {
 		t.Error("expected a token, got empty string")
 	}
 }
+
+func TestUserAuthService_Login_Throttled(t *testing.T) {
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	repo := &mockUserAuthRepo{
+		LoginFunc: func(regno, pass string) (*db.User, error) {
+			return nil, auth.ErrInvalidCredentials
+		},
+	}
+	cfg := config.Default().Login
+	s := NewUserAuthService(repo, tokens, loginguard.NewLoginGuard(nil, nil, cfg), nil, nil, nil, config.AccountConfig{})
+
+	for i := 0; i <= cfg.FreeAttempts; i++ {
+		_, _, _, err := s.Login(context.Background(), "22bcs1234", "wrong", "127.0.0.1")
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+
+	_, _, _, err = s.Login(context.Background(), "22bcs1234", "wrong", "127.0.0.1")
+	var blocked *loginguard.BlockedError
+	if !errors.As(err, &blocked) {
+		t.Errorf("expected login to be throttled, got %v", err)
+	}
+}
+
+func TestUserAuthService_Login_Blocked(t *testing.T) {
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ended := now.Add(-time.Hour)
+	ends := now.Add(time.Hour)
+
+	cases := []struct {
+		name string
+		user db.User
+		want func(error) bool
+	}{
+		{"banned", db.User{Suspension: &db.Suspension{Reason: "spam"}}, func(err error) bool {
+			var suspended *auth.SuspendedError
+			return errors.As(err, &suspended) && suspended.Until == nil && suspended.Reason == "spam"
+		}},
+		{"suspended", db.User{Suspension: &db.Suspension{Reason: "spam", Until: &ends}}, func(err error) bool {
+			var suspended *auth.SuspendedError
+			return errors.As(err, &suspended) && suspended.Until.Equal(ends)
+		}},
+		{"suspension ended", db.User{Suspension: &db.Suspension{Reason: "spam", Until: &ended}}, func(err error) bool {
+			return err == nil
+		}},
+		{"password reset required", db.User{PasswordResetRequired: true}, func(err error) bool {
+			return errors.Is(err, auth.ErrPasswordResetRequired)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &mockUserAuthRepo{
+				LoginFunc: func(regno, pass string) (*db.User, error) {
+					user := c.user
+					user.ID, user.Regno = "11111111-1111-1111-1111-111111111111", regno
+					return &user, nil
+				},
+			}
+			s := NewUserAuthService(repo, tokens, nil, nil, nil, nil, config.AccountConfig{})
+			s.now = func() time.Time { return now }
+
+			if _, _, _, err := s.Login(context.Background(), "22bcs1234", "password", "127.0.0.1"); !c.want(err) {
+				t.Errorf("unexpected login result %v", err)
+			}
+		})
+	}
+}
+
+type mockSender struct {
+	sent chan mail.Message
+}
+
+func (m *mockSender) Send(msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// tokenFromLink extracts the token query parameter from an emailed link.
+func tokenFromLink(t *testing.T, body string) string {
+	t.Helper()
+	_, after, ok := strings.Cut(body, "token=")
+	if !ok {
+		t.Fatalf("no token in mail body %q", body)
+	}
+	return strings.Fields(after)[0]
+}
+
+func TestUserAuthService_EmailVerification(t *testing.T) {
+	var stored struct{ purpose, hash, email string }
+	repo := &mockUserAuthRepo{
+		GetUserByEmailFunc: func(email string) (*db.User, error) {
+			if email == "taken@example.edu" {
+				return &db.User{ID: "other"}, nil
+			}
+			return nil, nil
+		},
+		CreateTokenFunc: func(userID, purpose, tokenHash, email string, ttl time.Duration) error {
+			stored.purpose, stored.hash, stored.email = purpose, tokenHash, email
+			return nil
+		},
+		ConsumeTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			if purpose != stored.purpose || tokenHash != stored.hash {
+				return nil, errors.New("invalid or expired token")
+			}
+			return &db.UserToken{UserID: "u1", Email: stored.email}, nil
+		},
+		SetEmailFunc: func(userID, email string) error { return nil },
+	}
+	sender := &mockSender{sent: make(chan mail.Message, 1)}
+	s := NewUserAuthService(repo, nil, nil, sender, nil, nil, config.Default().Account)
+
+	if err := s.AttachEmail(context.Background(), "u1", "not an address"); err == nil {
+		t.Error("expected invalid address error")
+	}
+	if err := s.AttachEmail(context.Background(), "u1", "taken@example.edu"); err == nil || err.Error() != "email already in use" {
+		t.Errorf("expected email already in use, got %v", err)
+	}
+
+	if err := s.AttachEmail(context.Background(), "u1", "Student@Example.edu"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	msg := <-sender.sent
+	if msg.To != "student@example.edu" || !strings.Contains(msg.Body, "http://localhost:3000/verify-email?token=") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	token := tokenFromLink(t, msg.Body)
+	if stored.hash == token || stored.hash != hashAccountToken(token) {
+		t.Error("expected only the token hash to be stored")
+	}
+
+	email, err := s.VerifyEmail(context.Background(), token)
+	if err != nil || email != "student@example.edu" {
+		t.Errorf("expected verified address, got %q %v", email, err)
+	}
+	if _, err := s.VerifyEmail(context.Background(), "forged"); err == nil {
+		t.Error("expected forged token to be rejected")
+	}
+}
+
+func TestUserAuthService_PasswordReset(t *testing.T) {
+	var storedHash, newPassword string
+	used := false
+	repo := &mockUserAuthRepo{
+		GetUserByEmailFunc: func(email string) (*db.User, error) {
+			if email == "student@example.edu" {
+				return &db.User{ID: "u1", Regno: "22bcs1234", Email: email}, nil
+			}
+			return nil, nil
+		},
+		CreateTokenFunc: func(userID, purpose, tokenHash, email string, ttl time.Duration) error {
+			if purpose != purposeResetPassword || ttl != 30*time.Minute {
+				t.Errorf("unexpected token purpose %q or ttl %v", purpose, ttl)
+			}
+			storedHash = tokenHash
+			return nil
+		},
+		FindTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			if used || tokenHash != storedHash {
+				return nil, errors.New("invalid or expired token")
+			}
+			return &db.UserToken{UserID: "u1", Regno: "22bcs1234", Username: "Jane"}, nil
+		},
+		ConsumeTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			if used || tokenHash != storedHash {
+				return nil, errors.New("invalid or expired token")
+			}
+			used = true
+			return &db.UserToken{UserID: "u1", Regno: "22bcs1234"}, nil
+		},
+		UpdatePasswordFunc: func(userID, pass string) error {
+			newPassword = pass
+			return nil
+		},
+	}
+	sender := &mockSender{sent: make(chan mail.Message, 1)}
+	guard := loginguard.NewLoginGuard(nil, nil, config.Default().Login)
+	s := NewUserAuthService(repo, nil, guard, sender, nil, nil, config.Default().Account)
+
+	if err := s.RequestPasswordReset(context.Background(), "unknown@example.edu"); err != nil {
+		t.Errorf("expected unknown address to look like success, got %v", err)
+	}
+
+	if err := s.RequestPasswordReset(context.Background(), "student@example.edu"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var msg mail.Message
+	select {
+	case msg = <-sender.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected reset email to be sent")
+	}
+	token := tokenFromLink(t, msg.Body)
+
+	// Fail until the account backs off.
+	for {
+		attempt, err := guard.Check(context.Background(), auth.RoleUser, "22bcs1234", "10.0.0.1")
+		if err != nil {
+			break
+		}
+		attempt.Fail(context.Background())
+	}
+
+	if err := s.ResetPassword(context.Background(), token, "new-password"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if newPassword != "new-password" {
+		t.Errorf("expected password to be updated, got %q", newPassword)
+	}
+	if _, err := guard.Check(context.Background(), auth.RoleUser, "22bcs1234", "10.0.0.2"); err != nil {
+		t.Errorf("expected reset to lift the lockout, got %v", err)
+	}
+	if err := s.ResetPassword(context.Background(), token, "again"); err == nil {
+		t.Error("expected token to be single-use")
+	}
+}
+
+type mockRoster map[string]*db.RosterEntry
+
+func (m mockRoster) GetEntry(ctx context.Context, regno string) (*db.RosterEntry, error) {
+	return m[regno], nil
+}
+
+func TestUserAuthService_Register_RosterModes(t *testing.T) {
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+
+	var storedHash, activated string
+	users := make(map[string]*db.User)
+	repo := &mockUserAuthRepo{
+		RegisterFunc: func(regno, username, pass, status string) (*db.User, error) {
+			u := &db.User{ID: "id-" + regno, Regno: regno, Username: username, Status: status}
+			users[regno] = u
+			return u, nil
+		},
+		LoginFunc: func(regno, pass string) (*db.User, error) {
+			return users[regno], nil
+		},
+		CreateTokenFunc: func(userID, purpose, tokenHash, email string, ttl time.Duration) error {
+			storedHash = tokenHash
+			return nil
+		},
+		ConsumeTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			if tokenHash != storedHash {
+				return nil, errors.New("invalid or expired token")
+			}
+			return &db.UserToken{UserID: "id-22bcs1234", Regno: "22bcs1234", Email: "jane@college.edu"}, nil
+		},
+		SetEmailFunc: func(userID, email string) error { return nil },
+		ActivateUserFunc: func(userID string) error {
+			activated = userID
+			return nil
+		},
+	}
+	roster := mockRoster{"22bcs1234": {Regno: "22bcs1234", Name: "Jane", Email: "jane@college.edu"}}
+	sender := &mockSender{sent: make(chan mail.Message, 1)}
+	account := config.Default().Account
+
+	account.RegistrationMode = "roster"
+	s := NewUserAuthService(repo, tokens, nil, sender, roster, nil, account)
+
+	if _, _, err := s.Register(context.Background(), "22bcs9999", "Mallory", "password"); err == nil || !strings.Contains(err.Error(), "not on the student roster") {
+		t.Errorf("expected regno off the roster to be rejected, got %v", err)
+	}
+	token, user, err := s.Register(context.Background(), "22BCS1234", "Jane", "password")
+	if err != nil || token != "" || user.Status != db.UserStatusPending {
+		t.Fatalf("expected pending registration without token, got %q %+v %v", token, user, err)
+	}
+	if msg := <-sender.sent; msg.To != "jane@college.edu" {
+		t.Errorf("expected confirmation to go to the roster address, got %q", msg.To)
+	}
+
+	account.RegistrationMode = "pending"
+	s = NewUserAuthService(repo, tokens, nil, sender, roster, nil, account)
+
+	token, user, err = s.Register(context.Background(), "22bcs1234", "Jane", "password")
+	if err != nil || token != "" || user.Status != db.UserStatusPending {
+		t.Fatalf("expected pending registration without token, got %q %+v %v", token, user, err)
+	}
+	if _, _, _, err = s.Login(context.Background(), "22bcs1234", "password", "127.0.0.1"); !errors.Is(err, ErrAccountPending) {
+		t.Errorf("expected pending account to be refused, got %v", err)
+	}
+
+	msg := <-sender.sent
+	if msg.To != "jane@college.edu" {
+		t.Errorf("expected confirmation to go to the roster address, got %q", msg.To)
+	}
+	if _, err = s.VerifyEmail(context.Background(), tokenFromLink(t, msg.Body)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if activated != "id-22bcs1234" {
+		t.Errorf("expected confirmation to activate the account, got %q", activated)
+	}
+
+	token, user, err = s.Register(context.Background(), "22bcs9999", "Mallory", "password")
+	if err != nil || user.Status != db.UserStatusPending || token != "" {
+		t.Errorf("expected regno off the roster to wait for an admin, got %q %+v %v", token, user, err)
+	}
+	select {
+	case msg = <-sender.sent:
+		t.Errorf("expected no confirmation email without a roster entry, got %+v", msg)
+	default:
+	}
+}
+
+func TestUserAuthService_PasswordPolicy(t *testing.T) {
+	repo := &mockUserAuthRepo{
+		RegisterFunc: func(regno, username, pass, status string) (*db.User, error) {
+			t.Fatal("expected weak password to be rejected before the repository")
+			return nil, nil
+		},
+		FindTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			return &db.UserToken{UserID: "u1", Regno: "22bcs1234", Username: "Jane"}, nil
+		},
+		ConsumeTokenFunc: func(purpose, tokenHash string) (*db.UserToken, error) {
+			t.Fatal("expected weak password to be rejected before the token is used")
+			return nil, nil
+		},
+	}
+	policy, err := password.NewPolicy(10, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewUserAuthService(repo, nil, nil, nil, nil, policy, config.Default().Account)
+
+	if _, _, err := s.Register(context.Background(), "22bcs1234", "Jane", "short"); err == nil || !strings.Contains(err.Error(), "at least 10") {
+		t.Errorf("expected short password to be rejected, got %v", err)
+	}
+	if _, _, err := s.Register(context.Background(), "22bcs1234", "Jane", "x-22bcs1234-x"); err == nil || !strings.Contains(err.Error(), "registration number") {
+		t.Errorf("expected password containing the regno to be rejected, got %v", err)
+	}
+	if err := s.ResetPassword(context.Background(), "token", "alllowercaseletters"); err == nil {
+		t.Error("expected weak reset password to be rejected")
+	}
+	if err := s.ResetPassword(context.Background(), "token", "Jane-and-22bcs1234"); err == nil || !strings.Contains(err.Error(), "registration number") {
+		t.Errorf("expected reset password containing the regno to be rejected, got %v", err)
+	}
+}
+
+func TestUserAuthService_ChangePassword(t *testing.T) {
+	var updated string
+	repo := &mockUserAuthRepo{
+		VerifyPasswordFunc: func(userID, pass string) (*db.User, error) {
+			if pass != "current-Password1" {
+				return nil, auth.ErrInvalidCredentials
+			}
+			return &db.User{ID: userID, Regno: "22bcs1234", Username: "Jane Doe"}, nil
+		},
+		UpdatePasswordFunc: func(userID, pass string) error {
+			updated = pass
+			return nil
+		},
+	}
+	policy, err := password.NewPolicy(10, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default().Login
+	s := NewUserAuthService(repo, nil, loginguard.NewLoginGuard(nil, nil, cfg), nil, nil, policy, config.Default().Account)
+
+	if err := s.ChangePassword(context.Background(), "u1", "wrong", "new-Password1", "127.0.0.1"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected wrong current password to be rejected, got %v", err)
+	}
+	if err := s.ChangePassword(context.Background(), "u1", "current-Password1", "x-22bcs1234-x", "127.0.0.1"); err == nil || updated != "" {
+		t.Errorf("expected new password containing the regno to be rejected, got %v", err)
+	}
+	if err := s.ChangePassword(context.Background(), "u1", "current-Password1", "new-Password1", "127.0.0.1"); err != nil || updated != "new-Password1" {
+		t.Errorf("expected password to change, got %v (%q)", err, updated)
+	}
+
+	for i := 0; i <= cfg.FreeAttempts; i++ {
+		s.ChangePassword(context.Background(), "u2", "wrong", "new-Password1", "127.0.0.1")
+	}
+	var blocked *loginguard.BlockedError
+	if err := s.ChangePassword(context.Background(), "u2", "current-Password1", "new-Password1", "127.0.0.1"); !errors.As(err, &blocked) {
+		t.Errorf("expected repeated wrong passwords to be throttled, got %v", err)
+	}
+}
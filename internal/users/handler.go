@@ -0,0 +1,418 @@
+package users
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+var (
+	errUnauthorized = errors.New("unauthorized: admin token required")
+	errForbidden    = errors.New("forbidden: " + auth.PermUsersManage + " permission required")
+)
+
+/*
+UsersHandler handles the admin endpoints for managing user accounts.
+*/
+type UsersHandler struct {
+	srv *UsersService
+}
+
+/*
+NewUsersHandler creates a new UsersHandler instance with the provided service.
+
+Parameters:
+- srv: The users service
+
+Returns:
+- *UsersHandler: A new handler instance
+*/
+func NewUsersHandler(srv *UsersService) *UsersHandler {
+	return &UsersHandler{srv: srv}
+}
+
+/*
+userManager returns the ID of the calling admin when it may manage users.
+Otherwise it refuses the request with 401 when the caller is not an admin
+and 403 when the admin lacks the users:manage permission.
+*/
+func userManager(w http.ResponseWriter, r *http.Request) (string, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	switch {
+	case !ok || !principal.IsAdmin():
+		utils.WriteError(w, errUnauthorized, http.StatusUnauthorized)
+	case !principal.Can(auth.PermUsersManage):
+		utils.WriteError(w, errForbidden, http.StatusForbidden)
+	default:
+		return principal.ID, true
+	}
+	return "", false
+}
+
+func writeUsersError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		utils.WriteError(w, err, http.StatusNotFound)
+	case errors.Is(err, ErrNotSuspended):
+		utils.WriteError(w, err, http.StatusConflict)
+	default:
+		utils.WriteError(w, err)
+	}
+}
+
+/*
+writeUser responds with the current state of a user after an admin action.
+*/
+func (h *UsersHandler) writeUser(w http.ResponseWriter, r *http.Request, userID string) {
+	user, err := h.srv.GetUser(r.Context(), userID)
+	if err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, user, http.StatusOK)
+}
+
+/*
+ListUsers lists user accounts, newest first.
+
+HTTP Method: GET
+Endpoint: /admin/users?q=<text>&regno=<prefix>&name=<text>&branch=<branch>&batch=<batch>&status=<status>&page=<n>&limit=<n>
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Query Parameters (all optional):
+- q: Matches the regno, registered name or roster name
+- regno: Matches a regno prefix
+- name: Matches the registered or roster name
+- branch, batch: Match the roster entry exactly
+- status: "active", "pending" or "suspended"
+- page: Page number, starting at 1
+- limit: Page size (default 20, at most 100)
+
+Response (200 OK):
+
+	{
+	  "users": [
+	    {
+	      "id": "user_uuid",
+	      "regno": "22bcs1234",
+	      "username": "Jane",
+	      "status": "active",
+	      "name": "Jane Doe",
+	      "branch": "CSE",
+	      "batch": "2026",
+	      "password_reset_required": false,
+	      "created_at": "2026-01-01T00:00:00Z"
+	    }
+	  ],
+	  "total": 1,
+	  "page": 1,
+	  "limit": 20
+	}
+
+Returns:
+- 200 OK: The page of accounts
+- 400 Bad Request: Invalid status, page or limit
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+*/
+func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := userManager(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	var page, limit int
+	for _, p := range []struct {
+		name string
+		dest *int
+	}{{"page", &page}, {"limit", &limit}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				utils.WriteError(w, errors.New(p.name+" must be a number"))
+				return
+			}
+			*p.dest = n
+		}
+	}
+
+	result, err := h.srv.ListUsers(r.Context(), UserFilter{
+		Query:  query.Get("q"),
+		Regno:  query.Get("regno"),
+		Name:   query.Get("name"),
+		Branch: query.Get("branch"),
+		Batch:  query.Get("batch"),
+		Status: query.Get("status"),
+	}, page, limit)
+	if err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, result, http.StatusOK)
+}
+
+/*
+GetUser returns a user account with its post counts and moderation history.
+
+HTTP Method: GET
+Endpoint: /admin/users/{id}
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Response (200 OK): the fields of the user list, plus
+
+	{
+	  "posts": {"total": 3, "approved": 2, "pending": 1},
+	  "moderation_log": [
+	    {
+	      "id": 1,
+	      "action": "suspend",
+	      "reason": "spam",
+	      "expires_at": "2026-02-01T00:00:00Z",
+	      "admin_id": "admin_uuid",
+	      "admin_username": "root",
+	      "created_at": "2026-01-01T00:00:00Z"
+	    }
+	  ]
+	}
+
+Returns:
+- 200 OK: The account
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+- 404 Not Found: No such user
+*/
+func (h *UsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	if _, ok := userManager(w, r); !ok {
+		return
+	}
+
+	h.writeUser(w, r, chi.URLParam(r, "id"))
+}
+
+/*
+suspendRequest represents the JSON payload for suspending a user.
+*/
+type suspendRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+/*
+Suspend suspends or bans a user account. Without "until" the account is
+banned until the ban is lifted.
+
+HTTP Method: PUT
+Endpoint: /admin/users/{id}/suspension
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body:
+
+	{
+	  "reason": "Posting other students' interview questions",
+	  "until": "2026-02-01T00:00:00Z"
+	}
+
+Returns:
+- 200 OK: The updated account
+- 400 Bad Request: Missing reason or an end in the past
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+- 404 Not Found: No such user
+*/
+func (h *UsersHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := userManager(w, r)
+	if !ok {
+		return
+	}
+
+	var payload suspendRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	if err := h.srv.Suspend(r.Context(), adminID, userID, payload.Reason, payload.Until); err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	h.writeUser(w, r, userID)
+}
+
+/*
+reasonRequest represents the optional JSON payload of admin actions that
+only take a note for the moderation log.
+*/
+type reasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// readReason reads an optional reasonRequest; an empty body is allowed.
+func readReason(r *http.Request) (string, error) {
+	var payload reasonRequest
+	if err := utils.ReadJSON(r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return payload.Reason, nil
+}
+
+/*
+Unsuspend lifts the suspension or ban of a user account.
+
+HTTP Method: DELETE
+Endpoint: /admin/users/{id}/suspension
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body (optional):
+
+	{
+	  "reason": "Appeal accepted"
+	}
+
+Returns:
+- 200 OK: The updated account
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+- 404 Not Found: No such user
+- 409 Conflict: The account is not suspended
+*/
+func (h *UsersHandler) Unsuspend(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := userManager(w, r)
+	if !ok {
+		return
+	}
+
+	reason, err := readReason(r)
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	if err = h.srv.Unsuspend(r.Context(), adminID, userID, reason); err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	h.writeUser(w, r, userID)
+}
+
+/*
+ForcePasswordReset emails a password reset link to the user and blocks the
+account until the password has been reset through it.
+
+HTTP Method: POST
+Endpoint: /admin/users/{id}/password-reset
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body (optional):
+
+	{
+	  "reason": "Password shared in a group chat"
+	}
+
+Returns:
+- 200 OK: The updated account
+- 400 Bad Request: The user has no verified email address, or the email could not be sent
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+- 404 Not Found: No such user
+*/
+func (h *UsersHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := userManager(w, r)
+	if !ok {
+		return
+	}
+
+	reason, err := readReason(r)
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	if err = h.srv.ForcePasswordReset(r.Context(), adminID, userID, reason); err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	h.writeUser(w, r, userID)
+}
+
+/*
+verificationRequest represents the JSON payload for the verification toggle.
+*/
+type verificationRequest struct {
+	Verified *bool  `json:"verified"`
+	Reason   string `json:"reason"`
+}
+
+/*
+SetVerification marks a registration as verified (active) or unverified
+(pending), e.g. after checking a student's ID in person.
+
+HTTP Method: PUT
+Endpoint: /admin/users/{id}/verification
+
+Headers Required:
+- Authorization: Bearer <admin_jwt_token>
+
+Request Body:
+
+	{
+	  "verified": true,
+	  "reason": "ID card checked at the placement cell"
+	}
+
+Returns:
+- 200 OK: The updated account
+- 400 Bad Request: Missing "verified"
+- 401 Unauthorized: Missing or invalid admin token
+- 403 Forbidden: The admin lacks the users:manage permission
+- 404 Not Found: No such user
+*/
+func (h *UsersHandler) SetVerification(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := userManager(w, r)
+	if !ok {
+		return
+	}
+
+	var payload verificationRequest
+	if err := utils.ReadJSON(r, &payload); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	if payload.Verified == nil {
+		utils.WriteError(w, errors.New("verified is required"))
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	if err := h.srv.SetVerified(r.Context(), adminID, userID, *payload.Verified, payload.Reason); err != nil {
+		writeUsersError(w, err)
+		return
+	}
+
+	h.writeUser(w, r, userID)
+}
@@ -0,0 +1,49 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestUsersHandler_RequiresUsersManage(t *testing.T) {
+	repo := &mockUsersRepo{
+		ListUsersFunc: func(filter UserFilter, offset, limit int) ([]db.UserSummary, int, error) {
+			return nil, 0, nil
+		},
+	}
+	h := NewUsersHandler(NewUsersService(repo, nil))
+
+	delegate := auth.NewPrincipal("admin-2", auth.RoleAdmin, "session-2")
+	delegate.Permissions = slices.DeleteFunc(delegate.Permissions, func(p string) bool { return p == auth.PermUsersManage })
+
+	cases := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"no principal", nil, http.StatusUnauthorized},
+		{"user", auth.NewPrincipal(testUserID, auth.RoleUser, "session-1"), http.StatusUnauthorized},
+		{"admin without users:manage", delegate, http.StatusForbidden},
+		{"admin", auth.NewPrincipal("admin-1", auth.RoleAdmin, "session-3"), http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			if c.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), c.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			h.ListUsers(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected %d, got %d: %s", c.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+// selectUserSummary selects the columns scanned by scanUserSummary.
+const selectUserSummary = `
+	SELECT u.id, u.regno, u.username, COALESCE(u.email, ''), u.status,
+		COALESCE(r.name, ''), COALESCE(r.branch, ''), COALESCE(r.batch, ''),
+		u.suspended_at, u.suspended_until, COALESCE(u.suspension_reason, ''),
+		u.password_reset_required, u.created_at
+	FROM placement_log_users u
+	LEFT JOIN placement_log_roster r ON r.regno = u.regno`
+
+/*
+UsersRepo handles the admin management of user accounts.
+*/
+type UsersRepo struct {
+	db *sql.DB
+}
+
+/*
+NewUsersRepo creates a new UsersRepo instance with the provided database connection.
+
+Parameters:
+- db: The database connection
+
+Returns:
+- *UsersRepo: A new repository instance
+*/
+func NewUsersRepo(db *sql.DB) *UsersRepo {
+	return &UsersRepo{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUserSummary(row rowScanner) (*db.UserSummary, error) {
+	var u db.UserSummary
+	var suspendedAt, createdAt sql.NullTime
+	var suspension db.Suspension
+
+	err := row.Scan(&u.ID, &u.Regno, &u.Username, &u.Email, &u.Status,
+		&u.Name, &u.Branch, &u.Batch,
+		&suspendedAt, &suspension.Until, &suspension.Reason,
+		&u.PasswordResetRequired, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if suspendedAt.Valid {
+		suspension.Since = suspendedAt.Time
+		u.Suspension = &suspension
+	}
+	u.CreatedAt = createdAt.Time
+
+	return &u, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a search term.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+/*
+ListUsers returns a page of user accounts matching the filter, newest first.
+
+Parameters:
+- ctx: The request context
+- filter: The search terms; empty fields match every account
+- offset: The number of matching accounts to skip
+- limit: The maximum number of accounts returned
+
+Returns:
+- []db.UserSummary: The page of accounts
+- int: The number of matching accounts
+- error: Any database error
+*/
+func (r *UsersRepo) ListUsers(ctx context.Context, filter UserFilter, offset, limit int) ([]db.UserSummary, int, error) {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.ListUsers", "SELECT", "placement_log_users").End()
+
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	if filter.Query != "" {
+		where(`(u.regno ILIKE $? OR u.username ILIKE $? OR r.name ILIKE $?)`, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Regno != "" {
+		where(`u.regno LIKE $?`, escapeLike(strings.ToLower(filter.Regno))+"%")
+	}
+	if filter.Name != "" {
+		where(`(u.username ILIKE $? OR r.name ILIKE $?)`, "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.Branch != "" {
+		where(`UPPER(r.branch) = UPPER($?)`, filter.Branch)
+	}
+	if filter.Batch != "" {
+		where(`r.batch = $?`, filter.Batch)
+	}
+	switch filter.Status {
+	case db.UserStatusActive, db.UserStatusPending:
+		where(`u.status = $?`, filter.Status)
+	case StatusSuspended:
+		conditions = append(conditions, `u.suspended_at IS NOT NULL AND (u.suspended_until IS NULL OR u.suspended_until > CURRENT_TIMESTAMP)`)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM placement_log_users u
+		LEFT JOIN placement_log_roster r ON r.regno = u.regno`+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+
+	args = append(args, limit, offset)
+	rows, err := r.db.QueryContext(ctx, selectUserSummary+whereClause+
+		fmt.Sprintf(" ORDER BY u.created_at DESC, u.id LIMIT $%d OFFSET $%d;", len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer rows.Close()
+
+	users := []db.UserSummary{}
+	for rows.Next() {
+		u, err := scanUserSummary(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+
+	return users, total, rows.Err()
+}
+
+/*
+GetUser returns a user account with its post counts and moderation history.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.UserDetail: The account
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) GetUser(ctx context.Context, userID string) (*db.UserDetail, error) {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.GetUser", "SELECT", "placement_log_users").End()
+
+	summary, err := scanUserSummary(r.db.QueryRowContext(ctx, selectUserSummary+` WHERE u.id = $1;`, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	detail := &db.UserDetail{UserSummary: *summary, ModerationLog: []db.ModerationEntry{}}
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE reviewed), COUNT(*) FILTER (WHERE NOT COALESCE(reviewed, FALSE))
+		FROM placement_log_posts
+		WHERE user_id = $1;
+	`, userID).Scan(&detail.Posts.Total, &detail.Posts.Approved, &detail.Posts.Pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts: %v", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.action, COALESCE(m.reason, ''), m.expires_at,
+			COALESCE(m.admin_id::text, ''), COALESCE(a.username, ''), m.created_at
+		FROM placement_log_moderation_log m
+		LEFT JOIN placement_log_admins a ON a.id = m.admin_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at DESC, m.id DESC;
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load moderation log: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e db.ModerationEntry
+		if err = rows.Scan(&e.ID, &e.Action, &e.Reason, &e.ExpiresAt, &e.AdminID, &e.AdminUsername, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		detail.ModerationLog = append(detail.ModerationLog, e)
+	}
+
+	return detail, rows.Err()
+}
+
+/*
+GetAccount returns the standing of a user account: its status, suspension
+and whether a password reset is required.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.User: The account
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) GetAccount(ctx context.Context, userID string) (*db.User, error) {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.GetAccount", "SELECT", "placement_log_users").End()
+
+	var u db.User
+	var suspendedAt sql.NullTime
+	var suspension db.Suspension
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, regno, username, COALESCE(email, ''), status,
+			suspended_at, suspended_until, COALESCE(suspension_reason, ''), password_reset_required
+		FROM placement_log_users
+		WHERE id = $1;
+	`, userID).Scan(&u.ID, &u.Regno, &u.Username, &u.Email, &u.Status,
+		&suspendedAt, &suspension.Until, &suspension.Reason, &u.PasswordResetRequired)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("db error: %v", err)
+	}
+
+	if suspendedAt.Valid {
+		suspension.Since = suspendedAt.Time
+		u.Suspension = &suspension
+	}
+
+	return &u, nil
+}
+
+/*
+moderate applies an admin action to a user account and records it in the
+moderation log, in one transaction. The update is a SET clause; $1 is the
+user ID and args fill $2 onwards.
+*/
+func (r *UsersRepo) moderate(ctx context.Context, userID string, entry db.ModerationEntry, set string, args ...any) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE placement_log_users SET `+set+` WHERE id = $1;`, append([]any{userID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO placement_log_moderation_log (user_id, admin_id, action, reason, expires_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, NULLIF($4, ''), $5);
+	`, userID, entry.AdminID, entry.Action, entry.Reason, entry.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record moderation: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("db error: %v", err)
+	}
+
+	return nil
+}
+
+/*
+Suspend suspends a user account until the given time, or bans it when until
+is nil. A new suspension replaces the current one.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- adminID: The acting admin
+- reason: Shown to the user and kept in the moderation log
+- until: The end of the suspension, or nil for a ban
+
+Returns:
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) Suspend(ctx context.Context, userID, adminID, reason string, until *time.Time) error {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.Suspend", "UPDATE", "placement_log_users").End()
+
+	action := db.ModerationSuspend
+	if until == nil {
+		action = db.ModerationBan
+	}
+
+	return r.moderate(ctx, userID, db.ModerationEntry{AdminID: adminID, Action: action, Reason: reason, ExpiresAt: until},
+		`suspended_at = CURRENT_TIMESTAMP, suspended_until = $2, suspension_reason = $3`, until, reason)
+}
+
+/*
+Unsuspend lifts the suspension or ban of a user account.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- adminID: The acting admin
+- reason: Kept in the moderation log
+
+Returns:
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) Unsuspend(ctx context.Context, userID, adminID, reason string) error {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.Unsuspend", "UPDATE", "placement_log_users").End()
+
+	return r.moderate(ctx, userID, db.ModerationEntry{AdminID: adminID, Action: db.ModerationUnsuspend, Reason: reason},
+		`suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL`)
+}
+
+/*
+RequirePasswordReset blocks a user account until its password is reset
+through an emailed link.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- adminID: The acting admin
+- reason: Kept in the moderation log
+
+Returns:
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) RequirePasswordReset(ctx context.Context, userID, adminID, reason string) error {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.RequirePasswordReset", "UPDATE", "placement_log_users").End()
+
+	return r.moderate(ctx, userID, db.ModerationEntry{AdminID: adminID, Action: db.ModerationForcePasswordReset, Reason: reason},
+		`password_reset_required = TRUE`)
+}
+
+/*
+SetStatus marks a registration as verified (active) or unverified (pending).
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+- adminID: The acting admin
+- status: db.UserStatusActive or db.UserStatusPending
+- reason: Kept in the moderation log
+
+Returns:
+- error: ErrUserNotFound or a database error
+*/
+func (r *UsersRepo) SetStatus(ctx context.Context, userID, adminID, status, reason string) error {
+	defer telemetry.TraceQuery(ctx, "UsersRepo.SetStatus", "UPDATE", "placement_log_users").End()
+
+	action := db.ModerationVerify
+	if status == db.UserStatusPending {
+		action = db.ModerationUnverify
+	}
+
+	return r.moderate(ctx, userID, db.ModerationEntry{AdminID: adminID, Action: action, Reason: reason},
+		`status = $2`, status)
+}
+
+// Ensure UsersRepo implements UsersRepository
+var _ UsersRepository = (*UsersRepo)(nil)
@@ -0,0 +1,370 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+// StatusSuspended filters the user list to suspended and banned accounts.
+const StatusSuspended = "suspended"
+
+// Page sizes of the user list.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// maxReasonLength bounds the reason given for a moderation action, in characters.
+const maxReasonLength = 500
+
+var (
+	// ErrUserNotFound is returned for unknown or malformed user IDs.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNotSuspended is returned when lifting a suspension from an account that has none.
+	ErrNotSuspended = errors.New("user is not suspended")
+)
+
+// Define UsersRepository interface for testability
+//go:generate mockgen -destination=mock_users_repo.go -package=users . UsersRepository
+
+type UsersRepository interface {
+	ListUsers(ctx context.Context, filter UserFilter, offset, limit int) ([]db.UserSummary, int, error)
+	GetUser(ctx context.Context, userID string) (*db.UserDetail, error)
+	GetAccount(ctx context.Context, userID string) (*db.User, error)
+	Suspend(ctx context.Context, userID, adminID, reason string, until *time.Time) error
+	Unsuspend(ctx context.Context, userID, adminID, reason string) error
+	RequirePasswordReset(ctx context.Context, userID, adminID, reason string) error
+	SetStatus(ctx context.Context, userID, adminID, status, reason string) error
+}
+
+/*
+PasswordResetSender emails a password reset link to a user's verified
+address.
+*/
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, user *db.User) error
+}
+
+/*
+UserFilter holds the search terms of the admin user list. Query matches the
+regno, registered name or roster name; Regno matches a prefix; Name matches
+the registered or roster name; Branch and Batch match the roster exactly;
+Status is "active", "pending" or "suspended".
+*/
+type UserFilter struct {
+	Query  string
+	Regno  string
+	Name   string
+	Branch string
+	Batch  string
+	Status string
+}
+
+/*
+UserPage is a page of the admin user list.
+*/
+type UserPage struct {
+	Users []db.UserSummary `json:"users"`
+	Total int              `json:"total"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+}
+
+/*
+UsersService handles the admin management of user accounts: search,
+suspensions and bans, forced password resets and manual verification. It
+also tells the authentication middleware whether an account is still in
+good standing.
+*/
+type UsersService struct {
+	repo   UsersRepository
+	resets PasswordResetSender
+	now    func() time.Time
+}
+
+/*
+NewUsersService creates a new UsersService instance.
+
+Parameters:
+- repo: The users repository
+- resets: Sends the password reset links of forced resets
+
+Returns:
+- *UsersService: A new service instance
+*/
+func NewUsersService(repo UsersRepository, resets PasswordResetSender) *UsersService {
+	return &UsersService{repo: repo, resets: resets, now: time.Now}
+}
+
+/*
+ListUsers returns a page of user accounts matching the filter, newest first.
+
+Parameters:
+- ctx: The request context
+- filter: The search terms
+- page: The page number, starting at 1 (0 means 1)
+- limit: The page size (0 means 20, at most 100)
+
+Returns:
+- *UserPage: The page and the number of matching accounts
+- error: Any error that occurred
+*/
+func (s *UsersService) ListUsers(ctx context.Context, filter UserFilter, page, limit int) (*UserPage, error) {
+	switch filter.Status {
+	case "", db.UserStatusActive, db.UserStatusPending, StatusSuspended:
+	default:
+		return nil, fmt.Errorf("status must be one of active, pending or suspended")
+	}
+
+	if page < 0 || limit < 0 {
+		return nil, fmt.Errorf("page and limit must be positive")
+	}
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+
+	users, total, err := s.repo.ListUsers(ctx, filter, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserPage{Users: users, Total: total, Page: page, Limit: limit}, nil
+}
+
+/*
+GetUser returns a user account with its post counts and moderation history.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- *db.UserDetail: The account
+- error: ErrUserNotFound or a database error
+*/
+func (s *UsersService) GetUser(ctx context.Context, userID string) (*db.UserDetail, error) {
+	if !utils.IsUUID(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.repo.GetUser(ctx, userID)
+}
+
+func normalizeReason(reason string, required bool) (string, error) {
+	reason = strings.TrimSpace(reason)
+
+	if required && reason == "" {
+		return "", fmt.Errorf("reason is required")
+	}
+
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", fmt.Errorf("reason must be at most %d characters", maxReasonLength)
+	}
+
+	return reason, nil
+}
+
+/*
+Suspend suspends a user account until the given time, or bans it.
+
+Parameters:
+- ctx: The request context
+- adminID: The acting admin
+- userID: The user's ID
+- reason: Shown to the user on login and on every refused request
+- until: The end of the suspension, or nil for a ban
+
+Returns:
+- error: Any error that occurred
+
+Suspended accounts cannot log in, and their tokens are refused by every
+user route until the suspension ends.
+
+Possible errors:
+- "reason is required": Missing reason
+- "suspension must end in the future": until has passed
+- ErrUserNotFound: No such user
+*/
+func (s *UsersService) Suspend(ctx context.Context, adminID, userID, reason string, until *time.Time) error {
+	reason, err := normalizeReason(reason, true)
+	if err != nil {
+		return err
+	}
+
+	if until != nil && !until.After(s.now()) {
+		return fmt.Errorf("suspension must end in the future")
+	}
+
+	if !utils.IsUUID(userID) {
+		return ErrUserNotFound
+	}
+
+	return s.repo.Suspend(ctx, userID, adminID, reason, until)
+}
+
+/*
+Unsuspend lifts the suspension or ban of a user account.
+
+Parameters:
+- ctx: The request context
+- adminID: The acting admin
+- userID: The user's ID
+- reason: Optional note for the moderation log
+
+Returns:
+- error: ErrUserNotFound, ErrNotSuspended or a database error
+*/
+func (s *UsersService) Unsuspend(ctx context.Context, adminID, userID, reason string) error {
+	reason, err := normalizeReason(reason, false)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.getAccount(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !account.Suspension.ActiveAt(s.now()) {
+		return ErrNotSuspended
+	}
+
+	return s.repo.Unsuspend(ctx, userID, adminID, reason)
+}
+
+/*
+ForcePasswordReset emails a password reset link to a user and blocks the
+account until the password has been reset through it.
+
+Parameters:
+- ctx: The request context
+- adminID: The acting admin
+- userID: The user's ID
+- reason: Optional note for the moderation log
+
+Returns:
+- error: Any error that occurred
+
+The link is sent before the account is blocked, so a failed email leaves
+the account unchanged.
+
+Possible errors:
+- ErrUserNotFound: No such user
+- "user has no verified email address": The link cannot be delivered
+*/
+func (s *UsersService) ForcePasswordReset(ctx context.Context, adminID, userID, reason string) error {
+	reason, err := normalizeReason(reason, false)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.getAccount(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if account.Email == "" {
+		return fmt.Errorf("user has no verified email address to send the reset link to")
+	}
+
+	if err = s.resets.SendPasswordReset(ctx, account); err != nil {
+		return fmt.Errorf("failed to send the reset link: %w", err)
+	}
+
+	return s.repo.RequirePasswordReset(ctx, userID, adminID, reason)
+}
+
+/*
+SetVerified marks a registration as verified (active) or unverified
+(pending). Unverified accounts cannot log in and their tokens are refused.
+
+Parameters:
+- ctx: The request context
+- adminID: The acting admin
+- userID: The user's ID
+- verified: The new state
+- reason: Optional note for the moderation log
+
+Returns:
+- error: ErrUserNotFound or a database error; setting the current state again does nothing
+*/
+func (s *UsersService) SetVerified(ctx context.Context, adminID, userID string, verified bool, reason string) error {
+	reason, err := normalizeReason(reason, false)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.getAccount(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	status := db.UserStatusPending
+	if verified {
+		status = db.UserStatusActive
+	}
+
+	if account.Status == status {
+		return nil
+	}
+
+	return s.repo.SetStatus(ctx, userID, adminID, status, reason)
+}
+
+func (s *UsersService) getAccount(ctx context.Context, userID string) (*db.User, error) {
+	if !utils.IsUUID(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.repo.GetAccount(ctx, userID)
+}
+
+/*
+CheckAccount reports whether a user account may still use its tokens. It
+implements auth.AccountChecker for the user authentication middleware.
+
+Parameters:
+- ctx: The request context
+- userID: The user's ID
+
+Returns:
+- error: nil for accounts in good standing, auth.ErrAccountNotFound, auth.ErrAccountUnverified, a *auth.SuspendedError, auth.ErrPasswordResetRequired or a database error
+*/
+func (s *UsersService) CheckAccount(ctx context.Context, userID string) error {
+	account, err := s.getAccount(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return auth.ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if account.Status == db.UserStatusPending {
+		return auth.ErrAccountUnverified
+	}
+
+	if account.Suspension.ActiveAt(s.now()) {
+		return &auth.SuspendedError{Reason: account.Suspension.Reason, Until: account.Suspension.Until}
+	}
+
+	if account.PasswordResetRequired {
+		return auth.ErrPasswordResetRequired
+	}
+
+	return nil
+}
+
+// Ensure UsersService implements auth.AccountChecker
+var _ auth.AccountChecker = (*UsersService)(nil)
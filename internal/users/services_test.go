@@ -0,0 +1,220 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/internal/db"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+type mockUsersRepo struct {
+	ListUsersFunc            func(filter UserFilter, offset, limit int) ([]db.UserSummary, int, error)
+	GetUserFunc              func(userID string) (*db.UserDetail, error)
+	GetAccountFunc           func(userID string) (*db.User, error)
+	SuspendFunc              func(userID, adminID, reason string, until *time.Time) error
+	UnsuspendFunc            func(userID, adminID, reason string) error
+	RequirePasswordResetFunc func(userID, adminID, reason string) error
+	SetStatusFunc            func(userID, adminID, status, reason string) error
+}
+
+func (m *mockUsersRepo) ListUsers(ctx context.Context, filter UserFilter, offset, limit int) ([]db.UserSummary, int, error) {
+	return m.ListUsersFunc(filter, offset, limit)
+}
+func (m *mockUsersRepo) GetUser(ctx context.Context, userID string) (*db.UserDetail, error) {
+	return m.GetUserFunc(userID)
+}
+func (m *mockUsersRepo) GetAccount(ctx context.Context, userID string) (*db.User, error) {
+	return m.GetAccountFunc(userID)
+}
+func (m *mockUsersRepo) Suspend(ctx context.Context, userID, adminID, reason string, until *time.Time) error {
+	return m.SuspendFunc(userID, adminID, reason, until)
+}
+func (m *mockUsersRepo) Unsuspend(ctx context.Context, userID, adminID, reason string) error {
+	return m.UnsuspendFunc(userID, adminID, reason)
+}
+func (m *mockUsersRepo) RequirePasswordReset(ctx context.Context, userID, adminID, reason string) error {
+	return m.RequirePasswordResetFunc(userID, adminID, reason)
+}
+func (m *mockUsersRepo) SetStatus(ctx context.Context, userID, adminID, status, reason string) error {
+	return m.SetStatusFunc(userID, adminID, status, reason)
+}
+
+type mockResetSender struct {
+	sentTo string
+	err    error
+}
+
+func (m *mockResetSender) SendPasswordReset(ctx context.Context, user *db.User) error {
+	m.sentTo = user.Email
+	return m.err
+}
+
+var testNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestService(repo UsersRepository, resets PasswordResetSender) *UsersService {
+	s := NewUsersService(repo, resets)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestUsersService_ListUsers_Paging(t *testing.T) {
+	var gotOffset, gotLimit int
+	repo := &mockUsersRepo{
+		ListUsersFunc: func(filter UserFilter, offset, limit int) ([]db.UserSummary, int, error) {
+			gotOffset, gotLimit = offset, limit
+			return []db.UserSummary{}, 250, nil
+		},
+	}
+	s := newTestService(repo, nil)
+
+	page, err := s.ListUsers(context.Background(), UserFilter{Branch: "CSE"}, 3, 500)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotOffset != 200 || gotLimit != maxPageSize || page.Page != 3 || page.Total != 250 {
+		t.Errorf("unexpected paging offset=%d limit=%d page=%+v", gotOffset, gotLimit, page)
+	}
+
+	if page, _ = s.ListUsers(context.Background(), UserFilter{}, 0, 0); gotOffset != 0 || gotLimit != defaultPageSize || page.Page != 1 {
+		t.Errorf("expected the first page by default, got offset=%d limit=%d", gotOffset, gotLimit)
+	}
+
+	if _, err = s.ListUsers(context.Background(), UserFilter{Status: "deleted"}, 1, 20); err == nil {
+		t.Error("expected unknown status to be rejected")
+	}
+}
+
+func TestUsersService_Suspend(t *testing.T) {
+	var gotUntil *time.Time
+	var suspended bool
+	repo := &mockUsersRepo{
+		SuspendFunc: func(userID, adminID, reason string, until *time.Time) error {
+			suspended, gotUntil = true, until
+			return nil
+		},
+	}
+	s := newTestService(repo, nil)
+
+	past := testNow.Add(-time.Hour)
+	if err := s.Suspend(context.Background(), "admin-1", testUserID, "spam", &past); err == nil || !strings.Contains(err.Error(), "future") {
+		t.Errorf("expected a suspension ending in the past to be rejected, got %v", err)
+	}
+	if err := s.Suspend(context.Background(), "admin-1", testUserID, "  ", nil); err == nil || !strings.Contains(err.Error(), "reason") {
+		t.Errorf("expected a missing reason to be rejected, got %v", err)
+	}
+	if suspended {
+		t.Fatal("expected invalid suspensions to be rejected before the repository")
+	}
+
+	if err := s.Suspend(context.Background(), "admin-1", testUserID, "spam", nil); err != nil || !suspended || gotUntil != nil {
+		t.Errorf("expected a ban, got %v until=%v", err, gotUntil)
+	}
+}
+
+func TestUsersService_Unsuspend_NotSuspended(t *testing.T) {
+	ended := testNow.Add(-time.Hour)
+	repo := &mockUsersRepo{
+		GetAccountFunc: func(userID string) (*db.User, error) {
+			return &db.User{ID: userID, Suspension: &db.Suspension{Until: &ended}}, nil
+		},
+	}
+
+	if err := newTestService(repo, nil).Unsuspend(context.Background(), "admin-1", testUserID, ""); !errors.Is(err, ErrNotSuspended) {
+		t.Errorf("expected ErrNotSuspended, got %v", err)
+	}
+}
+
+func TestUsersService_ForcePasswordReset(t *testing.T) {
+	email := ""
+	required := false
+	repo := &mockUsersRepo{
+		GetAccountFunc: func(userID string) (*db.User, error) {
+			return &db.User{ID: userID, Email: email}, nil
+		},
+		RequirePasswordResetFunc: func(userID, adminID, reason string) error {
+			required = true
+			return nil
+		},
+	}
+	resets := &mockResetSender{}
+	s := newTestService(repo, resets)
+
+	if err := s.ForcePasswordReset(context.Background(), "admin-1", testUserID, ""); err == nil || required {
+		t.Errorf("expected accounts without an email address to be refused, got %v", err)
+	}
+
+	email = "jane@college.edu"
+	resets.err = errors.New("smtp down")
+	if err := s.ForcePasswordReset(context.Background(), "admin-1", testUserID, ""); err == nil || required {
+		t.Errorf("expected a failed email to leave the account unchanged, got %v", err)
+	}
+
+	resets.err = nil
+	if err := s.ForcePasswordReset(context.Background(), "admin-1", testUserID, "leaked"); err != nil || !required || resets.sentTo != email {
+		t.Errorf("expected a reset link and a blocked account, got %v required=%v sent=%q", err, required, resets.sentTo)
+	}
+}
+
+func TestUsersService_SetVerified(t *testing.T) {
+	var status string
+	repo := &mockUsersRepo{
+		GetAccountFunc: func(userID string) (*db.User, error) {
+			return &db.User{ID: userID, Status: db.UserStatusActive}, nil
+		},
+		SetStatusFunc: func(userID, adminID, s, reason string) error {
+			status = s
+			return nil
+		},
+	}
+	s := newTestService(repo, nil)
+
+	if err := s.SetVerified(context.Background(), "admin-1", testUserID, true, ""); err != nil || status != "" {
+		t.Errorf("expected verifying an active account to do nothing, got %v %q", err, status)
+	}
+	if err := s.SetVerified(context.Background(), "admin-1", testUserID, false, ""); err != nil || status != db.UserStatusPending {
+		t.Errorf("expected the account to become pending, got %v %q", err, status)
+	}
+}
+
+func TestUsersService_CheckAccount(t *testing.T) {
+	ends := testNow.Add(time.Hour)
+	ended := testNow.Add(-time.Hour)
+
+	cases := []struct {
+		name    string
+		account *db.User
+		err     error
+		want    func(error) bool
+	}{
+		{"active", &db.User{Status: db.UserStatusActive}, nil, func(err error) bool { return err == nil }},
+		{"deleted", nil, ErrUserNotFound, func(err error) bool { return errors.Is(err, auth.ErrAccountNotFound) }},
+		{"pending", &db.User{Status: db.UserStatusPending}, nil, func(err error) bool { return errors.Is(err, auth.ErrAccountUnverified) }},
+		{"suspended", &db.User{Status: db.UserStatusActive, Suspension: &db.Suspension{Reason: "spam", Until: &ends}}, nil, func(err error) bool {
+			var suspended *auth.SuspendedError
+			return errors.As(err, &suspended) && suspended.Reason == "spam"
+		}},
+		{"suspension ended", &db.User{Status: db.UserStatusActive, Suspension: &db.Suspension{Until: &ended}}, nil, func(err error) bool { return err == nil }},
+		{"password reset required", &db.User{Status: db.UserStatusActive, PasswordResetRequired: true}, nil, func(err error) bool {
+			return errors.Is(err, auth.ErrPasswordResetRequired)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &mockUsersRepo{
+				GetAccountFunc: func(userID string) (*db.User, error) {
+					return c.account, c.err
+				},
+			}
+
+			if err := newTestService(repo, nil).CheckAccount(context.Background(), testUserID); !c.want(err) {
+				t.Errorf("unexpected result %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account behind a token no longer exists.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountUnverified is returned for accounts whose registration awaits verification.
+	ErrAccountUnverified = errors.New("account is awaiting verification")
+	// ErrPasswordResetRequired is returned for accounts that an admin has made reset their password.
+	ErrPasswordResetRequired = errors.New("password reset required: use the reset link sent to your email address")
+	// ErrAccountDisabled is returned for admin accounts that have been disabled.
+	ErrAccountDisabled = errors.New("account is disabled")
+)
+
+/*
+SuspendedError is returned for accounts that an admin has suspended. A
+suspension without an end (Until is nil) is a ban.
+*/
+type SuspendedError struct {
+	Reason string
+	Until  *time.Time
+}
+
+func (e *SuspendedError) Error() string {
+	msg := "account banned"
+	if e.Until != nil {
+		msg = "account suspended until " + e.Until.UTC().Format(time.RFC3339)
+	}
+
+	if e.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Reason)
+	}
+
+	return msg
+}
+
+/*
+AccountChecker reports whether an account may still use the tokens issued to
+it. Tokens outlive changes to the account, so the authentication middlewares
+ask on every request.
+*/
+type AccountChecker interface {
+	// CheckAccount returns nil for accounts in good standing, ErrAccountNotFound,
+	// ErrAccountUnverified, ErrPasswordResetRequired, ErrAccountDisabled or a
+	// *SuspendedError for blocked ones, and any other error when the check
+	// itself failed.
+	CheckAccount(ctx context.Context, userID string) error
+}
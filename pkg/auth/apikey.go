@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// APIKeyPrefix starts every API key, so keys are told apart from tokens and
+// recognised by secret scanners.
+const APIKeyPrefix = "plk_"
+
+// ErrInvalidAPIKey is returned for unknown, expired and revoked API keys alike.
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+/*
+APIKeyVerifier authenticates API keys for the authentication middlewares.
+*/
+type APIKeyVerifier interface {
+	// VerifyAPIKey returns the principal of a valid key, ErrInvalidAPIKey for
+	// unknown, expired and revoked keys, and any other error when the check
+	// itself failed.
+	VerifyAPIKey(ctx context.Context, key string) (*Principal, error)
+}
@@ -0,0 +1,9 @@
+package auth
+
+import "errors"
+
+/*
+ErrInvalidCredentials is returned by every login for both an unknown account
+and a wrong password, so the response does not reveal which accounts exist.
+*/
+var ErrInvalidCredentials = errors.New("invalid credentials")
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrImpersonationRevoked is returned for impersonation tokens whose admin is
+// no longer an enabled super admin.
+var ErrImpersonationRevoked = errors.New("impersonation revoked: the admin is no longer an enabled super admin")
+
+/*
+ImpersonatedRequest is a request made with an impersonation token, as
+recorded in the audit log.
+*/
+type ImpersonatedRequest struct {
+	// SessionID is the token's session ID (jti), issued with the impersonation.
+	SessionID string
+	AdminID   string
+	UserID    string
+	Method    string
+	Path      string
+	Status    int
+}
+
+/*
+ImpersonationAuditor records the requests made with impersonation tokens.
+*/
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(ctx context.Context, req ImpersonatedRequest) error
+}
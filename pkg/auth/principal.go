@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Roles carried in tokens and principals.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleAPIKey marks principals authenticated by an API key instead of a token.
+	RoleAPIKey = "api_key"
+)
+
+// Second factor states carried in tokens. Tokens of accounts without a
+// second factor carry none.
+const (
+	// SecondFactorPending marks a password login whose TOTP code has not been entered yet.
+	SecondFactorPending = "pending"
+	// SecondFactorEnroll marks a password login of a role that requires a
+	// second factor, by an account that has not set one up yet.
+	SecondFactorEnroll = "enroll"
+	// SecondFactorVerified marks a login completed with a second factor.
+	SecondFactorVerified = "verified"
+)
+
+// Permissions granted to principals. Each admin route requires one through
+// middleware.RequirePermission, and handlers check these instead of roles
+// where an action could reasonably be delegated later.
+const (
+	PermPostsWrite      = "posts:write"
+	PermPostsModerate   = "posts:moderate"
+	PermPlacementsWrite = "placements:write"
+	PermAdminsManage    = "admins:manage"
+	PermUsersManage     = "users:manage"
+	PermAPIKeysManage   = "apikeys:manage"
+	PermStatsRead       = "stats:read"
+	// Read permissions are the scopes granted to API keys; the routes behind
+	// them are public or admin-only for token holders.
+	PermPlacementsRead = "placements:read"
+	PermPostsRead      = "posts:read"
+)
+
+// APIKeyScopes are the permissions an API key can be granted.
+var APIKeyScopes = []string{PermPlacementsRead, PermPostsRead, PermStatsRead}
+
+var rolePermissions = map[string][]string{
+	RoleUser:  {PermPostsWrite},
+	RoleAdmin: {PermPostsModerate, PermPlacementsWrite, PermAdminsManage, PermUsersManage, PermAPIKeysManage, PermStatsRead},
+}
+
+/*
+Principal is the authenticated caller of a request.
+It is created by the authentication middlewares from a validated token and
+stored in the request context; handlers must read identity from here and
+never from request headers, which the client controls.
+*/
+type Principal struct {
+	ID          string
+	Role        string
+	Permissions []string
+	SessionID   string
+	// SecondFactor is the second factor state of the session, if any.
+	SecondFactor string
+	// ImpersonatorID is the admin acting as the user through an
+	// impersonation token; empty for the user's own sessions.
+	ImpersonatorID string
+	// ReadOnly is set for impersonation tokens that may only read.
+	ReadOnly bool
+}
+
+/*
+NewPrincipal creates a Principal with the permissions granted to its role.
+
+Parameters:
+- id: The user or admin ID
+- role: The role from the token ("user" or "admin")
+- sessionID: The token's session identifier (JWT ID)
+
+Returns:
+- *Principal: The new principal
+*/
+func NewPrincipal(id, role, sessionID string) *Principal {
+	return &Principal{
+		ID:          id,
+		Role:        role,
+		Permissions: slices.Clone(rolePermissions[role]),
+		SessionID:   sessionID,
+	}
+}
+
+// IsUser reports whether the principal is a student account.
+func (p *Principal) IsUser() bool {
+	return p != nil && p.Role == RoleUser
+}
+
+// IsAdmin reports whether the principal is an admin account.
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == RoleAdmin
+}
+
+// IsAPIKey reports whether the principal is an API key.
+func (p *Principal) IsAPIKey() bool {
+	return p != nil && p.Role == RoleAPIKey
+}
+
+/*
+NewAPIKeyPrincipal creates the Principal of an API key, holding exactly the
+key's scopes.
+
+Parameters:
+- id: The API key ID
+- scopes: The scopes granted to the key
+
+Returns:
+- *Principal: The new principal
+*/
+func NewAPIKeyPrincipal(id string, scopes []string) *Principal {
+	return &Principal{
+		ID:          id,
+		Role:        RoleAPIKey,
+		Permissions: slices.Clone(scopes),
+	}
+}
+
+// IsImpersonated reports whether an admin is acting as the user.
+func (p *Principal) IsImpersonated() bool {
+	return p != nil && p.ImpersonatorID != ""
+}
+
+/*
+NeedsSecondFactor reports whether the session still has to verify or set up
+a second factor. Such principals may only use the second factor endpoints.
+*/
+func (p *Principal) NeedsSecondFactor() bool {
+	return p != nil && (p.SecondFactor == SecondFactorPending || p.SecondFactor == SecondFactorEnroll)
+}
+
+// Can reports whether the principal holds the given permission.
+func (p *Principal) Can(permission string) bool {
+	return p != nil && slices.Contains(p.Permissions, permission)
+}
+
+type principalKey struct{}
+
+/*
+NewContext returns a copy of ctx carrying the principal.
+*/
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+/*
+FromContext returns the principal stored in ctx.
+
+Returns:
+- *Principal: The authenticated principal, or nil for anonymous requests
+- bool: Whether a principal was present
+*/
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+/*
+UserID returns the ID of the authenticated student, if the request has one.
+
+Returns:
+- string: The user ID
+- bool: False for anonymous requests and for admin principals
+*/
+func UserID(ctx context.Context) (string, bool) {
+	p, ok := FromContext(ctx)
+	if !ok || !p.IsUser() {
+		return "", false
+	}
+	return p.ID, true
+}
+
+/*
+AdminID returns the ID of the authenticated admin, if the request has one.
+
+Returns:
+- string: The admin ID
+- bool: False for anonymous requests and for user principals
+*/
+func AdminID(ctx context.Context) (string, bool) {
+	p, ok := FromContext(ctx)
+	if !ok || !p.IsAdmin() {
+		return "", false
+	}
+	return p.ID, true
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+/*
+Entry is a cached, fully serialized response body together with the
+validators used for conditional requests.
+*/
+type Entry struct {
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+/*
+Store is the cache backend used by the read endpoints.
+Implementations must be safe for concurrent use. Entries hold serialized
+bytes only, so a shared backend such as Redis can implement Store without
+changes to the callers.
+*/
+type Store interface {
+	// Get returns the entry stored under key, if present and not expired.
+	Get(key string) (*Entry, bool)
+	// Set stores the entry under key for at most ttl.
+	Set(key string, entry *Entry, ttl time.Duration)
+	// Delete removes the given keys; missing keys are ignored.
+	Delete(keys ...string)
+}
+
+/*
+NewEntry wraps a serialized body in an Entry.
+The ETag is a strong validator derived from the SHA-256 of the body, and
+LastModified is the current time truncated to whole seconds, the precision
+of the HTTP date format.
+
+Parameters:
+- body: The serialized response body
+
+Returns:
+- *Entry: The new cache entry
+*/
+func NewEntry(body []byte) *Entry {
+	sum := sha256.Sum256(body)
+
+	return &Entry{
+		Body:         body,
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
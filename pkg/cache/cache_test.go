@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	s.Set("k", NewEntry([]byte("v")), time.Minute)
+	if _, ok := s.Get("k"); !ok {
+		t.Fatal("expected entry before expiry")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := s.Get("k"); ok {
+		t.Error("expected entry to expire")
+	}
+}
+
+func TestCache_ServeJSON(t *testing.T) {
+	c := New(NewMemoryStore(), time.Minute)
+	loads := 0
+	load := func() (any, error) {
+		loads++
+		return []string{"a", "b"}, nil
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	lastModified := rec.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatal("expected ETag and Last-Modified headers")
+	}
+
+	t.Run("served from cache", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+		if loads != 1 {
+			t.Errorf("expected 1 load, got %d", loads)
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("expected stable ETag %s, got %s", etag, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("if-none-match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, req, "posts", load)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("expected 304, got %d", rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Error("expected empty body on 304")
+		}
+	})
+
+	t.Run("if-none-match mismatch ignores if-modified-since", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("If-None-Match", `"other"`)
+		req.Header.Set("If-Modified-Since", lastModified)
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, req, "posts", load)
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("if-modified-since", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("If-Modified-Since", lastModified)
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, req, "posts", load)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("expected 304, got %d", rec.Code)
+		}
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		c.Invalidate("posts")
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+		if loads != 2 {
+			t.Errorf("expected reload after invalidation, got %d loads", loads)
+		}
+	})
+}
+
+func TestCache_ServeJSON_ConcurrentMissesShareLoad(t *testing.T) {
+	c := New(NewMemoryStore(), time.Minute)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func() (any, error) {
+		if loads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return []string{"a"}, nil
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+			codes[i] = rec.Code
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected concurrent misses to share 1 load, got %d", n)
+	}
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected 200, got %d", i, code)
+		}
+	}
+}
+
+func TestCache_ServeJSON_InvalidatedLoadNotStored(t *testing.T) {
+	c := New(NewMemoryStore(), time.Minute)
+	loads := 0
+	load := func() (any, error) {
+		loads++
+		if loads == 1 {
+			// A write lands while the first load is still reading.
+			c.Invalidate("posts")
+		}
+		return loads, nil
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the stale load to be served to its own request, got %d", rec.Code)
+	}
+	if _, ok := c.store.Get("posts"); ok {
+		t.Fatal("expected a load overlapping an invalidation not to be stored")
+	}
+
+	c.ServeJSON(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", load)
+	if loads != 2 {
+		t.Errorf("expected the next request to reload, got %d loads", loads)
+	}
+	if _, ok := c.store.Get("posts"); !ok {
+		t.Error("expected the fresh load to be stored")
+	}
+}
+
+func TestCache_ServeJSON_ErrorNotCached(t *testing.T) {
+	c := New(NewMemoryStore(), time.Minute)
+
+	rec := httptest.NewRecorder()
+	c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", func() (any, error) {
+		return nil, errors.New("db error")
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected error status, got %d", rec.Code)
+	}
+	if _, ok := c.store.Get("posts"); ok {
+		t.Error("expected error response not to be cached")
+	}
+}
+
+func TestCache_NilDisablesCaching(t *testing.T) {
+	var c *Cache
+	loads := 0
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		c.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/posts", nil), "posts", func() (any, error) {
+			loads++
+			return "ok", nil
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("expected every request to load, got %d loads", loads)
+	}
+	c.Invalidate("posts")
+}
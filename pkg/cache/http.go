@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+/*
+Cache serves read endpoints from a Store and invalidates them on writes.
+A nil *Cache is valid and disables caching: ServeJSON always loads fresh
+data and Invalidate does nothing.
+*/
+type Cache struct {
+	store Store
+	ttl   time.Duration
+
+	// mu guards gens and fills. gens counts the invalidations of each key,
+	// so a load that overlapped one does not store its result; fills holds
+	// the loads in progress, which concurrent misses on the key wait for.
+	mu    sync.Mutex
+	gens  map[string]uint64
+	fills map[string]*fill
+}
+
+// fill is a load in progress; done is closed once entry or err is set.
+type fill struct {
+	done   chan struct{}
+	entry  *Entry
+	err    error
+	status int
+}
+
+/*
+New creates a new Cache.
+
+Parameters:
+- store: The backend holding serialized responses
+- ttl: Upper bound on how long an entry is served without invalidation
+
+Returns:
+- *Cache: A new cache instance
+*/
+func New(store Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl, gens: make(map[string]uint64), fills: make(map[string]*fill)}
+}
+
+/*
+Invalidate removes the given keys so the next request reloads them.
+Loads of the keys that are still running are discarded: their result is
+not stored, and later misses start a new load instead of waiting for them.
+
+Parameters:
+- keys: The cache keys to remove
+*/
+func (c *Cache) Invalidate(keys ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		c.gens[key]++
+		delete(c.fills, key)
+	}
+	c.store.Delete(keys...)
+}
+
+/*
+ServeJSON writes the response for key, loading it on a cache miss.
+
+Parameters:
+- w: The response writer
+- r: The request, whose If-None-Match / If-Modified-Since headers are honoured
+- key: The cache key identifying the response
+- load: Produces the data on a miss; it is wrapped in the standard utils.Response envelope
+
+The function:
+1. Returns the stored entry, or calls load, serializes the result and stores it; concurrent misses on key share one load
+2. Sets the ETag, Last-Modified and Cache-Control headers
+3. Replies 304 Not Modified when the client's copy is current
+4. Otherwise writes the body with 200 OK
+
+Errors from load are written with utils.WriteError and are not cached.
+*/
+func (c *Cache) ServeJSON(w http.ResponseWriter, r *http.Request, key string, load func() (any, error)) {
+	var entry *Entry
+	var ok bool
+
+	if c != nil {
+		entry, ok = c.store.Get(key)
+	}
+
+	if !ok {
+		var err error
+		var status int
+		if c != nil {
+			entry, status, err = c.fill(key, load)
+		} else {
+			entry, status, err = loadEntry(load)
+		}
+		if err != nil {
+			utils.WriteError(w, err, status)
+			return
+		}
+	}
+
+	w.Header().Set("ETag", entry.ETag)
+	w.Header().Set("Last-Modified", entry.LastModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if notModified(r, entry) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(entry.Body)
+}
+
+/*
+fill loads the entry for key once for all concurrent misses and stores it,
+unless key was invalidated while the load ran.
+*/
+func (c *Cache) fill(key string, load func() (any, error)) (*Entry, int, error) {
+	c.mu.Lock()
+	if f, ok := c.fills[key]; ok {
+		c.mu.Unlock()
+		<-f.done
+		return f.entry, f.status, f.err
+	}
+
+	f := &fill{done: make(chan struct{})}
+	c.fills[key] = f
+	gen := c.gens[key]
+	c.mu.Unlock()
+
+	f.entry, f.status, f.err = loadEntry(load)
+
+	c.mu.Lock()
+	if c.fills[key] == f {
+		delete(c.fills, key)
+	}
+	if f.err == nil && c.gens[key] == gen {
+		c.store.Set(key, f.entry, c.ttl)
+	}
+	c.mu.Unlock()
+	close(f.done)
+
+	return f.entry, f.status, f.err
+}
+
+// loadEntry calls load and serializes the result, returning the status to
+// answer with if either fails.
+func loadEntry(load func() (any, error)) (*Entry, int, error) {
+	data, err := load()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	body, err := utils.EncodeJSON(data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return NewEntry(body), 0, nil
+}
+
+/*
+notModified evaluates the conditional request headers as described in
+RFC 9110 section 13.2.2: If-None-Match takes precedence, and If-Modified-Since
+is only consulted when If-None-Match is absent.
+*/
+func notModified(r *http.Request, entry *Entry) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == entry.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err == nil && !entry.LastModified.After(since) {
+			return true
+		}
+	}
+
+	return false
+}
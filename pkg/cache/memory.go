@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	entry     *Entry
+	expiresAt time.Time
+}
+
+/*
+MemoryStore is an in-process Store.
+Each server instance has its own copy, so invalidations only reach the
+instance that performed the write; the TTL bounds how stale other instances
+can be.
+*/
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+	now   func() time.Time
+}
+
+/*
+NewMemoryStore creates an empty in-process store.
+
+Returns:
+- *MemoryStore: A new store instance
+*/
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items: make(map[string]memoryItem),
+		now:   time.Now,
+	}
+}
+
+// Get returns the entry stored under key, if present and not expired.
+func (s *MemoryStore) Get(key string) (*Entry, bool) {
+	s.mu.RLock()
+	item, ok := s.items[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if !item.expiresAt.IsZero() && s.now().After(item.expiresAt) {
+		s.Delete(key)
+		return nil, false
+	}
+
+	return item.entry, true
+}
+
+// Set stores the entry under key for at most ttl. A ttl <= 0 never expires.
+func (s *MemoryStore) Set(key string, entry *Entry, ttl time.Duration) {
+	item := memoryItem{entry: entry}
+	if ttl > 0 {
+		item.expiresAt = s.now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	s.items[key] = item
+	s.mu.Unlock()
+}
+
+// Delete removes the given keys; missing keys are ignored.
+func (s *MemoryStore) Delete(keys ...string) {
+	s.mu.Lock()
+	for _, key := range keys {
+		delete(s.items, key)
+	}
+	s.mu.Unlock()
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,90 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+)
+
+/*
+JWK is the public part of a signing key in JSON Web Key format (RFC 7517).
+*/
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+/*
+JWKS is a JSON Web Key Set.
+*/
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+/*
+JWKS returns the public keys other services need to verify our tokens:
+every asymmetric key that is scheduled, current, or retired but still within
+the grace period. HS256 secrets are never published.
+*/
+func (m *Manager) JWKS() JWKS {
+	now := m.now()
+	set := JWKS{Keys: []JWK{}}
+
+	for _, k := range m.keys {
+		if !m.acceptsKey(k, now) {
+			continue
+		}
+
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+/*
+JWKSHandler publishes the key set.
+The body is the bare JWKS document, without the usual response envelope,
+because JWT libraries fetch it directly.
+
+HTTP Method: GET
+Endpoint: /.well-known/jwks.json
+
+Response (200 OK):
+
+	{
+	  "keys": [
+	    {"kty": "OKP", "kid": "2025-01", "use": "sig", "alg": "EdDSA", "crv": "Ed25519", "x": "..."}
+	  ]
+	}
+*/
+func (m *Manager) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(m.JWKS())
+}
@@ -1,15 +1,17 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secret = []byte(os.Getenv("SECRET"))
-
 /*
 Claims represents the JWT token claims structure.
 Contains user ID, role, and standard JWT registered claims.
@@ -17,40 +19,309 @@ Contains user ID, role, and standard JWT registered claims.
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// MFA is the second factor state of the session (see auth.SecondFactor*);
+	// empty for accounts without a second factor.
+	MFA string `json:"mfa,omitempty"`
+	// ImpersonatedBy is the ID of the admin an impersonation token was issued
+	// to; empty for the user's own tokens.
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+	// ReadOnly limits an impersonation token to reading.
+	ReadOnly bool `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ChallengeTTL is the lifetime of tokens that still wait for a second factor.
+const ChallengeTTL = 15 * time.Minute
+
+/*
+Options configures a Manager.
+*/
+type Options struct {
+	// Secret is the legacy HS256 key. It signs tokens while no asymmetric key
+	// is active and verifies HS256 tokens while AcceptHS256 is set.
+	Secret []byte
+	// Keys are the asymmetric signing keys. The active key with the latest
+	// NotBefore signs new tokens.
+	Keys []*Key
+	// RotationGrace is how long tokens signed by a key stay valid after a
+	// newer key has taken over signing. It should be at least the longest token TTL.
+	RotationGrace time.Duration
+	// AcceptHS256 keeps HS256 tokens valid once asymmetric keys are in use,
+	// so users are not logged out during the migration.
+	AcceptHS256 bool
+	// UserTTL is the lifetime of tokens issued with the "user" role.
+	UserTTL time.Duration
+	// AdminTTL is the lifetime of tokens issued with the "admin" role.
+	AdminTTL time.Duration
+}
+
+/*
+Manager issues and validates JWT tokens.
+A single Manager is created at startup from the configuration and shared by
+the services and middlewares that need it.
+*/
+type Manager struct {
+	secret      []byte
+	keys        []*Key
+	grace       time.Duration
+	acceptHS256 bool
+	userTTL     time.Duration
+	adminTTL    time.Duration
+	now         func() time.Time
+}
+
+/*
+NewManager creates a new Manager with the provided options.
+
+Parameters:
+- opts: The signing keys, legacy secret and token lifetimes
+
+Returns:
+- *Manager: A new token manager
+- error: If there is nothing to sign with, or key IDs are duplicated
+
+Without Keys the manager signs and verifies HS256 with Secret only, exactly
+as before asymmetric keys were introduced.
+*/
+func NewManager(opts Options) (*Manager, error) {
+	signers := 0
+	seen := make(map[string]bool)
+	for _, k := range opts.Keys {
+		if seen[k.ID] {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.ID)
+		}
+		seen[k.ID] = true
+		if k.CanSign() {
+			signers++
+		}
+	}
+
+	if signers == 0 && len(opts.Secret) == 0 {
+		return nil, errors.New("jwt secret must not be empty")
+	}
+
+	keys := slices.Clone(opts.Keys)
+	slices.SortStableFunc(keys, func(a, b *Key) int { return a.NotBefore.Compare(b.NotBefore) })
+
+	m := &Manager{
+		secret:      opts.Secret,
+		keys:        keys,
+		grace:       opts.RotationGrace,
+		acceptHS256: len(keys) == 0 || opts.AcceptHS256,
+		userTTL:     opts.UserTTL,
+		adminTTL:    opts.AdminTTL,
+		now:         time.Now,
+	}
+
+	if m.userTTL <= 0 {
+		m.userTTL = 24 * time.Hour
+	}
+
+	if m.adminTTL <= 0 {
+		m.adminTTL = 24 * time.Hour
+	}
+
+	return m, nil
+}
+
+func (m *Manager) ttl(role string) time.Duration {
+	if role == "admin" {
+		return m.adminTTL
+	}
+	return m.userTTL
+}
+
+/*
+newSessionID returns a random identifier stored in the token's jti claim.
+It identifies the login session the token belongs to.
+*/
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating session id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 /*
 GenerateJwtToken creates a new JWT token with the specified user ID and role.
-The token expires in 24 hours from creation.
+The token expires after the lifetime configured for the role and carries a
+fresh session ID in its jti claim.
+
+Parameters:
+- userID: The unique identifier of the user
+- role: The role of the user ("user" or "admin")
+
+Returns:
+- string: The generated JWT token
+- error: Any error that occurred during token generation
+*/
+func (m *Manager) GenerateJwtToken(userID string, role string) (string, error) {
+	return m.generate(userID, role, "", m.ttl(role))
+}
+
+/*
+IssueToken issues a full session token for a login whose password has been
+verified. It lets the Manager stand in for the second factor service where
+no second factor is used.
+
+Parameters:
+- ctx: Unused; the Manager looks nothing up
+- accountType: The role of the account ("user" or "admin")
+- accountID: The unique identifier of the account
+
+Returns:
+- string: The generated JWT token
+- string: The second factor state, always empty
+- error: Any error that occurred during token generation
+*/
+func (m *Manager) IssueToken(ctx context.Context, accountType, accountID string) (string, string, error) {
+	token, err := m.GenerateJwtToken(accountID, accountType)
+	return token, "", err
+}
+
+/*
+GenerateChallengeToken creates a short-lived token for a login that still
+needs a second factor. It expires after ChallengeTTL and only admits its
+holder to the second factor endpoints.
+
+Parameters:
+- userID: The unique identifier of the user
+- role: The role of the user ("user" or "admin")
+- mfa: The second factor state, auth.SecondFactorPending or auth.SecondFactorEnroll
+
+Returns:
+- string: The generated JWT token
+- error: Any error that occurred during token generation
+*/
+func (m *Manager) GenerateChallengeToken(userID, role, mfa string) (string, error) {
+	return m.generate(userID, role, mfa, ChallengeTTL)
+}
+
+/*
+GenerateStepUpToken creates a token with the role's full lifetime for a
+login that has completed its second factor.
 
 Parameters:
 - userID: The unique identifier of the user
 - role: The role of the user ("user" or "admin")
+- mfa: The second factor state, normally auth.SecondFactorVerified
 
 Returns:
 - string: The generated JWT token
 - error: Any error that occurred during token generation
 */
-func GenerateJwtToken(userID string, role string) (string, error) {
-	claims := Claims{
+func (m *Manager) GenerateStepUpToken(userID, role, mfa string) (string, error) {
+	return m.generate(userID, role, mfa, m.ttl(role))
+}
+
+/*
+GenerateImpersonationToken creates a token that lets an admin act as a
+user. It carries the "user" role together with the admin's ID, so the
+middlewares can mark, restrict and audit everything done with it.
+
+Parameters:
+- userID: The impersonated user
+- adminID: The impersonating admin
+- readOnly: Whether the token may only be used for reading
+- ttl: The lifetime of the token
+
+Returns:
+- string: The generated JWT token
+- string: Its session ID (jti), which identifies the impersonation in the audit log
+- error: Any error that occurred during token generation
+*/
+func (m *Manager) GenerateImpersonationToken(userID, adminID string, readOnly bool, ttl time.Duration) (string, string, error) {
+	claims, err := m.claims(userID, "user", "", ttl)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims.ImpersonatedBy = adminID
+	claims.ReadOnly = readOnly
+
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, claims.ID, nil
+}
+
+func (m *Manager) generate(userID, role, mfa string, ttl time.Duration) (string, error) {
+	claims, err := m.claims(userID, role, mfa, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return m.sign(claims)
+}
+
+func (m *Manager) claims(userID, role, mfa string, ttl time.Duration) (*Claims, error) {
+	now := m.now()
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Claims{
 		UserID: userID,
 		Role:   role,
+		MFA:    mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
+	}, nil
+}
+
+/*
+sign serializes the claims with the current signing key, adding its kid
+header, or with the HS256 secret while no asymmetric key is active.
+*/
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	if key := m.signingKey(m.now()); key != nil {
+		token := jwt.NewWithClaims(key.method(), claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.private)
+	}
+
+	if len(m.secret) == 0 {
+		return "", errors.New("no active jwt signing key")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+/*
+ParseToken validates a JWT token and returns all of its claims.
+Verifies the token signature and expiration.
+
+Parameters:
+- tokenString: The JWT token to validate
 
-	tokenString, err := token.SignedString(secret)
+Returns:
+- *Claims: The token claims
+- error: Any error that occurred during validation
+*/
+func (m *Manager) ParseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc,
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA, AlgHS256}),
+		jwt.WithTimeFunc(m.now),
+	)
 
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	// Extract claims
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
 	}
 
-	return tokenString, nil
+	return nil, fmt.Errorf("invalid token claims")
 }
 
 /*
@@ -65,25 +336,13 @@ Returns:
 - string: The role from the token
 - error: Any error that occurred during validation
 */
-func ValidateJwtToken(tokenString string) (string, string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Enforce HS256
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secret, nil
-	})
-
+func (m *Manager) ValidateJwtToken(tokenString string) (string, string, error) {
+	claims, err := m.ParseToken(tokenString)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid token: %w", err)
-	}
-
-	// Extract claims
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.UserID, claims.Role, nil
+		return "", "", err
 	}
 
-	return "", "", fmt.Errorf("invalid token claims")
+	return claims.UserID, claims.Role, nil
 }
 
 /*
@@ -97,8 +356,8 @@ Returns:
 - string: The user ID from the token
 - error: Any error that occurred during validation or if token is not a user token
 */
-func ValidateUserToken(tokenString string) (string, error) {
-	userID, role, err := ValidateJwtToken(tokenString)
+func (m *Manager) ValidateUserToken(tokenString string) (string, error) {
+	userID, role, err := m.ValidateJwtToken(tokenString)
 	if err != nil {
 		return "", err
 	}
@@ -119,8 +378,8 @@ Returns:
 - string: The admin ID from the token
 - error: Any error that occurred during validation or if token is not an admin token
 */
-func ValidateAdminToken(tokenString string) (string, error) {
-	userID, role, err := ValidateJwtToken(tokenString)
+func (m *Manager) ValidateAdminToken(tokenString string) (string, error) {
+	userID, role, err := m.ValidateJwtToken(tokenString)
 	if err != nil {
 		return "", err
 	}
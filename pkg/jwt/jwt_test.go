@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func newEdKey(t *testing.T, id string, notBefore time.Time) *Key {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := NewKey(id, AlgEdDSA, priv, notBefore)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	return key
+}
+
+func TestLoadKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	pub, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+
+	t.Run("pkcs8 rsa", func(t *testing.T) {
+		key, err := LoadKey(KeyOptions{ID: "k1", Algorithm: AlgRS256, File: writePEM(t, "PRIVATE KEY", pkcs8)})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !key.CanSign() {
+			t.Error("expected private key to sign")
+		}
+	})
+	t.Run("pkcs1 rsa", func(t *testing.T) {
+		_, err := LoadKey(KeyOptions{ID: "k1", Algorithm: AlgRS256, File: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+	t.Run("public key is verify only", func(t *testing.T) {
+		key, err := LoadKey(KeyOptions{ID: "k1", Algorithm: AlgRS256, File: writePEM(t, "PUBLIC KEY", pub)})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if key.CanSign() {
+			t.Error("expected public key not to sign")
+		}
+	})
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		_, err := LoadKey(KeyOptions{ID: "k1", Algorithm: AlgEdDSA, File: writePEM(t, "PRIVATE KEY", pkcs8)})
+		if err == nil || !strings.Contains(err.Error(), "EdDSA requires an Ed25519 key") {
+			t.Errorf("expected algorithm mismatch error, got %v", err)
+		}
+	})
+}
+
+func TestManager_HS256Only(t *testing.T) {
+	m, err := NewManager(Options{Secret: []byte("secret")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	token, err := m.GenerateJwtToken("u1", "user")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	userID, err := m.ValidateUserToken(token)
+	if err != nil || userID != "u1" {
+		t.Errorf("expected u1, got %q %v", userID, err)
+	}
+
+	if _, err := NewManager(Options{}); err == nil {
+		t.Error("expected error without secret or keys")
+	}
+}
+
+func TestManager_GenerateImpersonationToken(t *testing.T) {
+	m, _ := NewManager(Options{Secret: []byte("secret")})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	token, sessionID, err := m.GenerateImpersonationToken("u1", "a1", true, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, err := m.ParseToken(token)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claims.UserID != "u1" || claims.Role != "user" || claims.ImpersonatedBy != "a1" || !claims.ReadOnly {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.ID != sessionID || sessionID == "" {
+		t.Errorf("expected session ID %q, got %q", sessionID, claims.ID)
+	}
+	if !claims.ExpiresAt.Time.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("expected 15 minute lifetime, got %v", claims.ExpiresAt.Time)
+	}
+
+	own, _ := m.GenerateJwtToken("u1", "user")
+	if claims, _ := m.ParseToken(own); claims.ImpersonatedBy != "" || claims.ReadOnly {
+		t.Errorf("expected own tokens without impersonation claims, got %+v", claims)
+	}
+}
+
+func TestManager_Rotation(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldKey := newEdKey(t, "old", start)
+	newKey := newEdKey(t, "new", start.Add(30*24*time.Hour))
+
+	m, err := NewManager(Options{
+		Keys:          []*Key{newKey, oldKey},
+		RotationGrace: 24 * time.Hour,
+		UserTTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	now := start.Add(time.Hour)
+	m.now = func() time.Time { return now }
+
+	oldToken, _ := m.GenerateJwtToken("u1", "user")
+	if claims, err := m.ParseToken(oldToken); err != nil || claims.UserID != "u1" {
+		t.Fatalf("expected old-key token to validate, got %v", err)
+	}
+
+	// The scheduled key takes over signing.
+	now = newKey.NotBefore.Add(time.Minute)
+	if got := m.signingKey(now); got != newKey {
+		t.Fatalf("expected new key to sign after its not_before, got %v", got.ID)
+	}
+
+	// Tokens from the old key keep working during the grace period (the
+	// token itself must still be unexpired, so mint a fresh one at the switch).
+	now = newKey.NotBefore.Add(-time.Minute)
+	graceToken, _ := m.GenerateJwtToken("u1", "user")
+	now = newKey.NotBefore.Add(30 * time.Minute)
+	if _, err := m.ParseToken(graceToken); err != nil {
+		t.Errorf("expected old-key token to validate during grace, got %v", err)
+	}
+	if len(m.JWKS().Keys) != 2 {
+		t.Errorf("expected both keys published during grace, got %d", len(m.JWKS().Keys))
+	}
+
+	// After the grace period the old key is retired.
+	now = newKey.NotBefore.Add(25 * time.Hour)
+	if m.acceptsKey(oldKey, now) {
+		t.Error("expected old key to be retired after grace")
+	}
+	if keys := m.JWKS().Keys; len(keys) != 1 || keys[0].KeyID != "new" {
+		t.Errorf("expected only the new key published, got %+v", keys)
+	}
+}
+
+func TestManager_LegacyHS256(t *testing.T) {
+	legacy, _ := NewManager(Options{Secret: []byte("secret")})
+	hsToken, _ := legacy.GenerateJwtToken("u1", "user")
+
+	key := newEdKey(t, "k1", time.Now().Add(-time.Hour))
+
+	accepting, _ := NewManager(Options{Secret: []byte("secret"), Keys: []*Key{key}, AcceptHS256: true})
+	if _, err := accepting.ParseToken(hsToken); err != nil {
+		t.Errorf("expected HS256 token accepted during migration, got %v", err)
+	}
+
+	strict, _ := NewManager(Options{Secret: []byte("secret"), Keys: []*Key{key}})
+	if _, err := strict.ParseToken(hsToken); err == nil {
+		t.Error("expected HS256 token rejected once migration is complete")
+	}
+
+	edToken, _ := strict.GenerateJwtToken("u1", "user")
+	if _, err := strict.ParseToken(edToken); err != nil {
+		t.Errorf("expected EdDSA token accepted, got %v", err)
+	}
+}
+
+func TestManager_JWKSHandler(t *testing.T) {
+	m, _ := NewManager(Options{Secret: []byte("secret"), Keys: []*Key{newEdKey(t, "k1", time.Now().Add(-time.Hour))}})
+
+	rec := httptest.NewRecorder()
+	m.JWKSHandler(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	var set JWKS
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("failed to decode JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyType != "OKP" || set.Keys[0].X == "" {
+		t.Errorf("unexpected JWKS: %+v", set)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Error("HS256 secret must never be published")
+	}
+}
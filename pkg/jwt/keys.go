@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+	AlgHS256 = "HS256"
+)
+
+/*
+Key is an asymmetric signing key identified by its kid.
+A key holding only a public part can verify tokens but never signs them,
+which is how a retired key is kept around after its private part is deleted.
+*/
+type Key struct {
+	ID        string
+	Algorithm string
+	// NotBefore is when the key starts signing tokens. Scheduling a key in the
+	// future rotates to it automatically at that time.
+	NotBefore time.Time
+
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+/*
+KeyOptions describes a key to load from a PEM file.
+*/
+type KeyOptions struct {
+	ID        string
+	Algorithm string
+	File      string
+	NotBefore time.Time
+}
+
+/*
+LoadKey reads a PEM-encoded key from disk.
+
+Parameters:
+- opts: The key ID, algorithm ("RS256" or "EdDSA"), PEM file and activation time
+
+Returns:
+- *Key: The loaded key
+- error: Any error reading or parsing the file, or if the key type does not match the algorithm
+
+Accepted PEM blocks are "PRIVATE KEY" (PKCS#8), "RSA PRIVATE KEY" (PKCS#1)
+and "PUBLIC KEY" (PKIX) for verify-only keys.
+*/
+func LoadKey(opts KeyOptions) (*Key, error) {
+	if opts.ID == "" {
+		return nil, fmt.Errorf("key id is required")
+	}
+
+	raw, err := os.ReadFile(opts.File)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key %s: %v", opts.ID, err)
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found in %s", opts.ID, opts.File)
+	}
+
+	key := &Key{ID: opts.ID, Algorithm: opts.Algorithm, NotBefore: opts.NotBefore}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", opts.ID, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %s: unsupported private key type %T", opts.ID, parsed)
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", opts.ID, err)
+		}
+		key.private = parsed
+		key.public = parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", opts.ID, err)
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM block %q", opts.ID, block.Type)
+	}
+
+	if err = key.checkAlgorithm(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+/*
+NewKey wraps an in-memory private key, e.g. one generated in tests.
+
+Parameters:
+- id: The key ID published as kid
+- algorithm: "RS256" for *rsa.PrivateKey or "EdDSA" for ed25519.PrivateKey
+- signer: The private key
+- notBefore: When the key starts signing
+
+Returns:
+- *Key: The key
+- error: If the key type does not match the algorithm
+*/
+func NewKey(id, algorithm string, signer crypto.Signer, notBefore time.Time) (*Key, error) {
+	key := &Key{ID: id, Algorithm: algorithm, NotBefore: notBefore, private: signer, public: signer.Public()}
+	if err := key.checkAlgorithm(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (k *Key) checkAlgorithm() error {
+	switch k.Algorithm {
+	case AlgRS256:
+		if _, ok := k.public.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("key %s: RS256 requires an RSA key, got %T", k.ID, k.public)
+		}
+	case AlgEdDSA:
+		if _, ok := k.public.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("key %s: EdDSA requires an Ed25519 key, got %T", k.ID, k.public)
+		}
+	default:
+		return fmt.Errorf("key %s: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+	return nil
+}
+
+// CanSign reports whether the private part of the key is available.
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+signingKey returns the key that signs tokens at the given time: the
+signing-capable key with the latest NotBefore that is not in the future.
+It returns nil while no asymmetric key is active.
+*/
+func (m *Manager) signingKey(now time.Time) *Key {
+	var active *Key
+	for _, k := range m.keys {
+		if k.CanSign() && !k.NotBefore.After(now) {
+			active = k
+		}
+	}
+	return active
+}
+
+/*
+retiredAt returns when the given key stopped signing: the NotBefore of the
+first later signing-capable key that is already active. A zero time means
+the key is still current (or has not started yet).
+*/
+func (m *Manager) retiredAt(key *Key, now time.Time) time.Time {
+	found := false
+	for _, k := range m.keys {
+		if k == key {
+			found = true
+			continue
+		}
+		if found && k.CanSign() && k.NotBefore.After(key.NotBefore) && !k.NotBefore.After(now) {
+			return k.NotBefore
+		}
+	}
+	return time.Time{}
+}
+
+/*
+acceptsKey reports whether tokens signed by key are still valid: the key is
+current, or was replaced less than the rotation grace period ago.
+*/
+func (m *Manager) acceptsKey(key *Key, now time.Time) bool {
+	retired := m.retiredAt(key, now)
+	return retired.IsZero() || now.Before(retired.Add(m.grace))
+}
+
+func (m *Manager) findKey(kid string) *Key {
+	for _, k := range m.keys {
+		if k.ID == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+/*
+keyFunc selects the verification key for a parsed token.
+Tokens with a kid header must be signed by that key with its algorithm;
+tokens without one are legacy HS256 tokens, accepted only while HS256 is
+allowed.
+*/
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if !m.acceptHS256 || len(m.secret) == 0 {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return m.secret, nil
+	}
+
+	key := m.findKey(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	if !m.acceptsKey(key, m.now()) {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+
+	return key.public, nil
+}
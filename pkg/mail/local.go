@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+FileSender writes every message as an .eml file into a directory, where it
+can be opened with a mail client. It is meant for local development.
+*/
+type FileSender struct {
+	dir  string
+	from string
+}
+
+/*
+NewFileSender creates a sender that writes into dir, creating it if needed.
+
+Parameters:
+- dir: The output directory
+- from: The sender address written into the From header
+
+Returns:
+- *FileSender: A new sender
+- error: If the directory cannot be created
+*/
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating mail directory: %v", err)
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+// Send writes the message to a new file named after the current time.
+func (s *FileSender) Send(msg Message) error {
+	now := time.Now()
+	body, err := render(s.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return fmt.Errorf("error naming mail file: %v", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	if err = os.WriteFile(filepath.Join(s.dir, name), body, 0o600); err != nil {
+		return fmt.Errorf("error writing mail file: %v", err)
+	}
+
+	return nil
+}
+
+/*
+LogSender prints every message to the standard logger.
+Messages contain verification and reset links, so it must never be used in
+production.
+*/
+type LogSender struct {
+	from string
+}
+
+/*
+NewLogSender creates a sender that logs messages.
+
+Parameters:
+- from: The sender address written into the From header
+
+Returns:
+- *LogSender: A new sender
+*/
+func NewLogSender(from string) *LogSender {
+	return &LogSender{from: from}
+}
+
+// Send logs the rendered message.
+func (s *LogSender) Send(msg Message) error {
+	body, err := render(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Printf("mail: not delivered (log driver):\n%s", body)
+	return nil
+}
+
+// Ensure the local senders implement Sender
+var (
+	_ Sender = (*FileSender)(nil)
+	_ Sender = (*LogSender)(nil)
+)
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+/*
+Message is a plain-text email.
+*/
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+/*
+Sender delivers email messages.
+SMTPSender is used in production; FileSender and LogSender keep messages
+local for development and tests.
+*/
+type Sender interface {
+	Send(msg Message) error
+}
+
+/*
+ValidateAddress checks that s is a single bare email address such as
+"student@example.edu" and returns it lowercased.
+
+Parameters:
+- s: The address to check
+
+Returns:
+- string: The normalized address
+- error: If s is not a bare address
+*/
+func ValidateAddress(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	addr, err := netmail.ParseAddress(s)
+	if err != nil || addr.Address != s || addr.Name != "" {
+		return "", fmt.Errorf("not a valid email address")
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+/*
+render serializes the message in RFC 5322 format.
+Header values containing line breaks are rejected so that user input can
+never inject additional headers or recipients.
+*/
+func render(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("invalid mail header value")
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return b.Bytes(), nil
+}
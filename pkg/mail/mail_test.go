@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"net/smtp"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAddress(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"Student@Example.edu", "student@example.edu", false},
+		{" student@example.edu ", "student@example.edu", false},
+		{"Student <student@example.edu>", "", true},
+		{"a@b.c, d@e.f", "", true},
+		{"not-an-address", "", true},
+	}
+	for _, c := range cases {
+		got, err := ValidateAddress(c.in)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("ValidateAddress(%q) = %q, %v", c.in, got, err)
+		}
+	}
+}
+
+func TestRender_RejectsHeaderInjection(t *testing.T) {
+	_, err := render("from@example.edu", Message{To: "a@example.edu\r\nBcc: x@example.edu", Subject: "hi"}, time.Now())
+	if err == nil {
+		t.Error("expected header injection to be rejected")
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	s, err := NewSMTPSender(SMTPOptions{Host: "smtp.example.edu", Port: 587, Username: "u", Password: "p", From: "PlacementLog <no-reply@example.edu>"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	s.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	if err = s.Send(Message{To: "student@example.edu", Subject: "Reset", Body: "line1\nline2"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if gotAddr != "smtp.example.edu:587" || gotFrom != "no-reply@example.edu" || gotTo[0] != "student@example.edu" {
+		t.Errorf("unexpected envelope %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	if !strings.Contains(string(gotMsg), "Subject: Reset\r\n") || !strings.HasSuffix(string(gotMsg), "line1\r\nline2") {
+		t.Errorf("unexpected message %q", gotMsg)
+	}
+}
+
+func TestFileSender_Send(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSender(dir, "no-reply@example.edu")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err = s.Send(Message{To: "student@example.edu", Subject: "Verify", Body: "hello"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Fatalf("expected one .eml file, got %v", entries)
+	}
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+/*
+SMTPOptions configures an SMTPSender.
+*/
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender, e.g. "PlacementLog <no-reply@placementlog.example>".
+	From string
+}
+
+/*
+SMTPSender delivers messages through an SMTP relay.
+net/smtp upgrades the connection with STARTTLS when the server offers it and
+refuses to send credentials over an unencrypted connection to a remote host.
+*/
+type SMTPSender struct {
+	opts     SMTPOptions
+	envelope string
+	send     func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+/*
+NewSMTPSender creates an SMTP sender.
+
+Parameters:
+- opts: The relay address, credentials and sender address
+
+Returns:
+- *SMTPSender: A new sender
+- error: If the sender address cannot be parsed
+*/
+func NewSMTPSender(opts SMTPOptions) (*SMTPSender, error) {
+	from, err := netmail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail from address: %v", err)
+	}
+
+	return &SMTPSender{opts: opts, envelope: from.Address, send: smtp.SendMail}, nil
+}
+
+/*
+Send delivers the message.
+
+Parameters:
+- msg: The message to send
+
+Returns:
+- error: Any error rendering or delivering the message
+*/
+func (s *SMTPSender) Send(msg Message) error {
+	body, err := render(s.opts.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	if err = s.send(addr, auth, s.envelope, []string{msg.To}, body); err != nil {
+		return fmt.Errorf("error sending mail: %v", err)
+	}
+
+	return nil
+}
+
+// Ensure SMTPSender implements Sender
+var _ Sender = (*SMTPSender)(nil)
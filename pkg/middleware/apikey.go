@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+)
+
+/*
+APIKeyMiddleware authenticates requests whose bearer credential is an API
+key (it starts with auth.APIKeyPrefix) and stores the key's principal in the
+request context. Unknown, expired and revoked keys are refused with 401.
+Other requests are left to the token middlewares, which refuse API keys, so
+keys only reach routes that accept them explicitly. It must be installed
+before every authentication middleware.
+
+Parameters:
+- keys: Verifies API keys
+*/
+func APIKeyMiddleware(keys auth.APIKeyVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !strings.HasPrefix(key, auth.APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := keys.VerifyAPIKey(r.Context(), key)
+			switch {
+			case errors.Is(err, auth.ErrInvalidAPIKey):
+				http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+			case err != nil:
+				http.Error(w, "failed to check API key", http.StatusInternalServerError)
+			default:
+				next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+			}
+		})
+	}
+}
+
+/*
+RequireScope limits the API keys admitted to a route to those granted the
+scope; other keys are refused with 403 Forbidden. Requests without an API
+key are not affected, so public routes stay public.
+
+Parameters:
+- scope: The scope the route requires, e.g. auth.PermPlacementsRead
+*/
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.FromContext(r.Context()); ok && principal.IsAPIKey() && !principal.Can(scope) {
+				http.Error(w, "forbidden: API key lacks the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+/*
+RequirePermission refuses principals without the permission with 403
+Forbidden. It must be installed after the route's authentication
+middleware, so every admin route states the permission it needs instead of
+relying on the handler to check it.
+
+Parameters:
+- permission: The permission the route requires, e.g. auth.PermPostsModerate
+*/
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, _ := auth.FromContext(r.Context()); !principal.Can(permission) {
+				http.Error(w, "forbidden: "+permission+" permission required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+/*
+PermissionMiddleware admits API keys granted the permission as a scope and
+users or admins whose tokens carry it, and stores the principal in the
+request context. Missing or invalid credentials are refused with 401, and
+callers without the permission with 403. Admin tokens are refused as by
+AdminAuthMiddleware once the admin is disabled.
+
+Parameters:
+- tokens: The token manager used to validate JWTs
+- admins: Checks that admin accounts still exist and are enabled (nil skips the check)
+- permission: The permission the route requires, e.g. auth.PermStatsRead
+*/
+func PermissionMiddleware(tokens *jwt.Manager, admins auth.AccountChecker, permission string) func(http.Handler) http.Handler {
+	authenticated := requireRole(tokens, "", false)
+
+	return func(next http.Handler) http.Handler {
+		permitted := RequirePermission(permission)(next)
+
+		var check http.Handler = permitted
+		if admins != nil {
+			checked := checkAccount(admins, permitted)
+			check = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if principal, _ := auth.FromContext(r.Context()); principal.IsAdmin() {
+					checked.ServeHTTP(w, r)
+					return
+				}
+				permitted.ServeHTTP(w, r)
+			})
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.FromContext(r.Context()); ok && principal.IsAPIKey() {
+				check.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated(check).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+// apiKeyVerifierFunc adapts a function to auth.APIKeyVerifier.
+type apiKeyVerifierFunc func(ctx context.Context, key string) (*auth.Principal, error)
+
+func (f apiKeyVerifierFunc) VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	return f(ctx, key)
+}
+
+var testKeys = apiKeyVerifierFunc(func(ctx context.Context, key string) (*auth.Principal, error) {
+	switch key {
+	case "plk_stats":
+		return auth.NewAPIKeyPrincipal("key-1", []string{auth.PermStatsRead}), nil
+	case "plk_posts":
+		return auth.NewAPIKeyPrincipal("key-2", []string{auth.PermPostsRead}), nil
+	case "plk_broken":
+		return nil, errors.New("db down")
+	}
+	return nil, auth.ErrInvalidAPIKey
+})
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantID     string
+	}{
+		{"no credentials", "", http.StatusOK, ""},
+		{"user token passes through", "Bearer " + userToken, http.StatusOK, ""},
+		{"valid key", "Bearer plk_stats", http.StatusOK, "key-1"},
+		{"unknown key", "Bearer plk_nope", http.StatusUnauthorized, ""},
+		{"verifier failure", "Bearer plk_broken", http.StatusInternalServerError, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got *auth.Principal
+			req := httptest.NewRequest(http.MethodGet, "/placements", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			APIKeyMiddleware(testKeys)(capturePrincipal(&got)).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+			if c.wantID == "" {
+				if got != nil {
+					t.Fatalf("expected no principal, got %+v", got)
+				}
+				return
+			}
+			if got == nil || got.ID != c.wantID || !got.IsAPIKey() {
+				t.Fatalf("expected API key principal %s, got %+v", c.wantID, got)
+			}
+		})
+	}
+}
+
+func TestTokenMiddlewaresRefuseAPIKeys(t *testing.T) {
+	tokens := newTestTokens(t)
+
+	for name, mw := range map[string]func(http.Handler) http.Handler{
+		"user":  UserAuthMiddleware(tokens, nil),
+		"admin": AdminAuthMiddleware(tokens, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got *auth.Principal
+			req := httptest.NewRequest(http.MethodGet, "/admin/posts", nil)
+			req.Header.Set("Authorization", "Bearer plk_stats")
+			rec := httptest.NewRecorder()
+			APIKeyMiddleware(testKeys)(mw(capturePrincipal(&got))).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"anonymous", "", http.StatusOK},
+		{"user token", "Bearer " + userToken, http.StatusOK},
+		{"key with scope", "Bearer plk_posts", http.StatusOK},
+		{"key without scope", "Bearer plk_stats", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			APIKeyMiddleware(testKeys)(OptionalAuthMiddleware(tokens)(RequireScope(auth.PermPostsRead)(ok))).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	adminToken, _ := tokens.GenerateJwtToken("admin-1", auth.RoleAdmin)
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"without the permission", "Bearer " + userToken, http.StatusForbidden},
+		{"with the permission", "Bearer " + adminToken, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/posts/1/review", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			AuthMiddleware(tokens)(RequirePermission(auth.PermPostsModerate)(ok)).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	adminToken, _ := tokens.GenerateJwtToken("admin-1", auth.RoleAdmin)
+	disabledToken, _ := tokens.GenerateJwtToken("admin-2", auth.RoleAdmin)
+
+	// Only admin principals are checked; admin-2 has been disabled.
+	admins := accountCheckerFunc(func(ctx context.Context, adminID string) error {
+		switch adminID {
+		case "admin-1":
+			return nil
+		case "admin-2":
+			return auth.ErrAccountDisabled
+		}
+		t.Errorf("unexpected account check for %q", adminID)
+		return nil
+	})
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantID     string
+	}{
+		{"missing header", "", http.StatusUnauthorized, ""},
+		{"user token", "Bearer " + userToken, http.StatusForbidden, ""},
+		{"admin token", "Bearer " + adminToken, http.StatusOK, "admin-1"},
+		{"disabled admin token", "Bearer " + disabledToken, http.StatusForbidden, ""},
+		{"key with scope", "Bearer plk_stats", http.StatusOK, "key-1"},
+		{"key without scope", "Bearer plk_posts", http.StatusForbidden, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got *auth.Principal
+			req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			APIKeyMiddleware(testKeys)(PermissionMiddleware(tokens, admins, auth.PermStatsRead)(capturePrincipal(&got))).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+			if c.wantID != "" && (got == nil || got.ID != c.wantID) {
+				t.Fatalf("expected principal %s, got %+v", c.wantID, got)
+			}
+		})
+	}
+}
@@ -1,122 +1,222 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
 	"github.com/varnit-ta/PlacementLog/pkg/jwt"
 )
 
+var errMissingBearer = errors.New("missing or invalid Authorization header")
+
+var errSecondFactorRequired = errors.New("two-factor authentication required")
+
+var errAPIKeyNotAccepted = errors.New("API keys are not accepted on this route")
+
+/*
+authenticate validates the bearer token of a request and builds the
+principal it represents.
+
+Returns:
+- *auth.Principal: The authenticated principal
+- error: errMissingBearer without a bearer token, errAPIKeyNotAccepted for API keys, or the validation error
+*/
+func authenticate(tokens *jwt.Manager, r *http.Request) (*auth.Principal, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, errMissingBearer
+	}
+
+	credential := strings.TrimPrefix(authHeader, "Bearer ")
+	if strings.HasPrefix(credential, auth.APIKeyPrefix) {
+		return nil, errAPIKeyNotAccepted
+	}
+
+	claims, err := tokens.ParseToken(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := auth.NewPrincipal(claims.UserID, claims.Role, claims.ID)
+	principal.SecondFactor = claims.MFA
+
+	if claims.ImpersonatedBy != "" {
+		principal.ImpersonatorID = claims.ImpersonatedBy
+		principal.ReadOnly = claims.ReadOnly
+		if claims.ReadOnly {
+			principal.Permissions = nil
+		}
+	}
+
+	return principal, nil
+}
+
+/*
+requireRole builds a middleware that only admits principals with the given
+role (any role when role is empty) and stores the principal in the request
+context. Sessions that still need a second factor are refused with 403
+unless allowChallenge is set.
+*/
+func requireRole(tokens *jwt.Manager, role string, allowChallenge bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(tokens, r)
+			if err != nil {
+				http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if role != "" && principal.Role != role {
+				http.Error(w, "unauthorized: "+role+" token required", http.StatusUnauthorized)
+				return
+			}
+
+			if !allowChallenge && principal.NeedsSecondFactor() {
+				http.Error(w, "forbidden: "+errSecondFactorRequired.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
 /*
-AuthMiddleware validates JWT tokens and extracts user information.
+AuthMiddleware validates JWT tokens and stores the caller in the request context.
 This middleware checks for the Authorization header, validates the JWT token,
-and adds user information to the request headers for downstream handlers.
+and makes the resulting auth.Principal available through auth.FromContext.
+
+Parameters:
+- tokens: The token manager used to validate the JWT
 
 The middleware expects:
 - Authorization header with format "Bearer <token>"
 - Valid JWT token
 
-It adds to request headers:
-- X-User-ID: The user ID from the token
-- X-User-Role: The role from the token ("user" or "admin")
-
 If validation fails, it returns a 401 Unauthorized response.
 */
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "unauthorized: missing or invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userID, role, err := jwt.ValidateJwtToken(token)
-		if err != nil {
-			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
-			return
-		}
-
-		// Add user info to request context
-		r.Header.Set("X-User-ID", userID)
-		r.Header.Set("X-User-Role", role)
-
-		next.ServeHTTP(w, r)
-	})
+func AuthMiddleware(tokens *jwt.Manager) func(http.Handler) http.Handler {
+	return requireRole(tokens, "", false)
 }
 
 /*
 UserAuthMiddleware ensures the request is from an authenticated user.
 This middleware specifically validates that the JWT token belongs to a user
-with the "user" role, not an admin.
+with the "user" role, not an admin, and stores the principal in the request
+context.
+
+Parameters:
+- tokens: The token manager used to validate the JWT
+- accounts: Checks that the account is still in good standing (nil skips the check)
 
 The middleware expects:
 - Authorization header with format "Bearer <token>"
 - Valid JWT token with "user" role
 
-It adds to request headers:
-- X-User-ID: The user ID from the token
-- X-User-Role: Set to "user"
-
 If validation fails or token is not a user token, it returns a 401 Unauthorized response.
+Tokens of suspended or banned accounts, of accounts that must reset their
+password and of accounts awaiting verification are refused with 403
+Forbidden, so admin actions take effect before the tokens expire.
 */
-func UserAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "unauthorized: missing or invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
+func UserAuthMiddleware(tokens *jwt.Manager, accounts auth.AccountChecker) func(http.Handler) http.Handler {
+	authenticated := requireRole(tokens, auth.RoleUser, false)
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userID, err := jwt.ValidateUserToken(token)
-		if err != nil {
-			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
-			return
-		}
+	if accounts == nil {
+		return authenticated
+	}
 
-		// Add user info to request context
-		r.Header.Set("X-User-ID", userID)
-		r.Header.Set("X-User-Role", "user")
+	return func(next http.Handler) http.Handler {
+		return authenticated(checkAccount(accounts, next))
+	}
+}
 
-		next.ServeHTTP(w, r)
+/*
+checkAccount asks accounts whether the authenticated principal's account may
+still use its token. Deleted accounts are refused with 401, blocked ones
+with 403 and the reason.
+*/
+func checkAccount(accounts auth.AccountChecker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.FromContext(r.Context())
+
+		err := accounts.CheckAccount(r.Context(), principal.ID)
+		var suspended *auth.SuspendedError
+		switch {
+		case err == nil:
+			next.ServeHTTP(w, r)
+		case errors.Is(err, auth.ErrAccountNotFound):
+			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+		case errors.As(err, &suspended), errors.Is(err, auth.ErrPasswordResetRequired), errors.Is(err, auth.ErrAccountUnverified),
+			errors.Is(err, auth.ErrAccountDisabled):
+			http.Error(w, "forbidden: "+err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "failed to check account", http.StatusInternalServerError)
+		}
 	})
 }
 
 /*
 AdminAuthMiddleware ensures the request is from an authenticated admin.
 This middleware specifically validates that the JWT token belongs to an admin
-with the "admin" role.
+with the "admin" role and stores the principal in the request context.
+
+Parameters:
+- tokens: The token manager used to validate the JWT
+- admins: Checks that the admin account still exists and is enabled (nil skips the check)
 
 The middleware expects:
 - Authorization header with format "Bearer <token>"
 - Valid JWT token with "admin" role
 
-It adds to request headers:
-- X-Admin-ID: The admin ID from the token
-- X-User-Role: Set to "admin"
-
 If validation fails or token is not an admin token, it returns a 401 Unauthorized response.
+Tokens of disabled admins are refused with 403 Forbidden, so disabling an
+admin takes effect before the tokens expire.
 */
-func AdminAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "unauthorized: missing or invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
+func AdminAuthMiddleware(tokens *jwt.Manager, admins auth.AccountChecker) func(http.Handler) http.Handler {
+	authenticated := requireRole(tokens, auth.RoleAdmin, false)
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		adminID, err := jwt.ValidateAdminToken(token)
-		if err != nil {
-			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
-			return
-		}
+	if admins == nil {
+		return authenticated
+	}
 
-		// Add admin info to request context
-		r.Header.Set("X-Admin-ID", adminID)
-		r.Header.Set("X-User-Role", "admin")
+	return func(next http.Handler) http.Handler {
+		return authenticated(checkAccount(admins, next))
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+/*
+SecondFactorMiddleware authenticates the second factor endpoints.
+It admits users and admins, including sessions that still have to verify or
+set up their second factor, which every other authenticated route refuses.
+
+Parameters:
+- tokens: The token manager used to validate the JWT
+*/
+func SecondFactorMiddleware(tokens *jwt.Manager) func(http.Handler) http.Handler {
+	return requireRole(tokens, "", true)
+}
+
+/*
+OptionalAuthMiddleware identifies the caller on public routes without
+requiring authentication.
+When the request carries a valid bearer token, the principal is stored in the
+request context; requests without a token, with an invalid or expired one, or
+with one that still needs a second factor, continue anonymously so public
+pages keep working for logged-out clients.
+
+Parameters:
+- tokens: The token manager used to validate the JWT
+*/
+func OptionalAuthMiddleware(tokens *jwt.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, err := authenticate(tokens, r); err == nil && !principal.NeedsSecondFactor() {
+				r = r.WithContext(auth.NewContext(r.Context(), principal))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+)
+
+func newTestTokens(t *testing.T) *jwt.Manager {
+	t.Helper()
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	return tokens
+}
+
+// capturePrincipal records the principal seen by the wrapped handler.
+func capturePrincipal(got **auth.Principal) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got, _ = auth.FromContext(r.Context())
+	})
+}
+
+func TestUserAuthMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	adminToken, _ := tokens.GenerateJwtToken("admin-1", auth.RoleAdmin)
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantID     string
+	}{
+		{"missing header", "", http.StatusUnauthorized, ""},
+		{"garbage token", "Bearer nope", http.StatusUnauthorized, ""},
+		{"admin token", "Bearer " + adminToken, http.StatusUnauthorized, ""},
+		{"user token", "Bearer " + userToken, http.StatusOK, "user-1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got *auth.Principal
+			req := httptest.NewRequest(http.MethodGet, "/posts/user", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			UserAuthMiddleware(tokens, nil)(capturePrincipal(&got)).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+			if c.wantID == "" {
+				return
+			}
+			if got == nil || got.ID != c.wantID || !got.IsUser() {
+				t.Fatalf("expected user principal %s, got %+v", c.wantID, got)
+			}
+			if got.SessionID == "" {
+				t.Error("expected session ID from jti claim")
+			}
+			if !got.Can(auth.PermPostsWrite) {
+				t.Error("expected user principal to hold posts:write")
+			}
+		})
+	}
+}
+
+// accountCheckerFunc adapts a function to auth.AccountChecker.
+type accountCheckerFunc func(ctx context.Context, userID string) error
+
+func (f accountCheckerFunc) CheckAccount(ctx context.Context, userID string) error {
+	return f(ctx, userID)
+}
+
+func TestUserAuthMiddleware_AccountChecks(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	until := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"good standing", nil, http.StatusOK},
+		{"suspended", &auth.SuspendedError{Reason: "spam", Until: &until}, http.StatusForbidden},
+		{"banned", &auth.SuspendedError{Reason: "spam"}, http.StatusForbidden},
+		{"password reset required", auth.ErrPasswordResetRequired, http.StatusForbidden},
+		{"deleted account", auth.ErrAccountNotFound, http.StatusUnauthorized},
+		{"database down", errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var checked string
+			accounts := accountCheckerFunc(func(ctx context.Context, userID string) error {
+				checked = userID
+				return c.err
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/posts/user", nil)
+			req.Header.Set("Authorization", "Bearer "+userToken)
+			rec := httptest.NewRecorder()
+			UserAuthMiddleware(tokens, accounts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus || checked != "user-1" {
+				t.Fatalf("expected %d after checking user-1, got %d after checking %q", c.wantStatus, rec.Code, checked)
+			}
+			if c.name == "suspended" && !strings.Contains(rec.Body.String(), "suspended until 2026-02-01T00:00:00Z: spam") {
+				t.Errorf("expected suspension details, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	adminToken, _ := tokens.GenerateJwtToken("admin-1", auth.RoleAdmin)
+
+	var got *auth.Principal
+	req := httptest.NewRequest(http.MethodGet, "/admin/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	AdminAuthMiddleware(tokens, nil)(capturePrincipal(&got)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if id, ok := auth.AdminID(auth.NewContext(req.Context(), got)); !ok || id != "admin-1" {
+		t.Errorf("expected admin principal admin-1, got %+v", got)
+	}
+}
+
+func TestAdminAuthMiddleware_AccountChecks(t *testing.T) {
+	tokens := newTestTokens(t)
+	adminToken, _ := tokens.GenerateJwtToken("admin-1", auth.RoleAdmin)
+
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"enabled", nil, http.StatusOK},
+		{"disabled", auth.ErrAccountDisabled, http.StatusForbidden},
+		{"deleted account", auth.ErrAccountNotFound, http.StatusUnauthorized},
+		{"database down", errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var checked string
+			admins := accountCheckerFunc(func(ctx context.Context, adminID string) error {
+				checked = adminID
+				return c.err
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/posts", nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			rec := httptest.NewRecorder()
+			AdminAuthMiddleware(tokens, admins)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus || checked != "admin-1" {
+				t.Fatalf("expected %d after checking admin-1, got %d after checking %q", c.wantStatus, rec.Code, checked)
+			}
+		})
+	}
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+
+	t.Run("anonymous ignores spoofed headers", func(t *testing.T) {
+		var got *auth.Principal
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("X-User-ID", "someone-else")
+		req.Header.Set("X-User-Role", "admin")
+		rec := httptest.NewRecorder()
+		OptionalAuthMiddleware(tokens)(capturePrincipal(&got)).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if got != nil {
+			t.Errorf("expected no principal, got %+v", got)
+		}
+	})
+
+	t.Run("invalid token continues anonymously", func(t *testing.T) {
+		var got *auth.Principal
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("Authorization", "Bearer expired")
+		rec := httptest.NewRecorder()
+		OptionalAuthMiddleware(tokens)(capturePrincipal(&got)).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || got != nil {
+			t.Errorf("expected anonymous 200, got %d %+v", rec.Code, got)
+		}
+	})
+
+	t.Run("valid token identifies caller", func(t *testing.T) {
+		var got *auth.Principal
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		OptionalAuthMiddleware(tokens)(capturePrincipal(&got)).ServeHTTP(httptest.NewRecorder(), req)
+
+		if got == nil || got.ID != "user-1" {
+			t.Errorf("expected principal user-1, got %+v", got)
+		}
+	})
+}
+
+func TestSecondFactorChallenge(t *testing.T) {
+	tokens := newTestTokens(t)
+	challenge, _ := tokens.GenerateChallengeToken("admin-1", auth.RoleAdmin, auth.SecondFactorPending)
+	verified, _ := tokens.GenerateStepUpToken("admin-1", auth.RoleAdmin, auth.SecondFactorVerified)
+
+	serve := func(mw func(http.Handler) http.Handler, token string) (int, *auth.Principal) {
+		var got *auth.Principal
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mw(capturePrincipal(&got)).ServeHTTP(rec, req)
+		return rec.Code, got
+	}
+
+	if code, _ := serve(AdminAuthMiddleware(tokens, nil), challenge); code != http.StatusForbidden {
+		t.Errorf("expected challenge token to be refused on admin routes, got %d", code)
+	}
+	if code, got := serve(SecondFactorMiddleware(tokens), challenge); code != http.StatusOK || !got.NeedsSecondFactor() {
+		t.Errorf("expected challenge token on second factor routes, got %d %+v", code, got)
+	}
+	if _, got := serve(OptionalAuthMiddleware(tokens), challenge); got != nil {
+		t.Errorf("expected challenge token to be anonymous on public routes, got %+v", got)
+	}
+	if code, got := serve(AdminAuthMiddleware(tokens, nil), verified); code != http.StatusOK || got.SecondFactor != auth.SecondFactorVerified {
+		t.Errorf("expected verified token to be admitted, got %d %+v", code, got)
+	}
+}
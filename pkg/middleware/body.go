@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+/*
+MaxBodyBytes limits the JSON request bodies read by utils.ReadJSON on the
+routes it wraps to n bytes; larger bodies are refused with 413 Request
+Entity Too Large. An inner MaxBodyBytes overrides an outer one, so a route
+group can raise or lower the server-wide limit.
+
+Parameters:
+- n: The limit in bytes
+*/
+func MaxBodyBytes(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(utils.WithMaxBodyBytes(r.Context(), n)))
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+)
+
+// ImpersonatedByHeader marks responses to impersonation tokens with the
+// impersonating admin's ID, so frontends can show a banner.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+/*
+ImpersonationMiddleware audits requests made with impersonation tokens.
+Every such request is recorded with its response status, tokens whose
+admin is no longer an enabled super admin are refused with 403, read-only
+tokens are refused with 403 on anything but GET, HEAD and OPTIONS, and
+responses carry the X-Impersonated-By header. Other requests pass untouched; the
+token is still authenticated by the route's own middleware. It must be
+installed before the routes, after the session cookie middleware.
+
+Parameters:
+- tokens: The token manager used to read the token's claims
+- audit: Records the requests; failures are logged and do not fail the request
+- admins: Checks that the impersonating admin is still an enabled super admin
+*/
+func ImpersonationMiddleware(tokens *jwt.Manager, audit auth.ImpersonationAuditor, admins auth.AccountChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			credential, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || strings.HasPrefix(credential, auth.APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := tokens.ParseToken(credential)
+			if err != nil || claims.ImpersonatedBy == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(ImpersonatedByHeader, claims.ImpersonatedBy)
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			err = admins.CheckAccount(r.Context(), claims.ImpersonatedBy)
+			switch {
+			case errors.Is(err, auth.ErrImpersonationRevoked):
+				http.Error(ww, "forbidden: "+err.Error(), http.StatusForbidden)
+			case err != nil:
+				http.Error(ww, "failed to check impersonation", http.StatusInternalServerError)
+			case claims.ReadOnly && !safeMethod(r.Method):
+				http.Error(ww, "forbidden: impersonation token is read-only", http.StatusForbidden)
+			default:
+				next.ServeHTTP(ww, r)
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// The audit record must be written even when the client has gone away.
+			err = audit.RecordImpersonatedRequest(context.WithoutCancel(r.Context()), auth.ImpersonatedRequest{
+				SessionID: claims.ID,
+				AdminID:   claims.ImpersonatedBy,
+				UserID:    claims.UserID,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Status:    status,
+			})
+			if err != nil {
+				log.Printf("failed to record impersonated request %s %s by admin %s: %v", r.Method, r.URL.Path, claims.ImpersonatedBy, err)
+			}
+		})
+	}
+}
+
+/*
+DenyImpersonation refuses impersonation tokens with 403 Forbidden on routes
+that change the account's credentials or hand out its data, such as
+passwords, second factors and exports. It must be installed after the
+route's authentication middleware.
+*/
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.FromContext(r.Context()); ok && principal.IsImpersonated() {
+			http.Error(w, "forbidden: not available while impersonating a user", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+// auditRecorder collects the recorded impersonated requests.
+type auditRecorder []auth.ImpersonatedRequest
+
+func (a *auditRecorder) RecordImpersonatedRequest(ctx context.Context, req auth.ImpersonatedRequest) error {
+	*a = append(*a, req)
+	return nil
+}
+
+// superAdmins admits the impersonations of admin-1 only.
+var superAdmins = accountCheckerFunc(func(ctx context.Context, adminID string) error {
+	if adminID != "admin-1" {
+		return auth.ErrImpersonationRevoked
+	}
+	return nil
+})
+
+func TestImpersonationMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	readOnly, readOnlySession, _ := tokens.GenerateImpersonationToken("user-1", "admin-1", true, time.Minute)
+	readWrite, _, _ := tokens.GenerateImpersonationToken("user-1", "admin-1", false, time.Minute)
+
+	cases := []struct {
+		name       string
+		method     string
+		token      string
+		wantStatus int
+		wantAudit  bool
+	}{
+		{"own token", http.MethodPost, userToken, http.StatusCreated, false},
+		{"read-only read", http.MethodGet, readOnly, http.StatusCreated, true},
+		{"read-only write", http.MethodPost, readOnly, http.StatusForbidden, true},
+		{"read-write write", http.MethodDelete, readWrite, http.StatusCreated, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var audit auditRecorder
+			var got *auth.Principal
+			handler := ImpersonationMiddleware(tokens, &audit, superAdmins)(UserAuthMiddleware(tokens, nil)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got, _ = auth.FromContext(r.Context())
+					w.WriteHeader(http.StatusCreated)
+				})))
+
+			req := httptest.NewRequest(c.method, "/posts", nil)
+			req.Header.Set("Authorization", "Bearer "+c.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, rec.Code)
+			}
+			if !c.wantAudit {
+				if len(audit) != 0 || rec.Header().Get(ImpersonatedByHeader) != "" {
+					t.Fatalf("expected own tokens not to be audited, got %+v", audit)
+				}
+				return
+			}
+			if len(audit) != 1 || audit[0].AdminID != "admin-1" || audit[0].UserID != "user-1" ||
+				audit[0].Method != c.method || audit[0].Path != "/posts" || audit[0].Status != c.wantStatus {
+				t.Fatalf("unexpected audit %+v", audit)
+			}
+			if rec.Header().Get(ImpersonatedByHeader) != "admin-1" {
+				t.Error("expected the X-Impersonated-By header")
+			}
+			if got != nil && (!got.IsImpersonated() || got.ID != "user-1") {
+				t.Errorf("expected an impersonated principal, got %+v", got)
+			}
+		})
+	}
+
+	var audit auditRecorder
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+readOnly)
+	ImpersonationMiddleware(tokens, &audit, superAdmins)(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), req)
+	if len(audit) != 1 || audit[0].SessionID != readOnlySession {
+		t.Errorf("expected the token's session ID in the audit, got %+v", audit)
+	}
+}
+
+func TestImpersonationMiddleware_Revoked(t *testing.T) {
+	tokens := newTestTokens(t)
+	token, _, _ := tokens.GenerateImpersonationToken("user-1", "admin-2", true, time.Minute)
+
+	var audit auditRecorder
+	called := false
+	handler := ImpersonationMiddleware(tokens, &audit, superAdmins)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || called {
+		t.Fatalf("expected 403 without calling the route, got %d", rec.Code)
+	}
+	if len(audit) != 1 || audit[0].AdminID != "admin-2" || audit[0].Status != http.StatusForbidden {
+		t.Errorf("expected the refused request in the audit, got %+v", audit)
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	tokens := newTestTokens(t)
+	userToken, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+	readWrite, _, _ := tokens.GenerateImpersonationToken("user-1", "admin-1", false, time.Minute)
+
+	for token, want := range map[string]int{userToken: http.StatusOK, readWrite: http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/users/me/password", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		UserAuthMiddleware(tokens, nil)(DenyImpersonation(ok)).ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("expected %d, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestAuthenticate_ReadOnlyImpersonation(t *testing.T) {
+	tokens := newTestTokens(t)
+	token, _, _ := tokens.GenerateImpersonationToken("user-1", "admin-1", true, time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/user", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	principal, err := authenticate(tokens, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if principal.ImpersonatorID != "admin-1" || !principal.ReadOnly || principal.Can(auth.PermPostsWrite) {
+		t.Errorf("expected a read-only impersonated principal without write permissions, got %+v", principal)
+	}
+}
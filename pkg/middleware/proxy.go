@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+/*
+TrustedProxy sets the request's RemoteAddr to the client address reported by
+the reverse proxy in front of the server.
+Only the last X-Forwarded-For entry is used: it is the one appended by the
+proxy itself, while earlier entries are supplied by the client and can be
+forged. Install it only when every request passes through such a proxy.
+*/
+func TrustedProxy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
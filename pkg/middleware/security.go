@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+/*
+SecurityHeaderOptions holds the values of the security headers set by
+SecurityHeaders. An empty value leaves the header out.
+*/
+type SecurityHeaderOptions struct {
+	// HSTS is the Strict-Transport-Security value.
+	HSTS                      string
+	ContentSecurityPolicy     string
+	ReferrerPolicy            string
+	FrameOptions              string
+	CrossOriginResourcePolicy string
+}
+
+/*
+SecurityHeaders adds security headers to every response: the configured
+Strict-Transport-Security, Content-Security-Policy, Referrer-Policy,
+X-Frame-Options and Cross-Origin-Resource-Policy, and always
+X-Content-Type-Options: nosniff. Routes can replace or remove any of them
+with HeaderOverrides.
+
+Parameters:
+- opts: The header values
+*/
+func SecurityHeaders(opts SecurityHeaderOptions) func(http.Handler) http.Handler {
+	headers := map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"Strict-Transport-Security":    opts.HSTS,
+		"Content-Security-Policy":      opts.ContentSecurityPolicy,
+		"Referrer-Policy":              opts.ReferrerPolicy,
+		"X-Frame-Options":              opts.FrameOptions,
+		"Cross-Origin-Resource-Policy": opts.CrossOriginResourcePolicy,
+	}
+
+	for name, value := range headers {
+		if value == "" {
+			delete(headers, name)
+		}
+	}
+
+	return setHeaders(headers)
+}
+
+/*
+HeaderOverrides replaces response headers set by outer middlewares, such as
+SecurityHeaders, on the routes it wraps. An empty value removes the header.
+
+Parameters:
+- headers: The header names and their values for these routes
+*/
+func HeaderOverrides(headers map[string]string) func(http.Handler) http.Handler {
+	return setHeaders(headers)
+}
+
+func setHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				if value == "" {
+					w.Header().Del(name)
+				} else {
+					w.Header().Set(name, value)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	secure := SecurityHeaders(SecurityHeaderOptions{
+		HSTS:                      "max-age=63072000",
+		ContentSecurityPolicy:     "default-src 'none'",
+		FrameOptions:              "DENY",
+		CrossOriginResourcePolicy: "same-origin",
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	secure(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts", nil))
+
+	want := map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"Strict-Transport-Security":    "max-age=63072000",
+		"Content-Security-Policy":      "default-src 'none'",
+		"X-Frame-Options":              "DENY",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("expected %s %q, got %q", name, value, got)
+		}
+	}
+	if _, set := rec.Header()["Referrer-Policy"]; set {
+		t.Error("expected an empty Referrer-Policy to be left out")
+	}
+
+	overridden := HeaderOverrides(map[string]string{
+		"Cross-Origin-Resource-Policy": "cross-origin",
+		"X-Frame-Options":              "",
+	})
+
+	rec = httptest.NewRecorder()
+	secure(overridden(ok)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1/avatar", nil))
+
+	if got := rec.Header().Get("Cross-Origin-Resource-Policy"); got != "cross-origin" {
+		t.Errorf("expected the route to override the resource policy, got %q", got)
+	}
+	if _, set := rec.Header()["X-Frame-Options"]; set {
+		t.Error("expected the route to remove X-Frame-Options")
+	}
+}
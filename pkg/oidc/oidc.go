@@ -0,0 +1,501 @@
+/*
+Package oidc implements the relying party side of OpenID Connect login with
+the authorization code flow and PKCE (RFC 7636): provider discovery, the
+authorization URL, the code exchange and verification of the RS256-signed
+ID token against the provider's published keys.
+*/
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// keyRefreshInterval limits how often an unknown key ID triggers a new
+	// fetch of the key set, so forged tokens cannot hammer the provider.
+	keyRefreshInterval = time.Minute
+	// clockSkew is the leeway allowed on ID token timestamps.
+	clockSkew = time.Minute
+	// maxResponseBytes bounds the documents read from the provider.
+	maxResponseBytes = 1 << 20
+)
+
+// ErrInvalidIDToken is returned when the ID token fails verification.
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+/*
+Config identifies the client at one provider.
+*/
+type Config struct {
+	// Issuer is the provider's issuer URL; discovery reads
+	// Issuer + "/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+	// HTTPClient is used for discovery, keys and the code exchange;
+	// nil uses a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+/*
+Claims are the ID token claims used to identify the user.
+*/
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+/*
+idTokenClaims is the decoded ID token. email_verified is a boolean in the
+specification, but some providers send the string "true".
+*/
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+/*
+Provider is an OpenID Connect provider. Its metadata is discovered on first
+use and its keys are cached until a token names an unknown key ID.
+A Provider is safe for concurrent use. Requests to the provider are made
+without holding the cache lock, and concurrent callers share one request.
+*/
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	meta        *metadata
+	metaFetch   *fetch
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+	keysFetch   *fetch
+}
+
+/*
+fetch is a request to the provider in flight, shared by the callers that
+need its result. err is set before done is closed.
+*/
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+// wait waits for the fetch to finish or ctx to end.
+func (f *fetch) wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+NewProvider creates a new Provider. No request is made until the provider
+is first used.
+
+Parameters:
+- cfg: The provider and client settings
+
+Returns:
+- *Provider: A new provider
+*/
+func NewProvider(cfg Config) *Provider {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+/*
+RandomString returns a random URL-safe string of 32 bytes of entropy, used
+for states, nonces and PKCE code verifiers.
+
+Returns:
+- string: The random string
+- error: If the random source fails
+*/
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+/*
+CodeChallenge derives the S256 PKCE code challenge of a code verifier.
+*/
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+/*
+AuthCodeURL builds the URL the browser is sent to for logging in at the
+provider.
+
+Parameters:
+- ctx: Bounds the discovery request, if one is needed
+- state: The opaque value returned to the redirect URL
+- nonce: The value the ID token must carry
+- challenge: The S256 PKCE code challenge
+
+Returns:
+- string: The authorization URL
+- error: If the provider metadata cannot be discovered
+*/
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %v", err)
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(append([]string{"openid"}, p.cfg.Scopes...), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+/*
+Exchange redeems an authorization code at the token endpoint and verifies
+the returned ID token.
+
+Parameters:
+- ctx: Bounds the requests to the provider
+- code: The code from the redirect
+- verifier: The PKCE code verifier of the login
+- nonce: The nonce sent with the authorization request
+
+Returns:
+- *Claims: The verified identity
+- error: Any error that occurred
+
+The ID token must be signed with RS256 by a key of the provider, be issued
+by the configured issuer for this client, not have expired and carry the
+nonce.
+
+Possible errors:
+- "code exchange failed": The provider refused the code
+- ErrInvalidIDToken (wrapped): The ID token failed verification
+*/
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("code exchange failed: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("code exchange failed: no id_token in the response")
+	}
+
+	return p.verify(ctx, meta, tokens.IDToken, nonce)
+}
+
+func (p *Provider) verify(ctx context.Context, meta *metadata, raw, nonce string) (*Claims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, meta, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	verified, _ := claims.EmailVerified.(bool)
+	if s, ok := claims.EmailVerified.(string); ok {
+		verified = s == "true"
+	}
+
+	return &Claims{
+		Subject:           claims.Subject,
+		Email:             strings.ToLower(claims.Email),
+		EmailVerified:     verified,
+		Name:              claims.Name,
+		PreferredUsername: strings.ToLower(claims.PreferredUsername),
+	}, nil
+}
+
+/*
+metadata returns the discovered provider metadata, fetching it on first use.
+Callers arriving while it is fetched wait for that fetch.
+*/
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	for {
+		p.mu.Lock()
+		if p.meta != nil {
+			meta := p.meta
+			p.mu.Unlock()
+			return meta, nil
+		}
+
+		if f := p.metaFetch; f != nil {
+			p.mu.Unlock()
+			if err := f.wait(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		f := &fetch{done: make(chan struct{})}
+		p.metaFetch = f
+		p.mu.Unlock()
+
+		meta, err := p.discover(ctx)
+
+		p.mu.Lock()
+		if err == nil {
+			p.meta = meta
+		}
+		p.metaFetch = nil
+		p.mu.Unlock()
+
+		f.err = err
+		close(f.done)
+
+		return meta, err
+	}
+}
+
+/*
+discover fetches the provider metadata. The discovered issuer must be the
+configured one.
+*/
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("provider discovery failed: %v", err)
+	}
+
+	var meta metadata
+	status, err := p.do(req, &meta)
+	if err != nil {
+		return nil, fmt.Errorf("provider discovery failed: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("provider discovery failed: status %d", status)
+	}
+
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("provider discovery failed: issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("provider discovery failed: incomplete metadata")
+	}
+
+	return &meta, nil
+}
+
+/*
+key returns the RSA key with the given ID, refetching the key set when the
+ID is unknown and the last fetch is older than keyRefreshInterval. Callers
+arriving while the key set is fetched wait for that fetch.
+*/
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (*rsa.PublicKey, error) {
+	for {
+		p.mu.Lock()
+		if k, ok := p.keys[kid]; ok {
+			p.mu.Unlock()
+			return k, nil
+		}
+
+		if f := p.keysFetch; f != nil {
+			p.mu.Unlock()
+			if err := f.wait(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if p.keys != nil && p.now().Sub(p.keysFetched) < keyRefreshInterval {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+
+		f := &fetch{done: make(chan struct{})}
+		p.keysFetch = f
+		p.mu.Unlock()
+
+		keys, err := p.fetchKeys(ctx, meta.JWKSURI)
+
+		p.mu.Lock()
+		if err == nil {
+			p.keys, p.keysFetched = keys, p.now()
+		}
+		p.keysFetch = nil
+		p.mu.Unlock()
+
+		f.err = err
+		close(f.done)
+
+		if err != nil {
+			return nil, err
+		}
+
+		k, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return k, nil
+	}
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, uri string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching provider keys: %v", err)
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.do(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching provider keys: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("error fetching provider keys: status %d", status)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+/*
+do sends a request and decodes the JSON response into v whatever the
+status, since error responses of the token endpoint are JSON as well.
+*/
+func (p *Provider) do(req *http.Request, v any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	if err = json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid response: %v", err)
+	}
+
+	return resp.StatusCode, nil
+}
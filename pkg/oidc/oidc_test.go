@@ -0,0 +1,298 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+mockProvider is a local OpenID Connect provider. Codes are registered with
+the claims of the ID token they redeem for; the token endpoint checks the
+PKCE verifier against the challenge of the authorization request.
+*/
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	codes  map[string]mockCode
+	// keyFetches counts requests for the key set.
+	keyFetches int
+}
+
+type mockCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m := &mockProvider{t: t, key: key, kid: "key-1", codes: map[string]mockCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.keyFetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		c, ok := m.codes[r.FormValue("code")]
+		delete(m.codes, r.FormValue("code"))
+		if !ok || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" ||
+			CodeChallenge(r.FormValue("code_verifier")) != c.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(c.claims)})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+// claims returns valid ID token claims for the nonce.
+func (m *mockProvider) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            "client",
+		"sub":            "subject-1",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "22BCS1234@college.example",
+		"email_verified": "true",
+		"name":           "Test Student",
+	}
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       m.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example/sso/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	m := newMockProvider(t)
+
+	raw, err := m.provider().AuthCodeURL(context.Background(), "state-1", "nonce-1", CodeChallenge("verifier"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != "client" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" ||
+		q.Get("scope") != "openid email profile" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") != CodeChallenge("verifier") || q.Get("redirect_uri") != "https://app.example/sso/callback" {
+		t.Errorf("unexpected authorization URL %s", raw)
+	}
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	verifier, _ := RandomString()
+
+	cases := []struct {
+		name        string
+		modify      func(c jwt.MapClaims)
+		verifier    string
+		wantRefused bool
+		wantErr     error
+	}{
+		{"valid", func(c jwt.MapClaims) {}, verifier, false, nil},
+		{"wrong verifier", func(c jwt.MapClaims) {}, "other", true, nil},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other" }, verifier, false, ErrInvalidIDToken},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, verifier, false, ErrInvalidIDToken},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, verifier, false, ErrInvalidIDToken},
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "replayed" }, verifier, false, ErrInvalidIDToken},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			claims := m.claims("nonce-1")
+			c.modify(claims)
+			m.codes["code-1"] = mockCode{challenge: CodeChallenge(verifier), claims: claims}
+
+			got, err := m.provider().Exchange(context.Background(), "code-1", c.verifier, "nonce-1")
+
+			switch {
+			case c.wantRefused:
+				if err == nil || errors.Is(err, ErrInvalidIDToken) {
+					t.Errorf("expected the code exchange to fail, got %v", err)
+				}
+			case c.wantErr != nil:
+				if !errors.Is(err, c.wantErr) {
+					t.Errorf("expected %v, got %v", c.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if got.Subject != "subject-1" || got.Email != "22bcs1234@college.example" || !got.EmailVerified || got.Name != "Test Student" {
+					t.Errorf("unexpected claims %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_ForgedSignature(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	forger, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims("nonce-1"))
+	token.Header["kid"] = m.kid
+	forged, _ := token.SignedString(forger)
+
+	meta, err := p.metadata(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = p.verify(context.Background(), meta, forged, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("expected a forged token to be refused, got %v", err)
+	}
+
+	// Unknown key IDs do not refetch the key set more than once a minute.
+	token.Header["kid"] = "unknown"
+	forged, _ = token.SignedString(forger)
+	p.verify(context.Background(), meta, forged, "nonce-1")
+	p.verify(context.Background(), meta, forged, "nonce-1")
+	if m.keyFetches != 1 {
+		t.Errorf("expected the key set to be fetched once, got %d", m.keyFetches)
+	}
+}
+
+func TestProvider_SlowDiscovery(t *testing.T) {
+	m := newMockProvider(t)
+
+	var discoveries atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if discoveries.Add(1) == 1 {
+			close(arrived)
+		}
+		<-release
+		m.server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	// The mock names itself as the issuer, so point the provider at it
+	// through the slow server's client.
+	p := m.provider()
+	p.client = &http.Client{Transport: rewriteHost{to: slow.URL}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.metadata(context.Background())
+			errs <- err
+		}()
+	}
+	<-arrived
+
+	// A caller does not wait past its own deadline for another caller's fetch.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.metadata(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to end the wait, got %v", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("expected the caller to give up after its deadline, waited %s", waited)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("expected concurrent callers to share one discovery request, got %d", n)
+	}
+}
+
+// rewriteHost sends every request to another server.
+type rewriteHost struct {
+	to string
+}
+
+func (rt rewriteHost) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(rt.to)
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestProvider_IssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+
+	// A provider whose discovery document names the mock as the issuer.
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	}))
+	defer other.Close()
+
+	p := NewProvider(Config{Issuer: other.URL, ClientID: "client"})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("expected discovery to refuse a different issuer")
+	}
+}
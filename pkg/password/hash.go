@@ -0,0 +1,168 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+var errUnknownHash = errors.New("unknown password hash format")
+
+/*
+Params are the argon2id cost parameters. Memory is in KiB.
+*/
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+/*
+Hasher hashes passwords with argon2id.
+Hashes are stored in the PHC string format, e.g.
+
+	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+
+so each hash carries the parameters it was made with, and raising the
+parameters does not invalidate existing hashes. Legacy bcrypt hashes are
+still verified and reported as needing a rehash.
+*/
+type Hasher struct {
+	params Params
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+/*
+NewHasher creates a hasher that hashes new passwords with params.
+
+Parameters:
+- params: The argon2id cost parameters
+
+Returns:
+- *Hasher: A new hasher
+*/
+func NewHasher(params Params) *Hasher {
+	return &Hasher{params: params}
+}
+
+/*
+Hash hashes a password with a random salt.
+
+Parameters:
+- password: The plain-text password
+
+Returns:
+- string: The encoded hash
+- error: If the random source fails
+*/
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error generating salt: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+/*
+Verify checks a password against a stored hash.
+
+Parameters:
+- password: The plain-text password
+- encoded: The stored argon2id or bcrypt hash
+
+Returns:
+- bool: Whether the password matches
+- bool: Whether the hash is bcrypt or uses other parameters and should be replaced by Hash(password)
+- error: If the stored hash cannot be parsed
+*/
+func (h *Hasher) Verify(password, encoded string) (bool, bool, error) {
+	if strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$") {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("invalid bcrypt hash: %v", err)
+		}
+		return true, true, nil
+	}
+
+	params, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	return true, params != h.params || len(key) != keyLength, nil
+}
+
+/*
+CompareDummy hashes the password against a fixed hash made with the current
+parameters. Logins call it when the account does not exist so that rejecting
+an unknown account takes as long as rejecting a wrong password.
+
+Parameters:
+- password: The plain-text password
+*/
+func (h *Hasher) CompareDummy(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = h.Hash(rand.Text())
+	})
+	_, _, _ = h.Verify(password, h.dummy)
+}
+
+// decode parses a PHC-formatted argon2id hash.
+func decode(encoded string) (Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return Params{}, nil, nil, errUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var p Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 salt: %v", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 key")
+	}
+
+	return p, salt, key, nil
+}
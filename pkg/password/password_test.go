@@ -0,0 +1,115 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testParams = Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	h := NewHasher(testParams)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+
+	match, rehash, err := h.Verify("correct horse", encoded)
+	if err != nil || !match || rehash {
+		t.Errorf("expected match without rehash, got %v %v %v", match, rehash, err)
+	}
+
+	match, _, err = h.Verify("wrong horse", encoded)
+	if err != nil || match {
+		t.Errorf("expected mismatch, got %v %v", match, err)
+	}
+
+	other, _ := h.Hash("correct horse")
+	if other == encoded {
+		t.Error("expected hashes of the same password to use different salts")
+	}
+}
+
+func TestHasher_RehashOnParameterChange(t *testing.T) {
+	encoded, _ := NewHasher(testParams).Hash("correct horse")
+
+	stronger := NewHasher(Params{Memory: 128, Iterations: 2, Parallelism: 1})
+	match, rehash, err := stronger.Verify("correct horse", encoded)
+	if err != nil || !match || !rehash {
+		t.Errorf("expected old parameters to verify and need a rehash, got %v %v %v", match, rehash, err)
+	}
+}
+
+func TestHasher_VerifyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHasher(testParams)
+
+	match, rehash, err := h.Verify("correct horse", string(legacy))
+	if err != nil || !match || !rehash {
+		t.Errorf("expected bcrypt hash to verify and need a rehash, got %v %v %v", match, rehash, err)
+	}
+
+	match, _, err = h.Verify("wrong horse", string(legacy))
+	if err != nil || match {
+		t.Errorf("expected mismatch, got %v %v", match, err)
+	}
+}
+
+func TestHasher_VerifyMalformed(t *testing.T) {
+	h := NewHasher(testParams)
+	for _, encoded := range []string{"", "plaintext", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$"} {
+		if _, _, err := h.Verify("x", encoded); err == nil {
+			t.Errorf("expected %q to be rejected", encoded)
+		}
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(file, []byte("# common passwords\nPassword123\n\nqwertyuiop1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPolicy(10, 2, file)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cases := []struct {
+		password string
+		personal []string
+		wantErr  string
+	}{
+		{"tr0mbone-velvet", nil, ""},
+		{"short1", nil, "at least 10"},
+		{"onlylowercaseletters", nil, "mix at least 2"},
+		{strings.Repeat("ab1", 43), nil, "at most 128"},
+		{"password123", nil, "too common"},
+		{"QWERTYUIOP1", nil, "too common"},
+		{"my22BCS1234pass", []string{"22bcs1234", "Jane"}, "registration number or name"},
+		{"ann-is-1-great", []string{"22bcs1234", "Ann"}, ""},
+	}
+	for _, c := range cases {
+		err := p.Check(c.password, c.personal...)
+		if c.wantErr == "" && err != nil {
+			t.Errorf("Check(%q) unexpected error: %v", c.password, err)
+		}
+		if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+			t.Errorf("Check(%q) = %v; want error containing %q", c.password, err, c.wantErr)
+		}
+	}
+
+	if _, err = NewPolicy(10, 2, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected missing blocklist file to be an error")
+	}
+}
@@ -0,0 +1,134 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds passwords so that hashing cost stays predictable.
+const MaxLength = 128
+
+// minPersonalLength is the shortest name that is checked for in passwords.
+const minPersonalLength = 4
+
+/*
+Policy decides which new passwords are acceptable.
+A nil *Policy accepts every password; the zero value only enforces
+MaxLength.
+*/
+type Policy struct {
+	minLength  int
+	minClasses int
+	blocklist  map[string]struct{}
+}
+
+/*
+NewPolicy creates a password policy.
+
+Parameters:
+- minLength: The minimum length in characters
+- minClasses: How many of lowercase letters, uppercase letters, digits and symbols a password must mix
+- blocklistFile: A file of breached or common passwords, one per line (blank lines and lines starting with # are skipped); empty disables the blocklist
+
+Returns:
+- *Policy: A new policy
+- error: If the blocklist cannot be read
+*/
+func NewPolicy(minLength, minClasses int, blocklistFile string) (*Policy, error) {
+	p := &Policy{minLength: minLength, minClasses: minClasses, blocklist: make(map[string]struct{})}
+
+	if blocklistFile == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(blocklistFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening password blocklist: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading password blocklist: %v", err)
+	}
+
+	return p, nil
+}
+
+/*
+Check validates a new password against the policy.
+
+Parameters:
+- password: The proposed password
+- personal: Identifiers of the account, such as the regno or username, that must not appear in the password
+
+Returns:
+- error: A message describing the first rule the password breaks
+
+Possible errors:
+- "password must be at least N characters"
+- "password must be at most 128 characters"
+- "password must mix at least N of lowercase letters, uppercase letters, digits and symbols"
+- "password is too common; choose another one"
+- "password must not contain your registration number or name"
+*/
+func (p *Policy) Check(password string, personal ...string) error {
+	if p == nil {
+		return nil
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength {
+		return fmt.Errorf("password must be at least %d characters", p.minLength)
+	}
+	if length > MaxLength {
+		return fmt.Errorf("password must be at most %d characters", MaxLength)
+	}
+
+	if classes(password) < p.minClasses {
+		return fmt.Errorf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.minClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := p.blocklist[lower]; ok {
+		return fmt.Errorf("password is too common; choose another one")
+	}
+
+	for _, s := range personal {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if utf8.RuneCountInString(s) >= minPersonalLength && strings.Contains(lower, s) {
+			return fmt.Errorf("password must not contain your registration number or name")
+		}
+	}
+
+	return nil
+}
+
+// classes counts the character classes used in s.
+func classes(s string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/utils"
+)
+
+/*
+Limiter applies one Limit to a group of routes.
+Authenticated callers get a bucket per account and anonymous callers a
+bucket per client IP, so it must be installed after the authentication
+middleware of its group.
+*/
+type Limiter struct {
+	store Store
+	name  string
+	limit Limit
+}
+
+/*
+New creates a Limiter.
+
+Parameters:
+- store: Where the buckets are kept
+- name: The route group name, used to keep the buckets of different groups apart
+- limit: The bucket size and refill window
+
+Returns:
+- *Limiter: A new limiter
+*/
+func New(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{store: store, name: name, limit: limit}
+}
+
+// key returns the bucket key of the caller.
+func (l *Limiter) key(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return l.name + ":" + p.Role + ":" + p.ID
+	}
+	return l.name + ":ip:" + utils.ClientIP(r)
+}
+
+/*
+Middleware takes a token for every request and rejects the request with
+429 Too Many Requests when the caller's bucket is empty.
+
+Every response carries the RateLimit-Limit, RateLimit-Remaining,
+RateLimit-Reset (seconds until the bucket is full) and RateLimit-Policy
+headers; rejected responses add Retry-After. When the store fails the
+request is let through, so an outage of the store does not take the API down.
+*/
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil || !l.limit.Enabled() {
+		return next
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", l.limit.Requests, int(l.limit.Window.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := l.store.Take(r.Context(), l.key(r), l.limit)
+		if err != nil {
+			log.Printf("ratelimit: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", policy)
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			utils.WriteError(w, fmt.Errorf("rate limit exceeded, retry in %s seconds", ceilSeconds(res.RetryAfter)), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
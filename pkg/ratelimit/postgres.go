@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/telemetry"
+)
+
+// refilledTokens is the bucket's stored tokens plus the refill since its last
+// update, capped at the bucket size ($2) with the refill rate per second in $3.
+const refilledTokens = `LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8)`
+
+// takeQuery refills the bucket and takes a token from it when one is
+// available, creating a full bucket on first use.
+var takeQuery = strings.ReplaceAll(`
+		INSERT INTO placement_log_rate_limits AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN {refilled} >= 1 THEN {refilled} - 1 ELSE {refilled} END,
+			allowed = {refilled} >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed;
+	`, "{refilled}", refilledTokens)
+
+// staleAfter is how long an idle bucket is kept. It must exceed the longest
+// configured window, after which an idle bucket is full anyway.
+const staleAfter = 24 * time.Hour
+
+/*
+PostgresStore keeps token buckets in the placement_log_rate_limits table so
+that all replicas share one budget per client.
+Each Take is a single upsert, which locks the bucket row, so concurrent
+requests on different replicas cannot overdraw it. Refill is computed with
+the database clock to avoid skew between replicas.
+*/
+type PostgresStore struct {
+	db          *sql.DB
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+/*
+NewPostgresStore creates a store backed by the given database.
+
+Parameters:
+- db: The primary database connection
+
+Returns:
+- *PostgresStore: A new store instance
+*/
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+/*
+Take removes one token from the bucket under key when one is available.
+
+Parameters:
+- ctx: The request context
+- key: The bucket key
+- limit: The bucket size and refill rate
+
+Returns:
+- Result: Whether the request is allowed and the bucket state
+- error: Any database error; callers should let the request through
+*/
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	defer telemetry.TraceQuery(ctx, "PostgresStore.Take", "INSERT", "placement_log_rate_limits").End()
+
+	s.cleanup(ctx)
+
+	var tokens float64
+	var allowed bool
+	err := s.db.QueryRowContext(ctx, takeQuery, key, limit.Requests, limit.rate()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %v", err)
+	}
+
+	return limit.result(tokens, allowed), nil
+}
+
+// cleanup deletes stale buckets at most once every ten minutes per instance.
+func (s *PostgresStore) cleanup(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastCleanup) < 10*time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	defer telemetry.TraceQuery(ctx, "PostgresStore.cleanup", "DELETE", "placement_log_rate_limits").End()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM placement_log_rate_limits WHERE updated_at < now() - $1::interval`, fmt.Sprintf("%d seconds", int(staleAfter.Seconds())))
+	if err != nil {
+		log.Printf("ratelimit: failed to delete stale buckets: %v", err)
+	}
+}
+
+// Ensure PostgresStore implements Store
+var _ Store = (*PostgresStore)(nil)
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+/*
+Limit is a token-bucket policy: a bucket holds up to Requests tokens and
+refills at Requests per Window, so a client can burst Requests calls and
+then sustain Requests/Window.
+A zero Requests disables limiting.
+*/
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// rate returns the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+/*
+refill returns the tokens in a bucket that held tokens elapsed ago.
+*/
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(l.Requests), tokens+elapsed.Seconds()*l.rate())
+}
+
+/*
+Result describes the outcome of taking a token, in the terms of the
+RateLimit response headers.
+*/
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size.
+	Limit int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, set when not Allowed.
+	RetryAfter time.Duration
+}
+
+/*
+result builds the Result for a bucket left with tokens after the attempt.
+*/
+func (l Limit) result(tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(l.Requests) - tokens) / l.rate()),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+/*
+Store keeps token buckets.
+MemoryStore serves a single instance; PostgresStore shares buckets between
+replicas.
+*/
+type Store interface {
+	// Take removes one token from the bucket under key when one is available.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		res, _ := s.Take(context.Background(), "k", limit)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i+1, 2-i, res)
+		}
+	}
+
+	res, _ := s.Take(context.Background(), "k", limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("expected denial with 1s retry and 3s reset, got %+v", res)
+	}
+
+	now = now.Add(time.Second)
+	if res, _ = s.Take(context.Background(), "k", limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected one refilled token, got %+v", res)
+	}
+
+	if res, _ = s.Take(context.Background(), "other", limit); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("expected separate bucket per key, got %+v", res)
+	}
+}
+
+type mockStore struct {
+	keys     []string
+	TakeFunc func(key string, limit Limit) (Result, error)
+}
+
+func (m *mockStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.keys = append(m.keys, key)
+	return m.TakeFunc(key, limit)
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	limit := Limit{Requests: 2, Window: time.Minute}
+
+	t.Run("headers and denial", func(t *testing.T) {
+		h := New(NewMemoryStore(), "public", limit).Middleware(ok)
+
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			rec = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			req.RemoteAddr = "203.0.113.7:1234"
+			h.ServeHTTP(rec, req)
+			if i == 0 && rec.Header().Get("RateLimit-Remaining") != "1" {
+				t.Errorf("expected 1 remaining, got %q", rec.Header().Get("RateLimit-Remaining"))
+			}
+		}
+
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Policy") != "2;w=60" {
+			t.Errorf("unexpected headers: %v", rec.Header())
+		}
+	})
+
+	t.Run("keys by principal or ip", func(t *testing.T) {
+		store := &mockStore{TakeFunc: func(key string, limit Limit) (Result, error) {
+			return Result{Allowed: true, Limit: limit.Requests}, nil
+		}}
+		h := New(store, "user", limit).Middleware(ok)
+
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		req = req.WithContext(auth.NewContext(req.Context(), auth.NewPrincipal("u1", auth.RoleUser, "s1")))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if store.keys[0] != "user:ip:203.0.113.7" || store.keys[1] != "user:user:u1" {
+			t.Errorf("unexpected keys %v", store.keys)
+		}
+	})
+
+	t.Run("store failure lets request through", func(t *testing.T) {
+		store := &mockStore{TakeFunc: func(key string, limit Limit) (Result, error) {
+			return Result{}, errors.New("db down")
+		}}
+		rec := httptest.NewRecorder()
+		New(store, "user", limit).Middleware(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+)
+
+// CSRFHeader carries the CSRF token of cookie sessions, both in the login
+// response and in every state-changing request.
+const CSRFHeader = "X-CSRF-Token"
+
+/*
+Options configures the session cookies.
+*/
+type Options struct {
+	// CookieName is the HttpOnly cookie holding the token.
+	CookieName string
+	// CSRFCookieName is the cookie holding the CSRF token; scripts can read it.
+	CSRFCookieName string
+	// Domain is the optional cookie domain.
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+/*
+Manager implements the cookie session mode for browser clients.
+Logins store the token in an HttpOnly cookie, out of reach of scripts, and
+Middleware accepts that cookie wherever a bearer token is accepted.
+
+Because browsers attach cookies to cross-site requests, cookie sessions are
+protected by double-submit CSRF tokens: the CSRF token is stored in a second,
+script-readable cookie and must be echoed in the X-CSRF-Token header of
+every state-changing request. It is derived from the session token, so a
+CSRF cookie planted by another site cannot be combined with the victim's
+session, and no server-side state is needed across replicas.
+
+A nil *Manager means cookie sessions are disabled; Issue and Clear then
+leave the response alone.
+*/
+type Manager struct {
+	tokens *jwt.Manager
+	opts   Options
+}
+
+/*
+NewManager creates a new Manager.
+
+Parameters:
+- tokens: The token manager, used to read the expiry of issued tokens
+- opts: The cookie settings
+
+Returns:
+- *Manager: A new session manager
+*/
+func NewManager(tokens *jwt.Manager, opts Options) *Manager {
+	return &Manager{tokens: tokens, opts: opts}
+}
+
+/*
+ParseSameSite converts a configured SameSite mode ("strict", "lax" or
+"none") to its http.SameSite value. Anything else yields lax.
+*/
+func ParseSameSite(mode string) http.SameSite {
+	switch mode {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+/*
+csrfToken derives the CSRF token of a session token.
+*/
+func csrfToken(token string) string {
+	sum := sha256.Sum256([]byte("csrf:" + token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (m *Manager) cookie(name, value string, expires time.Time, httpOnly bool) *http.Cookie {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   m.opts.Domain,
+		Secure:   m.opts.Secure,
+		HttpOnly: httpOnly,
+		SameSite: m.opts.SameSite,
+	}
+
+	if value == "" {
+		c.MaxAge = -1
+	} else {
+		c.Expires = expires
+	}
+
+	return c
+}
+
+/*
+Issue starts a cookie session for a newly issued token.
+
+Parameters:
+- w: The response to set the cookies on
+- token: The issued token
+
+Returns:
+- string: The token to return in the response body; empty in cookie mode, so scripts never see it
+- error: If the token cannot be read
+
+In cookie mode the token cookie and the CSRF cookie expire with the token,
+and the CSRF token is also sent in the X-CSRF-Token response header for
+clients that cannot read the cookie of another origin.
+*/
+func (m *Manager) Issue(w http.ResponseWriter, token string) (string, error) {
+	if m == nil {
+		return token, nil
+	}
+
+	claims, err := m.tokens.ParseToken(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to start session: %v", err)
+	}
+
+	var expires time.Time
+	if claims.ExpiresAt != nil {
+		expires = claims.ExpiresAt.Time
+	}
+
+	csrf := csrfToken(token)
+	http.SetCookie(w, m.cookie(m.opts.CookieName, token, expires, true))
+	http.SetCookie(w, m.cookie(m.opts.CSRFCookieName, csrf, expires, false))
+	w.Header().Set(CSRFHeader, csrf)
+
+	return "", nil
+}
+
+/*
+Clear ends the cookie session of the response's client, if any.
+*/
+func (m *Manager) Clear(w http.ResponseWriter) {
+	if m == nil {
+		return
+	}
+
+	http.SetCookie(w, m.cookie(m.opts.CookieName, "", time.Time{}, true))
+	http.SetCookie(w, m.cookie(m.opts.CSRFCookieName, "", time.Time{}, false))
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+/*
+Middleware lets cookie sessions through the authentication middlewares.
+Requests with an Authorization header are left alone, since browsers never
+add one on their own. Otherwise the session cookie is passed on as the
+bearer token, after checking the X-CSRF-Token header of every request that
+is not GET, HEAD or OPTIONS; a missing or wrong CSRF token is refused with
+403 Forbidden. It must be installed before the authentication middlewares.
+*/
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(m.opts.CookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !safeMethod(r.Method) {
+			got := r.Header.Get(CSRFHeader)
+			if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(csrfToken(cookie.Value))) != 1 {
+				http.Error(w, "forbidden: missing or invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+cookie.Value)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/varnit-ta/PlacementLog/pkg/auth"
+	"github.com/varnit-ta/PlacementLog/pkg/jwt"
+)
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	tokens, err := jwt.NewManager(jwt.Options{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	token, _ := tokens.GenerateJwtToken("user-1", auth.RoleUser)
+
+	return NewManager(tokens, Options{
+		CookieName:     "session",
+		CSRFCookieName: "csrf",
+		Secure:         true,
+		SameSite:       http.SameSiteLaxMode,
+	}), token
+}
+
+func TestManager_Issue(t *testing.T) {
+	m, token := newTestManager(t)
+
+	rec := httptest.NewRecorder()
+	body, err := m.Issue(rec, token)
+	if err != nil || body != "" {
+		t.Fatalf("expected the token to be kept out of the body, got %q %v", body, err)
+	}
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+
+	session, csrf := cookies["session"], cookies["csrf"]
+	if session == nil || session.Value != token || !session.HttpOnly || !session.Secure || session.Expires.IsZero() {
+		t.Errorf("unexpected session cookie %+v", session)
+	}
+	if csrf == nil || csrf.HttpOnly || csrf.Value != csrfToken(token) || rec.Header().Get(CSRFHeader) != csrf.Value {
+		t.Errorf("unexpected CSRF cookie %+v", csrf)
+	}
+
+	var disabled *Manager
+	if body, _ = disabled.Issue(httptest.NewRecorder(), token); body != token {
+		t.Errorf("expected the token in the body without cookie sessions, got %q", body)
+	}
+}
+
+func TestManager_Middleware(t *testing.T) {
+	m, token := newTestManager(t)
+
+	cases := []struct {
+		name       string
+		method     string
+		cookie     bool
+		csrf       string
+		header     string
+		wantStatus int
+		wantAuth   string
+	}{
+		{"no session", http.MethodPost, false, "", "", http.StatusOK, ""},
+		{"cookie read", http.MethodGet, true, "", "", http.StatusOK, "Bearer " + token},
+		{"cookie write without CSRF token", http.MethodPost, true, "", "", http.StatusForbidden, ""},
+		{"cookie write with wrong CSRF token", http.MethodDelete, true, "forged", "", http.StatusForbidden, ""},
+		{"cookie write with CSRF token", http.MethodPost, true, csrfToken(token), "", http.StatusOK, "Bearer " + token},
+		{"bearer token wins", http.MethodPost, true, "", "Bearer other", http.StatusOK, "Bearer other"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var gotAuth string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+			})
+
+			req := httptest.NewRequest(c.method, "/posts", nil)
+			if c.cookie {
+				req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			}
+			if c.csrf != "" {
+				req.Header.Set(CSRFHeader, c.csrf)
+			}
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			m.Middleware(next).ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus || gotAuth != c.wantAuth {
+				t.Errorf("expected %d with %q, got %d with %q", c.wantStatus, c.wantAuth, rec.Code, gotAuth)
+			}
+		})
+	}
+}
+
+func TestManager_Clear(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	rec := httptest.NewRecorder()
+	m.Clear(rec)
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 2 {
+		t.Fatalf("expected both cookies to be cleared, got %v", cookies)
+	}
+	for _, c := range cookies {
+		if c.MaxAge >= 0 || c.Value != "" {
+			t.Errorf("expected %s to be expired, got %+v", c.Name, c)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package telemetry
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+/*
+Registry holds every PlacementLog metric.
+A dedicated registry (instead of prometheus.DefaultRegisterer) keeps /metrics
+free of anything a dependency might register and lets tests read values back.
+*/
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts handled requests by chi route pattern, method and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "placementlog",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes request latency by chi route pattern, method and status code.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "placementlog",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route pattern, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// PostsSubmitted counts posts created by users and waiting for review.
+	PostsSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "placementlog",
+		Name:      "posts_submitted_total",
+		Help:      "Posts submitted by users.",
+	})
+
+	// PostsApproved counts posts approved by admins.
+	PostsApproved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "placementlog",
+		Name:      "posts_approved_total",
+		Help:      "Posts approved by admins.",
+	})
+
+	// PostsRejected counts posts rejected by admins.
+	PostsRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "placementlog",
+		Name:      "posts_rejected_total",
+		Help:      "Posts rejected by admins.",
+	})
+
+	// PlacementsAdded counts placement events recorded by admins.
+	PlacementsAdded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "placementlog",
+		Name:      "placements_added_total",
+		Help:      "Placement events added by admins.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		PostsSubmitted,
+		PostsApproved,
+		PostsRejected,
+		PlacementsAdded,
+	)
+}
+
+/*
+RegisterDBStats exposes the connection pool statistics of a database handle
+(open, in-use and idle connections, wait counts and durations) as metrics.
+
+Parameters:
+- db: The database connection pool
+- name: The value of the db_name label, e.g. "primary"
+
+Returns:
+- error: Any error that occurred during registration (e.g. a duplicate name)
+*/
+func RegisterDBStats(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+/*
+MetricsHandler serves the contents of Registry in the Prometheus text format.
+
+HTTP Method: GET
+Endpoint: /metrics
+*/
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return exporter
+}
+
+func TestMiddleware_RecordsRouteMetricsAndSpan(t *testing.T) {
+	exporter := newTestTracer(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues("/posts/{id}", "GET", "418"))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts/42", nil))
+
+	after := testutil.ToFloat64(HTTPRequests.WithLabelValues("/posts/{id}", "GET", "418"))
+	if after-before != 1 {
+		t.Errorf("expected request counter to increase by 1, got %v", after-before)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /posts/{id}" {
+		t.Errorf("expected span name %q, got %q", "GET /posts/{id}", spans[0].Name)
+	}
+}
+
+func TestMiddleware_UnmatchedRoute(t *testing.T) {
+	newTestTracer(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/posts", func(w http.ResponseWriter, r *http.Request) {})
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues("unmatched", "GET", "404"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/does-not-exist", nil))
+
+	after := testutil.ToFloat64(HTTPRequests.WithLabelValues("unmatched", "GET", "404"))
+	if after-before != 1 {
+		t.Errorf("expected unmatched counter to increase by 1, got %v", after-before)
+	}
+}
+
+func TestTraceQuery(t *testing.T) {
+	exporter := newTestTracer(t)
+
+	ctx, parent := Tracer().Start(context.Background(), "GET /posts")
+	TraceQuery(ctx, "PostsRepo.GetAllPosts", "SELECT", "placement_log_posts").End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() || spans[0].SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("expected the query span to be a child of the request span")
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["db.operation.name"] != "SELECT" {
+		t.Errorf("expected db.operation.name SELECT, got %q", attrs["db.operation.name"])
+	}
+	if attrs["db.collection.name"] != "placement_log_posts" {
+		t.Errorf("expected db.collection.name placement_log_posts, got %q", attrs["db.collection.name"])
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	PostsSubmitted.Inc()
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "placementlog_posts_submitted_total") {
+		t.Error("expected posts_submitted_total in /metrics output")
+	}
+}
@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "placementlog"
+	tracerName  = "github.com/varnit-ta/PlacementLog"
+)
+
+/*
+InitTracing installs a global OpenTelemetry tracer provider that batches spans
+to the given exporter.
+
+Parameters:
+- exporter: The span exporter (OTLP in production, in-memory in tests)
+
+Returns:
+- func(context.Context) error: Flushes pending spans and shuts the provider down
+
+Passing a nil exporter leaves the default no-op provider in place, so spans
+created by the middleware and repositories cost almost nothing.
+*/
+func InitTracing(exporter sdktrace.SpanExporter) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown
+}
+
+/*
+Tracer returns the tracer used for all PlacementLog spans.
+It is resolved on every call so that providers installed after package
+initialization (for example by tests) are picked up.
+*/
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+/*
+TraceQuery starts a client span for a single repository query, as a child of
+the span in ctx (normally the HTTP request span started by Middleware).
+
+Parameters:
+- ctx: The caller's context
+- name: The repository method, e.g. "PostsRepo.GetAllPosts"
+- operation: The SQL operation, e.g. "SELECT", "INSERT", "UPDATE" or "DELETE"
+- table: The main table the query touches
+
+Returns:
+- trace.Span: The started span; callers must End it, typically with defer
+
+Usage:
+
+	defer telemetry.TraceQuery(ctx, "PostsRepo.GetAllPosts", "SELECT", "placement_log_posts").End()
+*/
+func TraceQuery(ctx context.Context, name, operation, table string) trace.Span {
+	_, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+			attribute.String("code.function", name),
+		),
+	)
+
+	return span
+}
@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; the 6-digit codes are their last six digits.
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		got, err := Code(rfcSecret, Step(time.Unix(c.unix, 0)))
+		if err != nil || got != c.want {
+			t.Errorf("Code at %d = %q, %v; want %q", c.unix, got, err, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current, _ := Code(rfcSecret, Step(now))
+	previous, _ := Code(rfcSecret, Step(now)-1)
+	old, _ := Code(rfcSecret, Step(now)-3)
+
+	if step, ok := Validate(rfcSecret, current[:3]+" "+current[3:], now, 1); !ok || step != Step(now) {
+		t.Errorf("expected current code to match step %d, got %d %v", Step(now), step, ok)
+	}
+	if step, ok := Validate(rfcSecret, previous, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("expected previous code to be accepted within skew, got %d %v", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, old, now, 1); ok {
+		t.Error("expected code outside the skew to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now, 1); ok {
+		t.Error("expected short code to be rejected")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("expected a 32 character secret, got %q %v", secret, err)
+	}
+	if _, err = Code(secret, 1); err != nil {
+		t.Errorf("expected generated secret to be usable, got %v", err)
+	}
+
+	uri := ProvisioningURI("PlacementLog", "admin one", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/PlacementLog:admin%20one?") || !strings.Contains(uri, "secret="+secret) || !strings.Contains(uri, "issuer=PlacementLog") {
+		t.Errorf("unexpected provisioning uri %q", uri)
+	}
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxBodyBytes bounds JSON request bodies of routes without their own limit.
+const DefaultMaxBodyBytes = 1 << 20
+
+type maxBodyKey struct{}
+
+/*
+WithMaxBodyBytes returns a context that limits the JSON request bodies read
+by ReadJSON to n bytes. middleware.MaxBodyBytes sets it per route.
+*/
+func WithMaxBodyBytes(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxBodyKey{}, n)
+}
+
+func maxBodyBytes(ctx context.Context) int64 {
+	if n, ok := ctx.Value(maxBodyKey{}).(int64); ok && n > 0 {
+		return n
+	}
+	return DefaultMaxBodyBytes
+}
+
+/*
+RequestError is a client error in a request body. WriteError answers it with
+Status unless the handler passes its own.
+*/
+type RequestError struct {
+	Status int
+	Msg    string
+	err    error
+}
+
+func (e *RequestError) Error() string {
+	return e.Msg
+}
+
+// Unwrap returns the underlying decoding error, e.g. io.EOF for empty bodies.
+func (e *RequestError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(err error, format string, args ...any) *RequestError {
+	return &RequestError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(format, args...), err: err}
+}
+
+/*
+ReadJSON decodes the JSON object in the request body into data.
+
+Parameters:
+- r: The request
+- data: A pointer to the value to decode into
+
+Returns:
+- error: A *RequestError describing the problem, or nil
+
+The body must be sent as application/json (or a +json type), must not exceed
+the route's limit (see WithMaxBodyBytes), must hold exactly one JSON value and
+must not contain fields that data does not have. Errors name the offending
+field and byte offset where possible. An empty body yields an error that
+matches io.EOF, so handlers can treat the body as optional.
+
+Possible errors:
+- 400 Bad Request: Empty, malformed or trailing data, unknown fields or values of the wrong type
+- 413 Request Entity Too Large: The body exceeds the limit
+- 415 Unsupported Media Type: The Content-Type is not JSON
+*/
+func ReadJSON(r *http.Request, data any) error {
+	if r.ContentLength == 0 {
+		return badRequest(io.EOF, "request body must not be empty")
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Msg: "Content-Type must be application/json"}
+		}
+	}
+
+	limit := maxBodyBytes(r.Context())
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, limit))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(data); err != nil {
+		return decodeError(err, dec.InputOffset(), limit)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err, dec.InputOffset(), limit)
+		}
+		return badRequest(err, "request body must contain a single JSON value (unexpected data at byte offset %d)", dec.InputOffset())
+	}
+
+	return nil
+}
+
+/*
+decodeError translates an error from json.Decoder into a *RequestError.
+offset is the decoder's input offset when the error occurred.
+*/
+func decodeError(err error, offset, limit int64) *RequestError {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return badRequest(err, "request body must not be empty")
+	case errors.As(err, &maxErr):
+		return &RequestError{Status: http.StatusRequestEntityTooLarge, Msg: fmt.Sprintf("request body must not exceed %d bytes", limit), err: err}
+	case errors.As(err, &syntaxErr):
+		return badRequest(err, "malformed JSON at byte offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest(err, "malformed JSON: body ends at byte offset %d", offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest(err, "request body must be %s, got %s", jsonType(typeErr.Type), typeErr.Value)
+		}
+		return badRequest(err, "field %q at byte offset %d must be %s, got %s", typeErr.Field, typeErr.Offset, jsonType(typeErr.Type), typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return badRequest(err, "unknown field %s at byte offset %d", field, offset)
+	default:
+		return badRequest(err, "invalid JSON: %v", err)
+	}
+}
+
+// jsonType names the JSON type that values of t are decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return t.String()
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testPayload struct {
+	Name    string         `json:"name"`
+	Count   int            `json:"count"`
+	Details map[string]any `json:"details"`
+}
+
+func TestReadJSON(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		contentType string
+		limit       int64
+		wantStatus  int
+		wantErr     string
+	}{
+		{"valid", `{"name":"a","count":1,"details":{"extra":true}}`, "application/json", 0, 0, ""},
+		{"charset parameter", `{"name":"a"}`, "application/json; charset=utf-8", 0, 0, ""},
+		{"no content type", `{"name":"a"}`, "", 0, 0, ""},
+		{"wrong content type", `{"name":"a"}`, "text/plain", 0, http.StatusUnsupportedMediaType, "Content-Type"},
+		{"unknown field", `{"name":"a","nmae":"b"}`, "application/json", 0, http.StatusBadRequest, `unknown field "nmae" at byte offset`},
+		{"wrong type", `{"name":"a","count":"one"}`, "application/json", 0, http.StatusBadRequest, `field "count" at byte offset 25 must be a number, got string`},
+		{"syntax error", `{"name":"a",}`, "application/json", 0, http.StatusBadRequest, "malformed JSON at byte offset 13"},
+		{"truncated", `{"name":"a"`, "application/json", 0, http.StatusBadRequest, "malformed JSON"},
+		{"trailing data", `{"name":"a"} {"name":"b"}`, "application/json", 0, http.StatusBadRequest, "single JSON value"},
+		{"not an object", `[1,2]`, "application/json", 0, http.StatusBadRequest, "must be an object"},
+		{"too large", `{"name":"` + strings.Repeat("a", 100) + `"}`, "application/json", 50, http.StatusRequestEntityTooLarge, "must not exceed 50 bytes"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			if c.limit > 0 {
+				req = req.WithContext(WithMaxBodyBytes(req.Context(), c.limit))
+			}
+
+			var payload testPayload
+			err := ReadJSON(req, &payload)
+
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) || reqErr.Status != c.wantStatus || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("expected %d %q, got %v", c.wantStatus, c.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadJSON_EmptyBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+
+	var payload testPayload
+	if err := ReadJSON(req, &payload); !errors.Is(err, io.EOF) {
+		t.Errorf("expected an error matching io.EOF, got %v", err)
+	}
+}
+
+func TestWriteError_RequestErrorStatus(t *testing.T) {
+	err := &RequestError{Status: http.StatusRequestEntityTooLarge, Msg: "too large"}
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, err)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, err, http.StatusConflict)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected an explicit status to win, got %d", rec.Code)
+	}
+}
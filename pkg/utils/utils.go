@@ -2,8 +2,12 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
 )
 
 type Response struct {
@@ -11,7 +15,8 @@ type Response struct {
 	Data any  `json:"data"`
 }
 
-func WriteJSON(w http.ResponseWriter, data any, status int, headers ...http.Header) error {
+// EncodeJSON serializes data in the success envelope used by WriteJSON.
+func EncodeJSON(data any) ([]byte, error) {
 	resp := Response{
 		Err:  false,
 		Data: data,
@@ -20,7 +25,17 @@ func WriteJSON(w http.ResponseWriter, data any, status int, headers ...http.Head
 	out, err := json.Marshal(resp)
 
 	if err != nil {
-		return fmt.Errorf("error marshalling data: %v", err)
+		return nil, fmt.Errorf("error marshalling data: %v", err)
+	}
+
+	return out, nil
+}
+
+func WriteJSON(w http.ResponseWriter, data any, status int, headers ...http.Header) error {
+	out, err := EncodeJSON(data)
+
+	if err != nil {
+		return err
 	}
 
 	if len(headers) > 0 {
@@ -40,9 +55,16 @@ func WriteJSON(w http.ResponseWriter, data any, status int, headers ...http.Head
 	return nil
 }
 
+// WriteError writes err in the error envelope with the given status, by
+// default the status of a *RequestError or 400 Bad Request.
 func WriteError(w http.ResponseWriter, err error, status ...int) {
 	code := http.StatusBadRequest
 
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		code = reqErr.Status
+	}
+
 	if len(status) > 0 {
 		code = status[0]
 	}
@@ -59,6 +81,30 @@ func WriteError(w http.ResponseWriter, err error, status ...int) {
 	w.Write(out)
 }
 
-func ReadJSON(r *http.Request, data any) error {
-	return json.NewDecoder(r.Body).Decode(data)
+// ClientIP returns the IP address of the client that sent the request,
+// without the port. Behind a reverse proxy, install middleware.TrustedProxy
+// so that RemoteAddr holds the forwarded client address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+var regnoPattern = regexp.MustCompile(`^\d{2}[a-z]{3}\d{4}$`)
+
+// NormalizeRegno lowercases a registration number (e.g. "22BCS1234") and
+// reports whether it has the expected format.
+func NormalizeRegno(regno string) (string, bool) {
+	regno = strings.ToLower(strings.TrimSpace(regno))
+	return regno, regnoPattern.MatchString(regno)
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID reports whether id is a textual UUID, so that malformed IDs from
+// URLs can be answered with 404 instead of reaching a uuid column.
+func IsUUID(id string) bool {
+	return uuidPattern.MatchString(id)
 }